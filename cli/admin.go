@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"yonatan/labpro/config"
+	"yonatan/labpro/database"
+	"yonatan/labpro/services"
+
+	"golang.org/x/term"
+)
+
+func runAdmin(cfg *config.Config, args []string) int {
+	if len(args) == 0 || args[0] != "create" {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	flags := flag.NewFlagSet("admin create", flag.ContinueOnError)
+	username := flags.String("username", "", "admin username")
+	email := flags.String("email", "", "admin email")
+	password := flags.String("password", "", "temporary admin password")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	// Prompt for anything not given on the command line, so the password can
+	// stay out of shell history. On a terminal the password is read without
+	// echoing it; piped input is read line by line.
+	reader := bufio.NewReader(os.Stdin)
+	for _, field := range []struct {
+		label  string
+		value  *string
+		secret bool
+	}{
+		{"Username", username, false},
+		{"Email", email, false},
+		{"Temporary password", password, true},
+	} {
+		if *field.value != "" {
+			continue
+		}
+		fmt.Printf("%s: ", field.label)
+		var line string
+		if field.secret && term.IsTerminal(int(os.Stdin.Fd())) {
+			secret, _ := term.ReadPassword(int(os.Stdin.Fd()))
+			fmt.Println()
+			line = string(secret)
+		} else {
+			line, _ = reader.ReadString('\n')
+		}
+		*field.value = strings.TrimSpace(line)
+		if *field.value == "" {
+			fmt.Fprintf(os.Stderr, "%s is required\n", field.label)
+			return 2
+		}
+	}
+
+	if len(*password) < 8 {
+		fmt.Fprintln(os.Stderr, "Password must be at least 8 characters")
+		return 2
+	}
+
+	database.Init(cfg.DatabaseURL, cfg.AutoMigrate == "true")
+
+	admin, err := services.NewUserService(database.GetDB()).CreateAdmin(*username, *email, *password)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to create admin:", err)
+		return 1
+	}
+
+	fmt.Printf("Admin %s created, the password must be changed on first login\n", admin.Username)
+	return 0
+}
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"yonatan/labpro/config"
+)
+
+const usage = `Usage: labpro <command> [arguments]
+
+Commands:
+  migrate up [N]     apply all pending migrations, or the next N
+  migrate down [N]   revert the last N applied migrations (default 1)
+  migrate status     list migrations and whether they have been applied
+  admin create       create an admin account; flags: -username, -email, -password
+                     (prompted for when omitted); the password must be changed
+                     on first login
+  trash purge        permanently delete users, courses and modules that have
+                     been in the trash longer than TRASH_RETENTION_DAYS,
+                     along with files nothing else uses
+  orphans scan       list stored files no course, module or revision refers
+                     to, and recorded files that are missing from storage
+  orphans purge      delete the files orphans scan lists
+
+Without a command the HTTP server is started.
+`
+
+// Run executes a management command and returns the process exit code
+func Run(cfg *config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	switch args[0] {
+	case "migrate":
+		return runMigrate(cfg, args[1:])
+	case "admin":
+		return runAdmin(cfg, args[1:])
+	case "trash":
+		return runTrash(cfg, args[1:])
+	case "orphans":
+		return runOrphans(cfg, args[1:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"yonatan/labpro/config"
+	"yonatan/labpro/database"
+)
+
+func runMigrate(cfg *config.Config, args []string) int {
+	if len(args) == 0 || len(args) > 2 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	steps := 0
+	if len(args) == 2 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			fmt.Fprintf(os.Stderr, "Invalid number of steps %q\n", args[1])
+			return 2
+		}
+		steps = n
+	}
+
+	database.Connect(cfg.DatabaseURL)
+	db := database.GetDB()
+
+	switch args[0] {
+	case "up":
+		applied, err := database.MigrateUp(db, steps)
+		for _, migration := range applied {
+			fmt.Printf("Applied %06d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Migration failed:", err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("No pending migrations")
+		}
+	case "down":
+		if steps == 0 {
+			steps = 1
+		}
+		reverted, err := database.MigrateDown(db, steps)
+		for _, migration := range reverted {
+			fmt.Printf("Reverted %06d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Migration failed:", err)
+			return 1
+		}
+		if len(reverted) == 0 {
+			fmt.Println("No applied migrations")
+		}
+	case "status":
+		if len(args) > 1 {
+			fmt.Fprint(os.Stderr, usage)
+			return 2
+		}
+		statuses, err := database.MigrationStatuses(db)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to read migration status:", err)
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%06d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		w.Flush()
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown migrate command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	return 0
+}
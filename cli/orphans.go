@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"yonatan/labpro/config"
+	"yonatan/labpro/database"
+	"yonatan/labpro/services"
+	"yonatan/labpro/storage"
+)
+
+func runOrphans(cfg *config.Config, args []string) int {
+	if len(args) != 1 || (args[0] != "scan" && args[0] != "purge") {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	// Falling back to local disk here could report every real file as missing
+	fileStorage, err := storage.New(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to initialize file storage:", err)
+		return 1
+	}
+
+	database.Init(cfg.DatabaseURL, cfg.AutoMigrate == "true")
+	mediaService := services.NewMediaService(database.GetDB(), cfg, fileStorage)
+
+	var report *services.OrphanReport
+	if args[0] == "scan" {
+		report, err = mediaService.ScanOrphans()
+	} else {
+		report, err = mediaService.PurgeOrphans()
+	}
+	if report == nil {
+		fmt.Fprintln(os.Stderr, "Failed to scan storage:", err)
+		return 1
+	}
+
+	var orphanBytes int64
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ORPHANED FILE\tSIZE\tSTORED AT\tTRACKED")
+	for _, orphan := range report.Orphans {
+		orphanBytes += orphan.Size
+		fmt.Fprintf(w, "%s\t%d\t%s\t%t\n", orphan.Key, orphan.Size, orphan.ModTime.Format("2006-01-02 15:04:05"), orphan.Tracked)
+	}
+	w.Flush()
+
+	if len(report.Missing) > 0 {
+		fmt.Println()
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "MISSING FILE\tKIND\tURL")
+		for _, asset := range report.Missing {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", asset.Key, asset.Kind, asset.URL)
+		}
+		w.Flush()
+	}
+
+	fmt.Printf("\n%d orphaned files (%d bytes), %d missing files\n", len(report.Orphans), orphanBytes, len(report.Missing))
+	if args[0] == "purge" {
+		fmt.Printf("Deleted %d orphaned files\n", report.Deleted)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Some files could not be purged:", err)
+		return 1
+	}
+	return 0
+}
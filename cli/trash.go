@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"yonatan/labpro/config"
+	"yonatan/labpro/database"
+	"yonatan/labpro/services"
+	"yonatan/labpro/storage"
+)
+
+func runTrash(cfg *config.Config, args []string) int {
+	if len(args) != 1 || args[0] != "purge" {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	fileStorage, err := storage.New(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to initialize file storage:", err)
+		return 1
+	}
+
+	database.Init(cfg.DatabaseURL, cfg.AutoMigrate == "true")
+
+	purged, err := services.NewTrashService(database.GetDB(), cfg, nil, fileStorage).Purge()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to purge the trash:", err)
+		return 1
+	}
+
+	fmt.Printf("Purged %d users, %d courses and %d modules\n", purged.Users, purged.Courses, purged.Modules)
+	return 0
+}
@@ -16,8 +16,102 @@ type Config struct {
 	Environment   string
 	BaseURL       string
 	UploadPath    string
-	MaxFileSize   string
 	CloudinaryURL string
+
+	// Uploads are checked by content, not by the type the client claims. Videos
+	// may be up to MaxVideoSize bytes and other files up to MaxFileSize. When
+	// ClamAVAddress points at a clamd daemon, "host:port" or "unix:///socket",
+	// every upload is scanned for malware and refused if the scan fails.
+	MaxFileSize   string
+	MaxVideoSize  string
+	ClamAVAddress string
+
+	// Uploads are stored by StorageDriver: "local" keeps them in UploadPath,
+	// "s3" in an S3-compatible bucket and "cloudinary" on Cloudinary. When it
+	// is empty, Cloudinary is used if CloudinaryURL is set and local disk
+	// otherwise
+	StorageDriver     string
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	S3PublicURL       string
+	S3ForcePathStyle  string
+
+	// Uploaded files are served through /media, which checks course access or a
+	// signed URL. Signed URLs handed to players expire after MediaURLTTLMinutes
+	// and are signed with MediaSigningKey, or JWTSecret when that is empty.
+	MediaURLTTLMinutes string
+	MediaSigningKey    string
+
+	// Module videos are transcoded to HLS with ffmpeg unless VideoTranscoding is
+	// "false". HLSRenditions lists the heights of the renditions to produce.
+	// Course thumbnails are encoded to WebP with the same ffmpeg.
+	VideoTranscoding string
+	FFmpegPath       string
+	FFprobePath      string
+	HLSRenditions    string
+
+	// Large module files can be sent in chunks through the resumable /api/uploads
+	// endpoint. Chunks collect in UploadTempDir, or a directory under the system
+	// temp dir when it is empty, and uploads without a chunk for
+	// ResumableUploadExpiryHours are discarded. ResumableUploadMaxSize is in bytes.
+	UploadTempDir              string
+	ResumableUploadMaxSize     string
+	ResumableUploadExpiryHours string
+
+	// Refunds are allowed within RefundWindowDays of purchase while course
+	// progress is below RefundMaxProgress percent
+	RefundWindowDays  string
+	RefundMaxProgress string
+
+	// AutoMigrate applies pending schema migrations on startup; when disabled
+	// the server refuses to start until `labpro migrate up` has been run
+	AutoMigrate string
+
+	// The first admin account is created from these when no admin exists yet;
+	// it has to change the password on first login
+	BootstrapAdminUsername string
+	BootstrapAdminEmail    string
+	BootstrapAdminPassword string
+
+	// Failed logins are throttled per account and per client IP. Accounts lock
+	// after LoginMaxAttempts failures and IPs after LoginIPMaxAttempts, both for
+	// LoginLockoutMinutes
+	LoginMaxAttempts    string
+	LoginIPMaxAttempts  string
+	LoginLockoutMinutes string
+
+	// Outgoing mail goes through SMTP when MailDriver is "smtp"; any other value
+	// writes messages to MailDir, or to the log when MailDir is empty
+	MailDriver   string
+	MailFrom     string
+	MailDir      string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+
+	// AllowUnverifiedPurchases lets users buy courses before confirming their
+	// email address
+	AllowUnverifiedPurchases string
+
+	// Single sign-on through an OpenID Connect provider is offered when
+	// OIDCIssuerURL and OIDCClientID are set. The redirect URL defaults to
+	// BaseURL + /auth/oidc/callback. Unknown users are created on first login
+	// unless OIDCAutoProvision is "false"
+	OIDCProviderName  string
+	OIDCIssuerURL     string
+	OIDCClientID      string
+	OIDCClientSecret  string
+	OIDCRedirectURL   string
+	OIDCScopes        string
+	OIDCAutoProvision string
+
+	// Deleted users, courses and modules stay in the trash, restorable, for
+	// TrashRetentionDays before they are purged; "0" keeps them forever
+	TrashRetentionDays string
 }
 
 func Load(envFiles ...string) *Config {
@@ -41,8 +135,65 @@ func Load(envFiles ...string) *Config {
 		Environment:   getEnv("ENVIRONMENT", "development"),
 		BaseURL:       getEnv("BASE_URL", "http://localhost:8080"),
 		UploadPath:    getEnv("UPLOAD_PATH", "./uploads"),
-		MaxFileSize:   getEnv("MAX_FILE_SIZE", "10485760"),
 		CloudinaryURL: getEnv("CLOUDINARY_URL", ""),
+
+		MaxFileSize:   getEnv("MAX_FILE_SIZE", "10485760"),
+		MaxVideoSize:  getEnv("MAX_VIDEO_SIZE", "104857600"),
+		ClamAVAddress: getEnv("CLAMAV_ADDRESS", ""),
+
+		StorageDriver:     getEnv("STORAGE_DRIVER", ""),
+		S3Endpoint:        getEnv("S3_ENDPOINT", ""),
+		S3Region:          getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:          getEnv("S3_BUCKET", ""),
+		S3AccessKeyID:     getEnv("S3_ACCESS_KEY_ID", ""),
+		S3SecretAccessKey: getEnv("S3_SECRET_ACCESS_KEY", ""),
+		S3PublicURL:       getEnv("S3_PUBLIC_URL", ""),
+		S3ForcePathStyle:  getEnv("S3_FORCE_PATH_STYLE", "true"),
+
+		MediaURLTTLMinutes: getEnv("MEDIA_URL_TTL_MINUTES", "60"),
+		MediaSigningKey:    getEnv("MEDIA_SIGNING_KEY", ""),
+
+		VideoTranscoding: getEnv("VIDEO_TRANSCODING", "true"),
+		FFmpegPath:       getEnv("FFMPEG_PATH", "ffmpeg"),
+		FFprobePath:      getEnv("FFPROBE_PATH", "ffprobe"),
+		HLSRenditions:    getEnv("HLS_RENDITIONS", "360,720,1080"),
+
+		UploadTempDir:              getEnv("UPLOAD_TEMP_DIR", ""),
+		ResumableUploadMaxSize:     getEnv("RESUMABLE_UPLOAD_MAX_SIZE", "5368709120"),
+		ResumableUploadExpiryHours: getEnv("RESUMABLE_UPLOAD_EXPIRY_HOURS", "24"),
+
+		RefundWindowDays:  getEnv("REFUND_WINDOW_DAYS", "7"),
+		RefundMaxProgress: getEnv("REFUND_MAX_PROGRESS", "30"),
+
+		AutoMigrate: getEnv("AUTO_MIGRATE", "true"),
+
+		BootstrapAdminUsername: getEnv("BOOTSTRAP_ADMIN_USERNAME", "admin"),
+		BootstrapAdminEmail:    getEnv("BOOTSTRAP_ADMIN_EMAIL", ""),
+		BootstrapAdminPassword: getEnv("BOOTSTRAP_ADMIN_PASSWORD", ""),
+
+		LoginMaxAttempts:    getEnv("LOGIN_MAX_ATTEMPTS", "5"),
+		LoginIPMaxAttempts:  getEnv("LOGIN_IP_MAX_ATTEMPTS", "50"),
+		LoginLockoutMinutes: getEnv("LOGIN_LOCKOUT_MINUTES", "15"),
+
+		MailDriver:   getEnv("MAIL_DRIVER", "log"),
+		MailFrom:     getEnv("MAIL_FROM", "Grocademy <no-reply@grocademy.local>"),
+		MailDir:      getEnv("MAIL_DIR", ""),
+		SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+
+		AllowUnverifiedPurchases: getEnv("ALLOW_UNVERIFIED_PURCHASES", "true"),
+
+		OIDCProviderName:  getEnv("OIDC_PROVIDER_NAME", "SSO"),
+		OIDCIssuerURL:     getEnv("OIDC_ISSUER_URL", ""),
+		OIDCClientID:      getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:  getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:   getEnv("OIDC_REDIRECT_URL", ""),
+		OIDCScopes:        getEnv("OIDC_SCOPES", "openid email profile"),
+		OIDCAutoProvision: getEnv("OIDC_AUTO_PROVISION", "true"),
+
+		TrashRetentionDays: getEnv("TRASH_RETENTION_DAYS", "30"),
 	}
 }
 
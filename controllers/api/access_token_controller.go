@@ -0,0 +1,178 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"yonatan/labpro/models"
+	"yonatan/labpro/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AccessTokenAPIController struct {
+	accessTokenService *services.AccessTokenService
+}
+
+func NewAccessTokenAPIController(accessTokenService *services.AccessTokenService) *AccessTokenAPIController {
+	return &AccessTokenAPIController{
+		accessTokenService: accessTokenService,
+	}
+}
+
+// GetTokens godoc
+// @Summary      List personal access tokens
+// @Description  List the current user's personal access tokens that have not been revoked, newest first. The tokens themselves are never shown again; hint holds their last four characters.
+// @Tags         auth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  object{status=string,message=string,data=array}
+// @Failure      401  {object}  object{status=string,message=string,data=object}
+// @Failure      500  {object}  object{status=string,message=string,data=object}
+// @Router       /auth/tokens [get]
+func (atc *AccessTokenAPIController) GetTokens(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status":  "error",
+			"message": "Unauthorized",
+			"data":    nil,
+		})
+		return
+	}
+
+	tokens, err := atc.accessTokenService.GetTokens(user.(models.User).ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to fetch access tokens",
+			"data":    nil,
+		})
+		return
+	}
+
+	result := make([]gin.H, len(tokens))
+	for i := range tokens {
+		result[i] = accessTokenResult(&tokens[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Access tokens retrieved successfully",
+		"data":    result,
+	})
+}
+
+// CreateToken godoc
+// @Summary      Create a personal access token
+// @Description  Create a named token for scripts. Send it as "Authorization: Bearer <token>"; it acts as the current user on the route groups in scopes (courses, modules, instructors, users, admin, me) and nowhere else. The token is returned only in this response. expires_in_days defaults to 30, at most 365. Personal access tokens cannot manage tokens themselves.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        token  body      object{name=string,scopes=[]string,expires_in_days=int}  true  "Token details"
+// @Success      201    {object}  object{status=string,message=string,data=object{id=string,name=string,token=string,hint=string,scopes=[]string,expires_at=string}}
+// @Failure      400    {object}  object{status=string,message=string,data=object}
+// @Failure      401    {object}  object{status=string,message=string,data=object}
+// @Failure      500    {object}  object{status=string,message=string,data=object}
+// @Router       /auth/tokens [post]
+func (atc *AccessTokenAPIController) CreateToken(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status":  "error",
+			"message": "Unauthorized",
+			"data":    nil,
+		})
+		return
+	}
+
+	var req struct {
+		Name          string   `json:"name" binding:"required"`
+		Scopes        []string `json:"scopes" binding:"required"`
+		ExpiresInDays int      `json:"expires_in_days"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid request data",
+			"data":    nil,
+		})
+		return
+	}
+
+	token, plain, err := atc.accessTokenService.CreateToken(user.(models.User).ID, req.Name, req.Scopes, req.ExpiresInDays)
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := "Failed to create access token"
+		if errors.Is(err, services.ErrInvalidTokenName) || errors.Is(err, services.ErrInvalidTokenScopes) || errors.Is(err, services.ErrInvalidTokenExpiry) {
+			status = http.StatusBadRequest
+			message = err.Error()
+		}
+
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": message,
+			"data":    nil,
+		})
+		return
+	}
+
+	result := accessTokenResult(token)
+	result["token"] = plain
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "Access token created, copy it now as it will not be shown again",
+		"data":    result,
+	})
+}
+
+// RevokeToken godoc
+// @Summary      Revoke a personal access token
+// @Description  Revoke one of the current user's personal access tokens; it stops working immediately
+// @Tags         auth
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Token ID"
+// @Success      200  {object}  object{status=string,message=string,data=object}
+// @Failure      401  {object}  object{status=string,message=string,data=object}
+// @Failure      404  {object}  object{status=string,message=string,data=object}
+// @Router       /auth/tokens/{id} [delete]
+func (atc *AccessTokenAPIController) RevokeToken(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status":  "error",
+			"message": "Unauthorized",
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := atc.accessTokenService.RevokeToken(user.(models.User).ID, c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Access token revoked",
+		"data":    nil,
+	})
+}
+
+func accessTokenResult(token *models.PersonalAccessToken) gin.H {
+	return gin.H{
+		"id":           token.ID,
+		"name":         token.Name,
+		"hint":         token.Hint,
+		"scopes":       token.ScopeList(),
+		"last_used_at": token.LastUsedAt,
+		"expires_at":   token.ExpiresAt,
+		"created_at":   token.CreatedAt,
+	}
+}
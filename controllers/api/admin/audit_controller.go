@@ -0,0 +1,91 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"yonatan/labpro/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditAPIController struct {
+	auditService *services.AuditService
+}
+
+func NewAuditAPIController(auditService *services.AuditService) *AuditAPIController {
+	return &AuditAPIController{
+		auditService: auditService,
+	}
+}
+
+// GetAuditEvents godoc
+// @Summary      Get the audit log (requires audit:read)
+// @Description  Get a paginated, filterable log of changes made by staff, newest first. before and after hold only the fields a change touched. With format=csv every matching event is returned as a CSV download instead.
+// @Tags         admin-audit
+// @Produce      json
+// @Produce      text/csv
+// @Security     BearerAuth
+// @Param        actor_id     query     string  false  "Filter by the user who made the change"
+// @Param        action       query     string  false  "Filter by action, e.g. user.delete or course.update"
+// @Param        target_type  query     string  false  "Filter by target type (user, course, module, role, instructor)"
+// @Param        target_id    query     string  false  "Filter by target ID"
+// @Param        q            query     string  false  "Search actor name, action, target ID and IP address"
+// @Param        from         query     string  false  "Only events on or after this date (YYYY-MM-DD)"
+// @Param        to           query     string  false  "Only events on or before this date (YYYY-MM-DD)"
+// @Param        format       query     string  false  "json (default) or csv"
+// @Param        page         query     int     false  "Page number (default: 1)"
+// @Param        limit        query     int     false  "Items per page (default: 20, max: 100)"
+// @Success      200          {object}  object{status=string,message=string,data=array,pagination=object}
+// @Failure      400          {object}  object{status=string,message=string,data=object}
+// @Failure      401          {object}  object{error=string}
+// @Failure      403          {object}  object{error=string}
+// @Failure      500          {object}  object{status=string,message=string,data=object}
+// @Router       /admin/audit [get]
+func (aac *AuditAPIController) GetAuditEvents(c *gin.Context) {
+	// Get query parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filter, err := services.NewAuditFilter(c.Query("actor_id"), c.Query("action"), c.Query("target_type"), c.Query("target_id"), c.Query("q"), c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	if c.Query("format") == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="audit.csv"`)
+		c.Status(http.StatusOK)
+		if err := aac.auditService.ExportCSV(filter, c.Writer); err != nil {
+			c.Error(err)
+		}
+		return
+	}
+
+	events, pagination, err := aac.auditService.GetEvents(filter, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to fetch audit events",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"message":    "Audit events retrieved successfully",
+		"data":       events,
+		"pagination": pagination,
+	})
+}
@@ -1,8 +1,11 @@
 package admin
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
+	"yonatan/labpro/middleware"
 	"yonatan/labpro/models"
 	"yonatan/labpro/services"
 
@@ -20,18 +23,19 @@ func NewCourseAPIController(courseService *services.CourseService) *CourseAPICon
 }
 
 // CreateCourse godoc
-// @Summary      Create a new course (Admin only)
-// @Description  Create a new course with title, description, instructor, price, topics and thumbnail
+// @Summary      Create a new course (requires courses:write)
+// @Description  Create a new course with title, description, instructor, price, topics and thumbnail. New courses start as drafts and stay out of the catalogue until they are published. The instructor is taken from instructor_id, else matched or created by the instructor name, else the author's own instructor profile.
 // @Tags         admin-courses
 // @Accept       multipart/form-data
 // @Produce      json
 // @Security     BearerAuth
-// @Param        title        formData  string   true   "Course title"
-// @Param        description  formData  string   false  "Course description"
-// @Param        instructor   formData  string   true   "Course instructor"
+// @Param        title          formData  string   true   "Course title"
+// @Param        description    formData  string   false  "Course description"
+// @Param        instructor_id  formData  string   false  "Instructor profile ID"
+// @Param        instructor     formData  string   false  "Instructor name"
 // @Param        price        formData  string   true   "Course price"
 // @Param        topics       formData  []string false  "Course topics array"
-// @Param        thumbnail    formData  file     false  "Course thumbnail image"
+// @Param        thumbnail    formData  file     false  "Course thumbnail image, JPEG, PNG, GIF or WebP of at least 640x360. It is cropped to card, detail and social preview sizes."
 // @Success      201          {object}  object{status=string,message=string,data=object}
 // @Failure      400          {object}  object{status=string,message=string,data=object}
 // @Failure      401          {object}  object{error=string}
@@ -46,10 +50,6 @@ func (cac *CourseAPIController) CreateCourse(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
-		return
-	}
 
 	// Handle form data for multipart/form-data
 	title := c.PostForm("title")
@@ -58,10 +58,10 @@ func (cac *CourseAPIController) CreateCourse(c *gin.Context) {
 	priceStr := c.PostForm("price")
 	topics := c.PostFormArray("topics")
 
-	if title == "" || instructor == "" || priceStr == "" {
+	if title == "" || priceStr == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"status":  "error",
-			"message": "Title, instructor, and price are required",
+			"message": "Title and price are required",
 			"data":    nil,
 		})
 		return
@@ -77,13 +77,23 @@ func (cac *CourseAPIController) CreateCourse(c *gin.Context) {
 		return
 	}
 
+	instructorID, err := cac.courseService.ResolveInstructor(userModel, c.PostForm("instructor_id"), instructor)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
 	// Handle thumbnail upload
 	thumbnailURL := ""
 	if file, header, err := c.Request.FormFile("thumbnail_image"); err == nil && header != nil {
 		defer file.Close()
 		thumbnailURL, err = cac.courseService.SaveThumbnail(header)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
+			c.JSON(uploadStatus(err), gin.H{
 				"status":  "error",
 				"message": "Failed to save thumbnail: " + err.Error(),
 				"data":    nil,
@@ -94,15 +104,16 @@ func (cac *CourseAPIController) CreateCourse(c *gin.Context) {
 
 	// Create course
 	course := &models.Course{
-		Title:       title,
-		Description: description,
-		Instructor:  instructor,
-		Price:       price,
-		Thumbnail:   thumbnailURL,
-		Topics:      topics,
+		Title:        title,
+		Description:  description,
+		InstructorID: instructorID,
+		Price:        price,
+		Thumbnail:    thumbnailURL,
+		Topics:       topics,
+		OwnerID:      &userModel.ID,
 	}
 
-	createdCourse, err := cac.courseService.CreateCourse(course)
+	createdCourse, err := cac.courseService.CreateCourse(course, middleware.CurrentActor(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
@@ -120,19 +131,20 @@ func (cac *CourseAPIController) CreateCourse(c *gin.Context) {
 }
 
 // UpdateCourse godoc
-// @Summary      Update a course (Admin only)
-// @Description  Update an existing course with new information
+// @Summary      Update a course (requires courses:write)
+// @Description  Update an existing course with new information. Without courses:write_any only owned courses can be updated. The status and schedule are changed through PUT /courses/{courseId}/status.
 // @Tags         admin-courses
 // @Accept       multipart/form-data
 // @Produce      json
 // @Security     BearerAuth
-// @Param        courseId     path      string   true   "Course ID"
-// @Param        title        formData  string   false  "Course title"
-// @Param        description  formData  string   false  "Course description"
-// @Param        instructor   formData  string   false  "Course instructor"
+// @Param        courseId       path      string   true   "Course ID"
+// @Param        title          formData  string   false  "Course title"
+// @Param        description    formData  string   false  "Course description"
+// @Param        instructor_id  formData  string   false  "Instructor profile ID"
+// @Param        instructor     formData  string   false  "Instructor name"
 // @Param        price        formData  string   false  "Course price"
 // @Param        topics       formData  []string false  "Course topics array"
-// @Param        thumbnail    formData  file     false  "Course thumbnail image"
+// @Param        thumbnail    formData  file     false  "Course thumbnail image, JPEG, PNG, GIF or WebP of at least 640x360. It is cropped to card, detail and social preview sizes."
 // @Success      200          {object}  object{status=string,message=string,data=object}
 // @Failure      400          {object}  object{status=string,message=string,data=object}
 // @Failure      401          {object}  object{error=string}
@@ -148,10 +160,6 @@ func (cac *CourseAPIController) UpdateCourse(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
-		return
-	}
 
 	courseID := c.Param("courseId")
 
@@ -166,6 +174,15 @@ func (cac *CourseAPIController) UpdateCourse(c *gin.Context) {
 		return
 	}
 
+	if canEdit, err := cac.courseService.CanEditCourse(userModel, courseID); err != nil || !canEdit {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  "error",
+			"message": services.ErrCourseNotOwned.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
 	// Handle form data for multipart/form-data
 	title := c.PostForm("title")
 	description := c.PostForm("description")
@@ -173,10 +190,10 @@ func (cac *CourseAPIController) UpdateCourse(c *gin.Context) {
 	priceStr := c.PostForm("price")
 	topics := c.PostFormArray("topics")
 
-	if title == "" || instructor == "" || priceStr == "" {
+	if title == "" || priceStr == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"status":  "error",
-			"message": "Title, instructor, and price are required",
+			"message": "Title and price are required",
 			"data":    nil,
 		})
 		return
@@ -192,20 +209,34 @@ func (cac *CourseAPIController) UpdateCourse(c *gin.Context) {
 		return
 	}
 
+	// Keep the current instructor unless the form names a different one
+	instructorID, _ := existingCourse["instructor_id"].(*string)
+	if c.PostForm("instructor_id") != "" || instructor != "" {
+		instructorID, err = cac.courseService.ResolveInstructor(userModel, c.PostForm("instructor_id"), instructor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": err.Error(),
+				"data":    nil,
+			})
+			return
+		}
+	}
+
 	// Create course object for update, preserving existing thumbnail
 	existingThumbnail := ""
-	if thumbnail, ok := existingCourse["thumbnail_image"].(string); ok {
-		existingThumbnail = thumbnail
+	if thumbnail, ok := existingCourse["thumbnail_image"].(map[string]interface{}); ok {
+		existingThumbnail, _ = thumbnail["src"].(string)
 	}
 
 	course := &models.Course{
-		ID:          courseID,
-		Title:       title,
-		Description: description,
-		Instructor:  instructor,
-		Price:       price,
-		Topics:      topics,
-		Thumbnail:   existingThumbnail, // Preserve existing thumbnail
+		ID:           courseID,
+		Title:        title,
+		Description:  description,
+		InstructorID: instructorID,
+		Price:        price,
+		Topics:       topics,
+		Thumbnail:    existingThumbnail, // Preserve existing thumbnail
 	}
 
 	// Handle thumbnail upload if provided - this will override the preserved thumbnail
@@ -213,7 +244,7 @@ func (cac *CourseAPIController) UpdateCourse(c *gin.Context) {
 		defer file.Close()
 		thumbnailURL, err := cac.courseService.SaveThumbnail(header)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
+			c.JSON(uploadStatus(err), gin.H{
 				"status":  "error",
 				"message": "Failed to save thumbnail: " + err.Error(),
 				"data":    nil,
@@ -223,7 +254,7 @@ func (cac *CourseAPIController) UpdateCourse(c *gin.Context) {
 		course.Thumbnail = thumbnailURL
 	}
 
-	updatedCourse, err := cac.courseService.UpdateCourse(course)
+	updatedCourse, err := cac.courseService.UpdateCourse(course, middleware.CurrentActor(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
@@ -240,9 +271,97 @@ func (cac *CourseAPIController) UpdateCourse(c *gin.Context) {
 	})
 }
 
+// SetCourseStatus godoc
+// @Summary      Change a course's status (requires courses:write)
+// @Description  Move a course through draft, review, published and archived, and schedule it. publish_at publishes a draft or course in review at that time; unpublish_at archives the course once it is published. Omitted timestamps clear the schedule. Only published courses are listed and sold, archived courses stay open to enrolled users. Without courses:write_any only owned courses can be moved, and only between draft and review.
+// @Tags         admin-courses
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        courseId  path      string                                                       true  "Course ID"
+// @Param        status    body      object{status=string,publish_at=string,unpublish_at=string}  true  "New status and optional RFC 3339 schedule"
+// @Success      200       {object}  object{status=string,message=string,data=object}
+// @Failure      400       {object}  object{status=string,message=string,data=object}
+// @Failure      401       {object}  object{error=string}
+// @Failure      403       {object}  object{status=string,message=string,data=object}
+// @Failure      404       {object}  object{status=string,message=string,data=object}
+// @Failure      500       {object}  object{status=string,message=string,data=object}
+// @Router       /courses/{courseId}/status [put]
+func (cac *CourseAPIController) SetCourseStatus(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userModel := user.(models.User)
+	courseID := c.Param("courseId")
+
+	var req struct {
+		Status      string     `json:"status" binding:"required"`
+		PublishAt   *time.Time `json:"publish_at"`
+		UnpublishAt *time.Time `json:"unpublish_at"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	if _, err := cac.courseService.GetCourseByID(courseID, nil); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "Course not found",
+			"data":    nil,
+		})
+		return
+	}
+
+	if canEdit, err := cac.courseService.CanEditCourse(userModel, courseID); err != nil || !canEdit {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  "error",
+			"message": services.ErrCourseNotOwned.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	change := services.CourseStatusChange{Status: req.Status, PublishAt: req.PublishAt, UnpublishAt: req.UnpublishAt}
+	course, err := cac.courseService.SetCourseStatus(userModel, courseID, change, middleware.CurrentActor(c))
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := "Failed to change course status"
+		switch {
+		case errors.Is(err, services.ErrInvalidCourseStatus), errors.Is(err, services.ErrInvalidPublishAt), errors.Is(err, services.ErrInvalidUnpublishAt):
+			status = http.StatusBadRequest
+			message = err.Error()
+		case errors.Is(err, services.ErrCoursePublishDenied):
+			status = http.StatusForbidden
+			message = err.Error()
+		}
+
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": message,
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Course status updated successfully",
+		"data":    course,
+	})
+}
+
 // DeleteCourse godoc
-// @Summary      Delete a course (Admin only)
-// @Description  Delete an existing course and all its associated data
+// @Summary      Delete a course (requires courses:write)
+// @Description  Move a course to the trash together with its modules, enrollments and progress; it can be restored until the trash is purged. Without courses:write_any only owned courses can be deleted.
 // @Tags         admin-courses
 // @Produce      json
 // @Security     BearerAuth
@@ -260,13 +379,18 @@ func (cac *CourseAPIController) DeleteCourse(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+	courseID := c.Param("courseId")
+
+	if canEdit, err := cac.courseService.CanEditCourse(userModel, courseID); err != nil || !canEdit {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  "error",
+			"message": services.ErrCourseNotOwned.Error(),
+			"data":    nil,
+		})
 		return
 	}
 
-	courseID := c.Param("courseId")
-	err := cac.courseService.DeleteCourse(courseID)
+	err := cac.courseService.DeleteCourse(courseID, middleware.CurrentActor(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
@@ -0,0 +1,179 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"yonatan/labpro/middleware"
+	"yonatan/labpro/models"
+	"yonatan/labpro/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InstructorAPIController struct {
+	instructorService *services.InstructorService
+}
+
+func NewInstructorAPIController(instructorService *services.InstructorService) *InstructorAPIController {
+	return &InstructorAPIController{
+		instructorService: instructorService,
+	}
+}
+
+type instructorProfileRequest struct {
+	Name      string   `json:"name"`
+	Bio       string   `json:"bio"`
+	AvatarURL string   `json:"avatar_url"`
+	Links     []string `json:"links"`
+}
+
+// UpdateMyProfile godoc
+// @Summary      Create or update own instructor profile (requires courses:write)
+// @Description  Save the instructor profile linked to the current user, creating it on first use. The name defaults to the user's full name.
+// @Tags         instructors
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request  body      object{name=string,bio=string,avatar_url=string,links=[]string}  true  "Profile"
+// @Success      200      {object}  object{status=string,message=string,data=object}
+// @Failure      400      {object}  object{status=string,message=string,data=object}
+// @Failure      401      {object}  object{error=string}
+// @Failure      403      {object}  object{status=string,message=string,data=object}
+// @Failure      500      {object}  object{status=string,message=string,data=object}
+// @Router       /instructors/me [put]
+func (iac *InstructorAPIController) UpdateMyProfile(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userModel := user.(models.User)
+
+	var req instructorProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	instructor, err := iac.instructorService.SaveOwnProfile(userModel, req.Name, req.Bio, req.AvatarURL, req.Links)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to save instructor profile",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Instructor profile saved successfully",
+		"data":    instructor,
+	})
+}
+
+// GetMyDashboard godoc
+// @Summary      Get own instructor dashboard (requires courses:write)
+// @Description  Enrollments and net revenue of every course taught by the current user's instructor profile
+// @Tags         instructors
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  object{status=string,message=string,data=object}
+// @Failure      401  {object}  object{error=string}
+// @Failure      403  {object}  object{status=string,message=string,data=object}
+// @Failure      404  {object}  object{status=string,message=string,data=object}
+// @Failure      500  {object}  object{status=string,message=string,data=object}
+// @Router       /instructors/me/dashboard [get]
+func (iac *InstructorAPIController) GetMyDashboard(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userModel := user.(models.User)
+
+	dashboard, err := iac.instructorService.GetDashboard(userModel.ID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := "Failed to fetch instructor dashboard"
+		if errors.Is(err, services.ErrInstructorNotFound) {
+			status = http.StatusNotFound
+			message = "You do not have an instructor profile yet"
+		}
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": message,
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Instructor dashboard retrieved successfully",
+		"data":    dashboard,
+	})
+}
+
+// UpdateInstructor godoc
+// @Summary      Update any instructor profile (requires courses:write_any)
+// @Description  Edit an instructor profile. Setting user_id links the profile to that account, e.g. for profiles migrated from free-text instructor names.
+// @Tags         instructors
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path      string  true  "Instructor ID"
+// @Param        request  body      object{name=string,bio=string,avatar_url=string,links=[]string,user_id=string}  true  "Profile"
+// @Success      200      {object}  object{status=string,message=string,data=object}
+// @Failure      400      {object}  object{status=string,message=string,data=object}
+// @Failure      401      {object}  object{error=string}
+// @Failure      403      {object}  object{status=string,message=string,data=object}
+// @Failure      404      {object}  object{status=string,message=string,data=object}
+// @Failure      500      {object}  object{status=string,message=string,data=object}
+// @Router       /instructors/{id} [put]
+func (iac *InstructorAPIController) UpdateInstructor(c *gin.Context) {
+	var req struct {
+		instructorProfileRequest
+		UserID *string `json:"user_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	instructor, err := iac.instructorService.UpdateInstructor(c.Param("id"), req.Name, req.Bio, req.AvatarURL, req.Links, req.UserID, middleware.CurrentActor(c))
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := "Failed to update instructor"
+		switch {
+		case errors.Is(err, services.ErrInstructorNotFound), errors.Is(err, services.ErrUserNotFound):
+			status = http.StatusNotFound
+			message = err.Error()
+		case errors.Is(err, services.ErrInstructorUserTaken):
+			status = http.StatusBadRequest
+			message = err.Error()
+		}
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": message,
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Instructor updated successfully",
+		"data":    instructor,
+	})
+}
@@ -1,11 +1,14 @@
 package admin
 
 import (
+	"errors"
 	"net/http"
+	"yonatan/labpro/middleware"
 	"yonatan/labpro/models"
 	"yonatan/labpro/services"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type ModuleAPIController struct {
@@ -19,7 +22,7 @@ func NewModuleAPIController(moduleService *services.ModuleService) *ModuleAPICon
 }
 
 // CreateModule godoc
-// @Summary      Create a new module (Admin only)
+// @Summary      Create a new module (requires courses:write)
 // @Description  Create a new module for a specific course with title, description, PDF and video files
 // @Tags         admin-modules
 // @Accept       multipart/form-data
@@ -44,13 +47,17 @@ func (mac *ModuleAPIController) CreateModule(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+	courseID := c.Param("courseId")
+
+	if canEdit, err := mac.moduleService.CanEditCourseModules(userModel, courseID); err != nil || !canEdit {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  "error",
+			"message": services.ErrCourseNotOwned.Error(),
+			"data":    nil,
+		})
 		return
 	}
 
-	courseID := c.Param("courseId")
-
 	// Handle form data for multipart/form-data
 	title := c.PostForm("title")
 	description := c.PostForm("description")
@@ -72,7 +79,7 @@ func (mac *ModuleAPIController) CreateModule(c *gin.Context) {
 		defer file.Close()
 		pdfContent, err := mac.moduleService.SavePDF(header)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
+			c.JSON(uploadStatus(err), gin.H{
 				"status":  "error",
 				"message": "Failed to save PDF: " + err.Error(),
 				"data":    nil,
@@ -87,7 +94,7 @@ func (mac *ModuleAPIController) CreateModule(c *gin.Context) {
 		defer file.Close()
 		videoContent, err := mac.moduleService.SaveVideo(header)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
+			c.JSON(uploadStatus(err), gin.H{
 				"status":  "error",
 				"message": "Failed to save video: " + err.Error(),
 				"data":    nil,
@@ -98,7 +105,7 @@ func (mac *ModuleAPIController) CreateModule(c *gin.Context) {
 	}
 
 	// Create module
-	createdModule, err := mac.moduleService.CreateModule(courseID, title, description, pdfURL, videoURL)
+	createdModule, err := mac.moduleService.CreateModule(courseID, title, description, pdfURL, videoURL, middleware.CurrentActor(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
@@ -116,7 +123,7 @@ func (mac *ModuleAPIController) CreateModule(c *gin.Context) {
 }
 
 // UpdateModule godoc
-// @Summary      Update a module (Admin only)
+// @Summary      Update a module (requires courses:write)
 // @Description  Update an existing module with new information
 // @Tags         admin-modules
 // @Accept       multipart/form-data
@@ -142,10 +149,6 @@ func (mac *ModuleAPIController) UpdateModule(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
-		return
-	}
 
 	moduleID := c.Param("id")
 
@@ -160,6 +163,15 @@ func (mac *ModuleAPIController) UpdateModule(c *gin.Context) {
 		return
 	}
 
+	if canEdit, err := mac.moduleService.CanEditModule(userModel, moduleID); err != nil || !canEdit {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  "error",
+			"message": services.ErrCourseNotOwned.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
 	// Handle form data for multipart/form-data
 	title := c.PostForm("title")
 	description := c.PostForm("description")
@@ -191,7 +203,7 @@ func (mac *ModuleAPIController) UpdateModule(c *gin.Context) {
 		defer file.Close()
 		pdfContent, err := mac.moduleService.SavePDF(header)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
+			c.JSON(uploadStatus(err), gin.H{
 				"status":  "error",
 				"message": "Failed to save PDF: " + err.Error(),
 				"data":    nil,
@@ -206,7 +218,7 @@ func (mac *ModuleAPIController) UpdateModule(c *gin.Context) {
 		defer file.Close()
 		videoContent, err := mac.moduleService.SaveVideo(header)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
+			c.JSON(uploadStatus(err), gin.H{
 				"status":  "error",
 				"message": "Failed to save video: " + err.Error(),
 				"data":    nil,
@@ -217,7 +229,7 @@ func (mac *ModuleAPIController) UpdateModule(c *gin.Context) {
 	}
 
 	// Update module
-	updatedModule, err := mac.moduleService.UpdateModule(moduleID, title, description, pdfURL, videoURL)
+	updatedModule, err := mac.moduleService.UpdateModule(moduleID, title, description, pdfURL, videoURL, middleware.CurrentActor(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
@@ -234,9 +246,73 @@ func (mac *ModuleAPIController) UpdateModule(c *gin.Context) {
 	})
 }
 
+// RetranscodeVideo godoc
+// @Summary      Transcode a module video again (requires courses:write)
+// @Description  Queue the module's video for HLS transcoding, after a failed job or for videos uploaded before transcoding was enabled
+// @Tags         admin-modules
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path      string  true  "Module ID"
+// @Success      202 {object}  object{status=string,message=string,data=object}
+// @Failure      400 {object}  object{status=string,message=string,data=object}
+// @Failure      401 {object}  object{error=string}
+// @Failure      403 {object}  object{error=string}
+// @Failure      404 {object}  object{status=string,message=string,data=object}
+// @Router       /modules/{id}/transcode [post]
+func (mac *ModuleAPIController) RetranscodeVideo(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userModel := user.(models.User)
+	moduleID := c.Param("id")
+
+	if canEdit, err := mac.moduleService.CanEditModule(userModel, moduleID); err != nil || !canEdit {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  "error",
+			"message": services.ErrCourseNotOwned.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	module, err := mac.moduleService.RetranscodeVideo(moduleID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "Module not found",
+			"data":    nil,
+		})
+		return
+	case errors.Is(err, services.ErrTranscodingDisabled), errors.Is(err, services.ErrModuleHasNoVideo):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to queue the video for transcoding",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":  "success",
+		"message": "Video queued for transcoding",
+		"data":    module,
+	})
+}
+
 // DeleteModule godoc
-// @Summary      Delete a module (Admin only)
-// @Description  Delete an existing module and all its associated data
+// @Summary      Delete a module (requires courses:write)
+// @Description  Move a module to the trash together with its progress; it can be restored until the trash is purged
 // @Tags         admin-modules
 // @Produce      json
 // @Security     BearerAuth
@@ -254,13 +330,18 @@ func (mac *ModuleAPIController) DeleteModule(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+	moduleID := c.Param("id")
+
+	if canEdit, err := mac.moduleService.CanEditModule(userModel, moduleID); err != nil || !canEdit {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  "error",
+			"message": services.ErrCourseNotOwned.Error(),
+			"data":    nil,
+		})
 		return
 	}
 
-	moduleID := c.Param("id")
-	err := mac.moduleService.DeleteModule(moduleID)
+	err := mac.moduleService.DeleteModule(moduleID, middleware.CurrentActor(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
@@ -274,7 +355,7 @@ func (mac *ModuleAPIController) DeleteModule(c *gin.Context) {
 }
 
 // ReorderModules godoc
-// @Summary      Reorder modules within a course (Admin only)
+// @Summary      Reorder modules within a course (requires courses:write)
 // @Description  Update the order of modules within a specific course
 // @Tags         admin-modules
 // @Accept       json
@@ -296,13 +377,17 @@ func (mac *ModuleAPIController) ReorderModules(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+	courseID := c.Param("courseId")
+
+	if canEdit, err := mac.moduleService.CanEditCourseModules(userModel, courseID); err != nil || !canEdit {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  "error",
+			"message": services.ErrCourseNotOwned.Error(),
+			"data":    nil,
+		})
 		return
 	}
 
-	courseID := c.Param("courseId")
-
 	var req struct {
 		ModuleOrder []struct {
 			ID    string `json:"id" binding:"required"`
@@ -320,7 +405,7 @@ func (mac *ModuleAPIController) ReorderModules(c *gin.Context) {
 	}
 
 	// Reorder modules
-	result, err := mac.moduleService.ReorderModules(courseID, req.ModuleOrder)
+	result, err := mac.moduleService.ReorderModules(courseID, req.ModuleOrder, middleware.CurrentActor(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
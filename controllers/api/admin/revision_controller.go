@@ -0,0 +1,272 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"yonatan/labpro/middleware"
+	"yonatan/labpro/models"
+	"yonatan/labpro/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RevisionAPIController struct {
+	revisionService *services.RevisionService
+}
+
+func NewRevisionAPIController(revisionService *services.RevisionService) *RevisionAPIController {
+	return &RevisionAPIController{
+		revisionService: revisionService,
+	}
+}
+
+// GetCourseRevisions godoc
+// @Summary      List a course's revisions (requires courses:write)
+// @Description  List the saved versions of a course's title, description, price and topics, newest first. Without courses:write_any only owned courses can be inspected.
+// @Tags         admin-revisions
+// @Produce      json
+// @Security     BearerAuth
+// @Param        courseId  path      string  true   "Course ID"
+// @Param        page      query     int     false  "Page number (default: 1)"
+// @Param        limit     query     int     false  "Items per page (default: 20, max: 100)"
+// @Success      200       {object}  object{status=string,message=string,data=array,pagination=object}
+// @Failure      401       {object}  object{error=string}
+// @Failure      403       {object}  object{status=string,message=string,data=object}
+// @Failure      500       {object}  object{status=string,message=string,data=object}
+// @Router       /courses/{courseId}/revisions [get]
+func (rac *RevisionAPIController) GetCourseRevisions(c *gin.Context) {
+	rac.getRevisions(c, models.RevisionTargetCourse, c.Param("courseId"))
+}
+
+// GetCourseRevision godoc
+// @Summary      Compare a course revision (requires courses:write)
+// @Description  Show a course revision and the fields it changed compared with the revision before it, or with the revision given by against
+// @Tags         admin-revisions
+// @Produce      json
+// @Security     BearerAuth
+// @Param        courseId  path      string  true   "Course ID"
+// @Param        number    path      int     true   "Revision number"
+// @Param        against   query     int     false  "Revision number to compare with (default: the previous revision)"
+// @Success      200       {object}  object{status=string,message=string,data=object}
+// @Failure      400       {object}  object{status=string,message=string,data=object}
+// @Failure      401       {object}  object{error=string}
+// @Failure      403       {object}  object{status=string,message=string,data=object}
+// @Failure      404       {object}  object{status=string,message=string,data=object}
+// @Failure      500       {object}  object{status=string,message=string,data=object}
+// @Router       /courses/{courseId}/revisions/{number} [get]
+func (rac *RevisionAPIController) GetCourseRevision(c *gin.Context) {
+	rac.getRevisionDiff(c, models.RevisionTargetCourse, c.Param("courseId"))
+}
+
+// RollbackCourse godoc
+// @Summary      Roll a course back to a revision (requires courses:write)
+// @Description  Restore a course's title, description, price and topics from a revision. The rollback is saved as a new revision.
+// @Tags         admin-revisions
+// @Produce      json
+// @Security     BearerAuth
+// @Param        courseId  path      string  true  "Course ID"
+// @Param        number    path      int     true  "Revision number"
+// @Success      200       {object}  object{status=string,message=string,data=object}
+// @Failure      400       {object}  object{status=string,message=string,data=object}
+// @Failure      401       {object}  object{error=string}
+// @Failure      403       {object}  object{status=string,message=string,data=object}
+// @Failure      404       {object}  object{status=string,message=string,data=object}
+// @Failure      500       {object}  object{status=string,message=string,data=object}
+// @Router       /courses/{courseId}/revisions/{number}/rollback [post]
+func (rac *RevisionAPIController) RollbackCourse(c *gin.Context) {
+	rac.rollback(c, models.RevisionTargetCourse, c.Param("courseId"), "Course", func(id string, number int, actor services.AuditActor) (interface{}, error) {
+		return rac.revisionService.RollbackCourse(id, number, actor)
+	})
+}
+
+// GetModuleRevisions godoc
+// @Summary      List a module's revisions (requires courses:write)
+// @Description  List the saved versions of a module's title, description, PDF and video, newest first. Without courses:write_any only modules of owned courses can be inspected.
+// @Tags         admin-revisions
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id     path      string  true   "Module ID"
+// @Param        page   query     int     false  "Page number (default: 1)"
+// @Param        limit  query     int     false  "Items per page (default: 20, max: 100)"
+// @Success      200    {object}  object{status=string,message=string,data=array,pagination=object}
+// @Failure      401    {object}  object{error=string}
+// @Failure      403    {object}  object{status=string,message=string,data=object}
+// @Failure      500    {object}  object{status=string,message=string,data=object}
+// @Router       /modules/{id}/revisions [get]
+func (rac *RevisionAPIController) GetModuleRevisions(c *gin.Context) {
+	rac.getRevisions(c, models.RevisionTargetModule, c.Param("id"))
+}
+
+// GetModuleRevision godoc
+// @Summary      Compare a module revision (requires courses:write)
+// @Description  Show a module revision and the fields it changed compared with the revision before it, or with the revision given by against
+// @Tags         admin-revisions
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path      string  true   "Module ID"
+// @Param        number   path      int     true   "Revision number"
+// @Param        against  query     int     false  "Revision number to compare with (default: the previous revision)"
+// @Success      200      {object}  object{status=string,message=string,data=object}
+// @Failure      400      {object}  object{status=string,message=string,data=object}
+// @Failure      401      {object}  object{error=string}
+// @Failure      403      {object}  object{status=string,message=string,data=object}
+// @Failure      404      {object}  object{status=string,message=string,data=object}
+// @Failure      500      {object}  object{status=string,message=string,data=object}
+// @Router       /modules/{id}/revisions/{number} [get]
+func (rac *RevisionAPIController) GetModuleRevision(c *gin.Context) {
+	rac.getRevisionDiff(c, models.RevisionTargetModule, c.Param("id"))
+}
+
+// RollbackModule godoc
+// @Summary      Roll a module back to a revision (requires courses:write)
+// @Description  Restore a module's title, description, PDF and video from a revision. Replaced files are kept, so earlier uploads come back as well. The rollback is saved as a new revision.
+// @Tags         admin-revisions
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id      path      string  true  "Module ID"
+// @Param        number  path      int     true  "Revision number"
+// @Success      200     {object}  object{status=string,message=string,data=object}
+// @Failure      400     {object}  object{status=string,message=string,data=object}
+// @Failure      401     {object}  object{error=string}
+// @Failure      403     {object}  object{status=string,message=string,data=object}
+// @Failure      404     {object}  object{status=string,message=string,data=object}
+// @Failure      500     {object}  object{status=string,message=string,data=object}
+// @Router       /modules/{id}/revisions/{number}/rollback [post]
+func (rac *RevisionAPIController) RollbackModule(c *gin.Context) {
+	rac.rollback(c, models.RevisionTargetModule, c.Param("id"), "Module", func(id string, number int, actor services.AuditActor) (interface{}, error) {
+		return rac.revisionService.RollbackModule(id, number, actor)
+	})
+}
+
+// authorize applies the edit rule of the course or module and writes the
+// response when it fails
+func (rac *RevisionAPIController) authorize(c *gin.Context, targetType, targetID string) bool {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return false
+	}
+
+	if canEdit, err := rac.revisionService.CanEditTarget(user.(models.User), targetType, targetID); err != nil || !canEdit {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  "error",
+			"message": services.ErrCourseNotOwned.Error(),
+			"data":    nil,
+		})
+		return false
+	}
+	return true
+}
+
+func (rac *RevisionAPIController) getRevisions(c *gin.Context, targetType, targetID string) {
+	if !rac.authorize(c, targetType, targetID) {
+		return
+	}
+
+	// Get query parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	revisions, pagination, err := rac.revisionService.GetRevisions(targetType, targetID, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to fetch revisions",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"message":    "Revisions retrieved successfully",
+		"data":       revisions,
+		"pagination": pagination,
+	})
+}
+
+func (rac *RevisionAPIController) getRevisionDiff(c *gin.Context, targetType, targetID string) {
+	if !rac.authorize(c, targetType, targetID) {
+		return
+	}
+
+	number, err := strconv.Atoi(c.Param("number"))
+	against, againstErr := strconv.Atoi(c.DefaultQuery("against", "0"))
+	if err != nil || againstErr != nil || number < 1 || against < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid revision number",
+			"data":    nil,
+		})
+		return
+	}
+
+	diff, err := rac.revisionService.GetRevisionDiff(targetType, targetID, number, against)
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := "Failed to compare revisions"
+		if errors.Is(err, services.ErrRevisionNotFound) {
+			status = http.StatusNotFound
+			message = err.Error()
+		}
+
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": message,
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Revision retrieved successfully",
+		"data":    diff,
+	})
+}
+
+func (rac *RevisionAPIController) rollback(c *gin.Context, targetType, targetID, label string, rollback func(string, int, services.AuditActor) (interface{}, error)) {
+	if !rac.authorize(c, targetType, targetID) {
+		return
+	}
+
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil || number < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid revision number",
+			"data":    nil,
+		})
+		return
+	}
+
+	restored, err := rollback(targetID, number, middleware.CurrentActor(c))
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := "Failed to roll back " + label
+		if errors.Is(err, services.ErrRevisionNotFound) {
+			status = http.StatusNotFound
+			message = err.Error()
+		}
+
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": message,
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": label + " rolled back to revision " + strconv.Itoa(number),
+		"data":    restored,
+	})
+}
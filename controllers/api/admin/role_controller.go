@@ -0,0 +1,214 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"yonatan/labpro/middleware"
+	"yonatan/labpro/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RoleAPIController struct {
+	roleService *services.RoleService
+}
+
+func NewRoleAPIController(roleService *services.RoleService) *RoleAPIController {
+	return &RoleAPIController{
+		roleService: roleService,
+	}
+}
+
+// GetRoles godoc
+// @Summary      List roles
+// @Description  List every role with the permissions it grants
+// @Tags         admin-roles
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  object{status=string,message=string,data=array}
+// @Failure      401  {object}  object{status=string,message=string,data=object}
+// @Failure      403  {object}  object{status=string,message=string,data=object}
+// @Failure      500  {object}  object{status=string,message=string,data=object}
+// @Router       /admin/roles [get]
+func (rac *RoleAPIController) GetRoles(c *gin.Context) {
+	roles, err := rac.roleService.GetRoles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to fetch roles",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Roles retrieved successfully",
+		"data":    roles,
+	})
+}
+
+// GetUserRoles godoc
+// @Summary      Get a user's roles
+// @Description  List the roles assigned to a user
+// @Tags         admin-roles
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "User ID"
+// @Success      200  {object}  object{status=string,message=string,data=array}
+// @Failure      401  {object}  object{status=string,message=string,data=object}
+// @Failure      403  {object}  object{status=string,message=string,data=object}
+// @Failure      404  {object}  object{status=string,message=string,data=object}
+// @Router       /admin/users/{id}/roles [get]
+func (rac *RoleAPIController) GetUserRoles(c *gin.Context) {
+	roles, err := rac.roleService.GetUserRoles(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "User roles retrieved successfully",
+		"data":    roles,
+	})
+}
+
+// AssignRole godoc
+// @Summary      Assign a role
+// @Description  Grant a role to a user. Granting the admin role also makes the user an admin.
+// @Tags         admin-roles
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path      string              true  "User ID"
+// @Param        request  body      object{role=string} true  "Role name"
+// @Success      200      {object}  object{status=string,message=string,data=object}
+// @Failure      400      {object}  object{status=string,message=string,data=object}
+// @Failure      401      {object}  object{status=string,message=string,data=object}
+// @Failure      403      {object}  object{status=string,message=string,data=object}
+// @Failure      404      {object}  object{status=string,message=string,data=object}
+// @Router       /admin/users/{id}/roles [post]
+func (rac *RoleAPIController) AssignRole(c *gin.Context) {
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := rac.roleService.AssignRole(c.Param("id"), req.Role, middleware.CurrentActor(c)); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrRoleNotFound) || errors.Is(err, services.ErrUserNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Role assigned successfully",
+		"data":    nil,
+	})
+}
+
+// RemoveRole godoc
+// @Summary      Remove a role
+// @Description  Take a role away from a user. The last remaining admin cannot lose the admin role.
+// @Tags         admin-roles
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id    path      string  true  "User ID"
+// @Param        role  path      string  true  "Role name"
+// @Success      200   {object}  object{status=string,message=string,data=object}
+// @Failure      400   {object}  object{status=string,message=string,data=object}
+// @Failure      401   {object}  object{status=string,message=string,data=object}
+// @Failure      403   {object}  object{status=string,message=string,data=object}
+// @Failure      404   {object}  object{status=string,message=string,data=object}
+// @Router       /admin/users/{id}/roles/{role} [delete]
+func (rac *RoleAPIController) RemoveRole(c *gin.Context) {
+	if err := rac.roleService.RemoveRole(c.Param("id"), c.Param("role"), middleware.CurrentActor(c)); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrRoleNotFound) || errors.Is(err, services.ErrRoleNotAssigned) || errors.Is(err, services.ErrUserNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Role removed successfully",
+		"data":    nil,
+	})
+}
+
+// SetRoleMFA godoc
+// @Summary      Require two-factor authentication for a role
+// @Description  Turn mandatory two-factor authentication on or off for every holder of the role. Holders without it are refused by permission-protected routes until they enable it.
+// @Tags         admin-roles
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        role  path      string                    true  "Role name"
+// @Param        mfa   body      object{require_mfa=bool}  true  "Whether the role requires two-factor authentication"
+// @Success      200   {object}  object{status=string,message=string,data=object}
+// @Failure      400   {object}  object{status=string,message=string,data=object}
+// @Failure      401   {object}  object{status=string,message=string,data=object}
+// @Failure      403   {object}  object{status=string,message=string,data=object}
+// @Failure      404   {object}  object{status=string,message=string,data=object}
+// @Router       /admin/roles/{role}/mfa [put]
+func (rac *RoleAPIController) SetRoleMFA(c *gin.Context) {
+	var req struct {
+		RequireMFA *bool `json:"require_mfa" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	role, err := rac.roleService.SetRequireMFA(c.Param("role"), *req.RequireMFA, middleware.CurrentActor(c))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrRoleNotFound) {
+			status = http.StatusNotFound
+		}
+
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Role updated successfully",
+		"data":    role,
+	})
+}
@@ -0,0 +1,57 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"yonatan/labpro/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StatsAPIController struct {
+	statsService *services.StatsService
+}
+
+func NewStatsAPIController(statsService *services.StatsService) *StatsAPIController {
+	return &StatsAPIController{
+		statsService: statsService,
+	}
+}
+
+// GetStats godoc
+// @Summary      Get platform statistics (requires stats:read)
+// @Description  Retrieve totals, daily purchases, revenue per course and completion funnels
+// @Tags         admin-stats
+// @Produce      json
+// @Security     BearerAuth
+// @Param        days  query     int  false  "Number of days in the daily purchases series (default: 30, max: 365)"
+// @Success      200   {object}  object{status=string,message=string,data=object}
+// @Failure      401   {object}  object{error=string}
+// @Failure      403   {object}  object{error=string}
+// @Failure      500   {object}  object{status=string,message=string,data=object}
+// @Router       /admin/stats [get]
+func (sac *StatsAPIController) GetStats(c *gin.Context) {
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if days < 1 {
+		days = 30
+	}
+	if days > 365 {
+		days = 365
+	}
+
+	stats, err := sac.statsService.GetDashboardStats(days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to fetch statistics",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Statistics retrieved successfully",
+		"data":    stats,
+	})
+}
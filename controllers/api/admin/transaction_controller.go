@@ -0,0 +1,107 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"yonatan/labpro/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TransactionAPIController struct {
+	transactionService *services.TransactionService
+}
+
+func NewTransactionAPIController(transactionService *services.TransactionService) *TransactionAPIController {
+	return &TransactionAPIController{
+		transactionService: transactionService,
+	}
+}
+
+// GetTransactions godoc
+// @Summary      Get balance transactions (requires transactions:read)
+// @Description  Get a paginated, filterable ledger of balance changes across all users, newest first
+// @Tags         admin-transactions
+// @Produce      json
+// @Security     BearerAuth
+// @Param        user_id    query     string  false  "Filter by user ID"
+// @Param        type       query     string  false  "Filter by type (topup, purchase, refund, adjustment, opening_balance)"
+// @Param        course_id  query     string  false  "Filter by course ID"
+// @Param        from       query     string  false  "Only transactions on or after this date (YYYY-MM-DD)"
+// @Param        to         query     string  false  "Only transactions on or before this date (YYYY-MM-DD)"
+// @Param        page       query     int     false  "Page number (default: 1)"
+// @Param        limit      query     int     false  "Items per page (default: 20, max: 100)"
+// @Success      200        {object}  object{status=string,message=string,data=array,pagination=object}
+// @Failure      400        {object}  object{status=string,message=string,data=object}
+// @Failure      401        {object}  object{error=string}
+// @Failure      403        {object}  object{error=string}
+// @Failure      500        {object}  object{status=string,message=string,data=object}
+// @Router       /admin/transactions [get]
+func (tac *TransactionAPIController) GetTransactions(c *gin.Context) {
+	// Get query parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filter, err := services.NewTransactionFilter(c.Query("user_id"), c.Query("type"), c.Query("course_id"), c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	transactions, pagination, err := tac.transactionService.GetTransactions(filter, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to fetch transactions",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"message":    "Transactions retrieved successfully",
+		"data":       transactions,
+		"pagination": pagination,
+	})
+}
+
+// ReconcileUserBalance godoc
+// @Summary      Reconcile a user's balance against the ledger (requires transactions:read)
+// @Description  Compare the stored balance of a user with the sum of their ledger entries
+// @Tags         admin-transactions
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path      string  true  "User ID"
+// @Success      200 {object}  object{status=string,message=string,data=object{user_id=string,balance=number,ledger_balance=number,difference=number,transaction_count=int,is_consistent=bool}}
+// @Failure      401 {object}  object{error=string}
+// @Failure      403 {object}  object{error=string}
+// @Failure      404 {object}  object{status=string,message=string,data=object}
+// @Router       /admin/users/{id}/reconciliation [get]
+func (tac *TransactionAPIController) ReconcileUserBalance(c *gin.Context) {
+	reconciliation, err := tac.transactionService.Reconcile(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "User not found",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Balance reconciled successfully",
+		"data":    reconciliation,
+	})
+}
@@ -0,0 +1,185 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"yonatan/labpro/middleware"
+	"yonatan/labpro/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TrashAPIController struct {
+	trashService *services.TrashService
+}
+
+func NewTrashAPIController(trashService *services.TrashService) *TrashAPIController {
+	return &TrashAPIController{
+		trashService: trashService,
+	}
+}
+
+// GetTrashedUsers godoc
+// @Summary      List deleted users (requires users:write)
+// @Description  List users in the trash, most recently deleted first. They can be restored until purge_at, when they and their enrollments are deleted for good; purge_at is null when the trash is never purged.
+// @Tags         admin-trash
+// @Produce      json
+// @Security     BearerAuth
+// @Param        q      query     string  false  "Search username, name and email"
+// @Param        page   query     int     false  "Page number (default: 1)"
+// @Param        limit  query     int     false  "Items per page (default: 20, max: 100)"
+// @Success      200    {object}  object{status=string,message=string,data=array,pagination=object}
+// @Failure      401    {object}  object{error=string}
+// @Failure      403    {object}  object{error=string}
+// @Failure      500    {object}  object{status=string,message=string,data=object}
+// @Router       /admin/trash/users [get]
+func (tac *TrashAPIController) GetTrashedUsers(c *gin.Context) {
+	tac.getTrash(c, services.TrashUsers)
+}
+
+// GetTrashedCourses godoc
+// @Summary      List deleted courses (requires courses:write_any)
+// @Description  List courses in the trash, most recently deleted first. detail holds the instructor's name.
+// @Tags         admin-trash
+// @Produce      json
+// @Security     BearerAuth
+// @Param        q      query     string  false  "Search course titles"
+// @Param        page   query     int     false  "Page number (default: 1)"
+// @Param        limit  query     int     false  "Items per page (default: 20, max: 100)"
+// @Success      200    {object}  object{status=string,message=string,data=array,pagination=object}
+// @Failure      401    {object}  object{error=string}
+// @Failure      403    {object}  object{error=string}
+// @Failure      500    {object}  object{status=string,message=string,data=object}
+// @Router       /admin/trash/courses [get]
+func (tac *TrashAPIController) GetTrashedCourses(c *gin.Context) {
+	tac.getTrash(c, services.TrashCourses)
+}
+
+// GetTrashedModules godoc
+// @Summary      List deleted modules (requires courses:write_any)
+// @Description  List modules in the trash, most recently deleted first, including those deleted along with their course. detail holds the course title; course_in_trash tells whether the course has to be restored instead.
+// @Tags         admin-trash
+// @Produce      json
+// @Security     BearerAuth
+// @Param        q      query     string  false  "Search module titles"
+// @Param        page   query     int     false  "Page number (default: 1)"
+// @Param        limit  query     int     false  "Items per page (default: 20, max: 100)"
+// @Success      200    {object}  object{status=string,message=string,data=array,pagination=object}
+// @Failure      401    {object}  object{error=string}
+// @Failure      403    {object}  object{error=string}
+// @Failure      500    {object}  object{status=string,message=string,data=object}
+// @Router       /admin/trash/modules [get]
+func (tac *TrashAPIController) GetTrashedModules(c *gin.Context) {
+	tac.getTrash(c, services.TrashModules)
+}
+
+// RestoreUser godoc
+// @Summary      Restore a deleted user (requires users:write)
+// @Description  Bring a user back from the trash together with the enrollments and progress deleted with them. Fails with 409 when another user has taken the username or email since.
+// @Tags         admin-trash
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "User ID"
+// @Success      200  {object}  object{status=string,message=string,data=object}
+// @Failure      401  {object}  object{error=string}
+// @Failure      403  {object}  object{error=string}
+// @Failure      404  {object}  object{status=string,message=string,data=object}
+// @Failure      409  {object}  object{status=string,message=string,data=object}
+// @Failure      500  {object}  object{status=string,message=string,data=object}
+// @Router       /admin/trash/users/{id}/restore [post]
+func (tac *TrashAPIController) RestoreUser(c *gin.Context) {
+	tac.restore(c, "User", tac.trashService.RestoreUser)
+}
+
+// RestoreCourse godoc
+// @Summary      Restore a deleted course (requires courses:write_any)
+// @Description  Bring a course back from the trash together with the modules, enrollments and progress deleted with it
+// @Tags         admin-trash
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Course ID"
+// @Success      200  {object}  object{status=string,message=string,data=object}
+// @Failure      401  {object}  object{error=string}
+// @Failure      403  {object}  object{error=string}
+// @Failure      404  {object}  object{status=string,message=string,data=object}
+// @Failure      500  {object}  object{status=string,message=string,data=object}
+// @Router       /admin/trash/courses/{id}/restore [post]
+func (tac *TrashAPIController) RestoreCourse(c *gin.Context) {
+	tac.restore(c, "Course", tac.trashService.RestoreCourse)
+}
+
+// RestoreModule godoc
+// @Summary      Restore a deleted module (requires courses:write_any)
+// @Description  Bring a module back from the trash together with the progress deleted with it. Fails with 409 while the module's course is in the trash.
+// @Tags         admin-trash
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Module ID"
+// @Success      200  {object}  object{status=string,message=string,data=object}
+// @Failure      401  {object}  object{error=string}
+// @Failure      403  {object}  object{error=string}
+// @Failure      404  {object}  object{status=string,message=string,data=object}
+// @Failure      409  {object}  object{status=string,message=string,data=object}
+// @Failure      500  {object}  object{status=string,message=string,data=object}
+// @Router       /admin/trash/modules/{id}/restore [post]
+func (tac *TrashAPIController) RestoreModule(c *gin.Context) {
+	tac.restore(c, "Module", tac.trashService.RestoreModule)
+}
+
+func (tac *TrashAPIController) getTrash(c *gin.Context, itemType string) {
+	// Get query parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	items, pagination, err := tac.trashService.GetTrash(itemType, c.Query("q"), page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to fetch the trash",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"message":    "Trash retrieved successfully",
+		"data":       items,
+		"pagination": pagination,
+	})
+}
+
+func (tac *TrashAPIController) restore(c *gin.Context, label string, restore func(string, services.AuditActor) error) {
+	if err := restore(c.Param("id"), middleware.CurrentActor(c)); err != nil {
+		status := http.StatusInternalServerError
+		message := "Failed to restore " + label
+		switch {
+		case errors.Is(err, services.ErrNotInTrash):
+			status = http.StatusNotFound
+			message = err.Error()
+		case errors.Is(err, services.ErrRestoreConflict), errors.Is(err, services.ErrCourseInTrash):
+			status = http.StatusConflict
+			message = err.Error()
+		}
+
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": message,
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": label + " restored successfully",
+		"data":    nil,
+	})
+}
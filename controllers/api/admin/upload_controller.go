@@ -0,0 +1,377 @@
+package admin
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"yonatan/labpro/filecheck"
+	"yonatan/labpro/middleware"
+	"yonatan/labpro/models"
+	"yonatan/labpro/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// tusVersion is the version of the tus resumable upload protocol spoken here
+const tusVersion = "1.0.0"
+
+// statusChecksumMismatch is the status tus gives to chunks that fail their checksum
+const statusChecksumMismatch = 460
+
+type UploadAPIController struct {
+	uploadService *services.UploadService
+}
+
+func NewUploadAPIController(uploadService *services.UploadService) *UploadAPIController {
+	return &UploadAPIController{
+		uploadService: uploadService,
+	}
+}
+
+type attachUploadRequest struct {
+	ModuleID string `json:"module_id" binding:"required"`
+}
+
+// tusResumable sets the protocol header every tus response carries and checks
+// the client speaks the same version
+func tusResumable(c *gin.Context) bool {
+	c.Header("Tus-Resumable", tusVersion)
+	if c.GetHeader("Tus-Resumable") != tusVersion {
+		c.Header("Tus-Version", tusVersion)
+		c.JSON(http.StatusPreconditionFailed, gin.H{
+			"status":  "error",
+			"message": "Unsupported Tus-Resumable version",
+			"data":    nil,
+		})
+		return false
+	}
+	return true
+}
+
+// uploadHeaders describes an upload's progress in tus headers
+func uploadHeaders(c *gin.Context, upload *models.ResumableUpload) {
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Received, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Header("Cache-Control", "no-store")
+}
+
+// parseUploadMetadata decodes an Upload-Metadata header, comma separated pairs
+// of a key and a base64 value
+func parseUploadMetadata(header string) (map[string]string, bool) {
+	metadata := map[string]string{}
+	if strings.TrimSpace(header) == "" {
+		return metadata, true
+	}
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, false
+		}
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, false
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, true
+}
+
+// uploadStatus is 400 for files the upload checks refused and 500 when saving
+// failed for another reason
+func uploadStatus(err error) int {
+	if filecheck.IsRejected(err) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// uploadError answers a failed upload request. Errors the client can act on
+// get their own status; anything else is a server error.
+func uploadError(c *gin.Context, err error, message string) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrUploadNotFound):
+		status, message = http.StatusNotFound, err.Error()
+	case errors.Is(err, services.ErrUploadTooLarge), errors.Is(err, filecheck.ErrTooLarge):
+		status, message = http.StatusRequestEntityTooLarge, err.Error()
+	case errors.Is(err, services.ErrUploadType):
+		status, message = http.StatusUnsupportedMediaType, err.Error()
+	case errors.Is(err, services.ErrUploadOffsetMismatch):
+		status, message = http.StatusConflict, err.Error()
+	case errors.Is(err, services.ErrUploadLocked):
+		status, message = http.StatusLocked, err.Error()
+	case errors.Is(err, services.ErrUploadChecksumInvalid), errors.Is(err, services.ErrUploadChecksumAlgorithm):
+		status, message = http.StatusBadRequest, err.Error()
+	case errors.Is(err, services.ErrUploadChecksumMismatch):
+		status, message = statusChecksumMismatch, err.Error()
+	case errors.Is(err, services.ErrUploadIncomplete):
+		status, message = http.StatusConflict, err.Error()
+	case filecheck.IsRejected(err):
+		status, message = http.StatusBadRequest, err.Error()
+	}
+	c.JSON(status, gin.H{
+		"status":  "error",
+		"message": message,
+		"data":    nil,
+	})
+}
+
+// Options godoc
+// @Summary      Describe the resumable upload endpoint
+// @Description  tus discovery: the protocol version, extensions, checksum algorithms and largest upload accepted
+// @Tags         admin-uploads
+// @Success      204
+// @Router       /uploads [options]
+func (uac *UploadAPIController) Options(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+	c.Header("Tus-Version", tusVersion)
+	c.Header("Tus-Extension", "creation,expiration,checksum,termination")
+	c.Header("Tus-Max-Size", strconv.FormatInt(uac.uploadService.MaxSize(), 10))
+	c.Header("Tus-Checksum-Algorithm", strings.Join(services.UploadChecksumAlgorithms, ","))
+	c.Status(http.StatusNoContent)
+}
+
+// CreateUpload godoc
+// @Summary      Start a resumable upload (requires courses:write)
+// @Description  tus creation: reserve an upload of Upload-Length bytes for a module video or PDF. Upload-Metadata carries the base64 encoded filename, whose extension decides between video and PDF. Send the file to the returned Location with PATCH.
+// @Tags         admin-uploads
+// @Produce      json
+// @Security     BearerAuth
+// @Param        Tus-Resumable    header  string  true   "Protocol version, 1.0.0"
+// @Param        Upload-Length    header  int     true   "File size in bytes"
+// @Param        Upload-Metadata  header  string  true   "filename, base64 encoded"
+// @Success      201
+// @Failure      400 {object}  object{status=string,message=string,data=object}
+// @Failure      401 {object}  object{error=string}
+// @Failure      403 {object}  object{error=string}
+// @Failure      412 {object}  object{status=string,message=string,data=object}
+// @Failure      413 {object}  object{status=string,message=string,data=object}
+// @Failure      415 {object}  object{status=string,message=string,data=object}
+// @Router       /uploads [post]
+func (uac *UploadAPIController) CreateUpload(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	if !tusResumable(c) {
+		return
+	}
+
+	size, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || size <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Upload-Length must be a positive number of bytes",
+			"data":    nil,
+		})
+		return
+	}
+	metadata, ok := parseUploadMetadata(c.GetHeader("Upload-Metadata"))
+	if !ok || metadata["filename"] == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Upload-Metadata must carry a filename",
+			"data":    nil,
+		})
+		return
+	}
+
+	upload, err := uac.uploadService.Create(user.(models.User), size, metadata["filename"], metadata["filetype"])
+	if err != nil {
+		uploadError(c, err, "Failed to start the upload")
+		return
+	}
+
+	c.Header("Location", strings.TrimSuffix(c.Request.URL.Path, "/")+"/"+upload.ID)
+	uploadHeaders(c, upload)
+	c.Status(http.StatusCreated)
+}
+
+// GetUploadOffset godoc
+// @Summary      Check how much of an upload has arrived (requires courses:write)
+// @Description  tus: Upload-Offset tells where to resume sending the file
+// @Tags         admin-uploads
+// @Security     BearerAuth
+// @Param        Tus-Resumable  header  string  true  "Protocol version, 1.0.0"
+// @Param        id             path    string  true  "Upload ID"
+// @Success      200
+// @Failure      401 {object}  object{error=string}
+// @Failure      404
+// @Router       /uploads/{id} [head]
+func (uac *UploadAPIController) GetUploadOffset(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+	if !tusResumable(c) {
+		return
+	}
+
+	upload, err := uac.uploadService.Get(user.(models.User), c.Param("id"))
+	if errors.Is(err, services.ErrUploadNotFound) {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	uploadHeaders(c, upload)
+	c.Status(http.StatusOK)
+}
+
+// PatchUpload godoc
+// @Summary      Send a chunk of an upload (requires courses:write)
+// @Description  tus: append the request body at Upload-Offset. With Upload-Checksum ("sha256 <base64 digest>", sha1 or md5) a chunk that does not match is discarded and answered with 460.
+// @Tags         admin-uploads
+// @Accept       application/offset+octet-stream
+// @Security     BearerAuth
+// @Param        Tus-Resumable    header  string  true   "Protocol version, 1.0.0"
+// @Param        Upload-Offset    header  int     true   "Bytes received so far"
+// @Param        Upload-Checksum  header  string  false  "Checksum of the chunk"
+// @Param        id               path    string  true   "Upload ID"
+// @Success      204
+// @Failure      400 {object}  object{status=string,message=string,data=object}
+// @Failure      401 {object}  object{error=string}
+// @Failure      404 {object}  object{status=string,message=string,data=object}
+// @Failure      409 {object}  object{status=string,message=string,data=object}
+// @Failure      415 {object}  object{status=string,message=string,data=object}
+// @Failure      460 {object}  object{status=string,message=string,data=object}
+// @Router       /uploads/{id} [patch]
+func (uac *UploadAPIController) PatchUpload(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	if !tusResumable(c) {
+		return
+	}
+
+	if c.ContentType() != "application/offset+octet-stream" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"status":  "error",
+			"message": "Chunks must be sent as application/offset+octet-stream",
+			"data":    nil,
+		})
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Upload-Offset must be a number of bytes",
+			"data":    nil,
+		})
+		return
+	}
+
+	upload, err := uac.uploadService.WriteChunk(user.(models.User), c.Param("id"), offset, c.GetHeader("Upload-Checksum"), c.Request.Body)
+	if err != nil {
+		if upload != nil {
+			uploadHeaders(c, upload)
+		}
+		uploadError(c, err, "Failed to save the chunk")
+		return
+	}
+
+	uploadHeaders(c, upload)
+	c.Status(http.StatusNoContent)
+}
+
+// DeleteUpload godoc
+// @Summary      Abandon an upload (requires courses:write)
+// @Description  tus termination: discard an upload and what it received
+// @Tags         admin-uploads
+// @Security     BearerAuth
+// @Param        Tus-Resumable  header  string  true  "Protocol version, 1.0.0"
+// @Param        id             path    string  true  "Upload ID"
+// @Success      204
+// @Failure      401 {object}  object{error=string}
+// @Failure      404 {object}  object{status=string,message=string,data=object}
+// @Router       /uploads/{id} [delete]
+func (uac *UploadAPIController) DeleteUpload(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	if !tusResumable(c) {
+		return
+	}
+
+	if err := uac.uploadService.Terminate(user.(models.User), c.Param("id")); err != nil {
+		uploadError(c, err, "Failed to delete the upload")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// AttachUpload godoc
+// @Summary      Attach a finished upload to a module (requires courses:write)
+// @Description  Check a complete upload's content and move it into storage as the module's video or PDF. Refused files are discarded with 400. Videos are queued for transcoding.
+// @Tags         admin-uploads
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path      string               true  "Upload ID"
+// @Param        request  body      attachUploadRequest  true  "Module to attach the file to"
+// @Success      200      {object}  object{status=string,message=string,data=object}
+// @Failure      400      {object}  object{status=string,message=string,data=object}
+// @Failure      401      {object}  object{error=string}
+// @Failure      403      {object}  object{status=string,message=string,data=object}
+// @Failure      404      {object}  object{status=string,message=string,data=object}
+// @Failure      409      {object}  object{status=string,message=string,data=object}
+// @Router       /uploads/{id}/attach [post]
+func (uac *UploadAPIController) AttachUpload(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req attachUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "module_id is required",
+			"data":    nil,
+		})
+		return
+	}
+
+	module, err := uac.uploadService.Attach(user.(models.User), c.Param("id"), req.ModuleID, middleware.CurrentActor(c))
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "Module not found",
+			"data":    nil,
+		})
+		return
+	case errors.Is(err, services.ErrModuleAccessDenied):
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  "error",
+			"message": services.ErrCourseNotOwned.Error(),
+			"data":    nil,
+		})
+		return
+	case err != nil:
+		uploadError(c, err, "Failed to attach the upload")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Upload attached to the module",
+		"data":    module,
+	})
+}
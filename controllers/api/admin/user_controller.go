@@ -1,8 +1,10 @@
 package admin
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"yonatan/labpro/middleware"
 	"yonatan/labpro/models"
 	"yonatan/labpro/services"
 
@@ -10,17 +12,21 @@ import (
 )
 
 type UserAPIController struct {
-	userService *services.UserService
+	userService   *services.UserService
+	loginThrottle *services.LoginThrottle
+	mfaService    *services.MFAService
 }
 
-func NewUserAPIController(userService *services.UserService) *UserAPIController {
+func NewUserAPIController(userService *services.UserService, loginThrottle *services.LoginThrottle, mfaService *services.MFAService) *UserAPIController {
 	return &UserAPIController{
-		userService: userService,
+		userService:   userService,
+		loginThrottle: loginThrottle,
+		mfaService:    mfaService,
 	}
 }
 
 // GetUsers godoc
-// @Summary      Get all users with pagination (Admin only)
+// @Summary      Get all users with pagination (requires users:read)
 // @Description  Retrieve a paginated list of all users with optional search functionality
 // @Tags         admin-users
 // @Produce      json
@@ -34,18 +40,6 @@ func NewUserAPIController(userService *services.UserService) *UserAPIController
 // @Failure      500   {object} object{status=string,message=string,data=object}
 // @Router       /users [get]
 func (uac *UserAPIController) GetUsers(c *gin.Context) {
-	user, exists := c.Get("user")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-		return
-	}
-
-	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
-		return
-	}
-
 	// Get query parameters
 	query := c.Query("q")
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
@@ -74,8 +68,8 @@ func (uac *UserAPIController) GetUsers(c *gin.Context) {
 }
 
 // GetUserByID godoc
-// @Summary      Get user details by ID (Admin only)
-// @Description  Retrieve detailed information for a specific user by their ID
+// @Summary      Get user details by ID (requires users:read)
+// @Description  Retrieve detailed information for a specific user by their ID. login_locked_for is the number of seconds the account is still locked after failed logins.
 // @Tags         admin-users
 // @Produce      json
 // @Security     BearerAuth
@@ -86,18 +80,6 @@ func (uac *UserAPIController) GetUsers(c *gin.Context) {
 // @Failure      404 {object}  object{status=string,message=string,data=object}
 // @Router       /users/{id} [get]
 func (uac *UserAPIController) GetUserByID(c *gin.Context) {
-	user, exists := c.Get("user")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-		return
-	}
-
-	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
-		return
-	}
-
 	userID := c.Param("id")
 	targetUser, err := uac.userService.GetUserByID(userID)
 	if err != nil {
@@ -108,6 +90,7 @@ func (uac *UserAPIController) GetUserByID(c *gin.Context) {
 		})
 		return
 	}
+	targetUser["login_locked_for"] = services.RetryAfterSeconds(uac.loginThrottle.LockedFor(userID))
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "success",
@@ -117,7 +100,7 @@ func (uac *UserAPIController) GetUserByID(c *gin.Context) {
 }
 
 // UpdateUserBalance godoc
-// @Summary      Update user balance (Admin only)
+// @Summary      Update user balance (requires balances:write)
 // @Description  Increment or decrement a user's balance by a specific amount
 // @Tags         admin-users
 // @Accept       json
@@ -132,18 +115,11 @@ func (uac *UserAPIController) GetUserByID(c *gin.Context) {
 // @Failure      500      {object}  object{status=string,message=string,data=object}
 // @Router       /users/{id}/balance [post]
 func (uac *UserAPIController) UpdateUserBalance(c *gin.Context) {
-	user, exists := c.Get("user")
-	if !exists {
+	if _, exists := c.Get("user"); !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
-		return
-	}
-
 	userID := c.Param("id")
 
 	var req struct {
@@ -160,7 +136,7 @@ func (uac *UserAPIController) UpdateUserBalance(c *gin.Context) {
 	}
 
 	// Update user balance
-	updatedUser, err := uac.userService.UpdateUserBalance(userID, req.Increment)
+	updatedUser, err := uac.userService.UpdateUserBalance(userID, req.Increment, middleware.CurrentActor(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
@@ -184,7 +160,7 @@ func (uac *UserAPIController) UpdateUserBalance(c *gin.Context) {
 }
 
 // UpdateUser godoc
-// @Summary      Update user information (Admin only)
+// @Summary      Update user information (requires users:write)
 // @Description  Update a user's profile information including email, username, names, and optionally password
 // @Tags         admin-users
 // @Accept       json
@@ -199,18 +175,6 @@ func (uac *UserAPIController) UpdateUserBalance(c *gin.Context) {
 // @Failure      500      {object}  object{status=string,message=string,data=object}
 // @Router       /users/{id} [put]
 func (uac *UserAPIController) UpdateUser(c *gin.Context) {
-	user, exists := c.Get("user")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
-		return
-	}
-
-	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
-		return
-	}
-
 	userID := c.Param("id")
 
 	var req struct {
@@ -231,7 +195,7 @@ func (uac *UserAPIController) UpdateUser(c *gin.Context) {
 	}
 
 	// Update user
-	updatedUser, err := uac.userService.UpdateUser(userID, req.Email, req.Username, req.FirstName, req.LastName, req.Password)
+	updatedUser, err := uac.userService.UpdateUser(userID, req.Email, req.Username, req.FirstName, req.LastName, req.Password, middleware.CurrentActor(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
@@ -257,8 +221,8 @@ func (uac *UserAPIController) UpdateUser(c *gin.Context) {
 }
 
 // DeleteUser godoc
-// @Summary      Delete a user (Admin only)
-// @Description  Delete a user account permanently. Admins cannot delete their own account.
+// @Summary      Delete a user (requires users:write)
+// @Description  Move a user account to the trash with its enrollments and progress and sign it out everywhere; it can be restored until the trash is purged. Admins cannot delete their own account, and the last remaining admin cannot be deleted.
 // @Tags         admin-users
 // @Produce      json
 // @Security     BearerAuth
@@ -277,10 +241,6 @@ func (uac *UserAPIController) DeleteUser(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
-		return
-	}
 
 	userID := c.Param("id")
 
@@ -294,7 +254,15 @@ func (uac *UserAPIController) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	err := uac.userService.DeleteUser(userID)
+	err := uac.userService.DeleteUser(userID, middleware.CurrentActor(c))
+	if errors.Is(err, services.ErrLastAdmin) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
@@ -306,3 +274,110 @@ func (uac *UserAPIController) DeleteUser(c *gin.Context) {
 
 	c.Status(http.StatusNoContent)
 }
+
+// RevokeUserSessions godoc
+// @Summary      Force sign-out of a user (requires users:write)
+// @Description  Revoke every access and refresh token of a user, ending all of their sessions
+// @Tags         admin-users
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path      string  true  "User ID"
+// @Success      200 {object}  object{status=string,message=string,data=object}
+// @Failure      401 {object}  object{error=string}
+// @Failure      403 {object}  object{error=string}
+// @Failure      404 {object}  object{status=string,message=string,data=object}
+// @Router       /users/{id}/sessions [delete]
+func (uac *UserAPIController) RevokeUserSessions(c *gin.Context) {
+	userID := c.Param("id")
+	if err := uac.userService.RevokeSessions(userID, middleware.CurrentActor(c)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "User sessions revoked successfully",
+		"data":    nil,
+	})
+}
+
+// UnlockUser godoc
+// @Summary      Unlock a user's login (requires users:write)
+// @Description  Lift a lockout caused by failed login attempts and reset the user's failure count
+// @Tags         admin-users
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path      string  true  "User ID"
+// @Success      200 {object}  object{status=string,message=string,data=object}
+// @Failure      401 {object}  object{error=string}
+// @Failure      403 {object}  object{error=string}
+// @Failure      404 {object}  object{status=string,message=string,data=object}
+// @Failure      500 {object}  object{status=string,message=string,data=object}
+// @Router       /users/{id}/unlock [post]
+func (uac *UserAPIController) UnlockUser(c *gin.Context) {
+	userID := c.Param("id")
+	if _, err := uac.userService.GetUserByID(userID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "User not found",
+			"data":    nil,
+		})
+		return
+	}
+
+	if err := uac.loginThrottle.UnlockAccount(userID, middleware.CurrentActor(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to unlock user",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "User unlocked successfully",
+		"data":    nil,
+	})
+}
+
+// ResetUserMFA godoc
+// @Summary      Reset a user's two-factor authentication (requires users:write)
+// @Description  Remove two-factor authentication and the recovery codes from an account, e.g. after the user lost their authenticator. They can set it up again after signing in with the password.
+// @Tags         admin-users
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path      string  true  "User ID"
+// @Success      200 {object}  object{status=string,message=string,data=object}
+// @Failure      401 {object}  object{error=string}
+// @Failure      403 {object}  object{error=string}
+// @Failure      404 {object}  object{status=string,message=string,data=object}
+// @Failure      500 {object}  object{status=string,message=string,data=object}
+// @Router       /users/{id}/mfa/reset [post]
+func (uac *UserAPIController) ResetUserMFA(c *gin.Context) {
+	if err := uac.mfaService.Reset(c.Param("id"), middleware.CurrentActor(c)); err != nil {
+		status := http.StatusInternalServerError
+		message := "Failed to reset two-factor authentication"
+		if errors.Is(err, services.ErrUserNotFound) {
+			status = http.StatusNotFound
+			message = "User not found"
+		}
+
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": message,
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Two-factor authentication reset successfully",
+		"data":    nil,
+	})
+}
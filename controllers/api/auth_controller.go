@@ -1,7 +1,10 @@
 package api
 
 import (
+	"errors"
+	"log"
 	"net/http"
+	"strings"
 	"yonatan/labpro/models"
 	"yonatan/labpro/services"
 
@@ -9,25 +12,33 @@ import (
 )
 
 type AuthAPIController struct {
-	authService *services.AuthService
+	authService    *services.AuthService
+	loginThrottle  *services.LoginThrottle
+	accountService *services.AccountService
+	mfaService     *services.MFAService
 }
 
-func NewAuthAPIController(authService *services.AuthService) *AuthAPIController {
+func NewAuthAPIController(authService *services.AuthService, loginThrottle *services.LoginThrottle, accountService *services.AccountService, mfaService *services.MFAService) *AuthAPIController {
 	return &AuthAPIController{
-		authService: authService,
+		authService:    authService,
+		loginThrottle:  loginThrottle,
+		accountService: accountService,
+		mfaService:     mfaService,
 	}
 }
 
 // Login godoc
 // @Summary      User login
-// @Description  Authenticate user with identifier (username/email) and password
+// @Description  Authenticate user with identifier (username/email) and password. Repeated failures are throttled per account and per IP; throttled requests get 429 with a Retry-After header. Accounts with two-factor authentication get mfa_required=true and a short-lived mfa_token instead of a session; finish the login at /auth/mfa/verify.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
 // @Param        login  body      object{identifier=string,password=string}  true  "Login credentials"
-// @Success      200    {object}  object{status=string,message=string,data=object{username=string,token=string}}
+// @Success      200    {object}  object{status=string,message=string,data=object{username=string,token=string,refresh_token=string,expires_in=int,must_change_password=bool,mfa_required=bool,mfa_token=string}}
 // @Failure      400    {object}  object{status=string,message=string,data=object}
 // @Failure      401    {object}  object{status=string,message=string,data=object}
+// @Failure      429    {object}  object{status=string,message=string,data=object}
+// @Header       429    {string}  Retry-After  "Seconds until the next attempt is allowed"
 // @Router       /auth/login [post]
 func (aac *AuthAPIController) Login(c *gin.Context) {
 	var req struct {
@@ -44,8 +55,47 @@ func (aac *AuthAPIController) Login(c *gin.Context) {
 		return
 	}
 
-	token, user, err := aac.authService.Login(req.Identifier, req.Password)
+	if wait := aac.loginThrottle.Check(req.Identifier, c.ClientIP()); wait > 0 {
+		c.Header("Retry-After", services.RetryAfterSeconds(wait))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"status":  "error",
+			"message": services.ErrTooManyLoginAttempts.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	tokens, user, err := aac.authService.Login(req.Identifier, req.Password)
+	if errors.Is(err, services.ErrMFARequired) {
+		// Failures stay counted until the second factor has been passed too
+		mfaToken, err := aac.authService.IssueMFAToken(user.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"message": "Failed to start two-factor login",
+				"data":    nil,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "success",
+			"message": "Two-factor authentication required",
+			"data": map[string]interface{}{
+				"username":     user.Username,
+				"mfa_required": true,
+				"mfa_token":    mfaToken,
+				"expires_in":   int64(services.MFATokenTTL.Seconds()),
+			},
+		})
+		return
+	}
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			if wait := aac.loginThrottle.RecordFailure(req.Identifier, c.ClientIP()); wait > 0 {
+				c.Header("Retry-After", services.RetryAfterSeconds(wait))
+			}
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"status":  "error",
 			"message": err.Error(),
@@ -53,22 +103,101 @@ func (aac *AuthAPIController) Login(c *gin.Context) {
 		})
 		return
 	}
+	aac.loginThrottle.RecordSuccess(req.Identifier)
 
-	result := map[string]interface{}{
-		"username": user.Username,
-		"token":    token,
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Login successful",
+		"data":    loginResult(user, tokens),
+	})
+}
+
+// VerifyMFA godoc
+// @Summary      Finish a two-factor login
+// @Description  Exchange the mfa_token from /auth/login and a code from the authenticator app, or an unused recovery code, for a session. Failed codes count towards the login lockout.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        mfa  body      object{mfa_token=string,code=string}  true  "Pending login and code"
+// @Success      200  {object}  object{status=string,message=string,data=object{username=string,token=string,refresh_token=string,expires_in=int,must_change_password=bool}}
+// @Failure      400  {object}  object{status=string,message=string,data=object}
+// @Failure      401  {object}  object{status=string,message=string,data=object}
+// @Failure      429  {object}  object{status=string,message=string,data=object}
+// @Header       429  {string}  Retry-After  "Seconds until the next attempt is allowed"
+// @Router       /auth/mfa/verify [post]
+func (aac *AuthAPIController) VerifyMFA(c *gin.Context) {
+	var req struct {
+		MFAToken string `json:"mfa_token" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	userID, err := aac.authService.ParseMFAToken(req.MFAToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	if wait := aac.loginThrottle.CheckUser(userID, c.ClientIP()); wait > 0 {
+		c.Header("Retry-After", services.RetryAfterSeconds(wait))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"status":  "error",
+			"message": services.ErrTooManyLoginAttempts.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	user, err := aac.mfaService.Verify(userID, req.Code)
+	if err != nil {
+		if wait := aac.loginThrottle.RecordUserFailure(userID, c.ClientIP()); wait > 0 {
+			c.Header("Retry-After", services.RetryAfterSeconds(wait))
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status":  "error",
+			"message": services.ErrInvalidMFACode.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	tokens, err := aac.authService.CompleteMFALogin(req.MFAToken)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidMFAToken) {
+			status = http.StatusUnauthorized
+		}
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
 	}
+	aac.loginThrottle.Unlock(user.ID)
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "success",
 		"message": "Login successful",
-		"data":    result,
+		"data":    loginResult(user, tokens),
 	})
 }
 
 // Register godoc
 // @Summary      User registration
-// @Description  Register a new user account
+// @Description  Register a new user account. A link to verify the email address is mailed to the user.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
@@ -115,11 +244,17 @@ func (aac *AuthAPIController) Register(c *gin.Context) {
 		return
 	}
 
+	// The account is usable right away; failing to mail the link only delays verification
+	if err := aac.accountService.SendVerificationEmail(user); err != nil {
+		log.Printf("Warning: Failed to send verification email to %s: %v", user.Email, err)
+	}
+
 	result := map[string]interface{}{
-		"id":         user.ID,
-		"username":   user.Username,
-		"first_name": user.FirstName,
-		"last_name":  user.LastName,
+		"id":             user.ID,
+		"username":       user.Username,
+		"first_name":     user.FirstName,
+		"last_name":      user.LastName,
+		"email_verified": false,
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
@@ -129,14 +264,85 @@ func (aac *AuthAPIController) Register(c *gin.Context) {
 	})
 }
 
+// Refresh godoc
+// @Summary      Refresh access token
+// @Description  Exchange a refresh token for a new access token and a rotated refresh token
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        refresh  body      object{refresh_token=string}  true  "Refresh token"
+// @Success      200      {object}  object{status=string,message=string,data=object{token=string,refresh_token=string,expires_in=int}}
+// @Failure      400      {object}  object{status=string,message=string,data=object}
+// @Failure      401      {object}  object{status=string,message=string,data=object}
+// @Router       /auth/refresh [post]
+func (aac *AuthAPIController) Refresh(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	tokens, err := aac.authService.Refresh(req.RefreshToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	result := map[string]interface{}{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Token refreshed successfully",
+		"data":    result,
+	})
+}
+
 // Logout godoc
 // @Summary      User logout
-// @Description  Logout current user
+// @Description  Logout current user, revoking the bearer token and the given refresh token if present
 // @Tags         auth
+// @Accept       json
 // @Produce      json
-// @Success      200  {object}  object{status=string,message=string,data=object}
+// @Param        logout  body      object{refresh_token=string}  false  "Refresh token to revoke"
+// @Success      200     {object}  object{status=string,message=string,data=object}
+// @Failure      500     {object}  object{status=string,message=string,data=object}
 // @Router       /auth/logout [post]
 func (aac *AuthAPIController) Logout(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	// The body is optional; a bare logout only revokes the bearer token
+	_ = c.ShouldBindJSON(&req)
+
+	accessToken := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if accessToken == c.GetHeader("Authorization") {
+		accessToken = ""
+	}
+
+	if err := aac.authService.Logout(accessToken, req.RefreshToken); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to logout",
+			"data":    nil,
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "success",
 		"message": "Logout successful",
@@ -144,13 +350,84 @@ func (aac *AuthAPIController) Logout(c *gin.Context) {
 	})
 }
 
+// ChangePassword godoc
+// @Summary      Change password
+// @Description  Replace the current user's password. All other sessions are ended and a new token pair is returned. Required before anything else when the account was created with a temporary password.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        password  body      object{current_password=string,new_password=string,confirm_password=string}  true  "Password change"
+// @Success      200       {object}  object{status=string,message=string,data=object{token=string,refresh_token=string,expires_in=int}}
+// @Failure      400       {object}  object{status=string,message=string,data=object}
+// @Failure      401       {object}  object{status=string,message=string,data=object}
+// @Router       /auth/change-password [post]
+func (aac *AuthAPIController) ChangePassword(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status":  "error",
+			"message": "Unauthorized",
+			"data":    nil,
+		})
+		return
+	}
+
+	var req struct {
+		CurrentPassword string `json:"current_password" binding:"required"`
+		NewPassword     string `json:"new_password" binding:"required,min=8"`
+		ConfirmPassword string `json:"confirm_password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	if req.NewPassword != req.ConfirmPassword {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Password and confirm password do not match",
+			"data":    nil,
+		})
+		return
+	}
+
+	userModel := user.(models.User)
+	tokens, err := aac.authService.ChangePassword(userModel.ID, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	result := map[string]interface{}{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Password changed successfully",
+		"data":    result,
+	})
+}
+
 // GetProfile godoc
 // @Summary      Get current user profile
 // @Description  Get the profile of the currently authenticated user
 // @Tags         auth
 // @Produce      json
 // @Security     BearerAuth
-// @Success      200  {object}  object{status=string,message=string,data=object{id=string,username=string,email=string,first_name=string,last_name=string,role=string}}
+// @Success      200  {object}  object{status=string,message=string,data=object{id=string,username=string,email=string,first_name=string,last_name=string,role=string,email_verified=bool,mfa_enabled=bool}}
 // @Failure      401  {object}  object{status=string,message=string,data=object}
 // @Router       /auth/self [get]
 func (aac *AuthAPIController) GetProfile(c *gin.Context) {
@@ -172,6 +449,10 @@ func (aac *AuthAPIController) GetProfile(c *gin.Context) {
 		"first_name": userModel.FirstName,
 		"last_name":  userModel.LastName,
 		"balance":    userModel.Balance,
+
+		"must_change_password": userModel.MustChangePassword,
+		"email_verified":       userModel.EmailVerifiedAt != nil,
+		"mfa_enabled":          userModel.TOTPEnabledAt != nil,
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -180,3 +461,381 @@ func (aac *AuthAPIController) GetProfile(c *gin.Context) {
 		"data":    result,
 	})
 }
+
+// ForgotPassword godoc
+// @Summary      Request a password reset
+// @Description  Mail a single-use password reset link to the account registered with the given email. The response is the same whether or not such an account exists.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      object{email=string}  true  "Account email"
+// @Success      200      {object}  object{status=string,message=string,data=object}
+// @Failure      400      {object}  object{status=string,message=string,data=object}
+// @Router       /auth/forgot-password [post]
+func (aac *AuthAPIController) ForgotPassword(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	// Failures are only logged so the response never reveals whether the account exists
+	if err := aac.accountService.RequestPasswordReset(req.Email); err != nil {
+		log.Printf("Warning: Failed to send password reset email to %s: %v", req.Email, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "If an account exists for this email, a password reset link has been sent",
+		"data":    nil,
+	})
+}
+
+// ResetPassword godoc
+// @Summary      Reset password
+// @Description  Set a new password using the token from a password reset email. The token works once; all sessions of the user are ended.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      object{token=string,new_password=string,confirm_password=string}  true  "Reset token and new password"
+// @Success      200      {object}  object{status=string,message=string,data=object}
+// @Failure      400      {object}  object{status=string,message=string,data=object}
+// @Router       /auth/reset-password [post]
+func (aac *AuthAPIController) ResetPassword(c *gin.Context) {
+	var req struct {
+		Token           string `json:"token" binding:"required"`
+		NewPassword     string `json:"new_password" binding:"required,min=8"`
+		ConfirmPassword string `json:"confirm_password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	if req.NewPassword != req.ConfirmPassword {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Password and confirm password do not match",
+			"data":    nil,
+		})
+		return
+	}
+
+	user, err := aac.accountService.ResetPassword(req.Token, req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+	// Whoever holds the mailbox may sign in again right away
+	aac.loginThrottle.Unlock(user.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Password reset successfully",
+		"data":    nil,
+	})
+}
+
+// VerifyEmail godoc
+// @Summary      Verify email address
+// @Description  Confirm the user's email address using the token from a verification email
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      object{token=string}  true  "Verification token"
+// @Success      200      {object}  object{status=string,message=string,data=object{username=string,email=string,email_verified_at=string}}
+// @Failure      400      {object}  object{status=string,message=string,data=object}
+// @Router       /auth/verify-email [post]
+func (aac *AuthAPIController) VerifyEmail(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	user, err := aac.accountService.VerifyEmail(req.Token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	result := map[string]interface{}{
+		"username":          user.Username,
+		"email":             user.Email,
+		"email_verified_at": user.EmailVerifiedAt,
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Email verified successfully",
+		"data":    result,
+	})
+}
+
+// ResendVerification godoc
+// @Summary      Resend verification email
+// @Description  Mail a new email verification link to the current user
+// @Tags         auth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  object{status=string,message=string,data=object}
+// @Failure      401  {object}  object{status=string,message=string,data=object}
+// @Failure      409  {object}  object{status=string,message=string,data=object}
+// @Failure      500  {object}  object{status=string,message=string,data=object}
+// @Router       /auth/verify-email/resend [post]
+func (aac *AuthAPIController) ResendVerification(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status":  "error",
+			"message": "Unauthorized",
+			"data":    nil,
+		})
+		return
+	}
+
+	userModel := user.(models.User)
+	if err := aac.accountService.SendVerificationEmail(&userModel); err != nil {
+		status := http.StatusInternalServerError
+		message := "Failed to send verification email"
+		if errors.Is(err, services.ErrEmailAlreadyVerified) {
+			status = http.StatusConflict
+			message = err.Error()
+		}
+
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": message,
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Verification email sent",
+		"data":    nil,
+	})
+}
+
+// SetupMFA godoc
+// @Summary      Start two-factor authentication setup
+// @Description  Generate a new TOTP secret for the current user. Show provisioning_uri as a QR code (or the secret for manual entry), then confirm with /auth/mfa/enable.
+// @Tags         auth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  object{status=string,message=string,data=object{secret=string,provisioning_uri=string}}
+// @Failure      401  {object}  object{status=string,message=string,data=object}
+// @Failure      409  {object}  object{status=string,message=string,data=object}
+// @Router       /auth/mfa/setup [post]
+func (aac *AuthAPIController) SetupMFA(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status":  "error",
+			"message": "Unauthorized",
+			"data":    nil,
+		})
+		return
+	}
+
+	setup, err := aac.mfaService.BeginSetup(user.(models.User).ID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrMFAAlreadyEnabled) {
+			status = http.StatusConflict
+		}
+
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Scan the code with your authenticator app",
+		"data":    setup,
+	})
+}
+
+// EnableMFA godoc
+// @Summary      Enable two-factor authentication
+// @Description  Confirm setup with a code from the authenticator app. Returns recovery codes, which are shown only once.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        mfa  body      object{code=string}  true  "Code from the authenticator app"
+// @Success      200  {object}  object{status=string,message=string,data=object{recovery_codes=[]string}}
+// @Failure      400  {object}  object{status=string,message=string,data=object}
+// @Failure      401  {object}  object{status=string,message=string,data=object}
+// @Failure      409  {object}  object{status=string,message=string,data=object}
+// @Failure      429  {object}  object{status=string,message=string,data=object}
+// @Header       429  {string}  Retry-After  "Seconds until the next attempt is allowed"
+// @Router       /auth/mfa/enable [post]
+func (aac *AuthAPIController) EnableMFA(c *gin.Context) {
+	aac.handleMFACode(c, func(userID, code string) (interface{}, error) {
+		codes, err := aac.mfaService.Enable(userID, code)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"recovery_codes": codes}, nil
+	}, "Two-factor authentication enabled")
+}
+
+// DisableMFA godoc
+// @Summary      Disable two-factor authentication
+// @Description  Turn off two-factor authentication with a current code or a recovery code. Not allowed when a role of the user requires it.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        mfa  body      object{code=string}  true  "Code from the authenticator app or a recovery code"
+// @Success      200  {object}  object{status=string,message=string,data=object}
+// @Failure      400  {object}  object{status=string,message=string,data=object}
+// @Failure      401  {object}  object{status=string,message=string,data=object}
+// @Failure      403  {object}  object{status=string,message=string,data=object}
+// @Failure      429  {object}  object{status=string,message=string,data=object}
+// @Header       429  {string}  Retry-After  "Seconds until the next attempt is allowed"
+// @Router       /auth/mfa/disable [post]
+func (aac *AuthAPIController) DisableMFA(c *gin.Context) {
+	aac.handleMFACode(c, func(userID, code string) (interface{}, error) {
+		return nil, aac.mfaService.Disable(userID, code)
+	}, "Two-factor authentication disabled")
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary      Regenerate recovery codes
+// @Description  Replace all recovery codes after checking a current code. The old codes stop working.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        mfa  body      object{code=string}  true  "Code from the authenticator app or a recovery code"
+// @Success      200  {object}  object{status=string,message=string,data=object{recovery_codes=[]string}}
+// @Failure      400  {object}  object{status=string,message=string,data=object}
+// @Failure      401  {object}  object{status=string,message=string,data=object}
+// @Failure      429  {object}  object{status=string,message=string,data=object}
+// @Header       429  {string}  Retry-After  "Seconds until the next attempt is allowed"
+// @Router       /auth/mfa/recovery-codes [post]
+func (aac *AuthAPIController) RegenerateRecoveryCodes(c *gin.Context) {
+	aac.handleMFACode(c, func(userID, code string) (interface{}, error) {
+		codes, err := aac.mfaService.RegenerateRecoveryCodes(userID, code)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"recovery_codes": codes}, nil
+	}, "Recovery codes regenerated")
+}
+
+// handleMFACode runs an MFA management action that is confirmed with a code
+func (aac *AuthAPIController) handleMFACode(c *gin.Context, action func(userID, code string) (interface{}, error), message string) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status":  "error",
+			"message": "Unauthorized",
+			"data":    nil,
+		})
+		return
+	}
+
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	// Wrong codes count against the login throttle, so a stolen session cannot
+	// be used to guess them
+	userID := user.(models.User).ID
+	if wait := aac.loginThrottle.CheckUser(userID, c.ClientIP()); wait > 0 {
+		c.Header("Retry-After", services.RetryAfterSeconds(wait))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"status":  "error",
+			"message": services.ErrTooManyLoginAttempts.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	result, err := action(userID, req.Code)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidMFACode) {
+			if wait := aac.loginThrottle.RecordUserFailure(userID, c.ClientIP()); wait > 0 {
+				c.Header("Retry-After", services.RetryAfterSeconds(wait))
+			}
+		}
+
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, services.ErrMFAAlreadyEnabled):
+			status = http.StatusConflict
+		case errors.Is(err, services.ErrMFAEnforced):
+			status = http.StatusForbidden
+		}
+
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": message,
+		"data":    result,
+	})
+}
+
+// loginResult is the response body of a completed login
+func loginResult(user *models.User, tokens *services.TokenPair) map[string]interface{} {
+	return map[string]interface{}{
+		"username":      user.Username,
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+
+		// Other routes answer 403 until the password has been changed
+		"must_change_password": user.MustChangePassword,
+	}
+}
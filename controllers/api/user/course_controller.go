@@ -1,8 +1,10 @@
 package user
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"yonatan/labpro/middleware"
 	"yonatan/labpro/models"
 	"yonatan/labpro/services"
 
@@ -21,7 +23,7 @@ func NewCourseAPIController(courseService *services.CourseService) *CourseAPICon
 
 // GetCourses godoc
 // @Summary      Get all available courses
-// @Description  Get a paginated list of available courses with optional search
+// @Description  Get a paginated list of available courses with optional search. thumbnail_image is null or an object with src, srcset and webp_srcset for responsive images and social for link previews.
 // @Tags         courses
 // @Produce      json
 // @Security     BearerAuth
@@ -70,7 +72,7 @@ func (cac *CourseAPIController) GetCourses(c *gin.Context) {
 
 // GetCourseByID godoc
 // @Summary      Get course by ID
-// @Description  Get detailed information about a specific course
+// @Description  Get detailed information about a specific course. Courses that are not published are only found by enrolled users and by those who may edit them. thumbnail_image is null or an object with src, srcset and webp_srcset for responsive images and social for link previews.
 // @Tags         courses
 // @Produce      json
 // @Security     BearerAuth
@@ -89,6 +91,17 @@ func (cac *CourseAPIController) GetCourseByID(c *gin.Context) {
 	userModel := user.(models.User)
 	courseID := c.Param("courseId")
 
+	// Courses outside the catalogue are hidden from everyone but their students and editors
+	canView, err := cac.courseService.CanViewCourse(userModel, courseID)
+	if err != nil || !canView {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "Course not found",
+			"data":    nil,
+		})
+		return
+	}
+
 	// Get course details
 	course, err := cac.courseService.GetCourseByID(courseID, userModel.ID)
 	if err != nil {
@@ -158,14 +171,17 @@ func (cac *CourseAPIController) GetMyCourses(c *gin.Context) {
 
 // PurchaseCourse godoc
 // @Summary      Purchase a course
-// @Description  Purchase/enroll in a specific course
+// @Description  Purchase/enroll in a specific course. Only published courses can be bought. Returns 403 for unverified email addresses when ALLOW_UNVERIFIED_PURCHASES is false. Send an Idempotency-Key header to make retries safe: a repeated request with the same key returns the original result instead of purchasing again.
 // @Tags         courses
 // @Produce      json
 // @Security     BearerAuth
-// @Param        courseId  path      string  true  "Course ID"
-// @Success      200       {object}  object{status=string,message=string,data=object}
-// @Failure      400       {object}  object{status=string,message=string,data=object}
-// @Failure      401       {object}  object{error=string}
+// @Param        courseId         path      string  true   "Course ID"
+// @Param        Idempotency-Key  header    string  false  "Client-generated key identifying this purchase attempt"
+// @Success      200              {object}  object{status=string,message=string,data=object}
+// @Failure      400              {object}  object{status=string,message=string,data=object}
+// @Failure      401              {object}  object{error=string}
+// @Failure      403              {object}  object{status=string,message=string,data=object}
+// @Failure      422              {object}  object{status=string,message=string,data=object}
 // @Router       /courses/{courseId}/buy [post]
 func (cac *CourseAPIController) PurchaseCourse(c *gin.Context) {
 	user, exists := c.Get("user")
@@ -177,10 +193,27 @@ func (cac *CourseAPIController) PurchaseCourse(c *gin.Context) {
 	userModel := user.(models.User)
 	courseID := c.Param("courseId")
 
+	idempotencyKey := c.GetHeader("Idempotency-Key")
+	if len(idempotencyKey) > 255 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Idempotency-Key must be at most 255 characters",
+			"data":    nil,
+		})
+		return
+	}
+
 	// Purchase course
-	result, err := cac.courseService.BuyCourse(courseID, userModel.ID)
+	result, err := cac.courseService.BuyCourse(courseID, userModel.ID, idempotencyKey)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrIdempotencyKeyReused) {
+			status = http.StatusUnprocessableEntity
+		} else if errors.Is(err, services.ErrEmailNotVerified) {
+			status = http.StatusForbidden
+		}
+
+		c.JSON(status, gin.H{
 			"status":  "error",
 			"message": err.Error(),
 			"data":    nil,
@@ -194,3 +227,47 @@ func (cac *CourseAPIController) PurchaseCourse(c *gin.Context) {
 		"data":    result,
 	})
 }
+
+// RefundCourse godoc
+// @Summary      Refund a purchased course
+// @Description  Revoke the enrollment and credit the price back to the balance. Allowed within the refund window after purchase and while course progress is below the refund threshold.
+// @Tags         courses
+// @Produce      json
+// @Security     BearerAuth
+// @Param        courseId  path      string  true  "Course ID"
+// @Success      200       {object}  object{status=string,message=string,data=object{course_id=string,refunded=number,user_balance=number,transaction_id=string}}
+// @Failure      400       {object}  object{status=string,message=string,data=object}
+// @Failure      401       {object}  object{error=string}
+// @Failure      404       {object}  object{status=string,message=string,data=object}
+// @Router       /courses/{courseId}/refund [post]
+func (cac *CourseAPIController) RefundCourse(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userModel := user.(models.User)
+	courseID := c.Param("courseId")
+
+	result, err := cac.courseService.RefundCourse(courseID, userModel.ID, middleware.CurrentActor(c), false)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrCourseNotPurchased) {
+			status = http.StatusNotFound
+		}
+
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Course refunded successfully",
+		"data":    result,
+	})
+}
@@ -0,0 +1,83 @@
+package user
+
+import (
+	"errors"
+	"net/http"
+	"yonatan/labpro/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InstructorAPIController struct {
+	instructorService *services.InstructorService
+}
+
+func NewInstructorAPIController(instructorService *services.InstructorService) *InstructorAPIController {
+	return &InstructorAPIController{
+		instructorService: instructorService,
+	}
+}
+
+// GetInstructors godoc
+// @Summary      List instructors
+// @Description  Get every instructor profile ordered by name
+// @Tags         instructors
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  object{status=string,message=string,data=array}
+// @Failure      401  {object}  object{error=string}
+// @Failure      500  {object}  object{status=string,message=string,data=object}
+// @Router       /instructors [get]
+func (iac *InstructorAPIController) GetInstructors(c *gin.Context) {
+	instructors, err := iac.instructorService.GetInstructors()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to fetch instructors",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Instructors retrieved successfully",
+		"data":    instructors,
+	})
+}
+
+// GetInstructor godoc
+// @Summary      Get instructor by ID
+// @Description  Get an instructor's profile together with the courses they teach
+// @Tags         instructors
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Instructor ID"
+// @Success      200  {object}  object{status=string,message=string,data=object}
+// @Failure      401  {object}  object{error=string}
+// @Failure      404  {object}  object{status=string,message=string,data=object}
+// @Failure      500  {object}  object{status=string,message=string,data=object}
+// @Router       /instructors/{id} [get]
+func (iac *InstructorAPIController) GetInstructor(c *gin.Context) {
+	instructor, err := iac.instructorService.GetInstructor(c.Param("id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := "Failed to fetch instructor"
+		if errors.Is(err, services.ErrInstructorNotFound) {
+			status = http.StatusNotFound
+			message = "Instructor not found"
+		}
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": message,
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Instructor retrieved successfully",
+		"data":    instructor,
+	})
+}
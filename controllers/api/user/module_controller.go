@@ -1,6 +1,7 @@
 package user
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"yonatan/labpro/models"
@@ -113,6 +114,52 @@ func (mac *ModuleAPIController) GetModuleByID(c *gin.Context) {
 	})
 }
 
+// StreamVideo godoc
+// @Summary      Stream a module video
+// @Description  Serve the HLS master playlist, rendition playlists, segments and poster frame of a transcoded module video to users who may watch the module
+// @Tags         modules
+// @Produce      application/vnd.apple.mpegurl
+// @Security     BearerAuth
+// @Param        id       path      string  true  "Module ID"
+// @Param        version  path      string  true  "Stream version, as found in the module's video_stream URL"
+// @Param        file     path      string  true  "Stream file, e.g. master.m3u8"
+// @Success      200      {file}    file
+// @Failure      401      {object}  object{error=string}
+// @Failure      403      {object}  object{status=string,message=string,data=object}
+// @Failure      404      {object}  object{status=string,message=string,data=object}
+// @Router       /modules/{id}/stream/{version}/{file} [get]
+func (mac *ModuleAPIController) StreamVideo(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userModel := user.(models.User)
+	reader, object, err := mac.moduleService.OpenVideoStream(userModel, c.Param("id"), c.Param("version"), c.Param("file"))
+	if errors.Is(err, services.ErrModuleAccessDenied) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  "error",
+			"message": "You don't have access to this module",
+			"data":    nil,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "Stream not found",
+			"data":    nil,
+		})
+		return
+	}
+	defer reader.Close()
+
+	// Streams never change once written; a new video gets a new prefix
+	c.Header("Cache-Control", "private, max-age=86400")
+	c.DataFromReader(http.StatusOK, object.Size, object.ContentType, reader, nil)
+}
+
 // CompleteModule godoc
 // @Summary      Mark module as completed
 // @Description  Mark a specific module as completed by the user
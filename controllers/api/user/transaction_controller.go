@@ -0,0 +1,75 @@
+package user
+
+import (
+	"net/http"
+	"strconv"
+	"yonatan/labpro/models"
+	"yonatan/labpro/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TransactionAPIController struct {
+	transactionService *services.TransactionService
+}
+
+func NewTransactionAPIController(transactionService *services.TransactionService) *TransactionAPIController {
+	return &TransactionAPIController{
+		transactionService: transactionService,
+	}
+}
+
+// GetMyTransactions godoc
+// @Summary      Get user's balance transactions
+// @Description  Get a paginated ledger of every change to the current user's balance, newest first
+// @Tags         me
+// @Produce      json
+// @Security     BearerAuth
+// @Param        type   query     string  false  "Transaction type (topup, purchase, refund, adjustment, opening_balance)"
+// @Param        page   query     int     false  "Page number (default: 1)"
+// @Param        limit  query     int     false  "Items per page (default: 15, max: 50)"
+// @Success      200    {object}  object{status=string,message=string,data=array,pagination=object}
+// @Failure      401    {object}  object{error=string}
+// @Failure      500    {object}  object{status=string,message=string,data=object}
+// @Router       /me/transactions [get]
+func (tac *TransactionAPIController) GetMyTransactions(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userModel := user.(models.User)
+
+	// Get query parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "15"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 50 {
+		limit = 15
+	}
+
+	filter := services.TransactionFilter{
+		UserID: userModel.ID,
+		Type:   c.Query("type"),
+	}
+
+	transactions, pagination, err := tac.transactionService.GetTransactions(filter, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to fetch transactions",
+			"data":    nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"message":    "Transactions retrieved successfully",
+		"data":       transactions,
+		"pagination": pagination,
+	})
+}
@@ -0,0 +1,87 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"yonatan/labpro/models"
+	"yonatan/labpro/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AccessTokenController struct {
+	accessTokenService *services.AccessTokenService
+}
+
+func NewAccessTokenController(accessTokenService *services.AccessTokenService) *AccessTokenController {
+	return &AccessTokenController{accessTokenService: accessTokenService}
+}
+
+func (atc *AccessTokenController) ShowTokensPage(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Redirect(http.StatusFound, "/auth/login")
+		return
+	}
+
+	atc.renderTokens(c, http.StatusOK, user.(models.User), gin.H{
+		"Success": c.Query("success"),
+		"Error":   c.Query("error"),
+	})
+}
+
+func (atc *AccessTokenController) HandleCreateToken(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Redirect(http.StatusFound, "/auth/login")
+		return
+	}
+
+	userModel := user.(models.User)
+	expiresInDays, _ := strconv.Atoi(c.PostForm("expires_in_days"))
+
+	token, plain, err := atc.accessTokenService.CreateToken(userModel.ID, c.PostForm("name"), c.PostFormArray("scopes"), expiresInDays)
+	if err != nil {
+		message := "Failed to create access token"
+		if errors.Is(err, services.ErrInvalidTokenName) || errors.Is(err, services.ErrInvalidTokenScopes) || errors.Is(err, services.ErrInvalidTokenExpiry) {
+			message = err.Error()
+		}
+		atc.renderTokens(c, http.StatusBadRequest, userModel, gin.H{"Error": message})
+		return
+	}
+
+	// The token is shown on this response only, so it is rendered instead of redirected
+	atc.renderTokens(c, http.StatusOK, userModel, gin.H{
+		"Success":  "Token " + token.Name + " created. Copy it now, it will not be shown again.",
+		"NewToken": plain,
+	})
+}
+
+func (atc *AccessTokenController) HandleRevokeToken(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Redirect(http.StatusFound, "/auth/login")
+		return
+	}
+
+	if err := atc.accessTokenService.RevokeToken(user.(models.User).ID, c.Param("id")); err != nil {
+		c.Redirect(http.StatusFound, "/auth/tokens?error="+err.Error())
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/auth/tokens?success=Access token revoked")
+}
+
+func (atc *AccessTokenController) renderTokens(c *gin.Context, status int, user models.User, data gin.H) {
+	tokens, err := atc.accessTokenService.GetTokens(user.ID)
+	if err != nil {
+		data["Error"] = "Failed to fetch access tokens"
+	}
+
+	data["Title"] = "API Tokens"
+	data["User"] = user
+	data["Tokens"] = tokens
+	data["Scopes"] = models.TokenScopes
+	c.HTML(status, "tokens.html", data)
+}
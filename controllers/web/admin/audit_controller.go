@@ -0,0 +1,92 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"yonatan/labpro/models"
+	"yonatan/labpro/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditController struct {
+	auditService *services.AuditService
+}
+
+func NewAuditController(auditService *services.AuditService) *AuditController {
+	return &AuditController{
+		auditService: auditService,
+	}
+}
+
+func (ac *AuditController) ShowAuditPage(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Redirect(http.StatusFound, "/auth/login")
+		return
+	}
+
+	userModel := user.(models.User)
+
+	// Get query parameters for pagination and filters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	data := gin.H{
+		"Title": "Audit Log",
+		"User":  userModel,
+		"Filters": gin.H{
+			"ActorID":    c.Query("actor_id"),
+			"Action":     c.Query("action"),
+			"TargetType": c.Query("target_type"),
+			"TargetID":   c.Query("target_id"),
+			"Query":      c.Query("q"),
+			"From":       c.Query("from"),
+			"To":         c.Query("to"),
+		},
+		"Actions":     models.AuditActions,
+		"TargetTypes": []string{"user", "course", "module", "role", "instructor"},
+		"ExportURL":   "/admin/audit/export?" + c.Request.URL.RawQuery,
+	}
+
+	filter, err := services.NewAuditFilter(c.Query("actor_id"), c.Query("action"), c.Query("target_type"), c.Query("target_id"), c.Query("q"), c.Query("from"), c.Query("to"))
+	if err != nil {
+		data["Error"] = err.Error()
+		c.HTML(http.StatusBadRequest, "audit.html", data)
+		return
+	}
+
+	events, pagination, err := ac.auditService.GetEvents(filter, page, limit)
+	if err != nil {
+		data["Error"] = "Failed to fetch audit events"
+		c.HTML(http.StatusInternalServerError, "audit.html", data)
+		return
+	}
+
+	data["Events"] = events
+	data["Pagination"] = pagination
+	c.HTML(http.StatusOK, "audit.html", data)
+}
+
+// ExportAudit downloads the events matching the page's filters as CSV
+func (ac *AuditController) ExportAudit(c *gin.Context) {
+	filter, err := services.NewAuditFilter(c.Query("actor_id"), c.Query("action"), c.Query("target_type"), c.Query("target_id"), c.Query("q"), c.Query("from"), c.Query("to"))
+	if err != nil {
+		// The audit page shows what is wrong with the filters
+		c.Redirect(http.StatusFound, "/admin/audit?"+c.Request.URL.RawQuery)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="audit.csv"`)
+	c.Status(http.StatusOK)
+	if err := ac.auditService.ExportCSV(filter, c.Writer); err != nil {
+		c.Error(err)
+	}
+}
@@ -1,8 +1,11 @@
 package admin
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
+	"yonatan/labpro/middleware"
 	"yonatan/labpro/models"
 	"yonatan/labpro/services"
 
@@ -10,12 +13,14 @@ import (
 )
 
 type CourseController struct {
-	courseService *services.CourseService
+	courseService     *services.CourseService
+	instructorService *services.InstructorService
 }
 
-func NewCourseController(courseService *services.CourseService) *CourseController {
+func NewCourseController(courseService *services.CourseService, instructorService *services.InstructorService) *CourseController {
 	return &CourseController{
-		courseService: courseService,
+		courseService:     courseService,
+		instructorService: instructorService,
 	}
 }
 
@@ -27,18 +32,15 @@ func (cc *CourseController) ShowCoursesPage(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.Redirect(http.StatusFound, "/dashboard")
-		return
-	}
 
 	// Get query parameters for pagination and search
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 	query := c.Query("q")
+	status := c.Query("status")
 
-	// Get courses from service
-	courses, pagination, err := cc.courseService.GetCourses(query, page, limit, userModel.ID)
+	// Staff see courses in every state, not just the catalogue
+	courses, pagination, err := cc.courseService.GetManagedCourses(query, status, page, limit)
 	if err != nil {
 		c.HTML(http.StatusInternalServerError, "courses.html", gin.H{
 			"Title": "Courses Management",
@@ -54,6 +56,9 @@ func (cc *CourseController) ShowCoursesPage(c *gin.Context) {
 		"Courses":    courses,
 		"Pagination": pagination,
 		"Query":      query,
+		"Status":     status,
+		"Statuses":   models.CourseStatuses,
+		"Error":      c.Query("error"),
 	})
 }
 
@@ -65,14 +70,13 @@ func (cc *CourseController) ShowCreateCoursePage(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.Redirect(http.StatusFound, "/dashboard")
-		return
-	}
+
+	instructors, _ := cc.instructorService.GetInstructors()
 
 	c.HTML(http.StatusOK, "course-create.html", gin.H{
-		"Title": "Create Course",
-		"User":  userModel,
+		"Title":       "Create Course",
+		"User":        userModel,
+		"Instructors": instructors,
 	})
 }
 
@@ -84,12 +88,14 @@ func (cc *CourseController) ShowEditCoursePage(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.Redirect(http.StatusFound, "/dashboard")
+
+	courseID := c.Param("id")
+
+	if canEdit, err := cc.courseService.CanEditCourse(userModel, courseID); err != nil || !canEdit {
+		c.Redirect(http.StatusFound, "/admin/courses?error="+services.ErrCourseNotOwned.Error())
 		return
 	}
 
-	courseID := c.Param("id")
 	course, err := cc.courseService.GetCourseByID(courseID, userModel.ID)
 	if err != nil {
 		c.HTML(http.StatusNotFound, "course-edit.html", gin.H{
@@ -100,10 +106,15 @@ func (cc *CourseController) ShowEditCoursePage(c *gin.Context) {
 		return
 	}
 
+	instructors, _ := cc.instructorService.GetInstructors()
+
 	c.HTML(http.StatusOK, "course-edit.html", gin.H{
-		"Title":  "Edit Course",
-		"User":   userModel,
-		"Course": course,
+		"Title":       "Edit Course",
+		"User":        userModel,
+		"Course":      course,
+		"Instructors": instructors,
+		"Statuses":    models.CourseStatuses,
+		"Error":       c.Query("error"),
 	})
 }
 
@@ -115,10 +126,6 @@ func (cc *CourseController) HandleCreateCourse(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.Redirect(http.StatusFound, "/dashboard")
-		return
-	}
 
 	// Handle form submission
 	title := c.PostForm("title")
@@ -127,12 +134,15 @@ func (cc *CourseController) HandleCreateCourse(c *gin.Context) {
 	priceStr := c.PostForm("price")
 	topics := c.PostFormArray("topics")
 
+	instructors, _ := cc.instructorService.GetInstructors()
+
 	// Validate required fields
-	if title == "" || instructor == "" || priceStr == "" {
+	if title == "" || priceStr == "" {
 		c.HTML(http.StatusBadRequest, "course-create.html", gin.H{
-			"Title": "Create Course",
-			"User":  userModel,
-			"Error": "Title, instructor, and price are required",
+			"Title":       "Create Course",
+			"User":        userModel,
+			"Instructors": instructors,
+			"Error":       "Title and price are required",
 		})
 		return
 	}
@@ -140,9 +150,21 @@ func (cc *CourseController) HandleCreateCourse(c *gin.Context) {
 	price, err := strconv.ParseFloat(priceStr, 64)
 	if err != nil {
 		c.HTML(http.StatusBadRequest, "course-create.html", gin.H{
-			"Title": "Create Course",
-			"User":  userModel,
-			"Error": "Invalid price format",
+			"Title":       "Create Course",
+			"User":        userModel,
+			"Instructors": instructors,
+			"Error":       "Invalid price format",
+		})
+		return
+	}
+
+	instructorID, err := cc.courseService.ResolveInstructor(userModel, c.PostForm("instructor_id"), instructor)
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "course-create.html", gin.H{
+			"Title":       "Create Course",
+			"User":        userModel,
+			"Instructors": instructors,
+			"Error":       err.Error(),
 		})
 		return
 	}
@@ -154,10 +176,11 @@ func (cc *CourseController) HandleCreateCourse(c *gin.Context) {
 		defer file.Close()
 		thumbnailURL, err = cc.courseService.SaveThumbnail(header)
 		if err != nil {
-			c.HTML(http.StatusInternalServerError, "course-create.html", gin.H{
-				"Title": "Create Course",
-				"User":  userModel,
-				"Error": "Failed to save thumbnail: " + err.Error(),
+			c.HTML(uploadStatus(err), "course-create.html", gin.H{
+				"Title":       "Create Course",
+				"User":        userModel,
+				"Instructors": instructors,
+				"Error":       "Failed to save thumbnail: " + err.Error(),
 			})
 			return
 		}
@@ -165,20 +188,22 @@ func (cc *CourseController) HandleCreateCourse(c *gin.Context) {
 
 	// Create course
 	course := &models.Course{
-		Title:       title,
-		Description: description,
-		Instructor:  instructor,
-		Price:       price,
-		Thumbnail:   thumbnailURL,
-		Topics:      topics,
+		Title:        title,
+		Description:  description,
+		InstructorID: instructorID,
+		Price:        price,
+		Thumbnail:    thumbnailURL,
+		Topics:       topics,
+		OwnerID:      &userModel.ID,
 	}
 
-	createdCourse, err := cc.courseService.CreateCourse(course)
+	createdCourse, err := cc.courseService.CreateCourse(course, middleware.CurrentActor(c))
 	if err != nil {
 		c.HTML(http.StatusInternalServerError, "course-create.html", gin.H{
-			"Title": "Create Course",
-			"User":  userModel,
-			"Error": "Failed to create course: " + err.Error(),
+			"Title":       "Create Course",
+			"User":        userModel,
+			"Instructors": instructors,
+			"Error":       "Failed to create course: " + err.Error(),
 		})
 		return
 	}
@@ -194,13 +219,14 @@ func (cc *CourseController) HandleUpdateCourse(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.Redirect(http.StatusFound, "/dashboard")
-		return
-	}
 
 	courseID := c.Param("id")
 
+	if canEdit, err := cc.courseService.CanEditCourse(userModel, courseID); err != nil || !canEdit {
+		c.Redirect(http.StatusFound, "/admin/courses?error="+services.ErrCourseNotOwned.Error())
+		return
+	}
+
 	// Get existing course
 	existingCourse, err := cc.courseService.GetCourseByID(courseID, userModel.ID)
 	if err != nil {
@@ -212,6 +238,8 @@ func (cc *CourseController) HandleUpdateCourse(c *gin.Context) {
 		return
 	}
 
+	instructors, _ := cc.instructorService.GetInstructors()
+
 	// Handle form submission
 	title := c.PostForm("title")
 	description := c.PostForm("description")
@@ -220,12 +248,13 @@ func (cc *CourseController) HandleUpdateCourse(c *gin.Context) {
 	topics := c.PostFormArray("topics")
 
 	// Validate required fields
-	if title == "" || instructor == "" || priceStr == "" {
+	if title == "" || priceStr == "" {
 		c.HTML(http.StatusBadRequest, "course-edit.html", gin.H{
-			"Title":  "Edit Course",
-			"User":   userModel,
-			"Course": existingCourse,
-			"Error":  "Title, instructor, and price are required",
+			"Title":       "Edit Course",
+			"User":        userModel,
+			"Course":      existingCourse,
+			"Instructors": instructors,
+			"Error":       "Title and price are required",
 		})
 		return
 	}
@@ -233,18 +262,35 @@ func (cc *CourseController) HandleUpdateCourse(c *gin.Context) {
 	price, err := strconv.ParseFloat(priceStr, 64)
 	if err != nil {
 		c.HTML(http.StatusBadRequest, "course-edit.html", gin.H{
-			"Title":  "Edit Course",
-			"User":   userModel,
-			"Course": existingCourse,
-			"Error":  "Invalid price format",
+			"Title":       "Edit Course",
+			"User":        userModel,
+			"Course":      existingCourse,
+			"Instructors": instructors,
+			"Error":       "Invalid price format",
 		})
 		return
 	}
 
+	// Keep the current instructor unless the form names a different one
+	instructorID, _ := existingCourse["instructor_id"].(*string)
+	if c.PostForm("instructor_id") != "" || instructor != "" {
+		instructorID, err = cc.courseService.ResolveInstructor(userModel, c.PostForm("instructor_id"), instructor)
+		if err != nil {
+			c.HTML(http.StatusBadRequest, "course-edit.html", gin.H{
+				"Title":       "Edit Course",
+				"User":        userModel,
+				"Course":      existingCourse,
+				"Instructors": instructors,
+				"Error":       err.Error(),
+			})
+			return
+		}
+	}
+
 	// Handle file upload
 	thumbnailURL := ""
-	if thumbnailValue, exists := existingCourse["thumbnail_image"]; exists && thumbnailValue != nil {
-		thumbnailURL = thumbnailValue.(string)
+	if thumbnail, ok := existingCourse["thumbnail_image"].(map[string]interface{}); ok {
+		thumbnailURL, _ = thumbnail["src"].(string)
 	}
 
 	file, header, err := c.Request.FormFile("thumbnail")
@@ -252,11 +298,12 @@ func (cc *CourseController) HandleUpdateCourse(c *gin.Context) {
 		defer file.Close()
 		thumbnailURL, err = cc.courseService.SaveThumbnail(header)
 		if err != nil {
-			c.HTML(http.StatusInternalServerError, "course-edit.html", gin.H{
-				"Title":  "Edit Course",
-				"User":   userModel,
-				"Course": existingCourse,
-				"Error":  "Failed to save thumbnail: " + err.Error(),
+			c.HTML(uploadStatus(err), "course-edit.html", gin.H{
+				"Title":       "Edit Course",
+				"User":        userModel,
+				"Course":      existingCourse,
+				"Instructors": instructors,
+				"Error":       "Failed to save thumbnail: " + err.Error(),
 			})
 			return
 		}
@@ -264,22 +311,23 @@ func (cc *CourseController) HandleUpdateCourse(c *gin.Context) {
 
 	// Update course
 	course := &models.Course{
-		ID:          courseID,
-		Title:       title,
-		Description: description,
-		Instructor:  instructor,
-		Price:       price,
-		Thumbnail:   thumbnailURL,
-		Topics:      topics,
+		ID:           courseID,
+		Title:        title,
+		Description:  description,
+		InstructorID: instructorID,
+		Price:        price,
+		Thumbnail:    thumbnailURL,
+		Topics:       topics,
 	}
 
-	_, err = cc.courseService.UpdateCourse(course)
+	_, err = cc.courseService.UpdateCourse(course, middleware.CurrentActor(c))
 	if err != nil {
 		c.HTML(http.StatusInternalServerError, "course-edit.html", gin.H{
-			"Title":  "Edit Course",
-			"User":   userModel,
-			"Course": existingCourse,
-			"Error":  "Failed to update course: " + err.Error(),
+			"Title":       "Edit Course",
+			"User":        userModel,
+			"Course":      existingCourse,
+			"Instructors": instructors,
+			"Error":       "Failed to update course: " + err.Error(),
 		})
 		return
 	}
@@ -287,6 +335,61 @@ func (cc *CourseController) HandleUpdateCourse(c *gin.Context) {
 	c.Redirect(http.StatusFound, "/admin/courses?success=Course updated successfully&id="+courseID)
 }
 
+// HandleSetCourseStatus applies the publishing form of the course edit page.
+// The schedule fields are datetime-local inputs in the server's time zone.
+func (cc *CourseController) HandleSetCourseStatus(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Redirect(http.StatusFound, "/auth/login")
+		return
+	}
+
+	userModel := user.(models.User)
+	courseID := c.Param("id")
+	editURL := "/admin/courses/" + courseID + "/edit"
+
+	if canEdit, err := cc.courseService.CanEditCourse(userModel, courseID); err != nil || !canEdit {
+		c.Redirect(http.StatusFound, "/admin/courses?error="+services.ErrCourseNotOwned.Error())
+		return
+	}
+
+	publishAt, err := parseScheduleTime(c.PostForm("publish_at"))
+	if err != nil {
+		c.Redirect(http.StatusFound, editURL+"?error=Invalid publish date")
+		return
+	}
+	unpublishAt, err := parseScheduleTime(c.PostForm("unpublish_at"))
+	if err != nil {
+		c.Redirect(http.StatusFound, editURL+"?error=Invalid unpublish date")
+		return
+	}
+
+	change := services.CourseStatusChange{Status: c.PostForm("status"), PublishAt: publishAt, UnpublishAt: unpublishAt}
+	if _, err := cc.courseService.SetCourseStatus(userModel, courseID, change, middleware.CurrentActor(c)); err != nil {
+		message := "Failed to change course status"
+		if errors.Is(err, services.ErrInvalidCourseStatus) || errors.Is(err, services.ErrInvalidPublishAt) ||
+			errors.Is(err, services.ErrInvalidUnpublishAt) || errors.Is(err, services.ErrCoursePublishDenied) {
+			message = err.Error()
+		}
+		c.Redirect(http.StatusFound, editURL+"?error="+message)
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/admin/courses?success=Course status updated&id="+courseID)
+}
+
+// parseScheduleTime reads a datetime-local input; an empty value means no schedule
+func parseScheduleTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	at, err := time.ParseInLocation("2006-01-02T15:04", value, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	return &at, nil
+}
+
 func (cc *CourseController) HandleDeleteCourse(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
@@ -295,13 +398,14 @@ func (cc *CourseController) HandleDeleteCourse(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+	courseID := c.Param("id")
+
+	if canEdit, err := cc.courseService.CanEditCourse(userModel, courseID); err != nil || !canEdit {
+		c.JSON(http.StatusForbidden, gin.H{"error": services.ErrCourseNotOwned.Error()})
 		return
 	}
 
-	courseID := c.Param("id")
-	err := cc.courseService.DeleteCourse(courseID)
+	err := cc.courseService.DeleteCourse(courseID, middleware.CurrentActor(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete course"})
 		return
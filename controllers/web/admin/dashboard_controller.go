@@ -3,14 +3,19 @@ package admin
 import (
 	"net/http"
 	"yonatan/labpro/models"
+	"yonatan/labpro/services"
 
 	"github.com/gin-gonic/gin"
 )
 
-type DashboardController struct{}
+type DashboardController struct {
+	statsService *services.StatsService
+}
 
-func NewDashboardController() *DashboardController {
-	return &DashboardController{}
+func NewDashboardController(statsService *services.StatsService) *DashboardController {
+	return &DashboardController{
+		statsService: statsService,
+	}
 }
 
 func (dc *DashboardController) ShowAdminDashboard(c *gin.Context) {
@@ -21,22 +26,44 @@ func (dc *DashboardController) ShowAdminDashboard(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.Redirect(http.StatusFound, "/dashboard")
+
+	// Get dashboard stats for the last 30 days
+	stats, err := dc.statsService.GetDashboardStats(30)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "dashboard.html", gin.H{
+			"Title": "Admin Dashboard",
+			"User":  userModel,
+			"Error": "Failed to load dashboard statistics",
+		})
 		return
 	}
 
-	// Get dashboard stats
-	stats := gin.H{
-		"TotalUsers":     100,      // Replace with actual count from database
-		"TotalCourses":   25,       // Replace with actual count from database
-		"TotalRevenue":   15000.50, // Replace with actual calculation
-		"ActiveStudents": 85,       // Replace with actual count from database
+	// Scale the daily bars against the busiest day
+	maxDailyPurchases := int64(0)
+	for _, day := range stats.DailyPurchases {
+		if day.Purchases > maxDailyPurchases {
+			maxDailyPurchases = day.Purchases
+		}
+	}
+
+	dailyBars := make([]gin.H, len(stats.DailyPurchases))
+	for i, day := range stats.DailyPurchases {
+		percent := int64(0)
+		if maxDailyPurchases > 0 {
+			percent = day.Purchases * 100 / maxDailyPurchases
+		}
+		dailyBars[i] = gin.H{
+			"Date":      day.Date,
+			"Purchases": day.Purchases,
+			"Revenue":   day.Revenue,
+			"Percent":   percent,
+		}
 	}
 
 	c.HTML(http.StatusOK, "dashboard.html", gin.H{
-		"Title": "Admin Dashboard",
-		"User":  userModel,
-		"Stats": stats,
+		"Title":     "Admin Dashboard",
+		"User":      userModel,
+		"Stats":     stats,
+		"DailyBars": dailyBars,
 	})
 }
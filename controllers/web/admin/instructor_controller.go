@@ -0,0 +1,71 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"yonatan/labpro/models"
+	"yonatan/labpro/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InstructorController struct {
+	instructorService *services.InstructorService
+}
+
+func NewInstructorController(instructorService *services.InstructorService) *InstructorController {
+	return &InstructorController{
+		instructorService: instructorService,
+	}
+}
+
+func (ic *InstructorController) ShowInstructorDashboard(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Redirect(http.StatusFound, "/auth/login")
+		return
+	}
+
+	userModel := user.(models.User)
+
+	// Users without a profile get the page with only the profile form
+	dashboard, err := ic.instructorService.GetDashboard(userModel.ID)
+	errorMsg := c.Query("error")
+	if err != nil && !errors.Is(err, services.ErrInstructorNotFound) {
+		errorMsg = "Failed to load instructor dashboard"
+	}
+
+	c.HTML(http.StatusOK, "instructor-dashboard.html", gin.H{
+		"Title":     "My Teaching",
+		"User":      userModel,
+		"Dashboard": dashboard,
+		"Success":   c.Query("success"),
+		"Error":     errorMsg,
+	})
+}
+
+func (ic *InstructorController) HandleUpdateProfile(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Redirect(http.StatusFound, "/auth/login")
+		return
+	}
+
+	userModel := user.(models.User)
+
+	var links []string
+	for _, link := range strings.Split(c.PostForm("links"), "\n") {
+		if link = strings.TrimSpace(link); link != "" {
+			links = append(links, link)
+		}
+	}
+
+	_, err := ic.instructorService.SaveOwnProfile(userModel, c.PostForm("name"), c.PostForm("bio"), c.PostForm("avatar_url"), links)
+	if err != nil {
+		c.Redirect(http.StatusFound, "/admin/instructor?error=Failed to save profile")
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/admin/instructor?success=Profile saved")
+}
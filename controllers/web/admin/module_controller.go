@@ -5,6 +5,8 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"yonatan/labpro/filecheck"
+	"yonatan/labpro/middleware"
 	"yonatan/labpro/models"
 	"yonatan/labpro/services"
 
@@ -23,6 +25,15 @@ func NewModuleController(moduleService *services.ModuleService, courseService *s
 	}
 }
 
+// uploadStatus is 400 for files the upload checks refused and 500 when saving
+// failed for another reason
+func uploadStatus(err error) int {
+	if filecheck.IsRejected(err) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
 func (mc *ModuleController) ShowCourseModulesPage(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
@@ -31,10 +42,6 @@ func (mc *ModuleController) ShowCourseModulesPage(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.Redirect(http.StatusFound, "/dashboard")
-		return
-	}
 
 	// Get course ID from URL parameter
 	courseID := c.Param("id")
@@ -69,10 +76,6 @@ func (mc *ModuleController) ShowModulesPage(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.Redirect(http.StatusFound, "/dashboard")
-		return
-	}
 
 	// Get query parameters
 	courseID := c.Query("course_id")
@@ -96,6 +99,7 @@ func (mc *ModuleController) ShowModulesPage(c *gin.Context) {
 		"Modules":    modules,
 		"Pagination": pagination,
 		"CourseID":   courseID,
+		"Error":      c.Query("error"),
 	})
 }
 
@@ -107,15 +111,11 @@ func (mc *ModuleController) ShowCreateModulePage(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.Redirect(http.StatusFound, "/dashboard")
-		return
-	}
 
 	courseID := c.Query("course_id")
 
-	// Get all courses for dropdown
-	courses, _, err := mc.courseService.GetCourses("", 1, 1000, userModel.ID)
+	// Get all courses for dropdown, drafts included
+	courses, _, err := mc.courseService.GetManagedCourses("", "", 1, 1000)
 	if err != nil {
 		c.HTML(http.StatusInternalServerError, "module-create.html", gin.H{
 			"Title":    "Create Module",
@@ -142,13 +142,14 @@ func (mc *ModuleController) ShowCreateModulePageForCourse(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.Redirect(http.StatusFound, "/dashboard")
-		return
-	}
 
 	courseID := c.Param("id")
 
+	if canEdit, err := mc.moduleService.CanEditCourseModules(userModel, courseID); err != nil || !canEdit {
+		c.Redirect(http.StatusFound, "/admin/modules?error="+services.ErrCourseNotOwned.Error())
+		return
+	}
+
 	// Get the specific course for display
 	course, err := mc.courseService.GetCourseByID(courseID, userModel.ID)
 	if err != nil {
@@ -175,12 +176,14 @@ func (mc *ModuleController) ShowEditModulePage(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.Redirect(http.StatusFound, "/dashboard")
+
+	moduleID := c.Param("id")
+
+	if canEdit, err := mc.moduleService.CanEditModule(userModel, moduleID); err != nil || !canEdit {
+		c.Redirect(http.StatusFound, "/admin/modules?error="+services.ErrCourseNotOwned.Error())
 		return
 	}
 
-	moduleID := c.Param("id")
 	module, err := mc.moduleService.GetModuleByID(moduleID, nil, "admin")
 	if err != nil {
 		c.HTML(http.StatusNotFound, "module-edit.html", gin.H{
@@ -209,11 +212,6 @@ func (mc *ModuleController) HandleCreateModule(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		log.Printf("HandleCreateModule: User %s is not admin, redirecting to dashboard", userModel.Email)
-		c.Redirect(http.StatusFound, "/dashboard")
-		return
-	}
 
 	log.Printf("HandleCreateModule: Admin user %s processing request", userModel.Email)
 
@@ -254,6 +252,11 @@ func (mc *ModuleController) HandleCreateModule(c *gin.Context) {
 		return
 	}
 
+	if canEdit, err := mc.moduleService.CanEditCourseModules(userModel, courseID); err != nil || !canEdit {
+		c.Redirect(http.StatusFound, "/admin/modules?error="+services.ErrCourseNotOwned.Error())
+		return
+	}
+
 	// Handle file uploads
 	var pdfURL, videoURL *string
 
@@ -283,23 +286,11 @@ func (mc *ModuleController) HandleCreateModule(c *gin.Context) {
 		defer pdfFile.Close()
 		log.Printf("HandleCreateModule: Processing PDF file: %s (size: %d bytes)", pdfHeader.Filename, pdfHeader.Size)
 
-		// Validate PDF file size (10MB limit)
-		if pdfHeader.Size > 10*1024*1024 {
-			log.Printf("HandleCreateModule: PDF file too large: %d bytes", pdfHeader.Size)
-			c.HTML(http.StatusBadRequest, "module-create.html", gin.H{
-				"Title":    "Create Module",
-				"User":     userModel,
-				"CourseID": courseID,
-				"Error":    "PDF file size must be less than 10MB",
-			})
-			return
-		}
-
 		log.Println("HandleCreateModule: Calling SavePDF service method")
 		pdfContent, err := mc.moduleService.SavePDF(pdfHeader)
 		if err != nil {
 			log.Printf("HandleCreateModule: Failed to save PDF: %v", err)
-			c.HTML(http.StatusInternalServerError, "module-create.html", gin.H{
+			c.HTML(uploadStatus(err), "module-create.html", gin.H{
 				"Title":    "Create Module",
 				"User":     userModel,
 				"CourseID": courseID,
@@ -326,23 +317,11 @@ func (mc *ModuleController) HandleCreateModule(c *gin.Context) {
 		defer videoFile.Close()
 		log.Printf("HandleCreateModule: Processing video file: %s (size: %d bytes)", videoHeader.Filename, videoHeader.Size)
 
-		// Validate video file size (100MB limit)
-		if videoHeader.Size > 100*1024*1024 {
-			log.Printf("HandleCreateModule: Video file too large: %d bytes", videoHeader.Size)
-			c.HTML(http.StatusBadRequest, "module-create.html", gin.H{
-				"Title":    "Create Module",
-				"User":     userModel,
-				"CourseID": courseID,
-				"Error":    "Video file size must be less than 100MB",
-			})
-			return
-		}
-
 		log.Println("HandleCreateModule: Calling SaveVideo service method")
 		videoContent, err := mc.moduleService.SaveVideo(videoHeader)
 		if err != nil {
 			log.Printf("HandleCreateModule: Failed to save video: %v", err)
-			c.HTML(http.StatusInternalServerError, "module-create.html", gin.H{
+			c.HTML(uploadStatus(err), "module-create.html", gin.H{
 				"Title":    "Create Module",
 				"User":     userModel,
 				"CourseID": courseID,
@@ -370,7 +349,7 @@ func (mc *ModuleController) HandleCreateModule(c *gin.Context) {
 
 	// Create module using service method signature
 	log.Printf("HandleCreateModule: Creating module with courseID: %s, title: %s", courseID, title)
-	createdModule, err := mc.moduleService.CreateModule(courseID, title, description, pdfURL, videoURL)
+	createdModule, err := mc.moduleService.CreateModule(courseID, title, description, pdfURL, videoURL, middleware.CurrentActor(c))
 	if err != nil {
 		log.Printf("HandleCreateModule: Failed to create module: %v", err)
 		c.HTML(http.StatusInternalServerError, "module-create.html", gin.H{
@@ -398,15 +377,15 @@ func (mc *ModuleController) HandleUpdateModule(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		log.Printf("HandleUpdateModule: User %s is not admin, redirecting to dashboard", userModel.Email)
-		c.Redirect(http.StatusFound, "/dashboard")
-		return
-	}
 
 	moduleID := c.Param("id")
 	log.Printf("HandleUpdateModule: Updating module with ID: %s", moduleID)
 
+	if canEdit, err := mc.moduleService.CanEditModule(userModel, moduleID); err != nil || !canEdit {
+		c.Redirect(http.StatusFound, "/admin/modules?error="+services.ErrCourseNotOwned.Error())
+		return
+	}
+
 	// Get existing module
 	existingModule, err := mc.moduleService.GetModuleByID(moduleID, nil, "admin")
 	if err != nil {
@@ -481,23 +460,11 @@ func (mc *ModuleController) HandleUpdateModule(c *gin.Context) {
 		defer pdfFile.Close()
 		log.Printf("HandleUpdateModule: Processing new PDF file: %s (size: %d bytes)", pdfHeader.Filename, pdfHeader.Size)
 
-		// Validate PDF file size (10MB limit)
-		if pdfHeader.Size > 10*1024*1024 {
-			log.Printf("HandleUpdateModule: PDF file too large: %d bytes", pdfHeader.Size)
-			c.HTML(http.StatusBadRequest, "module-edit.html", gin.H{
-				"Title":  "Edit Module",
-				"User":   userModel,
-				"Module": existingModule,
-				"Error":  "PDF file size must be less than 10MB",
-			})
-			return
-		}
-
 		log.Println("HandleUpdateModule: Calling SavePDF service method")
 		pdfContent, err := mc.moduleService.SavePDF(pdfHeader)
 		if err != nil {
 			log.Printf("HandleUpdateModule: Failed to save PDF: %v", err)
-			c.HTML(http.StatusInternalServerError, "module-edit.html", gin.H{
+			c.HTML(uploadStatus(err), "module-edit.html", gin.H{
 				"Title":  "Edit Module",
 				"User":   userModel,
 				"Module": existingModule,
@@ -517,23 +484,11 @@ func (mc *ModuleController) HandleUpdateModule(c *gin.Context) {
 		defer videoFile.Close()
 		log.Printf("HandleUpdateModule: Processing new video file: %s (size: %d bytes)", videoHeader.Filename, videoHeader.Size)
 
-		// Validate video file size (100MB limit)
-		if videoHeader.Size > 100*1024*1024 {
-			log.Printf("HandleUpdateModule: Video file too large: %d bytes", videoHeader.Size)
-			c.HTML(http.StatusBadRequest, "module-edit.html", gin.H{
-				"Title":  "Edit Module",
-				"User":   userModel,
-				"Module": existingModule,
-				"Error":  "Video file size must be less than 100MB",
-			})
-			return
-		}
-
 		log.Println("HandleUpdateModule: Calling SaveVideo service method")
 		videoContent, err := mc.moduleService.SaveVideo(videoHeader)
 		if err != nil {
 			log.Printf("HandleUpdateModule: Failed to save video: %v", err)
-			c.HTML(http.StatusInternalServerError, "module-edit.html", gin.H{
+			c.HTML(uploadStatus(err), "module-edit.html", gin.H{
 				"Title":  "Edit Module",
 				"User":   userModel,
 				"Module": existingModule,
@@ -561,7 +516,7 @@ func (mc *ModuleController) HandleUpdateModule(c *gin.Context) {
 
 	// Update module using service method signature
 	log.Printf("HandleUpdateModule: Updating module with ID: %s, title: %s", moduleID, title)
-	_, err = mc.moduleService.UpdateModule(moduleID, title, description, pdfURL, videoURL)
+	_, err = mc.moduleService.UpdateModule(moduleID, title, description, pdfURL, videoURL, middleware.CurrentActor(c))
 	if err != nil {
 		log.Printf("HandleUpdateModule: Failed to update module: %v", err)
 		c.HTML(http.StatusInternalServerError, "module-edit.html", gin.H{
@@ -598,13 +553,14 @@ func (mc *ModuleController) HandleDeleteModule(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+	moduleID := c.Param("id")
+
+	if canEdit, err := mc.moduleService.CanEditModule(userModel, moduleID); err != nil || !canEdit {
+		c.JSON(http.StatusForbidden, gin.H{"error": services.ErrCourseNotOwned.Error()})
 		return
 	}
 
-	moduleID := c.Param("id")
-	err := mc.moduleService.DeleteModule(moduleID)
+	err := mc.moduleService.DeleteModule(moduleID, middleware.CurrentActor(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete module"})
 		return
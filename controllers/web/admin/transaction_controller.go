@@ -0,0 +1,87 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"yonatan/labpro/models"
+	"yonatan/labpro/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TransactionController struct {
+	transactionService *services.TransactionService
+}
+
+func NewTransactionController(transactionService *services.TransactionService) *TransactionController {
+	return &TransactionController{
+		transactionService: transactionService,
+	}
+}
+
+func (tc *TransactionController) ShowTransactionsPage(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Redirect(http.StatusFound, "/auth/login")
+		return
+	}
+
+	userModel := user.(models.User)
+
+	// Get query parameters for pagination and filters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filters := gin.H{
+		"UserID": c.Query("user_id"),
+		"Type":   c.Query("type"),
+		"From":   c.Query("from"),
+		"To":     c.Query("to"),
+	}
+	types := []string{
+		models.TransactionTypeTopUp,
+		models.TransactionTypePurchase,
+		models.TransactionTypeRefund,
+		models.TransactionTypeAdjustment,
+		models.TransactionTypeOpening,
+	}
+
+	filter, err := services.NewTransactionFilter(c.Query("user_id"), c.Query("type"), c.Query("course_id"), c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.HTML(http.StatusBadRequest, "transactions.html", gin.H{
+			"Title":   "Transactions",
+			"User":    userModel,
+			"Filters": filters,
+			"Types":   types,
+			"Error":   err.Error(),
+		})
+		return
+	}
+
+	transactions, pagination, err := tc.transactionService.GetTransactions(filter, page, limit)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "transactions.html", gin.H{
+			"Title":   "Transactions",
+			"User":    userModel,
+			"Filters": filters,
+			"Types":   types,
+			"Error":   "Failed to fetch transactions",
+		})
+		return
+	}
+
+	c.HTML(http.StatusOK, "transactions.html", gin.H{
+		"Title":        "Transactions",
+		"User":         userModel,
+		"Transactions": transactions,
+		"Pagination":   pagination,
+		"Filters":      filters,
+		"Types":        types,
+	})
+}
@@ -0,0 +1,106 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"yonatan/labpro/middleware"
+	"yonatan/labpro/models"
+	"yonatan/labpro/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TrashController struct {
+	trashService *services.TrashService
+}
+
+func NewTrashController(trashService *services.TrashService) *TrashController {
+	return &TrashController{
+		trashService: trashService,
+	}
+}
+
+// Each trash tab has its own route so it can require the permission needed to
+// delete that kind of item in the first place
+
+func (tc *TrashController) ShowUsersTrash(c *gin.Context) {
+	tc.showTrash(c, services.TrashUsers)
+}
+
+func (tc *TrashController) ShowCoursesTrash(c *gin.Context) {
+	tc.showTrash(c, services.TrashCourses)
+}
+
+func (tc *TrashController) ShowModulesTrash(c *gin.Context) {
+	tc.showTrash(c, services.TrashModules)
+}
+
+func (tc *TrashController) HandleRestoreUser(c *gin.Context) {
+	tc.handleRestore(c, services.TrashUsers, "User", tc.trashService.RestoreUser)
+}
+
+func (tc *TrashController) HandleRestoreCourse(c *gin.Context) {
+	tc.handleRestore(c, services.TrashCourses, "Course", tc.trashService.RestoreCourse)
+}
+
+func (tc *TrashController) HandleRestoreModule(c *gin.Context) {
+	tc.handleRestore(c, services.TrashModules, "Module", tc.trashService.RestoreModule)
+}
+
+func (tc *TrashController) showTrash(c *gin.Context, itemType string) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Redirect(http.StatusFound, "/auth/login")
+		return
+	}
+
+	userModel := user.(models.User)
+
+	// Get query parameters for pagination and search
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	query := c.Query("q")
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	data := gin.H{
+		"Title":   "Trash",
+		"User":    userModel,
+		"Type":    itemType,
+		"Types":   services.TrashTypes,
+		"Query":   query,
+		"Success": c.Query("success"),
+		"Error":   c.Query("error"),
+	}
+
+	items, pagination, err := tc.trashService.GetTrash(itemType, query, page, limit)
+	if err != nil {
+		data["Error"] = "Failed to fetch the trash"
+		c.HTML(http.StatusInternalServerError, "trash.html", data)
+		return
+	}
+
+	data["Items"] = items
+	data["Pagination"] = pagination
+	c.HTML(http.StatusOK, "trash.html", data)
+}
+
+func (tc *TrashController) handleRestore(c *gin.Context, itemType, label string, restore func(string, services.AuditActor) error) {
+	redirectURL := "/admin/trash/" + itemType
+
+	if err := restore(c.Param("id"), middleware.CurrentActor(c)); err != nil {
+		message := "Failed to restore " + label
+		if errors.Is(err, services.ErrNotInTrash) || errors.Is(err, services.ErrRestoreConflict) || errors.Is(err, services.ErrCourseInTrash) {
+			message = err.Error()
+		}
+		c.Redirect(http.StatusFound, redirectURL+"?error="+message)
+		return
+	}
+
+	c.Redirect(http.StatusFound, redirectURL+"?success="+label+" restored")
+}
@@ -1,8 +1,10 @@
 package admin
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"yonatan/labpro/middleware"
 	"yonatan/labpro/models"
 	"yonatan/labpro/services"
 
@@ -10,12 +12,22 @@ import (
 )
 
 type UserController struct {
-	userService *services.UserService
+	userService        *services.UserService
+	transactionService *services.TransactionService
+	courseService      *services.CourseService
+	roleService        *services.RoleService
+	loginThrottle      *services.LoginThrottle
+	mfaService         *services.MFAService
 }
 
-func NewUserController(userService *services.UserService) *UserController {
+func NewUserController(userService *services.UserService, transactionService *services.TransactionService, courseService *services.CourseService, roleService *services.RoleService, loginThrottle *services.LoginThrottle, mfaService *services.MFAService) *UserController {
 	return &UserController{
-		userService: userService,
+		userService:        userService,
+		transactionService: transactionService,
+		courseService:      courseService,
+		roleService:        roleService,
+		loginThrottle:      loginThrottle,
+		mfaService:         mfaService,
 	}
 }
 
@@ -27,10 +39,6 @@ func (uc *UserController) ShowUsersPage(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.Redirect(http.StatusFound, "/dashboard")
-		return
-	}
 
 	// Get query parameters for pagination and search
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
@@ -65,10 +73,6 @@ func (uc *UserController) ShowEditUserPage(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.Redirect(http.StatusFound, "/dashboard")
-		return
-	}
 
 	userID := c.Param("id")
 	targetUser, err := uc.userService.GetUserByID(userID)
@@ -96,10 +100,6 @@ func (uc *UserController) HandleUpdateUser(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.Redirect(http.StatusFound, "/dashboard")
-		return
-	}
 
 	userID := c.Param("id")
 
@@ -136,7 +136,7 @@ func (uc *UserController) HandleUpdateUser(c *gin.Context) {
 	isAdmin := isAdminStr == "on" || isAdminStr == "true"
 
 	// Update user (note: password is optional, empty string means no change)
-	_, err = uc.userService.UpdateUser(userID, email, username, firstName, lastName, password)
+	_, err = uc.userService.UpdateUser(userID, email, username, firstName, lastName, password, middleware.CurrentActor(c))
 	if err != nil {
 		c.HTML(http.StatusInternalServerError, "user-edit.html", gin.H{
 			"Title":      "Edit User",
@@ -161,10 +161,6 @@ func (uc *UserController) HandleDeleteUser(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
-		return
-	}
 
 	userID := c.Param("id")
 
@@ -174,7 +170,11 @@ func (uc *UserController) HandleDeleteUser(c *gin.Context) {
 		return
 	}
 
-	err := uc.userService.DeleteUser(userID)
+	err := uc.userService.DeleteUser(userID, middleware.CurrentActor(c))
+	if errors.Is(err, services.ErrLastAdmin) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
 		return
@@ -191,10 +191,6 @@ func (uc *UserController) ShowUserDetails(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.Redirect(http.StatusFound, "/dashboard")
-		return
-	}
 
 	userID := c.Param("id")
 	targetUser, err := uc.userService.GetUserByID(userID)
@@ -207,9 +203,25 @@ func (uc *UserController) ShowUserDetails(c *gin.Context) {
 		return
 	}
 
-	// Get user's enrolled courses (for now, just return empty slice)
-	// TODO: Add GetUserEnrolledCourses method to UserService if needed
-	enrolledCourses := []models.Course{}
+	// Get user's enrolled courses
+	enrolledCourses, err := uc.userService.GetUserEnrolledCourses(userID)
+	if err != nil {
+		enrolledCourses = []models.Course{}
+	}
+
+	// Latest ledger entries and a consistency check of the stored balance
+	transactions, _, _ := uc.transactionService.GetTransactions(services.TransactionFilter{UserID: userID}, 1, 10)
+	reconciliation, _ := uc.transactionService.Reconcile(userID)
+
+	// Assigned roles and the ones that can still be granted
+	userRoles, _ := uc.roleService.GetUserRoles(userID)
+	allRoles, _ := uc.roleService.GetRoles()
+
+	// Remaining lockout after failed logins, in whole seconds
+	lockedFor := int64(0)
+	if wait := uc.loginThrottle.LockedFor(userID); wait > 0 {
+		lockedFor, _ = strconv.ParseInt(services.RetryAfterSeconds(wait), 10, 64)
+	}
 
 	// Get success and error messages from query parameters
 	successMsg := c.Query("success")
@@ -220,6 +232,12 @@ func (uc *UserController) ShowUserDetails(c *gin.Context) {
 		"User":            userModel,
 		"TargetUser":      targetUser,
 		"EnrolledCourses": enrolledCourses,
+		"Transactions":    transactions,
+		"Reconciliation":  reconciliation,
+		"UserRoles":       userRoles,
+		"AllRoles":        allRoles,
+		"CanManageRoles":  uc.roleService.HasPermission(userModel, models.PermissionRolesWrite),
+		"LockedFor":       lockedFor,
 		"Success":         successMsg,
 		"Error":           errorMsg,
 	})
@@ -233,10 +251,6 @@ func (uc *UserController) ShowCreateUserPage(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.Redirect(http.StatusFound, "/dashboard")
-		return
-	}
 
 	c.HTML(http.StatusOK, "user-create.html", gin.H{
 		"Title": "Create User",
@@ -252,10 +266,6 @@ func (uc *UserController) CreateUser(c *gin.Context) {
 	}
 
 	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.Redirect(http.StatusFound, "/dashboard")
-		return
-	}
 
 	// Get form data
 	firstName := c.PostForm("first_name")
@@ -278,7 +288,7 @@ func (uc *UserController) CreateUser(c *gin.Context) {
 	}
 
 	// Create user
-	newUser, err := uc.userService.CreateUser(firstName, lastName, username, email, password, isAdmin)
+	newUser, err := uc.userService.CreateUser(firstName, lastName, username, email, password, isAdmin, middleware.CurrentActor(c))
 	if err != nil {
 		c.HTML(http.StatusBadRequest, "user-create.html", gin.H{
 			"Title": "Create User",
@@ -293,18 +303,11 @@ func (uc *UserController) CreateUser(c *gin.Context) {
 }
 
 func (uc *UserController) HandleUpdateBalance(c *gin.Context) {
-	user, exists := c.Get("user")
-	if !exists {
+	if _, exists := c.Get("user"); !exists {
 		c.Redirect(http.StatusFound, "/auth/login")
 		return
 	}
 
-	userModel := user.(models.User)
-	if !userModel.IsAdmin {
-		c.Redirect(http.StatusFound, "/dashboard")
-		return
-	}
-
 	userID := c.Param("id")
 	amountStr := c.PostForm("amount")
 
@@ -315,7 +318,7 @@ func (uc *UserController) HandleUpdateBalance(c *gin.Context) {
 	}
 
 	// Update the user's balance
-	_, err = uc.userService.UpdateUserBalance(userID, amount)
+	_, err = uc.userService.UpdateUserBalance(userID, amount, middleware.CurrentActor(c))
 	if err != nil {
 		c.Redirect(http.StatusFound, "/admin/users/"+userID+"?error=Failed to update balance")
 		return
@@ -324,3 +327,76 @@ func (uc *UserController) HandleUpdateBalance(c *gin.Context) {
 	// Redirect back to user details with success message
 	c.Redirect(http.StatusFound, "/admin/users/"+userID+"?success=Balance updated successfully")
 }
+
+func (uc *UserController) HandleRefundCourse(c *gin.Context) {
+	if _, exists := c.Get("user"); !exists {
+		c.Redirect(http.StatusFound, "/auth/login")
+		return
+	}
+
+	userID := c.Param("id")
+	courseID := c.Param("courseId")
+
+	// Admins bypass the refund window and progress threshold
+	if _, err := uc.courseService.RefundCourse(courseID, userID, middleware.CurrentActor(c), true); err != nil {
+		c.Redirect(http.StatusFound, "/admin/users/"+userID+"?error=Failed to refund course: "+err.Error())
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/admin/users/"+userID+"?success=Course refunded successfully")
+}
+
+func (uc *UserController) HandleRevokeSessions(c *gin.Context) {
+	userID := c.Param("id")
+
+	if err := uc.userService.RevokeSessions(userID, middleware.CurrentActor(c)); err != nil {
+		c.Redirect(http.StatusFound, "/admin/users/"+userID+"?error=Failed to sign out user")
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/admin/users/"+userID+"?success=User signed out of all sessions")
+}
+
+func (uc *UserController) HandleUnlockUser(c *gin.Context) {
+	userID := c.Param("id")
+
+	if err := uc.loginThrottle.UnlockAccount(userID, middleware.CurrentActor(c)); err != nil {
+		c.Redirect(http.StatusFound, "/admin/users/"+userID+"?error=Failed to unlock user")
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/admin/users/"+userID+"?success=User unlocked")
+}
+
+func (uc *UserController) HandleResetMFA(c *gin.Context) {
+	userID := c.Param("id")
+
+	if err := uc.mfaService.Reset(userID, middleware.CurrentActor(c)); err != nil {
+		c.Redirect(http.StatusFound, "/admin/users/"+userID+"?error=Failed to reset two-factor authentication")
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/admin/users/"+userID+"?success=Two-factor authentication reset")
+}
+
+func (uc *UserController) HandleAssignRole(c *gin.Context) {
+	userID := c.Param("id")
+
+	if err := uc.roleService.AssignRole(userID, c.PostForm("role"), middleware.CurrentActor(c)); err != nil {
+		c.Redirect(http.StatusFound, "/admin/users/"+userID+"?error="+err.Error())
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/admin/users/"+userID+"?success=Role assigned")
+}
+
+func (uc *UserController) HandleRemoveRole(c *gin.Context) {
+	userID := c.Param("id")
+
+	if err := uc.roleService.RemoveRole(userID, c.Param("role"), middleware.CurrentActor(c)); err != nil {
+		c.Redirect(http.StatusFound, "/admin/users/"+userID+"?error="+err.Error())
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/admin/users/"+userID+"?success=Role removed")
+}
@@ -1,19 +1,32 @@
 package web
 
 import (
+	"crypto/subtle"
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 	"yonatan/labpro/models"
 	"yonatan/labpro/services"
 
 	"github.com/gin-gonic/gin"
 )
 
+// oidcLoginTTL is how long a user has to finish signing in at the identity provider
+const oidcLoginTTL = 10 * time.Minute
+
 type AuthController struct {
-	authService *services.AuthService
+	authService    *services.AuthService
+	loginThrottle  *services.LoginThrottle
+	accountService *services.AccountService
+	mfaService     *services.MFAService
+	oidcService    *services.OIDCService
 }
 
-func NewAuthController(authService *services.AuthService) *AuthController {
-	return &AuthController{authService: authService}
+func NewAuthController(authService *services.AuthService, loginThrottle *services.LoginThrottle, accountService *services.AccountService, mfaService *services.MFAService, oidcService *services.OIDCService) *AuthController {
+	return &AuthController{authService: authService, loginThrottle: loginThrottle, accountService: accountService, mfaService: mfaService, oidcService: oidcService}
 }
 
 // Web Authentication Methods
@@ -25,7 +38,7 @@ func (ac *AuthController) ShowLoginPage(c *gin.Context) {
 		return
 	}
 
-	c.HTML(http.StatusOK, "login.html", gin.H{
+	ac.renderLogin(c, http.StatusOK, gin.H{
 		"Title": "Login",
 	})
 }
@@ -47,22 +60,209 @@ func (ac *AuthController) HandleLogin(c *gin.Context) {
 	password := c.PostForm("password")
 
 	if identifier == "" || password == "" {
-		c.HTML(http.StatusBadRequest, "login.html", gin.H{
+		ac.renderLogin(c, http.StatusBadRequest, gin.H{
 			"Title": "Login",
 			"Error": "Please provide both identifier and password",
 		})
 		return
 	}
 
-	token, user, err := ac.authService.Login(identifier, password)
+	if wait := ac.loginThrottle.Check(identifier, c.ClientIP()); wait > 0 {
+		c.Header("Retry-After", services.RetryAfterSeconds(wait))
+		ac.renderLogin(c, http.StatusTooManyRequests, gin.H{
+			"Title": "Login",
+			"Error": "Too many login attempts. Please wait " + services.RetryAfterSeconds(wait) + " seconds and try again.",
+		})
+		return
+	}
+
+	tokens, user, err := ac.authService.Login(identifier, password)
+	if errors.Is(err, services.ErrMFARequired) {
+		ac.beginMFA(c, user)
+		return
+	}
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			if wait := ac.loginThrottle.RecordFailure(identifier, c.ClientIP()); wait > 0 {
+				c.Header("Retry-After", services.RetryAfterSeconds(wait))
+			}
+		}
+		ac.renderLogin(c, http.StatusUnauthorized, gin.H{
+			"Title": "Login",
+			"Error": err.Error(),
+		})
+		return
+	}
+	ac.loginThrottle.RecordSuccess(identifier)
+
+	ac.startSession(c, user, tokens.AccessToken)
+}
+
+// beginMFA parks a login that still needs the second factor in a short-lived
+// cookie and sends the user to the code form
+func (ac *AuthController) beginMFA(c *gin.Context, user *models.User) {
+	mfaToken, err := ac.authService.IssueMFAToken(user.ID)
+	if err != nil {
+		ac.renderLogin(c, http.StatusInternalServerError, gin.H{
+			"Title": "Login",
+			"Error": "Failed to start two-factor login",
+		})
+		return
+	}
+
+	c.SetCookie("mfa_token", mfaToken, int(services.MFATokenTTL.Seconds()), "/auth/mfa", "", false, true)
+	c.Redirect(http.StatusFound, "/auth/mfa")
+}
+
+// HandleOIDCLogin sends the user to the identity provider. The state, nonce and
+// PKCE verifier wait in a cookie until the provider redirects back.
+func (ac *AuthController) HandleOIDCLogin(c *gin.Context) {
+	request, err := ac.oidcService.BeginLogin(c.Request.Context())
+	if err != nil {
+		status := http.StatusBadGateway
+		message := services.ErrOIDCLoginFailed.Error()
+		if errors.Is(err, services.ErrOIDCDisabled) {
+			status = http.StatusNotFound
+			message = err.Error()
+		} else {
+			log.Printf("Failed to start single sign-on: %v", err)
+		}
+
+		ac.renderLogin(c, status, gin.H{
+			"Title": "Login",
+			"Error": message,
+		})
+		return
+	}
+
+	c.SetCookie("oidc_login", request.State+"."+request.Nonce+"."+request.Verifier, int(oidcLoginTTL.Seconds()), "/auth/oidc", "", false, true)
+	c.Redirect(http.StatusFound, request.URL)
+}
+
+// HandleOIDCCallback finishes a single sign-on login and starts the same session
+// as a password login
+func (ac *AuthController) HandleOIDCCallback(c *gin.Context) {
+	renderError := func(status int, message string) {
+		ac.renderLogin(c, status, gin.H{
+			"Title": "Login",
+			"Error": message,
+		})
+	}
+
+	pending, _ := c.Cookie("oidc_login")
+	c.SetCookie("oidc_login", "", -1, "/auth/oidc", "", false, true)
+
+	parts := strings.Split(pending, ".")
+	if len(parts) != 3 || subtle.ConstantTimeCompare([]byte(parts[0]), []byte(c.Query("state"))) != 1 {
+		renderError(http.StatusBadRequest, "Your sign-in session expired, please try again")
+		return
+	}
+	if c.Query("error") != "" || c.Query("code") == "" {
+		renderError(http.StatusUnauthorized, services.ErrOIDCLoginFailed.Error())
+		return
+	}
+
+	user, err := ac.oidcService.CompleteLogin(c.Request.Context(), c.Query("code"), parts[2], parts[1])
+	if err != nil {
+		message := services.ErrOIDCLoginFailed.Error()
+		if errors.Is(err, services.ErrOIDCEmailNotVerified) || errors.Is(err, services.ErrOIDCNoAccount) || errors.Is(err, services.ErrOIDCDisabled) {
+			message = err.Error()
+		} else if !errors.Is(err, services.ErrOIDCLoginFailed) {
+			log.Printf("Failed to complete single sign-on: %v", err)
+		}
+		renderError(http.StatusUnauthorized, message)
+		return
+	}
+
+	// Accounts with two-factor authentication still need their code
+	if user.TOTPEnabledAt != nil {
+		ac.beginMFA(c, user)
+		return
+	}
+
+	tokens, err := ac.authService.CreateSession(user.ID)
+	if err != nil {
+		renderError(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ac.startSession(c, user, tokens.AccessToken)
+}
+
+// renderLogin shows the login form, offering single sign-on when it is configured
+func (ac *AuthController) renderLogin(c *gin.Context, status int, data gin.H) {
+	if ac.oidcService.Enabled() {
+		data["OIDCProvider"] = ac.oidcService.ProviderName()
+	}
+	c.HTML(status, "login.html", data)
+}
+
+func (ac *AuthController) ShowMFAPage(c *gin.Context) {
+	if token, err := c.Cookie("mfa_token"); err != nil || token == "" {
+		c.Redirect(http.StatusFound, "/auth/login")
+		return
+	}
+
+	c.HTML(http.StatusOK, "mfa.html", gin.H{
+		"Title": "Two-Factor Authentication",
+	})
+}
+
+func (ac *AuthController) HandleMFA(c *gin.Context) {
+	renderError := func(status int, message string) {
+		c.HTML(status, "mfa.html", gin.H{
+			"Title": "Two-Factor Authentication",
+			"Error": message,
+		})
+	}
+
+	mfaToken, _ := c.Cookie("mfa_token")
+	userID, err := ac.authService.ParseMFAToken(mfaToken)
+	if err != nil {
+		c.SetCookie("mfa_token", "", -1, "/auth/mfa", "", false, true)
+		ac.renderLogin(c, http.StatusUnauthorized, gin.H{
+			"Title": "Login",
+			"Error": err.Error(),
+		})
+		return
+	}
+
+	if wait := ac.loginThrottle.CheckUser(userID, c.ClientIP()); wait > 0 {
+		c.Header("Retry-After", services.RetryAfterSeconds(wait))
+		renderError(http.StatusTooManyRequests, "Too many attempts. Please wait "+services.RetryAfterSeconds(wait)+" seconds and try again.")
+		return
+	}
+
+	user, err := ac.mfaService.Verify(userID, c.PostForm("code"))
 	if err != nil {
-		c.HTML(http.StatusUnauthorized, "login.html", gin.H{
+		if wait := ac.loginThrottle.RecordUserFailure(userID, c.ClientIP()); wait > 0 {
+			c.Header("Retry-After", services.RetryAfterSeconds(wait))
+		}
+		renderError(http.StatusUnauthorized, services.ErrInvalidMFACode.Error())
+		return
+	}
+
+	tokens, err := ac.authService.CompleteMFALogin(mfaToken)
+	if errors.Is(err, services.ErrInvalidMFAToken) {
+		c.SetCookie("mfa_token", "", -1, "/auth/mfa", "", false, true)
+		ac.renderLogin(c, http.StatusUnauthorized, gin.H{
 			"Title": "Login",
 			"Error": err.Error(),
 		})
 		return
 	}
+	if err != nil {
+		renderError(http.StatusInternalServerError, err.Error())
+		return
+	}
+	ac.loginThrottle.Unlock(user.ID)
+	c.SetCookie("mfa_token", "", -1, "/auth/mfa", "", false, true)
 
+	ac.startSession(c, user, tokens.AccessToken)
+}
+
+// startSession stores the access token and sends the user to their landing page
+func (ac *AuthController) startSession(c *gin.Context, user *models.User, token string) {
 	// Set token as a cookie
 	c.SetCookie("token", token, 3600*24*7, "/", "", false, true) // 7 days
 
@@ -74,37 +274,12 @@ func (ac *AuthController) HandleLogin(c *gin.Context) {
 		userRole = "admin"
 	}
 
-	// Create a simple HTML page that sets localStorage and redirects
-	redirectHTML := `<!DOCTYPE html>
-<html>
-<head>
-    <title>Redirecting...</title>
-</head>
-<body>
-    <div style="display: flex; justify-content: center; align-items: center; height: 100vh; font-family: Arial, sans-serif;">
-        <div style="text-align: center;">
-            <div style="display: inline-block; width: 40px; height: 40px; border: 4px solid #f3f3f3; border-top: 4px solid #3498db; border-radius: 50%; animation: spin 1s linear infinite;"></div>
-            <p>Redirecting to dashboard...</p>
-        </div>
-    </div>
-    <style>
-        @keyframes spin {
-            0% { transform: rotate(0deg); }
-            100% { transform: rotate(360deg); }
-        }
-    </style>
-    <script>
-        localStorage.setItem('isLoggedIn', 'true');
-        localStorage.setItem('userRole', '` + userRole + `');
-        localStorage.setItem('authToken', '` + token + `');
-        setTimeout(function() {
-            window.location.href = '` + redirectURL + `';
-        }, 500);
-    </script>
-</body>
-</html>`
+	// Accounts created with a temporary password go straight to the change form
+	if user.MustChangePassword {
+		redirectURL = "/auth/change-password"
+	}
 
-	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(redirectHTML))
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(sessionRedirectHTML("Redirecting...", "Redirecting to dashboard...", userRole, token, redirectURL, 500)))
 }
 
 func (ac *AuthController) HandleRegister(c *gin.Context) {
@@ -156,16 +331,22 @@ func (ac *AuthController) HandleRegister(c *gin.Context) {
 		return
 	}
 
+	// The account is usable right away; failing to mail the link only delays verification
+	if err := ac.accountService.SendVerificationEmail(user); err != nil {
+		log.Printf("Warning: Failed to send verification email to %s: %v", user.Email, err)
+	}
+
 	// Auto login after registration
-	token, _, err := ac.authService.Login(username, password)
+	tokens, _, err := ac.authService.Login(username, password)
 	if err != nil {
 		// Registration succeeded but login failed, redirect to login page
-		c.HTML(http.StatusOK, "login.html", gin.H{
+		ac.renderLogin(c, http.StatusOK, gin.H{
 			"Title":   "Login",
 			"Success": "Registration successful! Please login with your credentials.",
 		})
 		return
 	}
+	token := tokens.AccessToken
 
 	// Set token as a cookie
 	c.SetCookie("token", token, 3600*24*7, "/", "", false, true)
@@ -178,40 +359,15 @@ func (ac *AuthController) HandleRegister(c *gin.Context) {
 		userRole = "admin"
 	}
 
-	// Create redirect HTML
-	redirectHTML := `<!DOCTYPE html>
-<html>
-<head>
-    <title>Registration Successful</title>
-</head>
-<body>
-    <div style="display: flex; justify-content: center; align-items: center; height: 100vh; font-family: Arial, sans-serif;">
-        <div style="text-align: center;">
-            <div style="display: inline-block; width: 40px; height: 40px; border: 4px solid #f3f3f3; border-top: 4px solid #3498db; border-radius: 50%; animation: spin 1s linear infinite;"></div>
-            <p>Registration successful! Redirecting to dashboard...</p>
-        </div>
-    </div>
-    <style>
-        @keyframes spin {
-            0% { transform: rotate(0deg); }
-            100% { transform: rotate(360deg); }
-        }
-    </style>
-    <script>
-        localStorage.setItem('isLoggedIn', 'true');
-        localStorage.setItem('userRole', '` + userRole + `');
-        localStorage.setItem('authToken', '` + token + `');
-        setTimeout(function() {
-            window.location.href = '` + redirectURL + `';
-        }, 1000);
-    </script>
-</body>
-</html>`
-
-	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(redirectHTML))
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(sessionRedirectHTML("Registration Successful", "Registration successful! Redirecting to dashboard...", userRole, token, redirectURL, 1000)))
 }
 
 func (ac *AuthController) HandleLogout(c *gin.Context) {
+	// Revoke the session server-side so a copied token stops working too
+	if token, err := c.Cookie("token"); err == nil && token != "" {
+		ac.authService.Logout(token, "")
+	}
+
 	// Clear the token cookie
 	c.SetCookie("token", "", -1, "/", "", false, true)
 
@@ -248,6 +404,315 @@ func (ac *AuthController) HandleLogout(c *gin.Context) {
 	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(logoutHTML))
 }
 
+func (ac *AuthController) ShowChangePasswordPage(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Redirect(http.StatusFound, "/auth/login")
+		return
+	}
+
+	c.HTML(http.StatusOK, "change-password.html", gin.H{
+		"Title": "Change Password",
+		"User":  user.(models.User),
+	})
+}
+
+func (ac *AuthController) HandleChangePassword(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Redirect(http.StatusFound, "/auth/login")
+		return
+	}
+
+	userModel := user.(models.User)
+	currentPassword := c.PostForm("current_password")
+	newPassword := c.PostForm("new_password")
+	confirmPassword := c.PostForm("confirm_password")
+
+	renderError := func(message string) {
+		c.HTML(http.StatusBadRequest, "change-password.html", gin.H{
+			"Title": "Change Password",
+			"User":  userModel,
+			"Error": message,
+		})
+	}
+
+	if currentPassword == "" || newPassword == "" {
+		renderError("All fields are required")
+		return
+	}
+	if len(newPassword) < 8 {
+		renderError("New password must be at least 8 characters")
+		return
+	}
+	if newPassword != confirmPassword {
+		renderError("Passwords do not match")
+		return
+	}
+
+	tokens, err := ac.authService.ChangePassword(userModel.ID, currentPassword, newPassword)
+	if err != nil {
+		renderError(err.Error())
+		return
+	}
+	token := tokens.AccessToken
+
+	// Every other session was ended, so swap in the new token
+	c.SetCookie("token", token, 3600*24*7, "/", "", false, true)
+
+	redirectURL := "/dashboard"
+	userRole := "user"
+	if userModel.IsAdmin {
+		redirectURL = "/admin/dashboard"
+		userRole = "admin"
+	}
+
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(sessionRedirectHTML("Password Changed", "Password changed! Redirecting to dashboard...", userRole, token, redirectURL, 1000)))
+}
+
+func (ac *AuthController) ShowForgotPasswordPage(c *gin.Context) {
+	c.HTML(http.StatusOK, "forgot-password.html", gin.H{
+		"Title": "Forgot Password",
+	})
+}
+
+func (ac *AuthController) HandleForgotPassword(c *gin.Context) {
+	email := c.PostForm("email")
+	if email == "" {
+		c.HTML(http.StatusBadRequest, "forgot-password.html", gin.H{
+			"Title": "Forgot Password",
+			"Error": "Please enter your email address",
+		})
+		return
+	}
+
+	// Failures are only logged so the page never reveals whether the account exists
+	if err := ac.accountService.RequestPasswordReset(email); err != nil {
+		log.Printf("Warning: Failed to send password reset email to %s: %v", email, err)
+	}
+
+	c.HTML(http.StatusOK, "forgot-password.html", gin.H{
+		"Title":   "Forgot Password",
+		"Success": "If an account exists for this email, a password reset link has been sent.",
+	})
+}
+
+func (ac *AuthController) ShowResetPasswordPage(c *gin.Context) {
+	token := c.Query("token")
+
+	data := gin.H{
+		"Title": "Reset Password",
+		"Token": token,
+	}
+	if token == "" {
+		data["Error"] = "This reset link is incomplete."
+	}
+
+	c.HTML(http.StatusOK, "reset-password.html", data)
+}
+
+func (ac *AuthController) HandleResetPassword(c *gin.Context) {
+	token := c.PostForm("token")
+	newPassword := c.PostForm("new_password")
+	confirmPassword := c.PostForm("confirm_password")
+
+	renderError := func(message string) {
+		c.HTML(http.StatusBadRequest, "reset-password.html", gin.H{
+			"Title": "Reset Password",
+			"Token": token,
+			"Error": message,
+		})
+	}
+
+	if len(newPassword) < 8 {
+		renderError("New password must be at least 8 characters")
+		return
+	}
+	if newPassword != confirmPassword {
+		renderError("Passwords do not match")
+		return
+	}
+
+	user, err := ac.accountService.ResetPassword(token, newPassword)
+	if err != nil {
+		// A used or expired token cannot be retried, so drop it from the form
+		c.HTML(http.StatusBadRequest, "reset-password.html", gin.H{
+			"Title": "Reset Password",
+			"Error": err.Error(),
+		})
+		return
+	}
+	ac.loginThrottle.Unlock(user.ID)
+
+	// Any session this browser had was ended with the others
+	c.SetCookie("token", "", -1, "/", "", false, true)
+
+	ac.renderLogin(c, http.StatusOK, gin.H{
+		"Title":   "Login",
+		"Success": "Your password has been reset. Please sign in with your new password.",
+	})
+}
+
+func (ac *AuthController) HandleVerifyEmail(c *gin.Context) {
+	if _, err := ac.accountService.VerifyEmail(c.Query("token")); err != nil {
+		c.HTML(http.StatusBadRequest, "verify-email.html", gin.H{
+			"Title":     "Verify Email",
+			"Error":     "This verification link is invalid or has expired.",
+			"CanResend": ac.isUserAuthenticated(c),
+		})
+		return
+	}
+
+	c.HTML(http.StatusOK, "verify-email.html", gin.H{
+		"Title":   "Verify Email",
+		"Success": "Your email address has been verified.",
+	})
+}
+
+func (ac *AuthController) HandleResendVerification(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Redirect(http.StatusFound, "/auth/login")
+		return
+	}
+
+	userModel := user.(models.User)
+	if err := ac.accountService.SendVerificationEmail(&userModel); err != nil {
+		message := "Failed to send the verification email. Please try again later."
+		if errors.Is(err, services.ErrEmailAlreadyVerified) {
+			message = "Your email address is already verified."
+		}
+
+		c.HTML(http.StatusBadRequest, "verify-email.html", gin.H{
+			"Title": "Verify Email",
+			"Error": message,
+		})
+		return
+	}
+
+	c.HTML(http.StatusOK, "verify-email.html", gin.H{
+		"Title":   "Verify Email",
+		"Success": "A new verification link has been sent to " + userModel.Email + ".",
+	})
+}
+
+func (ac *AuthController) ShowMFASetupPage(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Redirect(http.StatusFound, "/auth/login")
+		return
+	}
+
+	ac.renderMFASetup(c, http.StatusOK, user.(models.User), gin.H{})
+}
+
+func (ac *AuthController) HandleEnableMFA(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Redirect(http.StatusFound, "/auth/login")
+		return
+	}
+
+	userModel := user.(models.User)
+	var codes []string
+	if !ac.runMFAAction(c, userModel, func() (err error) {
+		codes, err = ac.mfaService.Enable(userModel.ID, c.PostForm("code"))
+		return err
+	}) {
+		return
+	}
+
+	now := time.Now()
+	userModel.TOTPEnabledAt = &now
+	ac.renderMFASetup(c, http.StatusOK, userModel, gin.H{
+		"Success":       "Two-factor authentication is enabled.",
+		"RecoveryCodes": codes,
+	})
+}
+
+func (ac *AuthController) HandleDisableMFA(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Redirect(http.StatusFound, "/auth/login")
+		return
+	}
+
+	userModel := user.(models.User)
+	if !ac.runMFAAction(c, userModel, func() error {
+		return ac.mfaService.Disable(userModel.ID, c.PostForm("code"))
+	}) {
+		return
+	}
+
+	userModel.TOTPEnabledAt = nil
+	ac.renderMFASetup(c, http.StatusOK, userModel, gin.H{"Success": "Two-factor authentication is disabled."})
+}
+
+func (ac *AuthController) HandleRegenerateRecoveryCodes(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Redirect(http.StatusFound, "/auth/login")
+		return
+	}
+
+	userModel := user.(models.User)
+	var codes []string
+	if !ac.runMFAAction(c, userModel, func() (err error) {
+		codes, err = ac.mfaService.RegenerateRecoveryCodes(userModel.ID, c.PostForm("code"))
+		return err
+	}) {
+		return
+	}
+
+	ac.renderMFASetup(c, http.StatusOK, userModel, gin.H{
+		"Success":       "New recovery codes generated. The old ones no longer work.",
+		"RecoveryCodes": codes,
+	})
+}
+
+// runMFAAction runs a two-factor settings change that is confirmed with a
+// code, rendering the settings with an error when it fails. Wrong codes count
+// against the login throttle, so a stolen session cannot be used to guess them.
+func (ac *AuthController) runMFAAction(c *gin.Context, user models.User, action func() error) bool {
+	if wait := ac.loginThrottle.CheckUser(user.ID, c.ClientIP()); wait > 0 {
+		c.Header("Retry-After", services.RetryAfterSeconds(wait))
+		ac.renderMFASetup(c, http.StatusTooManyRequests, user, gin.H{"Error": "Too many attempts. Please wait " + services.RetryAfterSeconds(wait) + " seconds and try again."})
+		return false
+	}
+
+	if err := action(); err != nil {
+		if errors.Is(err, services.ErrInvalidMFACode) {
+			if wait := ac.loginThrottle.RecordUserFailure(user.ID, c.ClientIP()); wait > 0 {
+				c.Header("Retry-After", services.RetryAfterSeconds(wait))
+			}
+		}
+		ac.renderMFASetup(c, http.StatusBadRequest, user, gin.H{"Error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// renderMFASetup shows the two-factor settings. Users without two-factor
+// authentication get a fresh secret to scan on every visit.
+func (ac *AuthController) renderMFASetup(c *gin.Context, status int, user models.User, data gin.H) {
+	data["Title"] = "Two-Factor Authentication"
+	data["User"] = user
+	data["Enabled"] = user.TOTPEnabledAt != nil
+
+	if user.TOTPEnabledAt == nil {
+		setup, err := ac.mfaService.BeginSetup(user.ID)
+		if err != nil {
+			data["Error"] = "Failed to start two-factor authentication setup"
+		} else {
+			data["Setup"] = setup
+		}
+	} else {
+		data["RemainingCodes"] = ac.mfaService.RemainingRecoveryCodes(user.ID)
+	}
+
+	c.HTML(status, "mfa-setup.html", data)
+}
+
 func (ac *AuthController) ShowDashboard(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
@@ -280,3 +745,36 @@ func (ac *AuthController) redirectAuthenticatedUser(c *gin.Context) {
 	// We'll use a simple redirect to the root path which has the logic to redirect based on user role
 	c.Redirect(http.StatusFound, "/")
 }
+
+// sessionRedirectHTML builds a page that stores the new session in localStorage
+// for the client-side scripts and then redirects
+func sessionRedirectHTML(title, message, userRole, token, redirectURL string, delayMs int) string {
+	return `<!DOCTYPE html>
+<html>
+<head>
+    <title>` + title + `</title>
+</head>
+<body>
+    <div style="display: flex; justify-content: center; align-items: center; height: 100vh; font-family: Arial, sans-serif;">
+        <div style="text-align: center;">
+            <div style="display: inline-block; width: 40px; height: 40px; border: 4px solid #f3f3f3; border-top: 4px solid #3498db; border-radius: 50%; animation: spin 1s linear infinite;"></div>
+            <p>` + message + `</p>
+        </div>
+    </div>
+    <style>
+        @keyframes spin {
+            0% { transform: rotate(0deg); }
+            100% { transform: rotate(360deg); }
+        }
+    </style>
+    <script>
+        localStorage.setItem('isLoggedIn', 'true');
+        localStorage.setItem('userRole', '` + userRole + `');
+        localStorage.setItem('authToken', '` + token + `');
+        setTimeout(function() {
+            window.location.href = '` + redirectURL + `';
+        }, ` + strconv.Itoa(delayMs) + `);
+    </script>
+</body>
+</html>`
+}
@@ -0,0 +1,110 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"yonatan/labpro/models"
+	"yonatan/labpro/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MediaController struct {
+	mediaService *services.MediaService
+}
+
+func NewMediaController(mediaService *services.MediaService) *MediaController {
+	return &MediaController{mediaService: mediaService}
+}
+
+// ServeAsset serves a stored file by asset ID to holders of a valid signed URL
+// or to signed-in users who may access it
+func (mc *MediaController) ServeAsset(c *gin.Context) {
+	asset, err := mc.mediaService.FindAsset(c.Param("assetId"))
+	mc.serve(c, asset, err)
+}
+
+// ServeUpload serves files by the /uploads addresses saved before files were
+// served through /media. Signed URLs are not accepted here.
+func (mc *MediaController) ServeUpload(c *gin.Context) {
+	asset, err := mc.mediaService.FindAssetByKey(strings.TrimPrefix(c.Param("key"), "/"))
+	if err == nil {
+		asset.ID = ""
+	}
+	mc.serve(c, asset, err)
+}
+
+func (mc *MediaController) serve(c *gin.Context, asset *models.MediaAsset, err error) {
+	if errors.Is(err, services.ErrMediaNotFound) {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	cacheControl, allowed, err := mc.authorize(c, *asset)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		if _, signedIn := c.Get("user"); signedIn {
+			c.Status(http.StatusForbidden)
+		} else {
+			c.Status(http.StatusUnauthorized)
+		}
+		return
+	}
+
+	file, object, redirectURL, err := mc.mediaService.Open(*asset)
+	if errors.Is(err, services.ErrMediaNotFound) {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Header("Cache-Control", cacheControl)
+	if redirectURL != "" {
+		c.Redirect(http.StatusFound, redirectURL)
+		return
+	}
+	defer file.Close()
+
+	// ServeContent answers range and conditional requests from these
+	c.Header("Content-Type", object.ContentType)
+	c.Header("ETag", fmt.Sprintf(`"%x-%x"`, object.ModTime.UnixNano(), object.Size))
+	c.Header("X-Content-Type-Options", "nosniff")
+	http.ServeContent(c.Writer, c.Request, path.Base(object.Key), object.ModTime, file)
+}
+
+// authorize decides whether the request may read the file and how the
+// response may be cached
+func (mc *MediaController) authorize(c *gin.Context, asset models.MediaAsset) (string, bool, error) {
+	public, err := mc.mediaService.IsPublic(asset)
+	if err != nil || public {
+		return "public, max-age=86400", public, err
+	}
+
+	// A signed URL is only good until it expires, and so is anything cached from it
+	if asset.ID != "" {
+		if remaining, ok := mc.mediaService.VerifySignature(asset.ID, c.Query("expires"), c.Query("signature")); ok {
+			return fmt.Sprintf("private, max-age=%d", int(remaining/time.Second)), true, nil
+		}
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		return "", false, nil
+	}
+	allowed, err := mc.mediaService.CanAccess(user.(models.User), asset)
+	// Access can be lost, so cached copies are checked with the server first
+	return "private, no-cache", allowed, err
+}
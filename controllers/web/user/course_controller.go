@@ -3,8 +3,7 @@ package user
 import (
 	"net/http"
 	"strconv"
-	"yonatan/labpro/config"
-	"yonatan/labpro/database"
+	"yonatan/labpro/middleware"
 	"yonatan/labpro/models"
 	"yonatan/labpro/services"
 
@@ -13,11 +12,13 @@ import (
 
 type CourseController struct {
 	courseService *services.CourseService
+	moduleService *services.ModuleService
 }
 
-func NewCourseController(courseService *services.CourseService) *CourseController {
+func NewCourseController(courseService *services.CourseService, moduleService *services.ModuleService) *CourseController {
 	return &CourseController{
 		courseService: courseService,
+		moduleService: moduleService,
 	}
 }
 
@@ -65,6 +66,17 @@ func (cc *CourseController) ShowCourseDetail(c *gin.Context) {
 	userModel := user.(models.User)
 	courseID := c.Param("id")
 
+	// Courses outside the catalogue are hidden from everyone but their students and editors
+	canView, err := cc.courseService.CanViewCourse(userModel, courseID)
+	if err != nil || !canView {
+		c.HTML(http.StatusNotFound, "course-detail.html", gin.H{
+			"Title": "Course Detail",
+			"User":  userModel,
+			"Error": "Course not found",
+		})
+		return
+	}
+
 	// Get course details
 	course, err := cc.courseService.GetCourseByID(courseID, userModel.ID)
 	if err != nil {
@@ -77,9 +89,7 @@ func (cc *CourseController) ShowCourseDetail(c *gin.Context) {
 	}
 
 	// Get course modules
-	cfg := config.Load()
-	moduleService := services.NewModuleService(database.DB, cfg)
-	modules, _, err := moduleService.GetModules(courseID, userModel.ID, 1, 100)
+	modules, _, err := cc.moduleService.GetModules(courseID, userModel.ID, 1, 100)
 	if err != nil {
 		modules = []map[string]interface{}{} // Default to empty slice
 	}
@@ -135,7 +145,7 @@ func (cc *CourseController) HandlePurchaseCourse(c *gin.Context) {
 	courseID := c.Param("id")
 
 	// Purchase course
-	result, err := cc.courseService.BuyCourse(courseID, userModel.ID)
+	result, err := cc.courseService.BuyCourse(courseID, userModel.ID, c.GetHeader("Idempotency-Key"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -146,3 +156,26 @@ func (cc *CourseController) HandlePurchaseCourse(c *gin.Context) {
 		"data":    result,
 	})
 }
+
+func (cc *CourseController) HandleRefundCourse(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	userModel := user.(models.User)
+	courseID := c.Param("id")
+
+	// Refund course
+	result, err := cc.courseService.RefundCourse(courseID, userModel.ID, middleware.CurrentActor(c), false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Course refunded",
+		"data":    result,
+	})
+}
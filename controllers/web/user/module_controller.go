@@ -1,6 +1,7 @@
 package user
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"yonatan/labpro/models"
@@ -101,6 +102,7 @@ func (mc *ModuleController) ShowModuleDetail(c *gin.Context) {
 
 	c.HTML(http.StatusOK, "module-detail.html", gin.H{
 		"Module":           module,
+		"VideoStream":      mc.moduleService.VideoStreamPath(moduleIDStr),
 		"Course":           course,
 		"AllModules":       allModules,
 		"CourseProgress":   courseProgress,
@@ -133,3 +135,29 @@ func (mc *ModuleController) HandleCompleteModule(c *gin.Context) {
 		"data":    result,
 	})
 }
+
+// StreamVideo serves the HLS playlists, segments and poster frame of a module
+// video to the module page's player
+func (mc *ModuleController) StreamVideo(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	userModel := user.(models.User)
+	reader, object, err := mc.moduleService.OpenVideoStream(userModel, c.Param("id"), c.Param("version"), c.Param("file"))
+	if errors.Is(err, services.ErrModuleAccessDenied) {
+		c.Status(http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	defer reader.Close()
+
+	// Streams never change once written; a new video gets a new prefix
+	c.Header("Cache-Control", "private, max-age=86400")
+	c.DataFromReader(http.StatusOK, object.Size, object.ContentType, reader, nil)
+}
@@ -2,7 +2,6 @@ package database
 
 import (
 	"log"
-	"yonatan/labpro/models"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -10,44 +9,38 @@ import (
 
 var DB *gorm.DB
 
-func Init(databaseURL string) {
+// Connect opens the database connection without touching the schema
+func Connect(databaseURL string) {
 	var err error
 	DB, err = gorm.Open(postgres.Open(databaseURL), &gorm.Config{})
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
+}
+
+// Init connects to the database and makes sure its schema is up to date. Pending
+// migrations are applied when autoMigrate is set; otherwise startup is refused
+// until they have been run with `labpro migrate up`.
+func Init(databaseURL string, autoMigrate bool) {
+	Connect(databaseURL)
 
-	// Auto migrate the schema
-	err = DB.AutoMigrate(
-		&models.User{},
-		&models.Course{},
-		&models.Module{},
-		&models.UserCourse{},
-		&models.UserModuleProgress{},
-	)
+	pending, err := PendingMigrations(DB)
 	if err != nil {
-		log.Fatal("Failed to migrate database:", err)
+		log.Fatal("Failed to read migration status:", err)
 	}
 
-	// Create admin user if not exists
-	createAdminUser()
-}
+	if len(pending) > 0 {
+		if !autoMigrate {
+			log.Fatalf("Database schema has %d pending migrations, run `labpro migrate up` first", len(pending))
+		}
 
-func createAdminUser() {
-	var admin models.User
-	result := DB.Where("username = ?", "admin").First(&admin)
-	if result.Error == gorm.ErrRecordNotFound {
-		admin = models.User{
-			Username:  "admin",
-			Email:     "admin@labpro.com",
-			FirstName: "Admin",
-			LastName:  "User",
-			Balance:   0,
-			IsAdmin:   true,
+		applied, err := MigrateUp(DB, 0)
+		if err != nil {
+			log.Fatal("Failed to migrate database:", err)
+		}
+		for _, migration := range applied {
+			log.Printf("Applied migration %d_%s", migration.Version, migration.Name)
 		}
-		admin.SetPassword("admin123")
-		DB.Create(&admin)
-		log.Println("Admin user created with username: admin, password: admin123")
 	}
 }
 
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS user_module_progresses;
+DROP TABLE IF EXISTS user_courses;
+DROP TABLE IF EXISTS modules;
+DROP TABLE IF EXISTS courses;
+DROP TABLE IF EXISTS users;
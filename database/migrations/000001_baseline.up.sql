@@ -0,0 +1,63 @@
+-- Baseline schema. Every statement is guarded so that databases created by the
+-- former AutoMigrate boot step can adopt migrations without changes.
+
+CREATE TABLE IF NOT EXISTS users (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    username text NOT NULL,
+    email text NOT NULL,
+    first_name text NOT NULL,
+    last_name text NOT NULL,
+    password text NOT NULL,
+    balance numeric DEFAULT 0,
+    is_admin boolean DEFAULT false,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+
+CREATE TABLE IF NOT EXISTS courses (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    title text NOT NULL,
+    description text,
+    instructor text NOT NULL,
+    price numeric NOT NULL,
+    thumbnail text,
+    topics text[],
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_courses_deleted_at ON courses (deleted_at);
+
+CREATE TABLE IF NOT EXISTS modules (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    course_id uuid NOT NULL,
+    title text NOT NULL,
+    description text NOT NULL,
+    "order" bigint NOT NULL,
+    pdf_content text,
+    video_content text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_courses_modules FOREIGN KEY (course_id) REFERENCES courses (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS user_courses (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL,
+    course_id uuid NOT NULL,
+    purchased_at timestamptz,
+    CONSTRAINT fk_user_courses_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_user_courses_course FOREIGN KEY (course_id) REFERENCES courses (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS user_module_progresses (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL,
+    module_id uuid NOT NULL,
+    is_completed boolean DEFAULT false,
+    completed_at timestamptz,
+    CONSTRAINT fk_user_module_progresses_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_user_module_progresses_module FOREIGN KEY (module_id) REFERENCES modules (id) ON DELETE CASCADE
+);
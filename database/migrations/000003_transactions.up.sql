@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS transactions (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL,
+    type text NOT NULL,
+    amount numeric NOT NULL,
+    balance_before numeric NOT NULL,
+    balance_after numeric NOT NULL,
+    course_id uuid,
+    actor_id uuid,
+    description text,
+    created_at timestamptz,
+    CONSTRAINT fk_transactions_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_transactions_course FOREIGN KEY (course_id) REFERENCES courses (id) ON DELETE SET NULL,
+    CONSTRAINT fk_transactions_actor FOREIGN KEY (actor_id) REFERENCES users (id) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS idx_transactions_user_id ON transactions (user_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_type ON transactions (type);
+CREATE INDEX IF NOT EXISTS idx_transactions_course_id ON transactions (course_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_created_at ON transactions (created_at);
+
+-- Balances that predate the ledger get an opening entry so they stay derivable from it
+INSERT INTO transactions (user_id, type, amount, balance_before, balance_after, description, created_at)
+SELECT id, 'opening_balance', balance, 0, balance, 'Balance carried over from before the ledger', NOW()
+FROM users
+WHERE balance <> 0 AND NOT EXISTS (SELECT 1 FROM transactions WHERE transactions.user_id = users.id);
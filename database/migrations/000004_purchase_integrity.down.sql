@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS idempotency_keys;
+DROP INDEX IF EXISTS idx_user_courses_user_course;
@@ -0,0 +1,19 @@
+-- Keep only the earliest of any duplicate enrollments left by concurrent purchases
+DELETE FROM user_courses a USING user_courses b
+WHERE a.user_id = b.user_id AND a.course_id = b.course_id
+AND (a.purchased_at > b.purchased_at OR (a.purchased_at = b.purchased_at AND a.id > b.id));
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_courses_user_course ON user_courses (user_id, course_id);
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL,
+    key text NOT NULL,
+    scope text NOT NULL,
+    response text NOT NULL,
+    expires_at timestamptz,
+    created_at timestamptz,
+    CONSTRAINT fk_idempotency_keys_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_idempotency_keys_user_key ON idempotency_keys (user_id, key);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS archived_module_progresses (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL,
+    module_id uuid NOT NULL,
+    course_id uuid NOT NULL,
+    is_completed boolean,
+    completed_at timestamptz,
+    reason text,
+    archived_at timestamptz,
+    CONSTRAINT fk_archived_module_progresses_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_archived_module_progresses_module FOREIGN KEY (module_id) REFERENCES modules (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_archived_module_progresses_user_id ON archived_module_progresses (user_id);
+CREATE INDEX IF NOT EXISTS idx_archived_module_progresses_course_id ON archived_module_progresses (course_id);
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS must_change_password boolean DEFAULT false;
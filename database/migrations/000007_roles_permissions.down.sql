@@ -0,0 +1,8 @@
+ALTER TABLE courses DROP CONSTRAINT IF EXISTS fk_courses_owner;
+DROP INDEX IF EXISTS idx_courses_owner_id;
+ALTER TABLE courses DROP COLUMN IF EXISTS owner_id;
+
+DROP TABLE IF EXISTS user_roles;
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS roles;
+DROP TABLE IF EXISTS permissions;
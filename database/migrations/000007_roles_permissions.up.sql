@@ -0,0 +1,75 @@
+CREATE TABLE IF NOT EXISTS permissions (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    name text NOT NULL,
+    description text
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_permissions_name ON permissions (name);
+
+CREATE TABLE IF NOT EXISTS roles (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    name text NOT NULL,
+    description text,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_roles_name ON roles (name);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id uuid NOT NULL,
+    permission_id uuid NOT NULL,
+    PRIMARY KEY (role_id, permission_id),
+    CONSTRAINT fk_role_permissions_role FOREIGN KEY (role_id) REFERENCES roles (id) ON DELETE CASCADE,
+    CONSTRAINT fk_role_permissions_permission FOREIGN KEY (permission_id) REFERENCES permissions (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id uuid NOT NULL,
+    role_id uuid NOT NULL,
+    PRIMARY KEY (user_id, role_id),
+    CONSTRAINT fk_user_roles_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_user_roles_role FOREIGN KEY (role_id) REFERENCES roles (id) ON DELETE CASCADE
+);
+
+INSERT INTO permissions (name, description) VALUES
+    ('stats:read', 'View the admin dashboard and statistics'),
+    ('courses:write', 'Create courses and edit or delete owned courses and their modules'),
+    ('courses:write_any', 'Edit or delete any course and its modules'),
+    ('users:read', 'View user accounts'),
+    ('users:write', 'Create, edit and delete user accounts and end their sessions'),
+    ('balances:write', 'Change user balances'),
+    ('transactions:read', 'View the balance ledger and reconciliation'),
+    ('refunds:write', 'Refund courses on behalf of users'),
+    ('roles:write', 'Assign and remove roles')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO roles (name, description, created_at) VALUES
+    ('admin', 'Full access to everything', now()),
+    ('instructor', 'Creates courses and manages their own', now()),
+    ('content-editor', 'Edits every course and module', now()),
+    ('support', 'Helps users with their accounts and refunds', now()),
+    ('finance', 'Manages balances, refunds and the ledger', now())
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON
+    r.name = 'admin'
+    OR (r.name = 'instructor' AND p.name IN ('courses:write'))
+    OR (r.name = 'content-editor' AND p.name IN ('courses:write', 'courses:write_any'))
+    OR (r.name = 'support' AND p.name IN ('users:read', 'transactions:read', 'refunds:write'))
+    OR (r.name = 'finance' AND p.name IN ('stats:read', 'users:read', 'transactions:read', 'balances:write', 'refunds:write'))
+ON CONFLICT DO NOTHING;
+
+INSERT INTO user_roles (user_id, role_id)
+SELECT u.id, r.id FROM users u JOIN roles r ON r.name = 'admin'
+WHERE u.is_admin = true
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE courses ADD COLUMN IF NOT EXISTS owner_id uuid;
+CREATE INDEX IF NOT EXISTS idx_courses_owner_id ON courses (owner_id);
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_courses_owner') THEN
+        ALTER TABLE courses ADD CONSTRAINT fk_courses_owner FOREIGN KEY (owner_id) REFERENCES users (id) ON DELETE SET NULL;
+    END IF;
+END $$;
@@ -0,0 +1,10 @@
+ALTER TABLE courses ADD COLUMN IF NOT EXISTS instructor text;
+UPDATE courses SET instructor = COALESCE(
+    (SELECT name FROM instructors WHERE instructors.id = courses.instructor_id), '');
+ALTER TABLE courses ALTER COLUMN instructor SET NOT NULL;
+
+ALTER TABLE courses DROP CONSTRAINT IF EXISTS fk_courses_instructor;
+DROP INDEX IF EXISTS idx_courses_instructor_id;
+ALTER TABLE courses DROP COLUMN IF EXISTS instructor_id;
+
+DROP TABLE IF EXISTS instructors;
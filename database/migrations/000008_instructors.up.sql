@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS instructors (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid,
+    name text NOT NULL,
+    bio text,
+    avatar_url text,
+    links text[],
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_instructors_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_instructors_user_id ON instructors (user_id);
+
+ALTER TABLE courses ADD COLUMN IF NOT EXISTS instructor_id uuid;
+CREATE INDEX IF NOT EXISTS idx_courses_instructor_id ON courses (instructor_id);
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_courses_instructor') THEN
+        ALTER TABLE courses ADD CONSTRAINT fk_courses_instructor FOREIGN KEY (instructor_id) REFERENCES instructors (id) ON DELETE SET NULL;
+    END IF;
+END $$;
+
+-- Turn every distinct free-text instructor into an unlinked profile and point the
+-- courses at it. Admins can link the profiles to user accounts afterwards.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'courses' AND column_name = 'instructor') THEN
+        INSERT INTO instructors (name, created_at, updated_at)
+        SELECT DISTINCT TRIM(instructor), NOW(), NOW() FROM courses
+        WHERE TRIM(COALESCE(instructor, '')) <> '';
+
+        UPDATE courses SET instructor_id = instructors.id
+        FROM instructors
+        WHERE instructors.name = TRIM(courses.instructor) AND courses.instructor_id IS NULL;
+
+        ALTER TABLE courses DROP COLUMN instructor;
+    END IF;
+END $$;
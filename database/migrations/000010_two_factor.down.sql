@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS recovery_codes;
+ALTER TABLE roles DROP COLUMN IF EXISTS require_mfa;
+ALTER TABLE users DROP COLUMN IF EXISTS totp_last_step;
+ALTER TABLE users DROP COLUMN IF EXISTS totp_enabled_at;
+ALTER TABLE users DROP COLUMN IF EXISTS totp_secret;
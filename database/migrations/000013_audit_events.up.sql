@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS audit_events (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    actor_id uuid,
+    actor_name text,
+    action text NOT NULL,
+    target_type text NOT NULL,
+    target_id text,
+    before jsonb,
+    after jsonb,
+    ip text,
+    user_agent text,
+    created_at timestamptz,
+    CONSTRAINT fk_audit_events_actor FOREIGN KEY (actor_id) REFERENCES users (id) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor_id ON audit_events (actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events (action);
+CREATE INDEX IF NOT EXISTS idx_audit_events_target ON audit_events (target_type, target_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events (created_at);
+
+INSERT INTO permissions (name, description) VALUES
+    ('audit:read', 'View the audit log of staff changes')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r
+JOIN permissions p ON p.name = 'audit:read'
+WHERE r.name = 'admin'
+ON CONFLICT DO NOTHING;
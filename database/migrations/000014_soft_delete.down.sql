@@ -0,0 +1,17 @@
+-- Rows still in the trash cannot be represented without the columns
+DELETE FROM user_module_progresses WHERE deleted_at IS NOT NULL;
+DELETE FROM user_courses WHERE deleted_at IS NOT NULL;
+DELETE FROM modules WHERE deleted_at IS NOT NULL;
+DELETE FROM users WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_user_courses_user_course;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_courses_user_course ON user_courses (user_id, course_id);
+DROP INDEX IF EXISTS idx_users_email;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+DROP INDEX IF EXISTS idx_users_username;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username);
+
+ALTER TABLE user_module_progresses DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE user_courses DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE modules DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,17 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+ALTER TABLE modules ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+ALTER TABLE user_courses ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+ALTER TABLE user_module_progresses ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_modules_deleted_at ON modules (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_user_courses_deleted_at ON user_courses (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_user_module_progresses_deleted_at ON user_module_progresses (deleted_at);
+
+-- Rows in the trash must not keep their usernames, emails or enrollments taken
+DROP INDEX IF EXISTS idx_users_username;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username) WHERE deleted_at IS NULL;
+DROP INDEX IF EXISTS idx_users_email;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email) WHERE deleted_at IS NULL;
+DROP INDEX IF EXISTS idx_user_courses_user_course;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_courses_user_course ON user_courses (user_id, course_id) WHERE deleted_at IS NULL;
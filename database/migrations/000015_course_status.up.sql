@@ -0,0 +1,6 @@
+-- Existing courses were already public, so they start out published
+ALTER TABLE courses ADD COLUMN IF NOT EXISTS status text NOT NULL DEFAULT 'published';
+ALTER TABLE courses ADD COLUMN IF NOT EXISTS publish_at timestamptz;
+ALTER TABLE courses ADD COLUMN IF NOT EXISTS unpublish_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS idx_courses_status ON courses (status);
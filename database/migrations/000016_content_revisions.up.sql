@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS content_revisions (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    target_type text NOT NULL,
+    target_id uuid NOT NULL,
+    number bigint NOT NULL,
+    content jsonb NOT NULL,
+    restored_from bigint,
+    actor_id uuid,
+    actor_name text,
+    created_at timestamptz,
+    CONSTRAINT fk_content_revisions_actor FOREIGN KEY (actor_id) REFERENCES users (id) ON DELETE SET NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_content_revisions_target_number ON content_revisions (target_type, target_id, number);
+CREATE INDEX IF NOT EXISTS idx_content_revisions_actor_id ON content_revisions (actor_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_courses_thumbnail;
+DROP INDEX IF EXISTS idx_modules_video_content;
+DROP INDEX IF EXISTS idx_modules_pdf_content;
+DROP TABLE IF EXISTS media_assets;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS media_assets (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    key text NOT NULL,
+    url text NOT NULL,
+    kind text NOT NULL,
+    content_type text,
+    size bigint,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_media_assets_key ON media_assets (key);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_media_assets_url ON media_assets (url);
+CREATE INDEX IF NOT EXISTS idx_media_assets_kind ON media_assets (kind);
+
+-- Orphan checks look files up by the URLs modules and revisions keep
+CREATE INDEX IF NOT EXISTS idx_modules_pdf_content ON modules (pdf_content);
+CREATE INDEX IF NOT EXISTS idx_modules_video_content ON modules (video_content);
+CREATE INDEX IF NOT EXISTS idx_courses_thumbnail ON courses (thumbnail);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_modules_video_status;
+
+ALTER TABLE modules DROP COLUMN IF EXISTS video_status_at;
+ALTER TABLE modules DROP COLUMN IF EXISTS video_stream;
+ALTER TABLE modules DROP COLUMN IF EXISTS video_error;
+ALTER TABLE modules DROP COLUMN IF EXISTS video_status;
@@ -0,0 +1,8 @@
+-- Videos uploaded before transcoding keep playing as they are, so existing
+-- modules start without a status
+ALTER TABLE modules ADD COLUMN IF NOT EXISTS video_status text NOT NULL DEFAULT '';
+ALTER TABLE modules ADD COLUMN IF NOT EXISTS video_error text NOT NULL DEFAULT '';
+ALTER TABLE modules ADD COLUMN IF NOT EXISTS video_stream text;
+ALTER TABLE modules ADD COLUMN IF NOT EXISTS video_status_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS idx_modules_video_status ON modules (video_status);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS resumable_uploads (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL,
+    kind text NOT NULL,
+    filename text NOT NULL,
+    content_type text NOT NULL,
+    size bigint NOT NULL,
+    received bigint NOT NULL DEFAULT 0,
+    expires_at timestamptz NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz,
+    CONSTRAINT fk_resumable_uploads_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_resumable_uploads_user_id ON resumable_uploads (user_id);
+CREATE INDEX IF NOT EXISTS idx_resumable_uploads_expires_at ON resumable_uploads (expires_at);
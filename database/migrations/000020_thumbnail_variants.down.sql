@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_media_assets_variant_of;
+
+ALTER TABLE media_assets DROP COLUMN IF EXISTS variant_of;
@@ -0,0 +1,5 @@
+-- Thumbnails are stored in several sizes and formats; every file of a set
+-- points at the one courses refer to
+ALTER TABLE media_assets ADD COLUMN IF NOT EXISTS variant_of text NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_media_assets_variant_of ON media_assets (variant_of);
@@ -0,0 +1,2 @@
+-- Expired refresh tokens are purged periodically, like revoked access tokens
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires_at ON refresh_tokens (expires_at);
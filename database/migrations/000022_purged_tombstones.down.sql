@@ -0,0 +1,2 @@
+ALTER TABLE courses DROP COLUMN IF EXISTS purged_at;
+ALTER TABLE users DROP COLUMN IF EXISTS purged_at;
@@ -0,0 +1,5 @@
+-- Users and courses the ledger refers to are not deleted when the trash is
+-- purged; they stay behind as tombstones, stripped of personal data and
+-- content, so every transaction keeps pointing at a row
+ALTER TABLE users ADD COLUMN IF NOT EXISTS purged_at timestamptz;
+ALTER TABLE courses ADD COLUMN IF NOT EXISTS purged_at timestamptz;
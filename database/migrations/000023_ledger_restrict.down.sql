@@ -0,0 +1,7 @@
+ALTER TABLE transactions
+    DROP CONSTRAINT IF EXISTS fk_transactions_user,
+    DROP CONSTRAINT IF EXISTS fk_transactions_course,
+    DROP CONSTRAINT IF EXISTS fk_transactions_actor,
+    ADD CONSTRAINT fk_transactions_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    ADD CONSTRAINT fk_transactions_course FOREIGN KEY (course_id) REFERENCES courses (id) ON DELETE SET NULL,
+    ADD CONSTRAINT fk_transactions_actor FOREIGN KEY (actor_id) REFERENCES users (id) ON DELETE SET NULL;
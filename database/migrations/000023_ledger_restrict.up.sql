@@ -0,0 +1,9 @@
+-- The ledger is append-only, so deleting a user or course must neither delete
+-- nor rewrite its entries; the retention purge keeps such rows as tombstones
+ALTER TABLE transactions
+    DROP CONSTRAINT IF EXISTS fk_transactions_user,
+    DROP CONSTRAINT IF EXISTS fk_transactions_course,
+    DROP CONSTRAINT IF EXISTS fk_transactions_actor,
+    ADD CONSTRAINT fk_transactions_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE RESTRICT,
+    ADD CONSTRAINT fk_transactions_course FOREIGN KEY (course_id) REFERENCES courses (id) ON DELETE RESTRICT,
+    ADD CONSTRAINT fk_transactions_actor FOREIGN KEY (actor_id) REFERENCES users (id) ON DELETE RESTRICT;
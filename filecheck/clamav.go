@@ -0,0 +1,97 @@
+package filecheck
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// Scanner checks file content for malware. It returns an error wrapping
+// ErrInfected for files it refuses, and any other error when it could not
+// decide; uploads are rejected either way.
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) error
+}
+
+// scanTimeout bounds a whole scan, long enough for large videos
+const scanTimeout = 10 * time.Minute
+
+// scanChunkSize is how much of a file each INSTREAM chunk carries
+const scanChunkSize = 64 << 10
+
+// ClamAV scans files with a clamd daemon, or anything speaking its INSTREAM
+// protocol. clamd refuses streams over its StreamMaxLength, so that has to be
+// at least as large as the biggest upload.
+type ClamAV struct {
+	network string
+	address string
+}
+
+// NewClamAV connects to clamd at address, either "unix:///path/to/clamd.sock"
+// or a TCP "host:port", optionally prefixed with "tcp://"
+func NewClamAV(address string) *ClamAV {
+	if socket, ok := strings.CutPrefix(address, "unix://"); ok {
+		return &ClamAV{network: "unix", address: socket}
+	}
+	return &ClamAV{network: "tcp", address: strings.TrimPrefix(address, "tcp://")}
+}
+
+// Scan streams r to clamd and reads its verdict
+func (c *ClamAV) Scan(ctx context.Context, r io.Reader) error {
+	ctx, cancel := context.WithTimeout(ctx, scanTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return fmt.Errorf("failed to reach the virus scanner: %v", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return fmt.Errorf("failed to send the file to the virus scanner: %v", err)
+	}
+	buf := make([]byte, 4+scanChunkSize)
+	for {
+		n, readErr := r.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				// clamd hangs up once a stream goes over its size limit; its
+				// reply says so
+				break
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+	conn.Write([]byte{0, 0, 0, 0})
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return fmt.Errorf("failed to read the virus scanner's reply: %v", err)
+	}
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+
+	switch {
+	case strings.HasSuffix(reply, " OK"):
+		return nil
+	case strings.HasSuffix(reply, " FOUND"):
+		name := strings.TrimSuffix(strings.TrimPrefix(reply, "stream: "), " FOUND")
+		return fmt.Errorf("%w: %s", ErrInfected, name)
+	default:
+		return fmt.Errorf("virus scanner error: %s", reply)
+	}
+}
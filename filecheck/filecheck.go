@@ -0,0 +1,278 @@
+// Package filecheck vets uploaded files before they reach storage. A file has to
+// carry an extension allowed for its kind and content whose magic bytes match
+// that extension, fit the configured size limits and, when a ClamAV daemon is
+// configured, pass a malware scan. Accepted files are stored under a sanitized,
+// randomized name rather than the one the client sent.
+package filecheck
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"yonatan/labpro/config"
+	"yonatan/labpro/models"
+)
+
+var (
+	ErrTooLarge            = errors.New("file is too large")
+	ErrExtensionNotAllowed = errors.New("file extension is not allowed")
+	ErrContentMismatch     = errors.New("file content does not match its type")
+	ErrInfected            = errors.New("file failed the malware scan")
+	ErrImageTooSmall       = errors.New("image is too small")
+)
+
+// sniffLength is how much of a file signatures are matched against
+const sniffLength = 512
+
+// Default limits, used when the configured ones are not valid numbers
+const (
+	defaultMaxFileSize  = 10 << 20
+	defaultMaxVideoSize = 100 << 20
+)
+
+// signature recognizes one file format by its leading bytes
+type signature struct {
+	contentType string
+	extensions  []string
+	match       func(head []byte) bool
+}
+
+func prefix(magic string) func([]byte) bool {
+	return func(head []byte) bool {
+		return bytes.HasPrefix(head, []byte(magic))
+	}
+}
+
+// riff matches RIFF containers such as AVI and WebP by their form type
+func riff(form string) func([]byte) bool {
+	return func(head []byte) bool {
+		return len(head) >= 12 && string(head[:4]) == "RIFF" && string(head[8:12]) == form
+	}
+}
+
+// isoMedia matches MP4 and QuickTime files, whose first box is usually ftyp.
+// Older QuickTime files may start with another top-level atom instead.
+func isoMedia(head []byte) bool {
+	if len(head) < 8 {
+		return false
+	}
+	switch string(head[4:8]) {
+	case "ftyp", "moov", "mdat", "wide", "free", "skip":
+		return true
+	}
+	return false
+}
+
+// signatures lists the formats each kind of upload accepts. Anything that
+// could run in a browser, such as SVG or HTML, is deliberately missing.
+var signatures = map[string][]signature{
+	models.MediaKindPDF: {
+		{"application/pdf", []string{".pdf"}, prefix("%PDF-")},
+	},
+	models.MediaKindVideo: {
+		{"video/mp4", []string{".mp4", ".m4v"}, isoMedia},
+		{"video/quicktime", []string{".mov"}, isoMedia},
+		{"video/webm", []string{".webm"}, prefix("\x1a\x45\xdf\xa3")},
+		{"video/ogg", []string{".ogv", ".ogg"}, prefix("OggS")},
+		{"video/x-msvideo", []string{".avi"}, riff("AVI ")},
+	},
+	models.MediaKindThumbnail: {
+		{"image/jpeg", []string{".jpg", ".jpeg"}, prefix("\xff\xd8\xff")},
+		{"image/png", []string{".png"}, prefix("\x89PNG\r\n\x1a\n")},
+		{"image/gif", []string{".gif"}, func(head []byte) bool {
+			return bytes.HasPrefix(head, []byte("GIF87a")) || bytes.HasPrefix(head, []byte("GIF89a"))
+		}},
+		{"image/webp", []string{".webp"}, riff("WEBP")},
+	},
+}
+
+// Extensions lists the file extensions a kind of upload may have, for accept
+// attributes and error messages
+func Extensions(kind string) []string {
+	var extensions []string
+	for _, sig := range signatures[kind] {
+		extensions = append(extensions, sig.extensions...)
+	}
+	return extensions
+}
+
+// File is an upload that passed the checks
+type File struct {
+	Kind string
+	// Name is the sanitized, randomized name to store the file under
+	Name string
+	// ContentType is detected from the content, never taken from the client
+	ContentType string
+}
+
+// Validator checks uploads against the limits of one configuration
+type Validator struct {
+	maxFileSize  int64
+	maxVideoSize int64
+	scanner      Scanner
+}
+
+// New returns a validator with the limits and scanner cfg describes. Files are
+// not scanned while ClamAVAddress is empty.
+func New(cfg *config.Config) *Validator {
+	var scanner Scanner
+	if cfg.ClamAVAddress != "" {
+		scanner = NewClamAV(cfg.ClamAVAddress)
+	}
+	return NewValidator(parseSize(cfg.MaxFileSize, defaultMaxFileSize), parseSize(cfg.MaxVideoSize, defaultMaxVideoSize), scanner)
+}
+
+// NewValidator returns a validator that allows videos up to maxVideoSize bytes
+// and other files up to maxFileSize. scanner may be nil.
+func NewValidator(maxFileSize, maxVideoSize int64, scanner Scanner) *Validator {
+	return &Validator{
+		maxFileSize:  maxFileSize,
+		maxVideoSize: maxVideoSize,
+		scanner:      scanner,
+	}
+}
+
+func parseSize(value string, fallback int64) int64 {
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || size <= 0 {
+		return fallback
+	}
+	return size
+}
+
+// MaxSize is the largest file of a kind that may be uploaded, in bytes
+func (v *Validator) MaxSize(kind string) int64 {
+	if kind == models.MediaKindVideo {
+		return v.maxVideoSize
+	}
+	return v.maxFileSize
+}
+
+// CheckSize rejects files larger than their kind allows
+func (v *Validator) CheckSize(kind string, size int64) error {
+	if max := v.MaxSize(kind); size > max {
+		return fmt.Errorf("%w: maximum %d MB allowed", ErrTooLarge, max>>20)
+	}
+	return nil
+}
+
+// KindOf finds which of kinds a file name's extension belongs to
+func (v *Validator) KindOf(filename string, kinds ...string) (string, error) {
+	ext := strings.ToLower(path.Ext(filename))
+	for _, kind := range kinds {
+		for _, sig := range signatures[kind] {
+			if contains(sig.extensions, ext) {
+				return kind, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrExtensionNotAllowed, ext)
+}
+
+// CheckContent checks a file's name and content, and scans it when a scanner
+// is configured. r is rewound afterwards so the file can be stored from it.
+func (v *Validator) CheckContent(ctx context.Context, kind, filename string, r io.ReadSeeker) (*File, error) {
+	ext := strings.ToLower(path.Ext(filename))
+	var candidates []signature
+	for _, sig := range signatures[kind] {
+		if contains(sig.extensions, ext) {
+			candidates = append(candidates, sig)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w: %s files must be %s", ErrExtensionNotAllowed, kind, strings.Join(Extensions(kind), ", "))
+	}
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	head = head[:n]
+
+	var matched *signature
+	for i := range candidates {
+		if candidates[i].match(head) {
+			matched = &candidates[i]
+			break
+		}
+	}
+	if matched == nil {
+		return nil, fmt.Errorf("%w: not a %s file", ErrContentMismatch, strings.TrimPrefix(ext, "."))
+	}
+
+	if v.scanner != nil {
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if err := v.scanner.Scan(ctx, r); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	return &File{
+		Kind:        kind,
+		Name:        SafeName(filename, ext),
+		ContentType: matched.contentType,
+	}, nil
+}
+
+// IsRejected reports whether err means the file itself was refused, as opposed
+// to the check failing
+func IsRejected(err error) bool {
+	return errors.Is(err, ErrTooLarge) || errors.Is(err, ErrExtensionNotAllowed) ||
+		errors.Is(err, ErrContentMismatch) || errors.Is(err, ErrInfected) ||
+		errors.Is(err, ErrImageTooSmall)
+}
+
+// maxStemLength keeps what is left of the client's file name readable in keys
+const maxStemLength = 64
+
+// SafeName turns a client supplied file name into "<random>_<stem><ext>". The
+// stem keeps only letters, digits, dots, dashes and underscores, so directory
+// parts, separators and control characters cannot reach storage keys, and the
+// random part keeps two uploads of the same name apart.
+func SafeName(filename, ext string) string {
+	base := path.Base(strings.ReplaceAll(filename, "\\", "/"))
+	stem := strings.TrimSuffix(base, path.Ext(base))
+
+	var b strings.Builder
+	for _, r := range stem {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteRune('-')
+		}
+		if b.Len() >= maxStemLength {
+			break
+		}
+	}
+	clean := strings.Trim(b.String(), ".-_")
+	if clean == "" {
+		clean = "file"
+	}
+
+	random := make([]byte, 8)
+	rand.Read(random)
+	return hex.EncodeToString(random) + "_" + clean + ext
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
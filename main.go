@@ -2,10 +2,15 @@ package main
 
 import (
 	"log"
+	"os"
+	"time"
+	"yonatan/labpro/cli"
 	"yonatan/labpro/config"
 	"yonatan/labpro/database"
 	_ "yonatan/labpro/docs"
 	"yonatan/labpro/router"
+	"yonatan/labpro/services"
+	"yonatan/labpro/storage"
 
 	"github.com/gin-gonic/gin"
 )
@@ -28,14 +33,34 @@ import (
 // @securityDefinitions.apikey BearerAuth
 // @in header
 // @name Authorization
-// @description Type "Bearer" followed by a space and JWT token.
+// @description Type "Bearer" followed by a space and a JWT or a personal access token.
 
 func main() {
 	// Load configuration
 	cfg := config.Load()
 
+	// Run a management command such as `labpro migrate up` instead of the server
+	if len(os.Args) > 1 {
+		os.Exit(cli.Run(cfg, os.Args[1:]))
+	}
+
 	// Initialize database
-	database.Init(cfg.DatabaseURL)
+	database.Init(cfg.DatabaseURL, cfg.AutoMigrate == "true")
+
+	// Create the first admin from BOOTSTRAP_ADMIN_* while none exists
+	userService := services.NewUserService(database.GetDB())
+	admin, err := userService.BootstrapAdmin(cfg.BootstrapAdminUsername, cfg.BootstrapAdminEmail, cfg.BootstrapAdminPassword)
+	if err != nil {
+		log.Fatal("Failed to bootstrap admin user:", err)
+	}
+	if admin != nil {
+		log.Printf("Admin user %s created, the password must be changed on first login", admin.Username)
+	} else if hasAdmin, _ := userService.HasAdmin(); !hasAdmin {
+		log.Println("Warning: no admin account exists, set BOOTSTRAP_ADMIN_* or run `labpro admin create`")
+	}
+
+	// Purge the trash once a day; the service logs what it removes
+	go services.NewTrashService(database.GetDB(), cfg, nil, storage.NewOrLocal(cfg)).RunRetention(24 * time.Hour)
 
 	// Set Gin mode
 	if cfg.Environment == "production" {
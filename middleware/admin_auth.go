@@ -5,12 +5,13 @@ import (
 	"yonatan/labpro/config"
 	"yonatan/labpro/database"
 	"yonatan/labpro/models"
+	"yonatan/labpro/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// WebAdminMiddleware checks for admin authentication via cookies for web routes
+// WebAdminMiddleware checks for staff authentication via cookies for web routes
 func WebAdminMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		cfg := config.Load()
@@ -36,9 +37,9 @@ func WebAdminMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Extract user ID from claims
+		// Extract user ID from claims and reject revoked tokens
 		userID, ok := (*claims)["user_id"].(string)
-		if !ok {
+		if !ok || isTokenRevoked(*claims) {
 			c.SetCookie("token", "", -1, "/", "", false, true)
 			c.Redirect(http.StatusFound, "/auth/login")
 			c.Abort()
@@ -55,13 +56,28 @@ func WebAdminMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Check if user is admin
-		if !user.IsAdmin {
+		// Nothing else is reachable until a handed-out password has been replaced
+		if user.MustChangePassword && !passwordChangeAllowed[c.FullPath()] {
+			c.Redirect(http.StatusFound, "/auth/change-password")
+			c.Abort()
+			return
+		}
+
+		// Any permission opens the admin area; each page checks its own
+		roleService := services.NewRoleService(db)
+		if !roleService.HasAnyPermission(user) {
 			c.Redirect(http.StatusFound, "/dashboard")
 			c.Abort()
 			return
 		}
 
+		// Roles that require two-factor authentication cannot be used without it
+		if user.TOTPEnabledAt == nil && roleService.RequiresMFA(user) {
+			c.Redirect(http.StatusFound, "/auth/mfa/setup")
+			c.Abort()
+			return
+		}
+
 		// Set user in context
 		c.Set("user", user)
 		c.Next()
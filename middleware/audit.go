@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"yonatan/labpro/models"
+	"yonatan/labpro/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CurrentActor describes the signed-in user making a request for the audit log.
+// It must run after one of the authentication middlewares.
+func CurrentActor(c *gin.Context) services.AuditActor {
+	actor := services.AuditActor{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+	if user, exists := c.Get("user"); exists {
+		if userModel, ok := user.(models.User); ok {
+			actor.UserID = userModel.ID
+			actor.Username = userModel.Username
+		}
+	}
+	return actor
+}
@@ -1,17 +1,22 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 	"yonatan/labpro/config"
 	"yonatan/labpro/database"
 	"yonatan/labpro/models"
+	"yonatan/labpro/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+// AuthMiddleware authenticates API requests by a JWT access token or a personal
+// access token. Personal access tokens are accepted only on routes that name the
+// scopes they serve, and only when the token holds one of them.
+func AuthMiddleware(cfg *config.Config, scopes ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -35,6 +40,26 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
+		if strings.HasPrefix(tokenString, models.AccessTokenPrefix) {
+			user, err := services.NewAccessTokenService(database.DB).Authenticate(tokenString, scopes)
+			if err != nil {
+				status := http.StatusUnauthorized
+				if errors.Is(err, services.ErrAccessTokenScope) {
+					status = http.StatusForbidden
+				}
+				c.JSON(status, gin.H{
+					"status":  "error",
+					"message": err.Error(),
+					"data":    nil,
+				})
+				c.Abort()
+				return
+			}
+
+			setAuthenticatedUser(c, *user)
+			return
+		}
+
 		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 			return []byte(cfg.JWTSecret), nil
 		})
@@ -71,52 +96,74 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
-		var user models.User
-		if err := database.DB.First(&user, "id = ?", userID).Error; err != nil {
+		if isTokenRevoked(claims) {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"status":  "error",
-				"message": "User not found",
+				"message": "Token has been revoked",
 				"data":    nil,
 			})
 			c.Abort()
 			return
 		}
 
-		c.Set("user", user)
-		c.Set("user_id", user.ID)
-		if user.IsAdmin {
-			c.Set("user_role", "admin")
-		} else {
-			c.Set("user_role", "user")
-		}
-		c.Next()
-	}
-}
-
-func AdminMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		user, exists := c.Get("user")
-		if !exists {
+		var user models.User
+		if err := database.DB.First(&user, "id = ?", userID).Error; err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"status":  "error",
-				"message": "User not authenticated",
+				"message": "User not found",
 				"data":    nil,
 			})
 			c.Abort()
 			return
 		}
 
-		userModel, ok := user.(models.User)
-		if !ok || !userModel.IsAdmin {
-			c.JSON(http.StatusForbidden, gin.H{
-				"status":  "error",
-				"message": "Admin access required",
-				"data":    nil,
-			})
-			c.Abort()
-			return
-		}
+		setAuthenticatedUser(c, user)
+	}
+}
+
+// setAuthenticatedUser stores the user for the handlers, unless a forced password
+// change blocks the route
+func setAuthenticatedUser(c *gin.Context, user models.User) {
+	if user.MustChangePassword && !passwordChangeAllowed[c.FullPath()] {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  "error",
+			"message": "Password change required",
+			"data":    nil,
+		})
+		c.Abort()
+		return
+	}
+
+	c.Set("user", user)
+	c.Set("user_id", user.ID)
+	if user.IsAdmin {
+		c.Set("user_role", "admin")
+	} else {
+		c.Set("user_role", "user")
+	}
+	c.Next()
+}
+
+// passwordChangeAllowed lists the routes a user with a pending forced password
+// change may still reach
+var passwordChangeAllowed = map[string]bool{
+	"/api/auth/self":            true,
+	"/api/auth/change-password": true,
+	"/auth/change-password":     true,
+	"/auth/logout":              true,
+}
+
+// isTokenRevoked reports whether the token's jti was revoked by a logout, password change
+// or forced sign-out. Tokens without a jti predate revocation support and are rejected.
+func isTokenRevoked(claims jwt.MapClaims) bool {
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return true
+	}
 
-		c.Next()
+	var count int64
+	if err := database.DB.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+		return true
 	}
+	return count > 0
 }
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"net/http"
+	"yonatan/labpro/database"
+	"yonatan/labpro/models"
+	"yonatan/labpro/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminHomePaths are the admin pages in the order a staff member lands on them,
+// each with the permission it needs
+var adminHomePaths = []struct {
+	permission string
+	path       string
+}{
+	{models.PermissionStatsRead, "/admin/dashboard"},
+	{models.PermissionCoursesWrite, "/admin/instructor"},
+	{models.PermissionCoursesWrite, "/admin/courses"},
+	{models.PermissionUsersRead, "/admin/users"},
+	{models.PermissionTransactionsRead, "/admin/transactions"},
+	{models.PermissionAuditRead, "/admin/audit"},
+}
+
+// RequirePermission rejects API requests from users without the given permission.
+// It must run after AuthMiddleware.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"status":  "error",
+				"message": "User not authenticated",
+				"data":    nil,
+			})
+			c.Abort()
+			return
+		}
+
+		userModel, ok := user.(models.User)
+		roleService := services.NewRoleService(database.GetDB())
+		if !ok || !roleService.HasPermission(userModel, permission) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"status":  "error",
+				"message": "Permission " + permission + " required",
+				"data":    nil,
+			})
+			c.Abort()
+			return
+		}
+
+		// Roles that require two-factor authentication cannot be used without it
+		if userModel.TOTPEnabledAt == nil && roleService.RequiresMFA(userModel) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"status":  "error",
+				"message": "Two-factor authentication required, set it up at /api/auth/mfa/setup",
+				"data":    nil,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireWebPermission is RequirePermission for web pages. Pages and form posts are
+// redirected to the first admin page the user may see; deletes, which are sent by
+// fetch, get a 403.
+// It must run after WebAdminMiddleware.
+func RequireWebPermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			c.Redirect(http.StatusFound, "/auth/login")
+			c.Abort()
+			return
+		}
+
+		userModel := user.(models.User)
+		roleService := services.NewRoleService(database.GetDB())
+		if roleService.HasPermission(userModel, permission) {
+			c.Next()
+			return
+		}
+
+		if c.Request.Method == http.MethodDelete {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			c.Abort()
+			return
+		}
+
+		redirectURL := "/dashboard"
+		for _, home := range adminHomePaths {
+			if home.path != c.Request.URL.Path && roleService.HasPermission(userModel, home.permission) {
+				redirectURL = home.path
+				break
+			}
+		}
+		c.Redirect(http.StatusFound, redirectURL)
+		c.Abort()
+	}
+}
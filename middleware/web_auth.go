@@ -36,9 +36,9 @@ func WebAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Extract user ID from claims
+		// Extract user ID from claims and reject revoked tokens
 		userID, ok := (*claims)["user_id"].(string)
-		if !ok {
+		if !ok || isTokenRevoked(*claims) {
 			c.SetCookie("token", "", -1, "/", "", false, true)
 			c.Redirect(http.StatusFound, "/auth/login")
 			c.Abort()
@@ -55,6 +55,13 @@ func WebAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Nothing else is reachable until a handed-out password has been replaced
+		if user.MustChangePassword && !passwordChangeAllowed[c.FullPath()] {
+			c.Redirect(http.StatusFound, "/auth/change-password")
+			c.Abort()
+			return
+		}
+
 		// Set user in context
 		c.Set("user", user)
 		c.Next()
@@ -86,9 +93,9 @@ func OptionalWebAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Extract user ID from claims
+		// Extract user ID from claims and reject revoked tokens
 		userID, ok := (*claims)["user_id"].(string)
-		if !ok {
+		if !ok || isTokenRevoked(*claims) {
 			c.SetCookie("token", "", -1, "/", "", false, true)
 			c.Next()
 			return
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// AccessTokenPrefix starts every personal access token so it can be told apart
+// from a JWT and recognised by secret scanners
+const AccessTokenPrefix = "gat_"
+
+// Token scopes name the API route groups a personal access token may call. The
+// routes under /api/courses/:courseId/modules belong to the modules group.
+const (
+	TokenScopeCourses     = "courses"
+	TokenScopeModules     = "modules"
+	TokenScopeInstructors = "instructors"
+	TokenScopeUsers       = "users"
+	TokenScopeAdmin       = "admin"
+	TokenScopeMe          = "me"
+)
+
+// TokenScopes lists every scope a token can be granted
+var TokenScopes = []string{
+	TokenScopeCourses,
+	TokenScopeModules,
+	TokenScopeInstructors,
+	TokenScopeUsers,
+	TokenScopeAdmin,
+	TokenScopeMe,
+}
+
+// PersonalAccessToken is a long-lived credential a user creates for scripts.
+// It acts as its owner, limited to the route groups in Scopes; permissions
+// still come from the owner's roles. Only a hash of the token is stored.
+type PersonalAccessToken struct {
+	ID         string     `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID     string     `json:"user_id" gorm:"not null;index"`
+	Name       string     `json:"name" gorm:"not null"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex;not null"`
+	Hint       string     `json:"hint" gorm:"not null"`
+	Scopes     string     `json:"-" gorm:"not null"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	User User `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+// ScopeList returns the scopes of the token
+func (t PersonalAccessToken) ScopeList() []string {
+	if t.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(t.Scopes, ",")
+}
+
+// HasScope reports whether the token was granted scope
+func (t PersonalAccessToken) HasScope(scope string) bool {
+	for _, s := range t.ScopeList() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
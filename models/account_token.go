@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+const (
+	AccountTokenEmailVerification = "email_verification"
+	AccountTokenPasswordReset     = "password_reset"
+)
+
+// AccountToken is a single-use token mailed to a user, proving control of the
+// email address when the link is followed. Only a hash of the token is stored.
+type AccountToken struct {
+	ID        string     `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID    string     `json:"user_id" gorm:"not null;index"`
+	Purpose   string     `json:"purpose" gorm:"not null"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	User User `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
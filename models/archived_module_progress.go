@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// ArchivedModuleProgress keeps the module progress of an enrollment that was
+// refunded, so a student's learning history outlives the enrollment itself.
+type ArchivedModuleProgress struct {
+	ID          string     `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID      string     `json:"user_id" gorm:"type:uuid;not null;index"`
+	ModuleID    string     `json:"module_id" gorm:"type:uuid;not null"`
+	CourseID    string     `json:"course_id" gorm:"type:uuid;not null;index"`
+	IsCompleted bool       `json:"is_completed"`
+	CompletedAt *time.Time `json:"completed_at"`
+	Reason      string     `json:"reason"`
+	ArchivedAt  time.Time  `json:"archived_at"`
+
+	User   User   `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Module Module `json:"-" gorm:"foreignKey:ModuleID;constraint:OnDelete:CASCADE"`
+}
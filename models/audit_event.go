@@ -0,0 +1,76 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Actions recorded in the audit log, named after their target type
+const (
+	AuditUserCreate         = "user.create"
+	AuditUserUpdate         = "user.update"
+	AuditUserDelete         = "user.delete"
+	AuditUserRestore        = "user.restore"
+	AuditUserBalance        = "user.balance"
+	AuditUserRevokeSessions = "user.revoke_sessions"
+	AuditUserUnlock         = "user.unlock"
+	AuditUserResetMFA       = "user.reset_mfa"
+	AuditUserAssignRole     = "user.assign_role"
+	AuditUserRemoveRole     = "user.remove_role"
+	AuditRoleRequireMFA     = "role.require_mfa"
+	AuditCourseCreate       = "course.create"
+	AuditCourseUpdate       = "course.update"
+	AuditCourseDelete       = "course.delete"
+	AuditCourseRestore      = "course.restore"
+	AuditCourseStatus       = "course.status"
+	AuditCourseRollback     = "course.rollback"
+	AuditCourseRefund       = "course.refund"
+	AuditModuleCreate       = "module.create"
+	AuditModuleUpdate       = "module.update"
+	AuditModuleDelete       = "module.delete"
+	AuditModuleRestore      = "module.restore"
+	AuditModuleReorder      = "module.reorder"
+	AuditModuleRollback     = "module.rollback"
+	AuditInstructorUpdate   = "instructor.update"
+)
+
+// AuditActions lists every action in the order the audit page offers them
+var AuditActions = []string{
+	AuditUserCreate, AuditUserUpdate, AuditUserDelete, AuditUserRestore, AuditUserBalance,
+	AuditUserRevokeSessions, AuditUserUnlock, AuditUserResetMFA,
+	AuditUserAssignRole, AuditUserRemoveRole, AuditRoleRequireMFA,
+	AuditCourseCreate, AuditCourseUpdate, AuditCourseDelete, AuditCourseRestore, AuditCourseStatus, AuditCourseRollback, AuditCourseRefund,
+	AuditModuleCreate, AuditModuleUpdate, AuditModuleDelete, AuditModuleRestore, AuditModuleReorder, AuditModuleRollback,
+	AuditInstructorUpdate,
+}
+
+var ErrAuditEventImmutable = errors.New("audit events are append-only")
+
+// AuditEvent records one change made by staff. Before and After hold only the
+// JSON fields the change touched; Before is empty for creations and After for
+// deletions. ActorName keeps the actor recognisable after their account is gone.
+type AuditEvent struct {
+	ID         string    `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	ActorID    *string   `json:"actor_id" gorm:"type:uuid;index"`
+	ActorName  string    `json:"actor_name"`
+	Action     string    `json:"action" gorm:"not null;index"`
+	TargetType string    `json:"target_type" gorm:"not null;index:idx_audit_events_target"`
+	TargetID   string    `json:"target_id" gorm:"index:idx_audit_events_target"`
+	Before     *string   `json:"before" gorm:"type:jsonb"`
+	After      *string   `json:"after" gorm:"type:jsonb"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+
+	Actor *User `json:"-" gorm:"foreignKey:ActorID;constraint:OnDelete:SET NULL"`
+}
+
+func (e *AuditEvent) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditEventImmutable
+}
+
+func (e *AuditEvent) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditEventImmutable
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Content types that keep revisions
+const (
+	RevisionTargetCourse = "course"
+	RevisionTargetModule = "module"
+)
+
+// ContentRevision is a snapshot of the editable content of a course or module,
+// taken whenever that content changes. Number counts the target's revisions from
+// 1; RestoredFrom is set when the revision was made by rolling back to another.
+type ContentRevision struct {
+	ID           string    `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	TargetType   string    `json:"target_type" gorm:"not null;uniqueIndex:idx_content_revisions_target_number"`
+	TargetID     string    `json:"target_id" gorm:"type:uuid;not null;uniqueIndex:idx_content_revisions_target_number"`
+	Number       int       `json:"number" gorm:"not null;uniqueIndex:idx_content_revisions_target_number"`
+	Content      string    `json:"content" gorm:"type:jsonb;not null"`
+	RestoredFrom *int      `json:"restored_from"`
+	ActorID      *string   `json:"actor_id" gorm:"type:uuid;index"`
+	ActorName    string    `json:"actor_name"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	Actor *User `json:"-" gorm:"foreignKey:ActorID;constraint:OnDelete:SET NULL"`
+}
@@ -7,11 +7,22 @@ import (
 	"gorm.io/gorm"
 )
 
+// Course lifecycle states. Only published courses are listed and sold; archived
+// courses stay available to the users enrolled in them.
+const (
+	CourseStatusDraft     = "draft"
+	CourseStatusReview    = "review"
+	CourseStatusPublished = "published"
+	CourseStatusArchived  = "archived"
+)
+
+// CourseStatuses lists every lifecycle state in workflow order
+var CourseStatuses = []string{CourseStatusDraft, CourseStatusReview, CourseStatusPublished, CourseStatusArchived}
+
 type Course struct {
 	ID          string         `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
 	Title       string         `json:"title" gorm:"not null"`
 	Description string         `json:"description"`
-	Instructor  string         `json:"instructor" gorm:"not null"`
 	Price       float64        `json:"price" gorm:"not null"`
 	Thumbnail   string         `json:"thumbnail"`
 	Topics      pq.StringArray `json:"topics" gorm:"type:text[]"`
@@ -19,6 +30,33 @@ type Course struct {
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 
+	// Status is the lifecycle state. PublishAt schedules a draft or course in review
+	// to be published, UnpublishAt a published course to be archived.
+	Status      string     `json:"status" gorm:"not null;default:published;index"`
+	PublishAt   *time.Time `json:"publish_at"`
+	UnpublishAt *time.Time `json:"unpublish_at"`
+
+	// OwnerID is the user who created the course; instructors may only edit their own
+	OwnerID *string `json:"owner_id" gorm:"type:uuid;index"`
+
+	// InstructorID is the profile presented as the course's teacher
+	InstructorID *string `json:"instructor_id" gorm:"type:uuid;index"`
+
+	// PurgedAt marks a course purged from the trash whose row is kept because
+	// the ledger refers to it
+	PurgedAt *time.Time `json:"-"`
+
 	// Relationships
-	Modules []Module `json:"modules" gorm:"foreignKey:CourseID;constraint:OnDelete:CASCADE"`
+	Modules    []Module    `json:"modules" gorm:"foreignKey:CourseID;constraint:OnDelete:CASCADE"`
+	Owner      *User       `json:"-" gorm:"foreignKey:OwnerID;constraint:OnDelete:SET NULL"`
+	Instructor *Instructor `json:"instructor,omitempty" gorm:"foreignKey:InstructorID;constraint:OnDelete:SET NULL"`
+}
+
+// InstructorName returns the name of the course's instructor, or an empty string
+// when the course has none or the profile was not preloaded.
+func (c Course) InstructorName() string {
+	if c.Instructor == nil {
+		return ""
+	}
+	return c.Instructor.Name
 }
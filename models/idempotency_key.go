@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// IdempotencyKey stores the result of a request sent with an Idempotency-Key
+// header, so that a client retrying the same request gets the original result.
+type IdempotencyKey struct {
+	ID        string    `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID    string    `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_idempotency_keys_user_key"`
+	Key       string    `json:"key" gorm:"not null;uniqueIndex:idx_idempotency_keys_user_key"`
+	Scope     string    `json:"scope" gorm:"not null"`
+	Response  string    `json:"-" gorm:"type:text;not null"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt time.Time `json:"created_at"`
+
+	User User `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Instructor is the public profile shown on the courses an instructor teaches.
+// Profiles migrated from the old free-text instructor column have no UserID until
+// an admin links them to an account.
+type Instructor struct {
+	ID        string         `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID    *string        `json:"user_id" gorm:"type:uuid;uniqueIndex"`
+	Name      string         `json:"name" gorm:"not null"`
+	Bio       string         `json:"bio" gorm:"type:text"`
+	AvatarURL string         `json:"avatar_url"`
+	Links     pq.StringArray `json:"links" gorm:"type:text[]"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+
+	User    *User    `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:SET NULL"`
+	Courses []Course `json:"-" gorm:"foreignKey:InstructorID"`
+}
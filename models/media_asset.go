@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Kinds of uploaded files
+const (
+	MediaKindPDF         = "pdf"
+	MediaKindVideo       = "video"
+	MediaKindThumbnail   = "thumbnail"
+	MediaKindCertificate = "certificate"
+	// Playlists and segments of transcoded videos, and their poster frames
+	MediaKindStream = "stream"
+	MediaKindPoster = "poster"
+)
+
+// MediaAsset is a file the application put in storage. Key locates it in the
+// storage backend and URL is the address courses and modules keep; an asset is
+// in use for as long as a course, module or revision refers to its URL.
+// Resized copies of a thumbnail keep the URL of the copy the course refers to
+// in VariantOf, and are in use for as long as that one is.
+type MediaAsset struct {
+	ID          string    `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	Key         string    `json:"key" gorm:"not null;uniqueIndex"`
+	URL         string    `json:"url" gorm:"not null;uniqueIndex"`
+	Kind        string    `json:"kind" gorm:"not null;index"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	VariantOf   string    `json:"variant_of" gorm:"not null;default:'';index"`
+	CreatedAt   time.Time `json:"created_at"`
+}
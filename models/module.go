@@ -1,17 +1,39 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Transcoding states of a module's video. A module without a status plays its
+// uploaded video as is.
+const (
+	VideoStatusPending    = "pending"
+	VideoStatusProcessing = "processing"
+	VideoStatusReady      = "ready"
+	VideoStatusFailed     = "failed"
+)
 
 type Module struct {
-	ID           string    `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
-	CourseID     string    `json:"course_id" gorm:"not null"`
-	Title        string    `json:"title" gorm:"not null"`
-	Description  string    `json:"description" gorm:"not null"`
-	Order        int       `json:"order" gorm:"not null"`
-	PDFContent   *string   `json:"pdf_content"`
-	VideoContent *string   `json:"video_content"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           string         `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	CourseID     string         `json:"course_id" gorm:"not null"`
+	Title        string         `json:"title" gorm:"not null"`
+	Description  string         `json:"description" gorm:"not null"`
+	Order        int            `json:"order" gorm:"not null"`
+	PDFContent   *string        `json:"pdf_content"`
+	VideoContent *string        `json:"video_content"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+
+	// VideoStatus tracks the HLS transcoding of VideoContent. Once it is ready,
+	// VideoStream is the storage prefix of the master playlist, the renditions
+	// and the poster frame. VideoStatusAt is when the status last changed.
+	VideoStatus   string     `json:"video_status" gorm:"not null;default:'';index"`
+	VideoError    string     `json:"video_error,omitempty" gorm:"not null;default:''"`
+	VideoStream   *string    `json:"-"`
+	VideoStatusAt *time.Time `json:"video_status_at"`
 
 	Course Course `json:"-" gorm:"foreignKey:CourseID;constraint:OnDelete:CASCADE"`
 }
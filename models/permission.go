@@ -0,0 +1,21 @@
+package models
+
+// Permission names checked by middleware.RequirePermission
+const (
+	PermissionStatsRead        = "stats:read"
+	PermissionCoursesWrite     = "courses:write"
+	PermissionCoursesWriteAny  = "courses:write_any"
+	PermissionUsersRead        = "users:read"
+	PermissionUsersWrite       = "users:write"
+	PermissionBalancesWrite    = "balances:write"
+	PermissionTransactionsRead = "transactions:read"
+	PermissionRefundsWrite     = "refunds:write"
+	PermissionRolesWrite       = "roles:write"
+	PermissionAuditRead        = "audit:read"
+)
+
+type Permission struct {
+	ID          string `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	Name        string `json:"name" gorm:"uniqueIndex;not null"`
+	Description string `json:"description"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// RecoveryCode is a single-use code that stands in for a TOTP code when the
+// user has lost their authenticator. Only a hash of the code is stored.
+type RecoveryCode struct {
+	ID        string     `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID    string     `json:"user_id" gorm:"not null;index"`
+	CodeHash  string     `json:"-" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+
+	User User `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
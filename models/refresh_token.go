@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+type RefreshToken struct {
+	ID         string     `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID     string     `json:"user_id" gorm:"not null;index"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex;not null"`
+	AccessJTI  string     `json:"-" gorm:"index;not null"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null;index"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	ReplacedBy *string    `json:"replaced_by" gorm:"type:uuid"`
+	CreatedAt  time.Time  `json:"created_at"`
+
+	User User `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
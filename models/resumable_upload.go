@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// ResumableUpload is a file being uploaded in chunks through /api/uploads. Its
+// bytes collect in a temporary file until Received reaches Size, after which
+// the file can be attached to a module.
+type ResumableUpload struct {
+	ID          string    `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID      string    `json:"user_id" gorm:"type:uuid;not null;index"`
+	Kind        string    `json:"kind" gorm:"not null"`
+	Filename    string    `json:"filename" gorm:"not null"`
+	ContentType string    `json:"content_type" gorm:"not null"`
+	Size        int64     `json:"size" gorm:"not null"`
+	Received    int64     `json:"received" gorm:"not null;default:0"`
+	ExpiresAt   time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	User User `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+// Complete reports whether every byte of the file has arrived
+func (u ResumableUpload) Complete() bool {
+	return u.Received == u.Size
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Built-in roles seeded by the migrations
+const (
+	RoleAdmin         = "admin"
+	RoleInstructor    = "instructor"
+	RoleContentEditor = "content-editor"
+	RoleSupport       = "support"
+	RoleFinance       = "finance"
+)
+
+type Role struct {
+	ID          string    `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	Name        string    `json:"name" gorm:"uniqueIndex;not null"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	// RequireMFA makes two-factor authentication mandatory for holders of the role
+	RequireMFA bool `json:"require_mfa" gorm:"default:false"`
+
+	// Relationships
+	Permissions []Permission `json:"permissions" gorm:"many2many:role_permissions;constraint:OnDelete:CASCADE"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	TransactionTypeTopUp      = "topup"
+	TransactionTypePurchase   = "purchase"
+	TransactionTypeRefund     = "refund"
+	TransactionTypeAdjustment = "adjustment"
+	TransactionTypeOpening    = "opening_balance"
+)
+
+var ErrTransactionImmutable = errors.New("transactions are append-only")
+
+// Transaction is a ledger entry recording a single change to a user's balance.
+// Entries are never updated or deleted; corrections are made with new entries.
+type Transaction struct {
+	ID            string    `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID        string    `json:"user_id" gorm:"type:uuid;not null;index"`
+	Type          string    `json:"type" gorm:"not null;index"`
+	Amount        float64   `json:"amount" gorm:"not null"`
+	BalanceBefore float64   `json:"balance_before" gorm:"not null"`
+	BalanceAfter  float64   `json:"balance_after" gorm:"not null"`
+	CourseID      *string   `json:"course_id" gorm:"type:uuid;index"`
+	ActorID       *string   `json:"actor_id" gorm:"type:uuid"`
+	Description   string    `json:"description"`
+	CreatedAt     time.Time `json:"created_at" gorm:"index"`
+
+	// Users and courses with entries are never deleted, only kept as tombstones,
+	// so the database refuses deletes that would reach into the ledger
+	User   User    `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:RESTRICT"`
+	Course *Course `json:"-" gorm:"foreignKey:CourseID;constraint:OnDelete:RESTRICT"`
+	Actor  *User   `json:"-" gorm:"foreignKey:ActorID;constraint:OnDelete:RESTRICT"`
+}
+
+func (t *Transaction) BeforeUpdate(tx *gorm.DB) error {
+	return ErrTransactionImmutable
+}
+
+func (t *Transaction) BeforeDelete(tx *gorm.DB) error {
+	return ErrTransactionImmutable
+}
@@ -4,19 +4,42 @@ import (
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type User struct {
-	ID        string    `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
-	Username  string    `json:"username" gorm:"uniqueIndex;not null"`
-	Email     string    `json:"email" gorm:"uniqueIndex;not null"`
-	FirstName string    `json:"first_name" gorm:"not null"`
-	LastName  string    `json:"last_name" gorm:"not null"`
-	Password  string    `json:"-" gorm:"not null"`
-	Balance   float64   `json:"balance" gorm:"default:0"`
-	IsAdmin   bool      `json:"is_admin" gorm:"default:false"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        string         `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	Username  string         `json:"username" gorm:"uniqueIndex:idx_users_username,where:deleted_at IS NULL;not null"`
+	Email     string         `json:"email" gorm:"uniqueIndex:idx_users_email,where:deleted_at IS NULL;not null"`
+	FirstName string         `json:"first_name" gorm:"not null"`
+	LastName  string         `json:"last_name" gorm:"not null"`
+	Password  string         `json:"-" gorm:"not null"`
+	Balance   float64        `json:"balance" gorm:"default:0"`
+	IsAdmin   bool           `json:"is_admin" gorm:"default:false"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+
+	// MustChangePassword blocks everything but a password change until the
+	// user replaces a password that was handed to them, e.g. the bootstrap admin's
+	MustChangePassword bool `json:"must_change_password" gorm:"default:false"`
+
+	// EmailVerifiedAt is set once the user follows the link mailed to Email
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+
+	// Two-factor authentication. TOTPSecret is set when setup starts and only
+	// takes effect once TOTPEnabledAt is set; TOTPLastStep is the last accepted
+	// time step, so a code cannot be used twice.
+	TOTPSecret    string     `json:"-"`
+	TOTPEnabledAt *time.Time `json:"totp_enabled_at"`
+	TOTPLastStep  int64      `json:"-" gorm:"default:0"`
+
+	// PurgedAt marks a user purged from the trash whose row is kept, anonymized,
+	// because the ledger refers to it
+	PurgedAt *time.Time `json:"-"`
+
+	// Roles grant permissions on top of IsAdmin, which still implies every permission
+	Roles []Role `json:"roles,omitempty" gorm:"many2many:user_roles;constraint:OnDelete:CASCADE"`
 }
 
 func (u *User) SetPassword(password string) error {
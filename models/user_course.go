@@ -7,10 +7,11 @@ import (
 )
 
 type UserCourse struct {
-	ID          string    `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
-	UserID      string    `json:"user_id" gorm:"not null"`
-	CourseID    string    `json:"course_id" gorm:"not null"`
-	PurchasedAt time.Time `json:"purchased_at"`
+	ID          string         `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID      string         `json:"user_id" gorm:"not null;uniqueIndex:idx_user_courses_user_course,where:deleted_at IS NULL"`
+	CourseID    string         `json:"course_id" gorm:"not null;uniqueIndex:idx_user_courses_user_course,where:deleted_at IS NULL"`
+	PurchasedAt time.Time      `json:"purchased_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 
 	User   User   `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
 	Course Course `json:"-" gorm:"foreignKey:CourseID;constraint:OnDelete:CASCADE"`
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// UserIdentity links a user to an account at an external identity provider.
+// Issuer and Subject together name the account; the email is kept as it was
+// at the last login, for display only.
+type UserIdentity struct {
+	ID          string     `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID      string     `json:"user_id" gorm:"not null;index"`
+	Issuer      string     `json:"issuer" gorm:"not null;uniqueIndex:idx_user_identities_issuer_subject"`
+	Subject     string     `json:"subject" gorm:"not null;uniqueIndex:idx_user_identities_issuer_subject"`
+	Email       string     `json:"email"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+
+	User User `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
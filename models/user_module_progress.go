@@ -1,13 +1,18 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type UserModuleProgress struct {
-	ID          string     `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
-	UserID      string     `json:"user_id" gorm:"not null"`
-	ModuleID    string     `json:"module_id" gorm:"not null"`
-	IsCompleted bool       `json:"is_completed" gorm:"default:false"`
-	CompletedAt *time.Time `json:"completed_at"`
+	ID          string         `json:"id" gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID      string         `json:"user_id" gorm:"not null"`
+	ModuleID    string         `json:"module_id" gorm:"not null"`
+	IsCompleted bool           `json:"is_completed" gorm:"default:false"`
+	CompletedAt *time.Time     `json:"completed_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 
 	User   User   `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
 	Module Module `json:"-" gorm:"foreignKey:ModuleID;constraint:OnDelete:CASCADE"`
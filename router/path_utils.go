@@ -30,6 +30,9 @@ func getProjectRoot() string {
 
 // getAbsolutePath converts a relative path to absolute path from project root
 func getAbsolutePath(relativePath string) string {
+	if filepath.IsAbs(relativePath) {
+		return relativePath
+	}
 	if projectRoot := getProjectRoot(); projectRoot != "" {
 		return filepath.Join(projectRoot, relativePath)
 	}
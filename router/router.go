@@ -1,17 +1,33 @@
 package router
 
 import (
+	"time"
 	"yonatan/labpro/config"
 	apiAuth "yonatan/labpro/controllers/api"
+	apiAdminAudit "yonatan/labpro/controllers/api/admin"
 	apiAdminCourse "yonatan/labpro/controllers/api/admin"
+	apiAdminInstructor "yonatan/labpro/controllers/api/admin"
 	apiAdminModule "yonatan/labpro/controllers/api/admin"
+	apiAdminRevision "yonatan/labpro/controllers/api/admin"
+	apiAdminRole "yonatan/labpro/controllers/api/admin"
+	apiAdminStats "yonatan/labpro/controllers/api/admin"
+	apiAdminTransaction "yonatan/labpro/controllers/api/admin"
+	apiAdminTrash "yonatan/labpro/controllers/api/admin"
+	apiAdminUpload "yonatan/labpro/controllers/api/admin"
 	apiAdminUser "yonatan/labpro/controllers/api/admin"
 	apiUserCourse "yonatan/labpro/controllers/api/user"
+	apiUserInstructor "yonatan/labpro/controllers/api/user"
 	apiUserModule "yonatan/labpro/controllers/api/user"
+	apiUserTransaction "yonatan/labpro/controllers/api/user"
 	webAuthController "yonatan/labpro/controllers/web"
+	webMedia "yonatan/labpro/controllers/web"
+	webAdminAudit "yonatan/labpro/controllers/web/admin"
 	webAdminCourse "yonatan/labpro/controllers/web/admin"
 	webAdminDashboard "yonatan/labpro/controllers/web/admin"
+	webAdminInstructor "yonatan/labpro/controllers/web/admin"
 	webAdminModule "yonatan/labpro/controllers/web/admin"
+	webAdminTransaction "yonatan/labpro/controllers/web/admin"
+	webAdminTrash "yonatan/labpro/controllers/web/admin"
 	webAdminUser "yonatan/labpro/controllers/web/admin"
 	webUserCourse "yonatan/labpro/controllers/web/user"
 	webUserDashboard "yonatan/labpro/controllers/web/user"
@@ -20,6 +36,7 @@ import (
 	"yonatan/labpro/routes/api"
 	"yonatan/labpro/routes/web"
 	"yonatan/labpro/services"
+	"yonatan/labpro/storage"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -39,44 +56,90 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 
 	// Serve static files with absolute paths
 	r.Static("/static", getAbsolutePath("./static"))
-	r.Static("/uploads", getAbsolutePath("./uploads"))
 
 	// Get database connection
 	db := database.GetDB()
 
+	// Uploaded files go to the configured storage backend
+	fileStorage := storage.NewOrLocal(cfg)
+
 	// Initialize Redis service
 	redisService := services.NewRedisService(cfg.RedisAddr, cfg.RedisPassword)
 
 	// Initialize services
 	authService := services.NewAuthService(cfg)
-	courseService := services.NewCourseService(db, cfg, redisService)
-	moduleService := services.NewModuleService(db, cfg)
+	courseService := services.NewCourseService(db, cfg, redisService, fileStorage)
+	moduleService := services.NewModuleService(db, cfg, fileStorage)
 	userService := services.NewUserService(db)
+	statsService := services.NewStatsService(db, redisService)
+	transactionService := services.NewTransactionService(db)
+	roleService := services.NewRoleService(db)
+	instructorService := services.NewInstructorService(db, redisService)
+	loginThrottle := services.NewLoginThrottle(db, cfg, redisService)
+	accountService := services.NewAccountService(db, cfg, services.NewMailer(cfg))
+	mfaService := services.NewMFAService(db)
+	accessTokenService := services.NewAccessTokenService(db)
+	oidcService := services.NewOIDCService(db, cfg)
+	auditService := services.NewAuditService(db)
+	trashService := services.NewTrashService(db, cfg, redisService, fileStorage)
+	revisionService := services.NewRevisionService(db, cfg, redisService, fileStorage)
+	mediaService := services.NewMediaService(db, cfg, fileStorage)
+	uploadService := services.NewUploadService(db, cfg, moduleService)
+
+	// Started here rather than in main so the scheduler shares the Redis client and
+	// can clear the catalogue cache when it publishes or archives a course
+	go courseService.RunScheduler(time.Minute)
+
+	if cfg.VideoTranscoding != "false" {
+		go services.NewTranscodeService(db, cfg, fileStorage).Run(30 * time.Second)
+	}
+
+	// Abandoned resumable uploads only live in this server's temp dir
+	go uploadService.RunExpiry(time.Hour)
+
+	go authService.RunTokenPurge(time.Hour)
 
 	// Initialize controllers
-	webAuthCtrl := webAuthController.NewAuthController(authService)
-	webAdminDashboardCtrl := webAdminDashboard.NewDashboardController()
-	webAdminCourseCtrl := webAdminCourse.NewCourseController(courseService)
-	webAdminUserCtrl := webAdminUser.NewUserController(userService)
+	webAuthCtrl := webAuthController.NewAuthController(authService, loginThrottle, accountService, mfaService, oidcService)
+	webAccessTokenCtrl := webAuthController.NewAccessTokenController(accessTokenService)
+	webAdminDashboardCtrl := webAdminDashboard.NewDashboardController(statsService)
+	webAdminCourseCtrl := webAdminCourse.NewCourseController(courseService, instructorService)
+	webAdminUserCtrl := webAdminUser.NewUserController(userService, transactionService, courseService, roleService, loginThrottle, mfaService)
 	webAdminModuleCtrl := webAdminModule.NewModuleController(moduleService, courseService)
+	webAdminTransactionCtrl := webAdminTransaction.NewTransactionController(transactionService)
+	webAdminInstructorCtrl := webAdminInstructor.NewInstructorController(instructorService)
+	webAdminAuditCtrl := webAdminAudit.NewAuditController(auditService)
+	webAdminTrashCtrl := webAdminTrash.NewTrashController(trashService)
 	webUserDashboardCtrl := webUserDashboard.NewDashboardController(courseService, userService, moduleService)
-	webUserCourseCtrl := webUserCourse.NewCourseController(courseService)
+	webUserCourseCtrl := webUserCourse.NewCourseController(courseService, moduleService)
 	webUserModuleCtrl := webUserModule.NewModuleController(moduleService, courseService)
+	webMediaCtrl := webMedia.NewMediaController(mediaService)
 
-	apiAuthCtrl := apiAuth.NewAuthAPIController(authService)
+	apiAuthCtrl := apiAuth.NewAuthAPIController(authService, loginThrottle, accountService, mfaService)
+	apiAccessTokenCtrl := apiAuth.NewAccessTokenAPIController(accessTokenService)
 	apiAdminCourseCtrl := apiAdminCourse.NewCourseAPIController(courseService)
 	apiAdminModuleCtrl := apiAdminModule.NewModuleAPIController(moduleService)
-	apiAdminUserCtrl := apiAdminUser.NewUserAPIController(userService)
+	apiAdminUserCtrl := apiAdminUser.NewUserAPIController(userService, loginThrottle, mfaService)
+	apiAdminStatsCtrl := apiAdminStats.NewStatsAPIController(statsService)
+	apiAdminTransactionCtrl := apiAdminTransaction.NewTransactionAPIController(transactionService)
+	apiAdminRoleCtrl := apiAdminRole.NewRoleAPIController(roleService)
+	apiAdminAuditCtrl := apiAdminAudit.NewAuditAPIController(auditService)
+	apiAdminTrashCtrl := apiAdminTrash.NewTrashAPIController(trashService)
+	apiAdminInstructorCtrl := apiAdminInstructor.NewInstructorAPIController(instructorService)
+	apiAdminRevisionCtrl := apiAdminRevision.NewRevisionAPIController(revisionService)
+	apiAdminUploadCtrl := apiAdminUpload.NewUploadAPIController(uploadService)
 	apiUserCourseCtrl := apiUserCourse.NewCourseAPIController(courseService)
+	apiUserInstructorCtrl := apiUserInstructor.NewInstructorAPIController(instructorService)
 	apiUserModuleCtrl := apiUserModule.NewModuleAPIController(moduleService)
+	apiUserTransactionCtrl := apiUserTransaction.NewTransactionAPIController(transactionService)
 
 	// Setup web routes (HTML pages)
-	web.SetupWebRoutes(r, webAuthCtrl, webAdminDashboardCtrl, webAdminCourseCtrl, webAdminUserCtrl, webAdminModuleCtrl, webUserDashboardCtrl, webUserCourseCtrl, webUserModuleCtrl)
+	web.SetupWebRoutes(r, webAuthCtrl, webAccessTokenCtrl, webAdminDashboardCtrl, webAdminCourseCtrl, webAdminUserCtrl, webAdminModuleCtrl, webAdminTransactionCtrl, webAdminInstructorCtrl, webAdminAuditCtrl, webAdminTrashCtrl, webUserDashboardCtrl, webUserCourseCtrl, webUserModuleCtrl, webMediaCtrl)
 
 	// Setup API routes
 	apiGroup := r.Group("/api")
 	{
-		api.SetupAPIRoutes(apiGroup, apiAuthCtrl, apiAdminCourseCtrl, apiAdminModuleCtrl, apiAdminUserCtrl, apiUserCourseCtrl, apiUserModuleCtrl, cfg)
+		api.SetupAPIRoutes(apiGroup, apiAuthCtrl, apiAccessTokenCtrl, apiAdminCourseCtrl, apiAdminModuleCtrl, apiAdminUserCtrl, apiAdminStatsCtrl, apiAdminTransactionCtrl, apiAdminRoleCtrl, apiAdminAuditCtrl, apiAdminTrashCtrl, apiAdminInstructorCtrl, apiAdminRevisionCtrl, apiAdminUploadCtrl, apiUserCourseCtrl, apiUserInstructorCtrl, apiUserModuleCtrl, apiUserTransactionCtrl, cfg)
 	}
 
 	// Setup Swagger documentation (only in development)
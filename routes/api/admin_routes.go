@@ -0,0 +1,59 @@
+package api
+
+import (
+	"yonatan/labpro/config"
+	apiAdminAudit "yonatan/labpro/controllers/api/admin"
+	apiAdminRole "yonatan/labpro/controllers/api/admin"
+	apiAdminStats "yonatan/labpro/controllers/api/admin"
+	apiAdminTransaction "yonatan/labpro/controllers/api/admin"
+	apiAdminTrash "yonatan/labpro/controllers/api/admin"
+	"yonatan/labpro/middleware"
+	"yonatan/labpro/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupAdminRoutes(api *gin.RouterGroup,
+	adminStatsController *apiAdminStats.StatsAPIController,
+	adminTransactionController *apiAdminTransaction.TransactionAPIController,
+	adminRoleController *apiAdminRole.RoleAPIController,
+	adminAuditController *apiAdminAudit.AuditAPIController,
+	adminTrashController *apiAdminTrash.TrashAPIController,
+	cfg *config.Config) {
+
+	// Staff reporting and access management routes
+	admin := api.Group("/admin")
+	admin.Use(middleware.AuthMiddleware(cfg, models.TokenScopeAdmin))
+	{
+		// GET /api/admin/stats
+		admin.GET("/stats", middleware.RequirePermission(models.PermissionStatsRead), adminStatsController.GetStats)
+		// GET /api/admin/transactions
+		admin.GET("/transactions", middleware.RequirePermission(models.PermissionTransactionsRead), adminTransactionController.GetTransactions)
+		// GET /api/admin/users/:id/reconciliation
+		admin.GET("/users/:id/reconciliation", middleware.RequirePermission(models.PermissionTransactionsRead), adminTransactionController.ReconcileUserBalance)
+		// GET /api/admin/roles
+		admin.GET("/roles", middleware.RequirePermission(models.PermissionRolesWrite), adminRoleController.GetRoles)
+		// PUT /api/admin/roles/:role/mfa
+		admin.PUT("/roles/:role/mfa", middleware.RequirePermission(models.PermissionRolesWrite), adminRoleController.SetRoleMFA)
+		// GET /api/admin/users/:id/roles
+		admin.GET("/users/:id/roles", middleware.RequirePermission(models.PermissionRolesWrite), adminRoleController.GetUserRoles)
+		// POST /api/admin/users/:id/roles
+		admin.POST("/users/:id/roles", middleware.RequirePermission(models.PermissionRolesWrite), adminRoleController.AssignRole)
+		// DELETE /api/admin/users/:id/roles/:role
+		admin.DELETE("/users/:id/roles/:role", middleware.RequirePermission(models.PermissionRolesWrite), adminRoleController.RemoveRole)
+		// GET /api/admin/audit
+		admin.GET("/audit", middleware.RequirePermission(models.PermissionAuditRead), adminAuditController.GetAuditEvents)
+		// GET /api/admin/trash/users
+		admin.GET("/trash/users", middleware.RequirePermission(models.PermissionUsersWrite), adminTrashController.GetTrashedUsers)
+		// POST /api/admin/trash/users/:id/restore
+		admin.POST("/trash/users/:id/restore", middleware.RequirePermission(models.PermissionUsersWrite), adminTrashController.RestoreUser)
+		// GET /api/admin/trash/courses
+		admin.GET("/trash/courses", middleware.RequirePermission(models.PermissionCoursesWriteAny), adminTrashController.GetTrashedCourses)
+		// POST /api/admin/trash/courses/:id/restore
+		admin.POST("/trash/courses/:id/restore", middleware.RequirePermission(models.PermissionCoursesWriteAny), adminTrashController.RestoreCourse)
+		// GET /api/admin/trash/modules
+		admin.GET("/trash/modules", middleware.RequirePermission(models.PermissionCoursesWriteAny), adminTrashController.GetTrashedModules)
+		// POST /api/admin/trash/modules/:id/restore
+		admin.POST("/trash/modules/:id/restore", middleware.RequirePermission(models.PermissionCoursesWriteAny), adminTrashController.RestoreModule)
+	}
+}
@@ -8,12 +8,30 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func SetupAuthRoutes(api *gin.RouterGroup, authController *apiAuth.AuthAPIController, cfg *config.Config) {
+func SetupAuthRoutes(api *gin.RouterGroup, authController *apiAuth.AuthAPIController, accessTokenController *apiAuth.AccessTokenAPIController, cfg *config.Config) {
 	auth := api.Group("/auth")
 	{
 		auth.POST("/register", authController.Register)
 		auth.POST("/login", authController.Login)
+		auth.POST("/refresh", authController.Refresh)
 		auth.POST("/logout", authController.Logout)
 		auth.GET("/self", middleware.AuthMiddleware(cfg), authController.GetProfile)
+		auth.POST("/change-password", middleware.AuthMiddleware(cfg), authController.ChangePassword)
+		auth.POST("/forgot-password", authController.ForgotPassword)
+		auth.POST("/reset-password", authController.ResetPassword)
+		auth.POST("/verify-email", authController.VerifyEmail)
+		auth.POST("/verify-email/resend", middleware.AuthMiddleware(cfg), authController.ResendVerification)
+
+		// Two-factor authentication
+		auth.POST("/mfa/verify", authController.VerifyMFA)
+		auth.POST("/mfa/setup", middleware.AuthMiddleware(cfg), authController.SetupMFA)
+		auth.POST("/mfa/enable", middleware.AuthMiddleware(cfg), authController.EnableMFA)
+		auth.POST("/mfa/disable", middleware.AuthMiddleware(cfg), authController.DisableMFA)
+		auth.POST("/mfa/recovery-codes", middleware.AuthMiddleware(cfg), authController.RegenerateRecoveryCodes)
+
+		// Personal access tokens; only a login session may manage them
+		auth.GET("/tokens", middleware.AuthMiddleware(cfg), accessTokenController.GetTokens)
+		auth.POST("/tokens", middleware.AuthMiddleware(cfg), accessTokenController.CreateToken)
+		auth.DELETE("/tokens/:id", middleware.AuthMiddleware(cfg), accessTokenController.RevokeToken)
 	}
 }
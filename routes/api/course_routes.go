@@ -5,6 +5,7 @@ import (
 	apiAdminCourse "yonatan/labpro/controllers/api/admin"
 	apiUserCourse "yonatan/labpro/controllers/api/user"
 	"yonatan/labpro/middleware"
+	"yonatan/labpro/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,7 +17,7 @@ func SetupCourseRoutes(api *gin.RouterGroup,
 
 	// User course routes
 	courses := api.Group("/courses")
-	courses.Use(middleware.AuthMiddleware(cfg))
+	courses.Use(middleware.AuthMiddleware(cfg, models.TokenScopeCourses))
 	{
 		// GET /api/courses
 		courses.GET("", userCourseController.GetCourses)
@@ -24,19 +25,23 @@ func SetupCourseRoutes(api *gin.RouterGroup,
 		courses.GET("/:courseId", userCourseController.GetCourseByID)
 		// POST /api/courses/:courseId/buy
 		courses.POST("/:courseId/buy", userCourseController.PurchaseCourse)
+		// POST /api/courses/:courseId/refund
+		courses.POST("/:courseId/refund", userCourseController.RefundCourse)
 		// GET /api/courses/my-courses
 		courses.GET("/my-courses", userCourseController.GetMyCourses)
 	}
 
-	// Admin course routes
+	// Course authoring routes; instructors may only change the courses they own
 	adminCourses := api.Group("/courses")
-	adminCourses.Use(middleware.AuthMiddleware(cfg), middleware.AdminMiddleware())
+	adminCourses.Use(middleware.AuthMiddleware(cfg, models.TokenScopeCourses), middleware.RequirePermission(models.PermissionCoursesWrite))
 	{
-		// POST /api/courses (admin only)
+		// POST /api/courses
 		adminCourses.POST("", adminCourseController.CreateCourse)
-		// PUT /api/courses/:courseId (admin only)
+		// PUT /api/courses/:courseId
 		adminCourses.PUT("/:courseId", adminCourseController.UpdateCourse)
-		// DELETE /api/courses/:courseId (admin only)
+		// PUT /api/courses/:courseId/status
+		adminCourses.PUT("/:courseId/status", adminCourseController.SetCourseStatus)
+		// DELETE /api/courses/:courseId
 		adminCourses.DELETE("/:courseId", adminCourseController.DeleteCourse)
 	}
 }
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"yonatan/labpro/config"
+	apiAdminInstructor "yonatan/labpro/controllers/api/admin"
+	apiUserInstructor "yonatan/labpro/controllers/api/user"
+	"yonatan/labpro/middleware"
+	"yonatan/labpro/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupInstructorRoutes(api *gin.RouterGroup,
+	adminInstructorController *apiAdminInstructor.InstructorAPIController,
+	userInstructorController *apiUserInstructor.InstructorAPIController,
+	cfg *config.Config) {
+
+	instructors := api.Group("/instructors")
+	instructors.Use(middleware.AuthMiddleware(cfg, models.TokenScopeInstructors))
+	{
+		// GET /api/instructors
+		instructors.GET("", userInstructorController.GetInstructors)
+		// GET /api/instructors/:id
+		instructors.GET("/:id", userInstructorController.GetInstructor)
+		// PUT /api/instructors/me
+		instructors.PUT("/me", middleware.RequirePermission(models.PermissionCoursesWrite), adminInstructorController.UpdateMyProfile)
+		// GET /api/instructors/me/dashboard
+		instructors.GET("/me/dashboard", middleware.RequirePermission(models.PermissionCoursesWrite), adminInstructorController.GetMyDashboard)
+		// PUT /api/instructors/:id
+		instructors.PUT("/:id", middleware.RequirePermission(models.PermissionCoursesWriteAny), adminInstructorController.UpdateInstructor)
+	}
+}
@@ -0,0 +1,23 @@
+package api
+
+import (
+	"yonatan/labpro/config"
+	apiUserTransaction "yonatan/labpro/controllers/api/user"
+	"yonatan/labpro/middleware"
+	"yonatan/labpro/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupMeRoutes(api *gin.RouterGroup,
+	userTransactionController *apiUserTransaction.TransactionAPIController,
+	cfg *config.Config) {
+
+	// Routes scoped to the authenticated user
+	me := api.Group("/me")
+	me.Use(middleware.AuthMiddleware(cfg, models.TokenScopeMe))
+	{
+		// GET /api/me/transactions
+		me.GET("/transactions", userTransactionController.GetMyTransactions)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	apiAdminModule "yonatan/labpro/controllers/api/admin"
 	apiUserModule "yonatan/labpro/controllers/api/user"
 	"yonatan/labpro/middleware"
+	"yonatan/labpro/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,39 +17,43 @@ func SetupModuleRoutes(api *gin.RouterGroup,
 
 	// User module routes
 	modules := api.Group("/modules")
-	modules.Use(middleware.AuthMiddleware(cfg))
+	modules.Use(middleware.AuthMiddleware(cfg, models.TokenScopeModules))
 	{
 		// GET /api/modules/:id
 		modules.GET("/:id", userModuleController.GetModuleByID)
 		// PATCH /api/modules/:id/complete
 		modules.PATCH("/:id/complete", userModuleController.CompleteModule)
+		// GET /api/modules/:id/stream/:version/*file
+		modules.GET("/:id/stream/:version/*file", userModuleController.StreamVideo)
 	}
 
 	// Course modules routes (both admin and user)
 	courseModules := api.Group("/courses/:courseId/modules")
-	courseModules.Use(middleware.AuthMiddleware(cfg))
+	courseModules.Use(middleware.AuthMiddleware(cfg, models.TokenScopeModules))
 	{
 		// GET /api/courses/:courseId/modules (all authenticated users)
 		courseModules.GET("", userModuleController.GetCourseModules)
 	}
 
-	// Admin module routes
+	// Module authoring routes; instructors may only change modules of courses they own
 	adminModules := api.Group("/modules")
-	adminModules.Use(middleware.AuthMiddleware(cfg), middleware.AdminMiddleware())
+	adminModules.Use(middleware.AuthMiddleware(cfg, models.TokenScopeModules), middleware.RequirePermission(models.PermissionCoursesWrite))
 	{
-		// PUT /api/modules/:id (admin only)
+		// PUT /api/modules/:id
 		adminModules.PUT("/:id", adminModuleController.UpdateModule)
-		// DELETE /api/modules/:id (admin only)
+		// DELETE /api/modules/:id
 		adminModules.DELETE("/:id", adminModuleController.DeleteModule)
+		// POST /api/modules/:id/transcode
+		adminModules.POST("/:id/transcode", adminModuleController.RetranscodeVideo)
 	}
 
-	// Admin course module routes
+	// Course module authoring routes
 	adminCourseModules := api.Group("/courses/:courseId/modules")
-	adminCourseModules.Use(middleware.AuthMiddleware(cfg), middleware.AdminMiddleware())
+	adminCourseModules.Use(middleware.AuthMiddleware(cfg, models.TokenScopeModules), middleware.RequirePermission(models.PermissionCoursesWrite))
 	{
-		// POST /api/courses/:courseId/modules (admin only)
+		// POST /api/courses/:courseId/modules
 		adminCourseModules.POST("", adminModuleController.CreateModule)
-		// PATCH /api/courses/:courseId/modules/reorder (admin only)
+		// PATCH /api/courses/:courseId/modules/reorder
 		adminCourseModules.PATCH("/reorder", adminModuleController.ReorderModules)
 	}
 }
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"yonatan/labpro/config"
+	apiAdminRevision "yonatan/labpro/controllers/api/admin"
+	"yonatan/labpro/middleware"
+	"yonatan/labpro/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupRevisionRoutes(api *gin.RouterGroup,
+	adminRevisionController *apiAdminRevision.RevisionAPIController,
+	cfg *config.Config) {
+
+	// Course revision routes; instructors may only see and restore courses they own
+	courseRevisions := api.Group("/courses/:courseId/revisions")
+	courseRevisions.Use(middleware.AuthMiddleware(cfg, models.TokenScopeCourses), middleware.RequirePermission(models.PermissionCoursesWrite))
+	{
+		// GET /api/courses/:courseId/revisions
+		courseRevisions.GET("", adminRevisionController.GetCourseRevisions)
+		// GET /api/courses/:courseId/revisions/:number
+		courseRevisions.GET("/:number", adminRevisionController.GetCourseRevision)
+		// POST /api/courses/:courseId/revisions/:number/rollback
+		courseRevisions.POST("/:number/rollback", adminRevisionController.RollbackCourse)
+	}
+
+	// Module revision routes
+	moduleRevisions := api.Group("/modules/:id/revisions")
+	moduleRevisions.Use(middleware.AuthMiddleware(cfg, models.TokenScopeModules), middleware.RequirePermission(models.PermissionCoursesWrite))
+	{
+		// GET /api/modules/:id/revisions
+		moduleRevisions.GET("", adminRevisionController.GetModuleRevisions)
+		// GET /api/modules/:id/revisions/:number
+		moduleRevisions.GET("/:number", adminRevisionController.GetModuleRevision)
+		// POST /api/modules/:id/revisions/:number/rollback
+		moduleRevisions.POST("/:number/rollback", adminRevisionController.RollbackModule)
+	}
+}
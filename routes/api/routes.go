@@ -3,11 +3,21 @@ package api
 import (
 	"yonatan/labpro/config"
 	apiAuth "yonatan/labpro/controllers/api"
+	apiAdminAudit "yonatan/labpro/controllers/api/admin"
 	apiAdminCourse "yonatan/labpro/controllers/api/admin"
+	apiAdminInstructor "yonatan/labpro/controllers/api/admin"
 	apiAdminModule "yonatan/labpro/controllers/api/admin"
+	apiAdminRevision "yonatan/labpro/controllers/api/admin"
+	apiAdminRole "yonatan/labpro/controllers/api/admin"
+	apiAdminStats "yonatan/labpro/controllers/api/admin"
+	apiAdminTransaction "yonatan/labpro/controllers/api/admin"
+	apiAdminTrash "yonatan/labpro/controllers/api/admin"
+	apiAdminUpload "yonatan/labpro/controllers/api/admin"
 	apiAdminUser "yonatan/labpro/controllers/api/admin"
 	apiUserCourse "yonatan/labpro/controllers/api/user"
+	apiUserInstructor "yonatan/labpro/controllers/api/user"
 	apiUserModule "yonatan/labpro/controllers/api/user"
+	apiUserTransaction "yonatan/labpro/controllers/api/user"
 
 	"github.com/gin-gonic/gin"
 )
@@ -15,15 +25,31 @@ import (
 // SetupAPIRoutes sets up all API routes
 func SetupAPIRoutes(api *gin.RouterGroup,
 	authController *apiAuth.AuthAPIController,
+	accessTokenController *apiAuth.AccessTokenAPIController,
 	adminCourseController *apiAdminCourse.CourseAPIController,
 	adminModuleController *apiAdminModule.ModuleAPIController,
 	adminUserController *apiAdminUser.UserAPIController,
+	adminStatsController *apiAdminStats.StatsAPIController,
+	adminTransactionController *apiAdminTransaction.TransactionAPIController,
+	adminRoleController *apiAdminRole.RoleAPIController,
+	adminAuditController *apiAdminAudit.AuditAPIController,
+	adminTrashController *apiAdminTrash.TrashAPIController,
+	adminInstructorController *apiAdminInstructor.InstructorAPIController,
+	adminRevisionController *apiAdminRevision.RevisionAPIController,
+	adminUploadController *apiAdminUpload.UploadAPIController,
 	userCourseController *apiUserCourse.CourseAPIController,
+	userInstructorController *apiUserInstructor.InstructorAPIController,
 	userModuleController *apiUserModule.ModuleAPIController,
+	userTransactionController *apiUserTransaction.TransactionAPIController,
 	cfg *config.Config) {
 	// Setup all API route groups
-	SetupAuthRoutes(api, authController, cfg)
+	SetupAuthRoutes(api, authController, accessTokenController, cfg)
 	SetupCourseRoutes(api, adminCourseController, userCourseController, cfg)
+	SetupInstructorRoutes(api, adminInstructorController, userInstructorController, cfg)
 	SetupModuleRoutes(api, adminModuleController, userModuleController, cfg)
+	SetupRevisionRoutes(api, adminRevisionController, cfg)
+	SetupUploadRoutes(api, adminUploadController, cfg)
 	SetupUserRoutes(api, adminUserController, cfg)
+	SetupAdminRoutes(api, adminStatsController, adminTransactionController, adminRoleController, adminAuditController, adminTrashController, cfg)
+	SetupMeRoutes(api, userTransactionController, cfg)
 }
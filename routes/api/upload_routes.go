@@ -0,0 +1,35 @@
+package api
+
+import (
+	"yonatan/labpro/config"
+	apiAdminUpload "yonatan/labpro/controllers/api/admin"
+	"yonatan/labpro/middleware"
+	"yonatan/labpro/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupUploadRoutes(api *gin.RouterGroup,
+	adminUploadController *apiAdminUpload.UploadAPIController,
+	cfg *config.Config) {
+
+	// tus discovery needs no token
+	// OPTIONS /api/uploads
+	api.OPTIONS("/uploads", adminUploadController.Options)
+
+	// Resumable uploads of module videos and PDFs
+	uploads := api.Group("/uploads")
+	uploads.Use(middleware.AuthMiddleware(cfg, models.TokenScopeModules), middleware.RequirePermission(models.PermissionCoursesWrite))
+	{
+		// POST /api/uploads
+		uploads.POST("", adminUploadController.CreateUpload)
+		// HEAD /api/uploads/:id
+		uploads.HEAD("/:id", adminUploadController.GetUploadOffset)
+		// PATCH /api/uploads/:id
+		uploads.PATCH("/:id", adminUploadController.PatchUpload)
+		// DELETE /api/uploads/:id
+		uploads.DELETE("/:id", adminUploadController.DeleteUpload)
+		// POST /api/uploads/:id/attach
+		uploads.POST("/:id/attach", adminUploadController.AttachUpload)
+	}
+}
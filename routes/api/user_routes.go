@@ -4,6 +4,7 @@ import (
 	"yonatan/labpro/config"
 	apiAdminUser "yonatan/labpro/controllers/api/admin"
 	"yonatan/labpro/middleware"
+	"yonatan/labpro/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,19 +13,25 @@ func SetupUserRoutes(api *gin.RouterGroup,
 	adminUserController *apiAdminUser.UserAPIController,
 	cfg *config.Config) {
 
-	// All user routes are admin-only according to the contract
+	// All user routes are staff-only according to the contract
 	users := api.Group("/users")
-	users.Use(middleware.AuthMiddleware(cfg), middleware.AdminMiddleware())
+	users.Use(middleware.AuthMiddleware(cfg, models.TokenScopeUsers))
 	{
 		// GET /api/users
-		users.GET("", adminUserController.GetUsers)
+		users.GET("", middleware.RequirePermission(models.PermissionUsersRead), adminUserController.GetUsers)
 		// GET /api/users/:id
-		users.GET("/:id", adminUserController.GetUserByID)
+		users.GET("/:id", middleware.RequirePermission(models.PermissionUsersRead), adminUserController.GetUserByID)
 		// POST /api/users/:id/balance
-		users.POST("/:id/balance", adminUserController.UpdateUserBalance)
+		users.POST("/:id/balance", middleware.RequirePermission(models.PermissionBalancesWrite), adminUserController.UpdateUserBalance)
 		// PUT /api/users/:id
-		users.PUT("/:id", adminUserController.UpdateUser)
+		users.PUT("/:id", middleware.RequirePermission(models.PermissionUsersWrite), adminUserController.UpdateUser)
 		// DELETE /api/users/:id
-		users.DELETE("/:id", adminUserController.DeleteUser)
+		users.DELETE("/:id", middleware.RequirePermission(models.PermissionUsersWrite), adminUserController.DeleteUser)
+		// DELETE /api/users/:id/sessions
+		users.DELETE("/:id/sessions", middleware.RequirePermission(models.PermissionUsersWrite), adminUserController.RevokeUserSessions)
+		// POST /api/users/:id/unlock
+		users.POST("/:id/unlock", middleware.RequirePermission(models.PermissionUsersWrite), adminUserController.UnlockUser)
+		// POST /api/users/:id/mfa/reset
+		users.POST("/:id/mfa/reset", middleware.RequirePermission(models.PermissionUsersWrite), adminUserController.ResetUserMFA)
 	}
 }
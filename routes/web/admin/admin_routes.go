@@ -1,11 +1,16 @@
 package admin
 
 import (
+	webAdminAudit "yonatan/labpro/controllers/web/admin"
 	webAdminCourse "yonatan/labpro/controllers/web/admin"
 	webAdminDashboard "yonatan/labpro/controllers/web/admin"
+	webAdminInstructor "yonatan/labpro/controllers/web/admin"
 	webAdminModule "yonatan/labpro/controllers/web/admin"
+	webAdminTransaction "yonatan/labpro/controllers/web/admin"
+	webAdminTrash "yonatan/labpro/controllers/web/admin"
 	webAdminUser "yonatan/labpro/controllers/web/admin"
 	"yonatan/labpro/middleware"
+	"yonatan/labpro/models"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,43 +19,73 @@ func SetupAdminRoutes(webRoutes *gin.RouterGroup,
 	adminDashboardController *webAdminDashboard.DashboardController,
 	adminCourseController *webAdminCourse.CourseController,
 	adminUserController *webAdminUser.UserController,
-	adminModuleController *webAdminModule.ModuleController) {
+	adminModuleController *webAdminModule.ModuleController,
+	adminTransactionController *webAdminTransaction.TransactionController,
+	adminInstructorController *webAdminInstructor.InstructorController,
+	adminAuditController *webAdminAudit.AuditController,
+	adminTrashController *webAdminTrash.TrashController) {
 
-	// Admin routes (admin authentication required)
+	// Admin routes (staff authentication required, each page checks its permission)
 	adminRoutes := webRoutes.Group("/admin")
 	adminRoutes.Use(middleware.WebAdminMiddleware())
 	{
-		adminRoutes.GET("", adminDashboardController.ShowAdminDashboard)
-		adminRoutes.GET("/dashboard", adminDashboardController.ShowAdminDashboard)
+		adminRoutes.GET("", middleware.RequireWebPermission(models.PermissionStatsRead), adminDashboardController.ShowAdminDashboard)
+		adminRoutes.GET("/dashboard", middleware.RequireWebPermission(models.PermissionStatsRead), adminDashboardController.ShowAdminDashboard)
 
 		// Course management routes
-		adminRoutes.GET("/courses", adminCourseController.ShowCoursesPage)
-		adminRoutes.GET("/courses/create", adminCourseController.ShowCreateCoursePage)
-		adminRoutes.POST("/courses/create", adminCourseController.HandleCreateCourse)
-		adminRoutes.GET("/courses/:id/edit", adminCourseController.ShowEditCoursePage)
-		adminRoutes.POST("/courses/:id/edit", adminCourseController.HandleUpdateCourse)
-		adminRoutes.DELETE("/courses/:id", adminCourseController.HandleDeleteCourse)
+		adminRoutes.GET("/courses", middleware.RequireWebPermission(models.PermissionCoursesWrite), adminCourseController.ShowCoursesPage)
+		adminRoutes.GET("/courses/create", middleware.RequireWebPermission(models.PermissionCoursesWrite), adminCourseController.ShowCreateCoursePage)
+		adminRoutes.POST("/courses/create", middleware.RequireWebPermission(models.PermissionCoursesWrite), adminCourseController.HandleCreateCourse)
+		adminRoutes.GET("/courses/:id/edit", middleware.RequireWebPermission(models.PermissionCoursesWrite), adminCourseController.ShowEditCoursePage)
+		adminRoutes.POST("/courses/:id/edit", middleware.RequireWebPermission(models.PermissionCoursesWrite), adminCourseController.HandleUpdateCourse)
+		adminRoutes.POST("/courses/:id/status", middleware.RequireWebPermission(models.PermissionCoursesWrite), adminCourseController.HandleSetCourseStatus)
+		adminRoutes.DELETE("/courses/:id", middleware.RequireWebPermission(models.PermissionCoursesWrite), adminCourseController.HandleDeleteCourse)
 
 		// Course modules management
-		adminRoutes.GET("/courses/:id/modules", adminModuleController.ShowCourseModulesPage)
-		adminRoutes.GET("/courses/:id/modules/create", adminModuleController.ShowCreateModulePageForCourse)
+		adminRoutes.GET("/courses/:id/modules", middleware.RequireWebPermission(models.PermissionCoursesWrite), adminModuleController.ShowCourseModulesPage)
+		adminRoutes.GET("/courses/:id/modules/create", middleware.RequireWebPermission(models.PermissionCoursesWrite), adminModuleController.ShowCreateModulePageForCourse)
 
 		// User management routes
-		adminRoutes.GET("/users", adminUserController.ShowUsersPage)
-		adminRoutes.GET("/users/create", adminUserController.ShowCreateUserPage)
-		adminRoutes.POST("/users/create", adminUserController.CreateUser)
-		adminRoutes.GET("/users/:id", adminUserController.ShowUserDetails)
-		adminRoutes.GET("/users/:id/edit", adminUserController.ShowEditUserPage)
-		adminRoutes.POST("/users/:id/edit", adminUserController.HandleUpdateUser)
-		adminRoutes.POST("/users/:id/balance", adminUserController.HandleUpdateBalance)
-		adminRoutes.DELETE("/users/:id", adminUserController.HandleDeleteUser)
+		adminRoutes.GET("/users", middleware.RequireWebPermission(models.PermissionUsersRead), adminUserController.ShowUsersPage)
+		adminRoutes.GET("/users/create", middleware.RequireWebPermission(models.PermissionUsersWrite), adminUserController.ShowCreateUserPage)
+		adminRoutes.POST("/users/create", middleware.RequireWebPermission(models.PermissionUsersWrite), adminUserController.CreateUser)
+		adminRoutes.GET("/users/:id", middleware.RequireWebPermission(models.PermissionUsersRead), adminUserController.ShowUserDetails)
+		adminRoutes.GET("/users/:id/edit", middleware.RequireWebPermission(models.PermissionUsersWrite), adminUserController.ShowEditUserPage)
+		adminRoutes.POST("/users/:id/edit", middleware.RequireWebPermission(models.PermissionUsersWrite), adminUserController.HandleUpdateUser)
+		adminRoutes.POST("/users/:id/balance", middleware.RequireWebPermission(models.PermissionBalancesWrite), adminUserController.HandleUpdateBalance)
+		adminRoutes.POST("/users/:id/sessions/revoke", middleware.RequireWebPermission(models.PermissionUsersWrite), adminUserController.HandleRevokeSessions)
+		adminRoutes.POST("/users/:id/unlock", middleware.RequireWebPermission(models.PermissionUsersWrite), adminUserController.HandleUnlockUser)
+		adminRoutes.POST("/users/:id/mfa/reset", middleware.RequireWebPermission(models.PermissionUsersWrite), adminUserController.HandleResetMFA)
+		adminRoutes.POST("/users/:id/courses/:courseId/refund", middleware.RequireWebPermission(models.PermissionRefundsWrite), adminUserController.HandleRefundCourse)
+		adminRoutes.DELETE("/users/:id", middleware.RequireWebPermission(models.PermissionUsersWrite), adminUserController.HandleDeleteUser)
+		adminRoutes.POST("/users/:id/roles", middleware.RequireWebPermission(models.PermissionRolesWrite), adminUserController.HandleAssignRole)
+		adminRoutes.POST("/users/:id/roles/:role/remove", middleware.RequireWebPermission(models.PermissionRolesWrite), adminUserController.HandleRemoveRole)
 
 		// Module management routes
-		adminRoutes.GET("/modules", adminModuleController.ShowModulesPage)
-		adminRoutes.GET("/modules/create", adminModuleController.ShowCreateModulePage)
-		adminRoutes.POST("/modules/create", adminModuleController.HandleCreateModule)
-		adminRoutes.GET("/modules/:id/edit", adminModuleController.ShowEditModulePage)
-		adminRoutes.POST("/modules/:id/edit", adminModuleController.HandleUpdateModule)
-		adminRoutes.DELETE("/modules/:id", adminModuleController.HandleDeleteModule)
+		adminRoutes.GET("/modules", middleware.RequireWebPermission(models.PermissionCoursesWrite), adminModuleController.ShowModulesPage)
+		adminRoutes.GET("/modules/create", middleware.RequireWebPermission(models.PermissionCoursesWrite), adminModuleController.ShowCreateModulePage)
+		adminRoutes.POST("/modules/create", middleware.RequireWebPermission(models.PermissionCoursesWrite), adminModuleController.HandleCreateModule)
+		adminRoutes.GET("/modules/:id/edit", middleware.RequireWebPermission(models.PermissionCoursesWrite), adminModuleController.ShowEditModulePage)
+		adminRoutes.POST("/modules/:id/edit", middleware.RequireWebPermission(models.PermissionCoursesWrite), adminModuleController.HandleUpdateModule)
+		adminRoutes.DELETE("/modules/:id", middleware.RequireWebPermission(models.PermissionCoursesWrite), adminModuleController.HandleDeleteModule)
+
+		// Instructor dashboard of the signed-in user
+		adminRoutes.GET("/instructor", middleware.RequireWebPermission(models.PermissionCoursesWrite), adminInstructorController.ShowInstructorDashboard)
+		adminRoutes.POST("/instructor/profile", middleware.RequireWebPermission(models.PermissionCoursesWrite), adminInstructorController.HandleUpdateProfile)
+
+		// Balance ledger
+		adminRoutes.GET("/transactions", middleware.RequireWebPermission(models.PermissionTransactionsRead), adminTransactionController.ShowTransactionsPage)
+
+		// Audit log of staff changes
+		adminRoutes.GET("/audit", middleware.RequireWebPermission(models.PermissionAuditRead), adminAuditController.ShowAuditPage)
+		adminRoutes.GET("/audit/export", middleware.RequireWebPermission(models.PermissionAuditRead), adminAuditController.ExportAudit)
+
+		// Deleted items, restorable until the retention job purges them
+		adminRoutes.GET("/trash/users", middleware.RequireWebPermission(models.PermissionUsersWrite), adminTrashController.ShowUsersTrash)
+		adminRoutes.POST("/trash/users/:id/restore", middleware.RequireWebPermission(models.PermissionUsersWrite), adminTrashController.HandleRestoreUser)
+		adminRoutes.GET("/trash/courses", middleware.RequireWebPermission(models.PermissionCoursesWriteAny), adminTrashController.ShowCoursesTrash)
+		adminRoutes.POST("/trash/courses/:id/restore", middleware.RequireWebPermission(models.PermissionCoursesWriteAny), adminTrashController.HandleRestoreCourse)
+		adminRoutes.GET("/trash/modules", middleware.RequireWebPermission(models.PermissionCoursesWriteAny), adminTrashController.ShowModulesTrash)
+		adminRoutes.POST("/trash/modules/:id/restore", middleware.RequireWebPermission(models.PermissionCoursesWriteAny), adminTrashController.HandleRestoreModule)
 	}
 }
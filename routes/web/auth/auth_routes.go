@@ -2,11 +2,12 @@ package auth
 
 import (
 	webAuth "yonatan/labpro/controllers/web"
+	"yonatan/labpro/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupAuthRoutes(webRoutes *gin.RouterGroup, authController *webAuth.AuthController) {
+func SetupAuthRoutes(webRoutes *gin.RouterGroup, authController *webAuth.AuthController, accessTokenController *webAuth.AccessTokenController) {
 	// Public routes (no authentication required)
 	authRoutes := webRoutes.Group("/auth")
 	{
@@ -15,5 +16,34 @@ func SetupAuthRoutes(webRoutes *gin.RouterGroup, authController *webAuth.AuthCon
 		authRoutes.GET("/register", authController.ShowRegisterPage)
 		authRoutes.POST("/register", authController.HandleRegister)
 		authRoutes.POST("/logout", authController.HandleLogout)
+
+		// Single sign-on through the configured OpenID Connect provider
+		authRoutes.GET("/oidc/login", authController.HandleOIDCLogin)
+		authRoutes.GET("/oidc/callback", authController.HandleOIDCCallback)
+
+		// Links mailed to users
+		authRoutes.GET("/forgot-password", authController.ShowForgotPasswordPage)
+		authRoutes.POST("/forgot-password", authController.HandleForgotPassword)
+		authRoutes.GET("/reset-password", authController.ShowResetPasswordPage)
+		authRoutes.POST("/reset-password", authController.HandleResetPassword)
+		authRoutes.GET("/verify-email", authController.HandleVerifyEmail)
+		authRoutes.POST("/verify-email/resend", middleware.WebAuthMiddleware(), authController.HandleResendVerification)
+
+		// Second login step and two-factor settings
+		authRoutes.GET("/mfa", authController.ShowMFAPage)
+		authRoutes.POST("/mfa", authController.HandleMFA)
+		authRoutes.GET("/mfa/setup", middleware.WebAuthMiddleware(), authController.ShowMFASetupPage)
+		authRoutes.POST("/mfa/setup", middleware.WebAuthMiddleware(), authController.HandleEnableMFA)
+		authRoutes.POST("/mfa/disable", middleware.WebAuthMiddleware(), authController.HandleDisableMFA)
+		authRoutes.POST("/mfa/recovery-codes", middleware.WebAuthMiddleware(), authController.HandleRegenerateRecoveryCodes)
+
+		// Personal access tokens for scripts
+		authRoutes.GET("/tokens", middleware.WebAuthMiddleware(), accessTokenController.ShowTokensPage)
+		authRoutes.POST("/tokens", middleware.WebAuthMiddleware(), accessTokenController.HandleCreateToken)
+		authRoutes.POST("/tokens/:id/revoke", middleware.WebAuthMiddleware(), accessTokenController.HandleRevokeToken)
+
+		// Forced on first login for accounts created with a temporary password
+		authRoutes.GET("/change-password", middleware.WebAuthMiddleware(), authController.ShowChangePasswordPage)
+		authRoutes.POST("/change-password", middleware.WebAuthMiddleware(), authController.HandleChangePassword)
 	}
 }
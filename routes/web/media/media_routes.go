@@ -0,0 +1,22 @@
+package media
+
+import (
+	webMedia "yonatan/labpro/controllers/web"
+	"yonatan/labpro/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetupMediaRoutes(webRoutes *gin.RouterGroup, mediaController *webMedia.MediaController) {
+	// Files are read with a signed URL or the session cookie
+	mediaRoutes := webRoutes.Group("/")
+	mediaRoutes.Use(middleware.OptionalWebAuthMiddleware())
+	{
+		mediaRoutes.GET("/media/:assetId", mediaController.ServeAsset)
+		mediaRoutes.HEAD("/media/:assetId", mediaController.ServeAsset)
+
+		// Addresses saved before files went through /media
+		mediaRoutes.GET("/uploads/*key", mediaController.ServeUpload)
+		mediaRoutes.HEAD("/uploads/*key", mediaController.ServeUpload)
+	}
+}
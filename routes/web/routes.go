@@ -4,9 +4,14 @@ import (
 	"os"
 	"path/filepath"
 	webAuth "yonatan/labpro/controllers/web"
+	webMedia "yonatan/labpro/controllers/web"
+	webAdminAudit "yonatan/labpro/controllers/web/admin"
 	webAdminCourse "yonatan/labpro/controllers/web/admin"
 	webAdminDashboard "yonatan/labpro/controllers/web/admin"
+	webAdminInstructor "yonatan/labpro/controllers/web/admin"
 	webAdminModule "yonatan/labpro/controllers/web/admin"
+	webAdminTransaction "yonatan/labpro/controllers/web/admin"
+	webAdminTrash "yonatan/labpro/controllers/web/admin"
 	webAdminUser "yonatan/labpro/controllers/web/admin"
 	webUserCourse "yonatan/labpro/controllers/web/user"
 	webUserDashboard "yonatan/labpro/controllers/web/user"
@@ -15,6 +20,7 @@ import (
 	"yonatan/labpro/models"
 	"yonatan/labpro/routes/web/admin"
 	"yonatan/labpro/routes/web/auth"
+	"yonatan/labpro/routes/web/media"
 	"yonatan/labpro/routes/web/user"
 
 	"github.com/gin-gonic/gin"
@@ -53,13 +59,19 @@ func getTemplatePattern() string {
 
 func SetupWebRoutes(r *gin.Engine,
 	authController *webAuth.AuthController,
+	accessTokenController *webAuth.AccessTokenController,
 	adminDashboardController *webAdminDashboard.DashboardController,
 	adminCourseController *webAdminCourse.CourseController,
 	adminUserController *webAdminUser.UserController,
 	adminModuleController *webAdminModule.ModuleController,
+	adminTransactionController *webAdminTransaction.TransactionController,
+	adminInstructorController *webAdminInstructor.InstructorController,
+	adminAuditController *webAdminAudit.AuditController,
+	adminTrashController *webAdminTrash.TrashController,
 	userDashboardController *webUserDashboard.DashboardController,
 	userCourseController *webUserCourse.CourseController,
-	userModuleController *webUserModule.ModuleController) {
+	userModuleController *webUserModule.ModuleController,
+	mediaController *webMedia.MediaController) {
 	// Load HTML templates with absolute path
 	r.LoadHTMLGlob(getTemplatePattern())
 
@@ -67,7 +79,10 @@ func SetupWebRoutes(r *gin.Engine,
 	webRoutes := r.Group("/")
 	{
 		// Setup auth routes
-		auth.SetupAuthRoutes(webRoutes, authController)
+		auth.SetupAuthRoutes(webRoutes, authController, accessTokenController)
+
+		// Setup uploaded file routes
+		media.SetupMediaRoutes(webRoutes, mediaController)
 
 		// Root route - redirect to dashboard if authenticated, login if not
 		webRoutes.Use(middleware.OptionalWebAuthMiddleware())
@@ -86,7 +101,7 @@ func SetupWebRoutes(r *gin.Engine,
 		})
 
 		// Setup admin routes
-		admin.SetupAdminRoutes(webRoutes, adminDashboardController, adminCourseController, adminUserController, adminModuleController)
+		admin.SetupAdminRoutes(webRoutes, adminDashboardController, adminCourseController, adminUserController, adminModuleController, adminTransactionController, adminInstructorController, adminAuditController, adminTrashController)
 
 		// Setup user routes
 		user.SetupUserRoutes(webRoutes, userDashboardController, userCourseController, userModuleController)
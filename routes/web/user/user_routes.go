@@ -25,10 +25,12 @@ func SetupUserRoutes(webRoutes *gin.RouterGroup,
 		userRoutes.GET("/courses", userCourseController.ShowCoursesPage)
 		userRoutes.GET("/courses/:id", userCourseController.ShowCourseDetail)
 		userRoutes.POST("/courses/:id/purchase", userCourseController.HandlePurchaseCourse)
+		userRoutes.POST("/courses/:id/refund", userCourseController.HandleRefundCourse)
 		userRoutes.GET("/my-courses", userCourseController.ShowMyCourses)
 
 		// Module viewing
 		userRoutes.GET("/modules/:id", userModuleController.ShowModuleDetail)
 		userRoutes.POST("/modules/:id/complete", userModuleController.HandleCompleteModule)
+		userRoutes.GET("/modules/:id/stream/:version/*file", userModuleController.StreamVideo)
 	}
 }
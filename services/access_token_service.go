@@ -0,0 +1,164 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+	"yonatan/labpro/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultAccessTokenDays = 30
+	maxAccessTokenDays     = 365
+
+	// accessTokenUsageInterval limits how often last_used_at is written for a busy token
+	accessTokenUsageInterval = time.Minute
+)
+
+var (
+	ErrInvalidAccessToken  = errors.New("invalid or expired access token")
+	ErrAccessTokenNotFound = errors.New("access token not found")
+	ErrAccessTokenScope    = errors.New("access token does not grant this scope")
+	ErrInvalidTokenName    = errors.New("name is required")
+	ErrInvalidTokenScopes  = errors.New("scopes must be one or more of: " + strings.Join(models.TokenScopes, ", "))
+	ErrInvalidTokenExpiry  = errors.New("expires_in_days must be between 1 and 365")
+)
+
+// AccessTokenService manages personal access tokens
+type AccessTokenService struct {
+	db *gorm.DB
+}
+
+func NewAccessTokenService(db *gorm.DB) *AccessTokenService {
+	return &AccessTokenService{db: db}
+}
+
+// CreateToken issues a token for the user. The plain token is returned only here;
+// afterwards it can only be recognised by its hint.
+func (ats *AccessTokenService) CreateToken(userID, name string, scopes []string, expiresInDays int) (*models.PersonalAccessToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", ErrInvalidTokenName
+	}
+
+	scopes, err := normalizeTokenScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if expiresInDays == 0 {
+		expiresInDays = defaultAccessTokenDays
+	}
+	if expiresInDays < 1 || expiresInDays > maxAccessTokenDays {
+		return nil, "", ErrInvalidTokenExpiry
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	plain := models.AccessTokenPrefix + secret
+
+	token := models.PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: hashToken(plain),
+		Hint:      plain[len(plain)-4:],
+		Scopes:    strings.Join(scopes, ","),
+		ExpiresAt: time.Now().AddDate(0, 0, expiresInDays),
+	}
+	if err := ats.db.Create(&token).Error; err != nil {
+		return nil, "", err
+	}
+
+	return &token, plain, nil
+}
+
+// GetTokens returns the user's tokens that have not been revoked, newest first
+func (ats *AccessTokenService) GetTokens(userID string) ([]models.PersonalAccessToken, error) {
+	var tokens []models.PersonalAccessToken
+	if err := ats.db.Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// RevokeToken stops one of the user's tokens from working
+func (ats *AccessTokenService) RevokeToken(userID, tokenID string) error {
+	result := ats.db.Model(&models.PersonalAccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", tokenID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil || result.RowsAffected == 0 {
+		return ErrAccessTokenNotFound
+	}
+	return nil
+}
+
+// Authenticate resolves a presented token to its owner. The token must be live
+// and hold one of the scopes the route accepts.
+func (ats *AccessTokenService) Authenticate(plain string, scopes []string) (*models.User, error) {
+	var token models.PersonalAccessToken
+	if err := ats.db.Where("token_hash = ?", hashToken(plain)).First(&token).Error; err != nil {
+		return nil, ErrInvalidAccessToken
+	}
+
+	now := time.Now()
+	if token.RevokedAt != nil || now.After(token.ExpiresAt) {
+		return nil, ErrInvalidAccessToken
+	}
+
+	allowed := false
+	for _, scope := range scopes {
+		if token.HasScope(scope) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, ErrAccessTokenScope
+	}
+
+	var user models.User
+	if err := ats.db.First(&user, "id = ?", token.UserID).Error; err != nil {
+		return nil, ErrInvalidAccessToken
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= accessTokenUsageInterval {
+		ats.db.Model(&token).Update("last_used_at", now)
+	}
+
+	return &user, nil
+}
+
+// normalizeTokenScopes checks scopes against the known ones and drops duplicates
+func normalizeTokenScopes(scopes []string) ([]string, error) {
+	seen := map[string]bool{}
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		valid := false
+		for _, known := range models.TokenScopes {
+			if scope == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, ErrInvalidTokenScopes
+		}
+		seen[scope] = true
+	}
+	if len(seen) == 0 {
+		return nil, ErrInvalidTokenScopes
+	}
+
+	result := make([]string, 0, len(seen))
+	for scope := range seen {
+		result = append(result, scope)
+	}
+	sort.Strings(result)
+	return result, nil
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"yonatan/labpro/config"
+	"yonatan/labpro/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	emailVerificationTTL = 48 * time.Hour
+	passwordResetTTL     = time.Hour
+)
+
+var (
+	ErrInvalidAccountToken  = errors.New("invalid or expired token")
+	ErrEmailAlreadyVerified = errors.New("email address is already verified")
+)
+
+// AccountService handles the self-service flows that prove control of an email
+// address: verifying it after registration and resetting a forgotten password.
+type AccountService struct {
+	db     *gorm.DB
+	config *config.Config
+	mailer Mailer
+}
+
+func NewAccountService(db *gorm.DB, cfg *config.Config, mailer Mailer) *AccountService {
+	return &AccountService{
+		db:     db,
+		config: cfg,
+		mailer: mailer,
+	}
+}
+
+// SendVerificationEmail mails the user a link that marks their address verified
+func (as *AccountService) SendVerificationEmail(user *models.User) error {
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+
+	token, err := as.issueToken(user.ID, models.AccountTokenEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\nThe link expires in %d hours. If you did not create a Grocademy account, you can ignore this email.\n",
+		user.FirstName, as.link("/auth/verify-email", token), int(emailVerificationTTL.Hours()))
+	return as.mailer.Send(user.Email, "Confirm your email address", body)
+}
+
+// VerifyEmail consumes a verification token and marks the owner's address verified
+func (as *AccountService) VerifyEmail(token string) (*models.User, error) {
+	var user models.User
+
+	err := as.db.Transaction(func(tx *gorm.DB) error {
+		record, err := consumeToken(tx, as.config.JWTSecret, token, models.AccountTokenEmailVerification)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.First(&user, "id = ?", record.UserID).Error; err != nil {
+			return ErrInvalidAccountToken
+		}
+		if user.EmailVerifiedAt == nil {
+			now := time.Now()
+			user.EmailVerifiedAt = &now
+			return tx.Model(&user).Update("email_verified_at", now).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// RequestPasswordReset mails a reset link to the account registered with email.
+// Unknown addresses are ignored so callers cannot probe which accounts exist.
+func (as *AccountService) RequestPasswordReset(email string) error {
+	var user models.User
+	if err := as.db.Where("LOWER(email) = ?", strings.ToLower(strings.TrimSpace(email))).First(&user).Error; err != nil {
+		return nil
+	}
+
+	token, err := as.issueToken(user.ID, models.AccountTokenPasswordReset, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your Grocademy account (%s). Open the link below to choose a new password:\n\n%s\n\nThe link expires in %d minutes and works once. If you did not ask for this, you can ignore this email.\n",
+		user.FirstName, user.Username, as.link("/auth/reset-password", token), int(passwordResetTTL.Minutes()))
+	return as.mailer.Send(user.Email, "Reset your password", body)
+}
+
+// ResetPassword consumes a reset token and sets a new password. Every session of
+// the user is ended and any other outstanding reset link stops working. The
+// address counts as verified since the link was received there.
+func (as *AccountService) ResetPassword(token, newPassword string) (*models.User, error) {
+	var user models.User
+
+	err := as.db.Transaction(func(tx *gorm.DB) error {
+		record, err := consumeToken(tx, as.config.JWTSecret, token, models.AccountTokenPasswordReset)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, "id = ?", record.UserID).Error; err != nil {
+			return ErrInvalidAccountToken
+		}
+
+		if err := user.SetPassword(newPassword); err != nil {
+			return errors.New("failed to hash password")
+		}
+		user.MustChangePassword = false
+		if user.EmailVerifiedAt == nil {
+			now := time.Now()
+			user.EmailVerifiedAt = &now
+		}
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.AccountToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", user.ID, models.AccountTokenPasswordReset).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		return revokeUserSessions(tx, user.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// issueToken stores a new single-use token and returns it in its signed form
+func (as *AccountService) issueToken(userID, purpose string, ttl time.Duration) (string, error) {
+	secret, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	record := models.AccountToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hashToken(secret),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := as.db.Create(&record).Error; err != nil {
+		return "", err
+	}
+
+	return secret + "." + signAccountToken(as.config.JWTSecret, purpose, secret), nil
+}
+
+func (as *AccountService) link(path, token string) string {
+	return strings.TrimRight(as.config.BaseURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+// consumeToken checks the signature of a token issued for purpose, then marks its
+// stored record used. The signature binds the token to its purpose, so a
+// verification link can never be replayed as a password reset.
+func consumeToken(tx *gorm.DB, key, token, purpose string) (*models.AccountToken, error) {
+	secret, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(signAccountToken(key, purpose, secret))) {
+		return nil, ErrInvalidAccountToken
+	}
+
+	var record models.AccountToken
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ? AND purpose = ?", hashToken(secret), purpose).First(&record).Error; err != nil {
+		return nil, ErrInvalidAccountToken
+	}
+	if record.UsedAt != nil || time.Now().After(record.ExpiresAt) {
+		return nil, ErrInvalidAccountToken
+	}
+
+	now := time.Now()
+	record.UsedAt = &now
+	if err := tx.Save(&record).Error; err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+func signAccountToken(key, purpose, secret string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(purpose + ":" + secret))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,313 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+	"yonatan/labpro/models"
+
+	"gorm.io/gorm"
+)
+
+// auditExportBatch is how many events the CSV export loads at a time
+const auditExportBatch = 500
+
+// AuditActor identifies who makes an admin change and from where. Changes made
+// without an actor, such as seeding the bootstrap admin, are not audited.
+type AuditActor struct {
+	UserID    string
+	Username  string
+	IP        string
+	UserAgent string
+}
+
+type AuditService struct {
+	db *gorm.DB
+}
+
+func NewAuditService(db *gorm.DB) *AuditService {
+	return &AuditService{db: db}
+}
+
+// AuditFilter narrows an audit listing. Empty fields are ignored; Query matches
+// the actor name, action, target ID and IP address.
+type AuditFilter struct {
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+	Query      string
+	From       *time.Time
+	To         *time.Time
+}
+
+// NewAuditFilter builds a filter from raw query values, with dates in the same
+// YYYY-MM-DD form as NewTransactionFilter
+func NewAuditFilter(actorID, action, targetType, targetID, query, from, to string) (AuditFilter, error) {
+	filter := AuditFilter{
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Query:      strings.TrimSpace(query),
+	}
+
+	var err error
+	if filter.From, filter.To, err = parseFilterDays(from, to); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+func (as *AuditService) filtered(filter AuditFilter) *gorm.DB {
+	db := as.db.Model(&models.AuditEvent{})
+
+	if filter.ActorID != "" {
+		db = db.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		db = db.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		db = db.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		db = db.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.Query != "" {
+		searchTerm := "%" + strings.ToLower(filter.Query) + "%"
+		db = db.Where("LOWER(actor_name) LIKE ? OR action LIKE ? OR LOWER(target_id) LIKE ? OR ip LIKE ?",
+			searchTerm, searchTerm, searchTerm, searchTerm)
+	}
+	if filter.From != nil {
+		db = db.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		db = db.Where("created_at < ?", *filter.To)
+	}
+
+	return db
+}
+
+func (as *AuditService) GetEvents(filter AuditFilter, page, limit int) ([]map[string]interface{}, map[string]interface{}, error) {
+	var events []models.AuditEvent
+	var total int64
+
+	db := as.filtered(filter)
+
+	// Count total
+	db.Count(&total)
+
+	// Apply pagination, newest first
+	offset := (page - 1) * limit
+	if err := db.Order("created_at DESC").Offset(offset).Limit(limit).Find(&events).Error; err != nil {
+		return nil, nil, err
+	}
+
+	// Convert to response format
+	result := make([]map[string]interface{}, len(events))
+	for i, event := range events {
+		result[i] = map[string]interface{}{
+			"id":          event.ID,
+			"actor_id":    event.ActorID,
+			"actor_name":  event.ActorName,
+			"action":      event.Action,
+			"target_type": event.TargetType,
+			"target_id":   event.TargetID,
+			"before":      auditJSON(event.Before),
+			"after":       auditJSON(event.After),
+			"ip":          event.IP,
+			"user_agent":  event.UserAgent,
+			"created_at":  event.CreatedAt,
+		}
+	}
+
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+
+	// Calculate pagination values
+	prevPage := page - 1
+	nextPage := page + 1
+
+	if prevPage < 1 {
+		prevPage = 1
+	}
+	if nextPage > totalPages {
+		nextPage = totalPages
+	}
+
+	pagination := map[string]interface{}{
+		"current_page": page,
+		"total_pages":  totalPages,
+		"total_items":  total,
+		"prev_page":    prevPage,
+		"next_page":    nextPage,
+	}
+
+	return result, pagination, nil
+}
+
+// ExportCSV writes every event matching the filter to w as CSV, newest first
+func (as *AuditService) ExportCSV(filter AuditFilter, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"created_at", "actor_id", "actor_name", "action", "target_type", "target_id", "before", "after", "ip", "user_agent"}); err != nil {
+		return err
+	}
+
+	var events []models.AuditEvent
+	err := as.filtered(filter).Order("created_at DESC").FindInBatches(&events, auditExportBatch, func(tx *gorm.DB, batch int) error {
+		for _, event := range events {
+			var actorID, before, after string
+			if event.ActorID != nil {
+				actorID = *event.ActorID
+			}
+			if event.Before != nil {
+				before = *event.Before
+			}
+			if event.After != nil {
+				after = *event.After
+			}
+
+			record := []string{
+				event.CreatedAt.Format(time.RFC3339), actorID, event.ActorName, event.Action,
+				event.TargetType, event.TargetID, before, after, event.IP, event.UserAgent,
+			}
+			for i := range record {
+				record[i] = csvSafe(record[i])
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// recordAudit is the hook every admin mutation calls to land in the audit log. It
+// must run in the same database transaction as the change, so an event exists
+// exactly when the change was committed. before and after are any values that
+// marshal to JSON objects; nil stands for "did not exist".
+func recordAudit(tx *gorm.DB, actor AuditActor, action, targetType, targetID string, before, after interface{}) error {
+	if actor.UserID == "" {
+		return nil
+	}
+
+	beforeJSON, afterJSON, err := auditDiff(before, after)
+	if err != nil {
+		return err
+	}
+
+	event := models.AuditEvent{
+		ActorID:    &actor.UserID,
+		ActorName:  actor.Username,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Before:     beforeJSON,
+		After:      afterJSON,
+		IP:         actor.IP,
+		UserAgent:  actor.UserAgent,
+	}
+	return tx.Create(&event).Error
+}
+
+// auditDiff reduces before and after to the fields whose values differ. When
+// both exist and nothing changed, both sides are left as empty objects.
+func auditDiff(before, after interface{}) (*string, *string, error) {
+	beforeFields, err := auditFields(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	afterFields, err := auditFields(after)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if beforeFields != nil && afterFields != nil {
+		// Every save bumps the timestamp, which says nothing about the change
+		delete(beforeFields, "updated_at")
+		delete(afterFields, "updated_at")
+
+		for key, value := range afterFields {
+			if previous, ok := beforeFields[key]; ok && reflect.DeepEqual(previous, value) {
+				delete(beforeFields, key)
+				delete(afterFields, key)
+			}
+		}
+	}
+
+	beforeJSON, err := encodeAuditFields(beforeFields)
+	if err != nil {
+		return nil, nil, err
+	}
+	afterJSON, err := encodeAuditFields(afterFields)
+	if err != nil {
+		return nil, nil, err
+	}
+	return beforeJSON, afterJSON, nil
+}
+
+func auditFields(value interface{}) (map[string]interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func encodeAuditFields(fields map[string]interface{}) (*string, error) {
+	if fields == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	encoded := string(data)
+	return &encoded, nil
+}
+
+// auditWith adds a field to a snapshot that its JSON leaves out, such as the fact
+// that a password was changed
+func auditWith(value interface{}, key string, extra interface{}) interface{} {
+	fields, err := auditFields(value)
+	if err != nil || fields == nil {
+		return value
+	}
+	fields[key] = extra
+	return fields
+}
+
+// auditJSON embeds a stored diff in a JSON response as an object rather than a string
+func auditJSON(value *string) json.RawMessage {
+	if value == nil {
+		return nil
+	}
+	return json.RawMessage(*value)
+}
+
+// csvSafe stops spreadsheet programs from evaluating user-supplied values such as
+// a user agent as formulas
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -1,15 +1,45 @@
 package services
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"log"
 	"time"
 	"yonatan/labpro/config"
 	"yonatan/labpro/database"
 	"yonatan/labpro/models"
 
 	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+const (
+	accessTokenTTL  = time.Hour
+	refreshTokenTTL = 7 * 24 * time.Hour
+
+	// MFATokenTTL is how long a login may wait between password and second factor
+	MFATokenTTL = 5 * time.Minute
+)
+
+var (
+	ErrInvalidCredentials  = errors.New("invalid credentials")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrIncorrectPassword   = errors.New("current password is incorrect")
+	ErrPasswordUnchanged   = errors.New("new password must differ from the current password")
+	ErrMFARequired         = errors.New("two-factor authentication code required")
+	ErrInvalidMFAToken     = errors.New("invalid or expired two-factor login, please sign in again")
+)
+
+// TokenPair is the set of credentials handed to a client after login or refresh
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int64
+}
+
 type AuthService struct {
 	config *config.Config
 }
@@ -51,35 +81,448 @@ func (as *AuthService) Register(firstName, lastName, username, email, password s
 	return &user, nil
 }
 
-func (as *AuthService) Login(identifier, password string) (string, *models.User, error) {
+func (as *AuthService) Login(identifier, password string) (*TokenPair, *models.User, error) {
 	var user models.User
 
 	// Find user by username or email
 	if err := database.DB.Where("username = ? OR email = ?", identifier, identifier).First(&user).Error; err != nil {
-		return "", nil, errors.New("invalid credentials")
+		return nil, nil, ErrInvalidCredentials
 	}
 
 	// Check password
 	if !user.CheckPassword(password) {
-		return "", nil, errors.New("invalid credentials")
+		return nil, nil, ErrInvalidCredentials
 	}
 
-	// Generate JWT token
-	token, err := as.generateToken(user.ID)
+	// The session is only issued once the second factor has been checked
+	if user.TOTPEnabledAt != nil {
+		return nil, &user, ErrMFARequired
+	}
+
+	// Generate access and refresh tokens
+	tokens, _, err := as.issueTokens(database.DB, user.ID)
 	if err != nil {
-		return "", nil, errors.New("failed to generate token")
+		return nil, nil, errors.New("failed to generate token")
 	}
 
-	return token, &user, nil
+	return tokens, &user, nil
 }
 
-func (as *AuthService) generateToken(userID string) (string, error) {
+// IssueMFAToken returns a short-lived token proving that the user passed the
+// password step of a login. It is signed with its own key, so it can never be
+// used as an access token, and it is good for a single login.
+func (as *AuthService) IssueMFAToken(userID string) (string, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+
 	claims := jwt.MapClaims{
 		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 1).Unix(), // 1 hour expiration
+		"purpose": "mfa_pending",
+		"jti":     jti,
+		"exp":     time.Now().Add(MFATokenTTL).Unix(),
+		"iat":     time.Now().Unix(),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(as.mfaKey())
+}
+
+// ParseMFAToken validates a token from IssueMFAToken and returns its user ID.
+// Tokens that already completed a login are rejected.
+func (as *AuthService) ParseMFAToken(tokenString string) (string, error) {
+	pending, err := as.parseMFAToken(tokenString)
+	if err != nil {
+		return "", err
+	}
+
+	var count int64
+	if err := database.DB.Model(&models.RevokedToken{}).Where("jti = ?", pending.JTI).Count(&count).Error; err != nil || count > 0 {
+		return "", ErrInvalidMFAToken
+	}
+
+	return pending.UserID, nil
+}
+
+// CompleteMFALogin issues a token pair for the user of a pending login whose
+// second factor has been checked. The pending token is used up in the same
+// transaction, so it cannot start a second session.
+func (as *AuthService) CompleteMFALogin(tokenString string) (*TokenPair, error) {
+	pending, err := as.parseMFAToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens *TokenPair
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		used := models.RevokedToken{
+			JTI:       pending.JTI,
+			UserID:    pending.UserID,
+			ExpiresAt: pending.ExpiresAt,
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&used)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidMFAToken
+		}
+
+		tokens, _, err = as.issueTokens(tx, pending.UserID)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrInvalidMFAToken) {
+			return nil, err
+		}
+		return nil, errors.New("failed to generate token")
+	}
+
+	return tokens, nil
+}
+
+// CreateSession issues a token pair for a user who completed every login step
+func (as *AuthService) CreateSession(userID string) (*TokenPair, error) {
+	tokens, _, err := as.issueTokens(database.DB, userID)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+	return tokens, nil
+}
+
+// mfaPending is what a token from IssueMFAToken carries
+type mfaPending struct {
+	UserID    string
+	JTI       string
+	ExpiresAt time.Time
+}
+
+func (as *AuthService) parseMFAToken(tokenString string) (*mfaPending, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return as.mfaKey(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidMFAToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != "mfa_pending" {
+		return nil, ErrInvalidMFAToken
+	}
+	userID, ok := claims["user_id"].(string)
+	if !ok || userID == "" {
+		return nil, ErrInvalidMFAToken
+	}
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return nil, ErrInvalidMFAToken
+	}
+	expiresAt, err := claims.GetExpirationTime()
+	if err != nil || expiresAt == nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	return &mfaPending{UserID: userID, JTI: jti, ExpiresAt: expiresAt.Time}, nil
+}
+
+func (as *AuthService) mfaKey() []byte {
+	return []byte(as.config.JWTSecret + ":mfa")
+}
+
+// Refresh exchanges a refresh token for a new token pair. The presented token is
+// rotated out; presenting it again afterwards is treated as theft and ends every
+// session of its owner.
+func (as *AuthService) Refresh(refreshToken string) (*TokenPair, error) {
+	var tokens *TokenPair
+	reused := false
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var current models.RefreshToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", hashToken(refreshToken)).First(&current).Error; err != nil {
+			return ErrInvalidRefreshToken
+		}
+
+		if current.RevokedAt != nil {
+			reused = true
+			return revokeUserSessions(tx, current.UserID)
+		}
+
+		if time.Now().After(current.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+
+		newTokens, record, err := as.issueTokens(tx, current.UserID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		current.RevokedAt = &now
+		current.ReplacedBy = &record.ID
+		if err := tx.Save(&current).Error; err != nil {
+			return err
+		}
+
+		tokens = newTokens
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return tokens, nil
+}
+
+// Logout revokes the given access token and/or refresh token. Either may be empty.
+func (as *AuthService) Logout(accessToken, refreshToken string) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if refreshToken != "" {
+			var record models.RefreshToken
+			if err := tx.Where("token_hash = ?", hashToken(refreshToken)).First(&record).Error; err == nil {
+				if err := revokeRefreshToken(tx, &record); err != nil {
+					return err
+				}
+			}
+		}
+
+		if accessToken != "" {
+			claims, err := as.ParseAccessToken(accessToken)
+			if err != nil {
+				// Expired or forged tokens cannot be used anyway
+				return nil
+			}
+
+			jti, _ := claims["jti"].(string)
+			userID, _ := claims["user_id"].(string)
+			if jti == "" || userID == "" {
+				return nil
+			}
+
+			if err := revokeAccessToken(tx, userID, jti); err != nil {
+				return err
+			}
+
+			// End the refresh token issued alongside this access token
+			var records []models.RefreshToken
+			tx.Where("access_jti = ? AND revoked_at IS NULL", jti).Find(&records)
+			for i := range records {
+				if err := revokeRefreshToken(tx, &records[i]); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// ChangePassword replaces a user's password after checking the current one and clears
+// any pending forced change. Every existing session is ended and a fresh token pair is
+// returned so the caller stays signed in.
+func (as *AuthService) ChangePassword(userID, currentPassword, newPassword string) (*TokenPair, error) {
+	var tokens *TokenPair
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, "id = ?", userID).Error; err != nil {
+			return errors.New("user not found")
+		}
+
+		if !user.CheckPassword(currentPassword) {
+			return ErrIncorrectPassword
+		}
+		if currentPassword == newPassword {
+			return ErrPasswordUnchanged
+		}
+
+		if err := user.SetPassword(newPassword); err != nil {
+			return errors.New("failed to hash password")
+		}
+		user.MustChangePassword = false
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+
+		if err := revokeUserSessions(tx, user.ID); err != nil {
+			return err
+		}
+
+		newTokens, _, err := as.issueTokens(tx, user.ID)
+		if err != nil {
+			return err
+		}
+		tokens = newTokens
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// PurgeExpiredTokens deletes refresh tokens, revoked access tokens and used
+// pending logins past their expiry. None is accepted once expired, so the rows
+// only take up space.
+func (as *AuthService) PurgeExpiredTokens() (int64, error) {
+	now := time.Now()
+	var purged int64
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("expires_at <= ?", now).Delete(&models.RefreshToken{})
+		if result.Error != nil {
+			return result.Error
+		}
+		purged = result.RowsAffected
+
+		result = tx.Where("expires_at <= ?", now).Delete(&models.RevokedToken{})
+		if result.Error != nil {
+			return result.Error
+		}
+		purged += result.RowsAffected
+		return nil
+	})
+	return purged, err
+}
+
+// RunTokenPurge purges expired tokens now and then every interval. It blocks,
+// so start it in its own goroutine.
+func (as *AuthService) RunTokenPurge(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := as.PurgeExpiredTokens()
+		if err != nil {
+			log.Printf("Failed to purge expired tokens: %v", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d expired tokens", purged)
+		}
+		<-ticker.C
+	}
+}
+
+// ParseAccessToken validates an access token's signature and expiry and returns its claims
+func (as *AuthService) ParseAccessToken(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return []byte(as.config.JWTSecret), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("invalid token claims")
+	}
+
+	return claims, nil
+}
+
+func (as *AuthService) issueTokens(tx *gorm.DB, userID string) (*TokenPair, *models.RefreshToken, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	accessToken, err := as.generateToken(userID, jti)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	record := models.RefreshToken{
+		UserID:    userID,
+		TokenHash: hashToken(refreshToken),
+		AccessJTI: jti,
+		ExpiresAt: time.Now().Add(refreshTokenTTL),
+	}
+	if err := tx.Create(&record).Error; err != nil {
+		return nil, nil, err
+	}
+
+	tokens := &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(accessTokenTTL.Seconds()),
+	}
+
+	return tokens, &record, nil
+}
+
+func (as *AuthService) generateToken(userID, jti string) (string, error) {
+	claims := jwt.MapClaims{
+		"user_id": userID,
+		"jti":     jti,
+		"exp":     time.Now().Add(accessTokenTTL).Unix(),
 		"iat":     time.Now().Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(as.config.JWTSecret))
 }
+
+// revokeUserSessions ends every session of a user: all refresh tokens are revoked
+// and every access token that may still be alive is blacklisted.
+func revokeUserSessions(tx *gorm.DB, userID string) error {
+	now := time.Now()
+
+	// Access tokens live at most accessTokenTTL, so only recently issued ones matter
+	var recent []models.RefreshToken
+	if err := tx.Where("user_id = ? AND created_at > ?", userID, now.Add(-accessTokenTTL)).Find(&recent).Error; err != nil {
+		return err
+	}
+	for _, record := range recent {
+		if err := revokeAccessToken(tx, userID, record.AccessJTI); err != nil {
+			return err
+		}
+	}
+
+	return tx.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error
+}
+
+func revokeRefreshToken(tx *gorm.DB, record *models.RefreshToken) error {
+	if err := revokeAccessToken(tx, record.UserID, record.AccessJTI); err != nil {
+		return err
+	}
+	if record.RevokedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	record.RevokedAt = &now
+	return tx.Save(record).Error
+}
+
+func revokeAccessToken(tx *gorm.DB, userID, jti string) error {
+	revoked := models.RevokedToken{
+		JTI:       jti,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(accessTokenTTL),
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&revoked).Error
+}
+
+func randomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
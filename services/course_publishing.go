@@ -0,0 +1,161 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"time"
+	"yonatan/labpro/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidCourseStatus = errors.New("status must be one of: " + strings.Join(models.CourseStatuses, ", "))
+	ErrInvalidPublishAt    = errors.New("publish_at can only be set on drafts and courses in review")
+	ErrInvalidUnpublishAt  = errors.New("unpublish_at cannot be set on archived courses and must come after publish_at")
+	ErrCoursePublishDenied = errors.New("only editors of every course can publish, archive or schedule courses")
+)
+
+// CourseStatusChange moves a course through its lifecycle. PublishAt and
+// UnpublishAt replace the current schedule; leave them nil to clear it.
+type CourseStatusChange struct {
+	Status      string
+	PublishAt   *time.Time
+	UnpublishAt *time.Time
+}
+
+// CoursePublishing counts the courses flipped by one run of the scheduler
+type CoursePublishing struct {
+	Published int64
+	Archived  int64
+}
+
+func isPrivateCourseStatus(status string) bool {
+	return status == models.CourseStatusDraft || status == models.CourseStatusReview
+}
+
+// SetCourseStatus changes the lifecycle state and schedule of a course. Anyone
+// who may edit the course can move it between draft and review; everything that
+// puts a course in or takes it out of the catalogue, including scheduling,
+// needs courses:write_any.
+func (cs *CourseService) SetCourseStatus(user models.User, courseID string, change CourseStatusChange, actor AuditActor) (*models.Course, error) {
+	if !slices.Contains(models.CourseStatuses, change.Status) {
+		return nil, ErrInvalidCourseStatus
+	}
+	if change.PublishAt != nil && !isPrivateCourseStatus(change.Status) {
+		return nil, ErrInvalidPublishAt
+	}
+	if change.UnpublishAt != nil {
+		if change.Status == models.CourseStatusArchived || (change.PublishAt != nil && !change.UnpublishAt.After(*change.PublishAt)) {
+			return nil, ErrInvalidUnpublishAt
+		}
+	}
+
+	var course models.Course
+	err := cs.db.Transaction(func(tx *gorm.DB) error {
+		var before models.Course
+		if err := tx.First(&before, "id = ?", courseID).Error; err != nil {
+			return err
+		}
+
+		editorial := !isPrivateCourseStatus(before.Status) || !isPrivateCourseStatus(change.Status) ||
+			change.PublishAt != nil || change.UnpublishAt != nil
+		if editorial && !userHasPermission(tx, user, models.PermissionCoursesWriteAny) {
+			return ErrCoursePublishDenied
+		}
+
+		course = before
+		course.Status = change.Status
+		course.PublishAt = change.PublishAt
+		course.UnpublishAt = change.UnpublishAt
+		if err := tx.Model(&course).Select("Status", "PublishAt", "UnpublishAt").Updates(&course).Error; err != nil {
+			return err
+		}
+
+		return recordAudit(tx, actor, models.AuditCourseStatus, "course", courseID, before, course)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cs.clearCourseCache()
+	return &course, nil
+}
+
+// CanViewCourse reports whether the user may open a course outside the catalogue.
+// Published courses are open to everyone, other states only to enrolled users
+// and to those who may edit the course.
+func (cs *CourseService) CanViewCourse(user models.User, courseID string) (bool, error) {
+	var course models.Course
+	if err := cs.db.Select("id", "status").First(&course, "id = ?", courseID).Error; err != nil {
+		return false, err
+	}
+	if course.Status == models.CourseStatusPublished {
+		return true, nil
+	}
+
+	var enrolled int64
+	if err := cs.db.Model(&models.UserCourse{}).Where("user_id = ? AND course_id = ?", user.ID, courseID).Count(&enrolled).Error; err != nil {
+		return false, err
+	}
+	if enrolled > 0 {
+		return true, nil
+	}
+
+	return canEditCourse(cs.db, user, courseID)
+}
+
+// PublishScheduled publishes drafts and courses in review whose publish_at has
+// passed and archives published courses whose unpublish_at has passed. Each
+// timestamp is cleared once it has been applied.
+func (cs *CourseService) PublishScheduled() (CoursePublishing, error) {
+	var flipped CoursePublishing
+	now := time.Now()
+
+	err := cs.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Course{}).
+			Where("status IN ? AND publish_at <= ?", []string{models.CourseStatusDraft, models.CourseStatusReview}, now).
+			Updates(map[string]interface{}{"status": models.CourseStatusPublished, "publish_at": nil})
+		if result.Error != nil {
+			return result.Error
+		}
+		flipped.Published = result.RowsAffected
+
+		result = tx.Model(&models.Course{}).
+			Where("status = ? AND unpublish_at <= ?", models.CourseStatusPublished, now).
+			Updates(map[string]interface{}{"status": models.CourseStatusArchived, "unpublish_at": nil})
+		if result.Error != nil {
+			return result.Error
+		}
+		flipped.Archived = result.RowsAffected
+
+		return nil
+	})
+	if err != nil {
+		return flipped, err
+	}
+
+	if flipped.Published+flipped.Archived > 0 {
+		cs.clearCourseCache()
+	}
+	return flipped, nil
+}
+
+// RunScheduler applies the publishing schedule now and then every interval. It
+// blocks, so start it in its own goroutine.
+func (cs *CourseService) RunScheduler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		flipped, err := cs.PublishScheduled()
+		if err != nil {
+			log.Printf("Failed to apply the course publishing schedule: %v", err)
+		} else if flipped.Published+flipped.Archived > 0 {
+			log.Printf("Published %d and archived %d scheduled courses", flipped.Published, flipped.Archived)
+		}
+		<-ticker.C
+	}
+}
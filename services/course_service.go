@@ -4,29 +4,40 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
 	"mime/multipart"
-	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 	"yonatan/labpro/config"
 	"yonatan/labpro/models"
+	"yonatan/labpro/storage"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrCourseAlreadyPurchased = errors.New("course already purchased")
+	ErrCourseNotPurchased     = errors.New("course not purchased")
+	ErrRefundWindowExpired    = errors.New("refund window has expired")
+	ErrRefundProgressTooHigh  = errors.New("course progress is too high to be refunded")
+	ErrEmailNotVerified       = errors.New("verify your email address before buying courses")
+	ErrCourseNotPurchasable   = errors.New("this course is not available for purchase")
 )
 
 type CourseService struct {
 	db           *gorm.DB
 	config       *config.Config
 	redisService *RedisService
+	media        *MediaService
 }
 
-func NewCourseService(db *gorm.DB, cfg *config.Config, redisService *RedisService) *CourseService {
+func NewCourseService(db *gorm.DB, cfg *config.Config, redisService *RedisService, fileStorage storage.Backend) *CourseService {
 	return &CourseService{
 		db:           db,
 		config:       cfg,
 		redisService: redisService,
+		media:        NewMediaService(db, cfg, fileStorage),
 	}
 }
 
@@ -39,12 +50,26 @@ func (cs *CourseService) clearCourseCache() {
 	}
 }
 
+// clearUserCourseCache invalidates cached course listings of a single user, whose
+// purchase flags change on buy and refund
+func (cs *CourseService) clearUserCourseCache(userID string) {
+	if cs.redisService != nil {
+		cs.redisService.DeletePattern(context.Background(), "courses:*:"+userID)
+	}
+}
+
 // CalculateCourseProgress calculates the progress percentage for a user in a specific course
 func (cs *CourseService) CalculateCourseProgress(userID, courseID string) (float64, int64, int64) {
+	return courseProgress(cs.db, userID, courseID)
+}
+
+// courseProgress is CalculateCourseProgress on the given connection, so a
+// transaction can read progress it has locked
+func courseProgress(db *gorm.DB, userID, courseID string) (float64, int64, int64) {
 	var totalModules, completedModules int64
 
-	cs.db.Model(&models.Module{}).Where("course_id = ?", courseID).Count(&totalModules)
-	cs.db.Model(&models.UserModuleProgress{}).
+	db.Model(&models.Module{}).Where("course_id = ?", courseID).Count(&totalModules)
+	db.Model(&models.UserModuleProgress{}).
 		Joins("JOIN modules ON user_module_progresses.module_id = modules.id").
 		Where("user_module_progresses.user_id = ? AND modules.course_id = ? AND user_module_progresses.is_completed = ?",
 			userID, courseID, true).Count(&completedModules)
@@ -57,8 +82,23 @@ func (cs *CourseService) CalculateCourseProgress(userID, courseID string) (float
 	return percentage, totalModules, completedModules
 }
 
-func (cs *CourseService) CreateCourse(course *models.Course) (*models.Course, error) {
-	if err := cs.db.Create(course).Error; err != nil {
+func (cs *CourseService) CreateCourse(course *models.Course, actor AuditActor) (*models.Course, error) {
+	// New courses stay out of the catalogue until they are published
+	if course.Status == "" {
+		course.Status = models.CourseStatusDraft
+	}
+
+	err := cs.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(course).Error; err != nil {
+			return err
+		}
+		if err := recordRevision(tx, actor, models.RevisionTargetCourse, course.ID, nil, courseRevisionContent(*course), nil); err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, models.AuditCourseCreate, "course", course.ID, nil, course)
+	})
+	if err != nil {
+		cs.media.Release(course.Thumbnail)
 		return nil, err
 	}
 	// Clear course cache after creating new course
@@ -66,6 +106,8 @@ func (cs *CourseService) CreateCourse(course *models.Course) (*models.Course, er
 	return course, nil
 }
 
+// GetCourses lists the course catalogue: published courses only, with purchase
+// flags for userID. Results are cached for five minutes.
 func (cs *CourseService) GetCourses(query string, page, limit int, userID interface{}) ([]map[string]interface{}, map[string]interface{}, error) {
 	// Create cache key based on parameters
 	userIDStr := ""
@@ -88,10 +130,42 @@ func (cs *CourseService) GetCourses(query string, page, limit int, userID interf
 		}
 	}
 
-	var courses []models.Course
-	var total int64
+	db := cs.db.Model(&models.Course{}).Where("status = ?", models.CourseStatusPublished)
+	result, pagination, err := cs.listCourses(db, query, page, limit, userID)
+	if err != nil {
+		return nil, nil, err
+	}
 
+	// Cache the result for 5 minutes
+	if cs.redisService != nil {
+		ctx := context.Background()
+		cacheData := struct {
+			Courses    []map[string]interface{} `json:"courses"`
+			Pagination map[string]interface{}   `json:"pagination"`
+		}{
+			Courses:    result,
+			Pagination: pagination,
+		}
+		cs.redisService.Set(ctx, cacheKey, cacheData, 5*time.Minute)
+	}
+
+	return result, pagination, nil
+}
+
+// GetManagedCourses lists courses in every state for the people managing them,
+// optionally narrowed to one status. It bypasses the catalogue cache so status
+// changes show up at once.
+func (cs *CourseService) GetManagedCourses(query, status string, page, limit int) ([]map[string]interface{}, map[string]interface{}, error) {
 	db := cs.db.Model(&models.Course{})
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	return cs.listCourses(db.Order("created_at DESC"), query, page, limit, nil)
+}
+
+func (cs *CourseService) listCourses(db *gorm.DB, query string, page, limit int, userID interface{}) ([]map[string]interface{}, map[string]interface{}, error) {
+	var courses []models.Course
+	var total int64
 
 	// Apply search filter
 	if query != "" {
@@ -105,10 +179,16 @@ func (cs *CourseService) GetCourses(query string, page, limit int, userID interf
 
 	// Apply pagination
 	offset := (page - 1) * limit
-	if err := db.Offset(offset).Limit(limit).Preload("Modules").Find(&courses).Error; err != nil {
+	if err := db.Offset(offset).Limit(limit).Preload("Modules").Preload("Instructor").Find(&courses).Error; err != nil {
 		return nil, nil, err
 	}
 
+	thumbnailURLs := make([]string, len(courses))
+	for i, course := range courses {
+		thumbnailURLs[i] = course.Thumbnail
+	}
+	thumbnails := courseThumbnails(cs.db, thumbnailURLs...)
+
 	// Convert to response format
 	result := make([]map[string]interface{}, len(courses))
 	for i, course := range courses {
@@ -123,12 +203,16 @@ func (cs *CourseService) GetCourses(query string, page, limit int, userID interf
 		result[i] = map[string]interface{}{
 			"id":              course.ID,
 			"title":           course.Title,
-			"instructor":      course.Instructor,
+			"instructor":      course.InstructorName(),
+			"instructor_id":   course.InstructorID,
 			"description":     course.Description,
 			"topics":          course.Topics,
 			"price":           course.Price,
-			"thumbnail_image": course.Thumbnail,
+			"thumbnail_image": thumbnails[course.Thumbnail],
 			"total_modules":   len(course.Modules),
+			"status":          course.Status,
+			"publish_at":      course.PublishAt,
+			"unpublish_at":    course.UnpublishAt,
 			"created_at":      course.CreatedAt,
 			"updated_at":      course.UpdatedAt,
 			"is_purchased":    isPurchased,
@@ -142,25 +226,12 @@ func (cs *CourseService) GetCourses(query string, page, limit int, userID interf
 		"total_items":  total,
 	}
 
-	// Cache the result for 5 minutes
-	if cs.redisService != nil {
-		ctx := context.Background()
-		cacheData := struct {
-			Courses    []map[string]interface{} `json:"courses"`
-			Pagination map[string]interface{}   `json:"pagination"`
-		}{
-			Courses:    result,
-			Pagination: pagination,
-		}
-		cs.redisService.Set(ctx, cacheKey, cacheData, 5*time.Minute)
-	}
-
 	return result, pagination, nil
 }
 
 func (cs *CourseService) GetCourseByID(id string, userID interface{}) (map[string]interface{}, error) {
 	var course models.Course
-	if err := cs.db.Preload("Modules").First(&course, "id = ?", id).Error; err != nil {
+	if err := cs.db.Preload("Modules").Preload("Instructor").First(&course, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
 
@@ -188,13 +259,17 @@ func (cs *CourseService) GetCourseByID(id string, userID interface{}) (map[strin
 		"id":                  course.ID,
 		"title":               course.Title,
 		"description":         course.Description,
-		"instructor":          course.Instructor,
+		"instructor":          course.InstructorName(),
+		"instructor_id":       course.InstructorID,
 		"topics":              course.Topics,
 		"price":               course.Price,
-		"thumbnail_image":     course.Thumbnail,
+		"thumbnail_image":     courseThumbnails(cs.db, course.Thumbnail)[course.Thumbnail],
 		"total_modules":       totalModules,
 		"completed_modules":   completedModules,
 		"progress_percentage": progressPercentage,
+		"status":              course.Status,
+		"publish_at":          course.PublishAt,
+		"unpublish_at":        course.UnpublishAt,
 		"created_at":          course.CreatedAt,
 		"updated_at":          course.UpdatedAt,
 		"is_purchased":        isPurchased,
@@ -203,18 +278,66 @@ func (cs *CourseService) GetCourseByID(id string, userID interface{}) (map[strin
 	return result, nil
 }
 
-func (cs *CourseService) UpdateCourse(course *models.Course) (*models.Course, error) {
-	if err := cs.db.Save(course).Error; err != nil {
+func (cs *CourseService) UpdateCourse(course *models.Course, actor AuditActor) (*models.Course, error) {
+	var before models.Course
+	err := cs.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&before, "id = ?", course.ID).Error; err != nil {
+			return err
+		}
+		course.CreatedAt = before.CreatedAt
+
+		// Ownership and the lifecycle only change through dedicated operations, never
+		// through an edit form
+		if err := tx.Omit("OwnerID", "Status", "PublishAt", "UnpublishAt").Save(course).Error; err != nil {
+			return err
+		}
+		course.OwnerID = before.OwnerID
+		course.Status = before.Status
+		course.PublishAt = before.PublishAt
+		course.UnpublishAt = before.UnpublishAt
+
+		if err := recordRevision(tx, actor, models.RevisionTargetCourse, course.ID, courseRevisionContent(before), courseRevisionContent(*course), nil); err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, models.AuditCourseUpdate, "course", course.ID, before, course)
+	})
+	if err != nil {
+		cs.media.Release(course.Thumbnail)
 		return nil, err
 	}
+	// Thumbnails are not part of revisions, so a replaced one goes right away
+	if before.Thumbnail != course.Thumbnail {
+		cs.media.Release(before.Thumbnail)
+	}
 	// Clear course cache after updating course
 	cs.clearCourseCache()
 	return course, nil
 }
 
-func (cs *CourseService) DeleteCourse(id string) error {
+// CanEditCourse reports whether the user may change or delete the course
+func (cs *CourseService) CanEditCourse(user models.User, courseID string) (bool, error) {
+	return canEditCourse(cs.db, user, courseID)
+}
+
+// ResolveInstructor returns the instructor profile ID for a course authored by user.
+// See resolveInstructor for how instructorID and name are interpreted.
+func (cs *CourseService) ResolveInstructor(user models.User, instructorID, name string) (*string, error) {
+	return resolveInstructor(cs.db, user, instructorID, name)
+}
+
+// DeleteCourse moves a course and its modules to the trash. Their files stay
+// until the trash is purged.
+func (cs *CourseService) DeleteCourse(id string, actor AuditActor) error {
 	// Use transaction to ensure data consistency
 	err := cs.db.Transaction(func(tx *gorm.DB) error {
+		// Everything goes to the trash with one timestamp, so restoring the course
+		// brings back exactly what was deleted with it
+		tx = trashSession(tx)
+
+		var course models.Course
+		if err := tx.First(&course, "id = ?", id).Error; err != nil {
+			return err
+		}
 		// First delete all user module progress records for modules in this course
 		if err := tx.Where("module_id IN (SELECT id FROM modules WHERE course_id = ?)", id).Delete(&models.UserModuleProgress{}).Error; err != nil {
 			return err
@@ -235,7 +358,7 @@ func (cs *CourseService) DeleteCourse(id string) error {
 			return err
 		}
 
-		return nil
+		return recordAudit(tx, actor, models.AuditCourseDelete, "course", id, course, nil)
 	})
 
 	if err == nil {
@@ -246,58 +369,220 @@ func (cs *CourseService) DeleteCourse(id string) error {
 	return err
 }
 
-func (cs *CourseService) BuyCourse(courseID, userID string) (map[string]interface{}, error) {
-	// Check if course exists
-	var course models.Course
-	if err := cs.db.First(&course, "id = ?", courseID).Error; err != nil {
-		return nil, errors.New("course not found")
-	}
+// BuyCourse enrolls a user in a course and charges their balance. The user row is
+// locked for the duration of the purchase so concurrent requests by the same user
+// run one after another. When idempotencyKey is set, the result is stored and
+// returned again for retries carrying the same key.
+func (cs *CourseService) BuyCourse(courseID, userID, idempotencyKey string) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	scope := "course_purchase:" + courseID
 
-	// Check if user already purchased this course
-	var existingUserCourse models.UserCourse
-	if err := cs.db.Where("user_id = ? AND course_id = ?", userID, courseID).First(&existingUserCourse).Error; err == nil {
-		return nil, errors.New("course already purchased")
-	}
+	err := cs.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the buyer so balance checks and enrollment checks cannot interleave
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, "id = ?", userID).Error; err != nil {
+			return errors.New("user not found")
+		}
 
-	// Get user and check balance
-	var user models.User
-	if err := cs.db.First(&user, "id = ?", userID).Error; err != nil {
-		return nil, errors.New("user not found")
-	}
+		if cs.config.AllowUnverifiedPurchases == "false" && user.EmailVerifiedAt == nil {
+			return ErrEmailNotVerified
+		}
 
-	if user.Balance < course.Price {
-		return nil, errors.New("insufficient balance")
-	}
+		if idempotencyKey != "" {
+			stored, err := findIdempotentResult(tx, userID, idempotencyKey, scope)
+			if err != nil {
+				return err
+			}
+			if stored != nil {
+				result = stored
+				return nil
+			}
+		}
+
+		// Check if course exists
+		var course models.Course
+		if err := tx.First(&course, "id = ?", courseID).Error; err != nil {
+			return errors.New("course not found")
+		}
+		if course.Status != models.CourseStatusPublished {
+			return ErrCourseNotPurchasable
+		}
+
+		// Check if user already purchased this course
+		var purchased int64
+		if err := tx.Model(&models.UserCourse{}).Where("user_id = ? AND course_id = ?", userID, courseID).Count(&purchased).Error; err != nil {
+			return err
+		}
+		if purchased > 0 {
+			return ErrCourseAlreadyPurchased
+		}
+
+		if user.Balance < course.Price {
+			return errors.New("insufficient balance")
+		}
+
+		// Deduct balance
+		user.Balance -= course.Price
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+
+		// Create user course relationship; the unique index is the last line of defence
+		userCourse := models.UserCourse{
+			UserID:   userID,
+			CourseID: courseID,
+		}
+		if err := tx.Create(&userCourse).Error; err != nil {
+			if isUniqueViolation(err) {
+				return ErrCourseAlreadyPurchased
+			}
+			return err
+		}
+
+		// Record the purchase in the ledger
+		transaction, err := recordTransaction(tx, &user, models.TransactionTypePurchase, -course.Price, &courseID, &userID, "Purchased "+course.Title)
+		if err != nil {
+			return err
+		}
 
-	// Start transaction
-	tx := cs.db.Begin()
+		result = map[string]interface{}{
+			"course_id":      courseID,
+			"user_balance":   user.Balance,
+			"transaction_id": transaction.ID,
+		}
 
-	// Deduct balance
-	user.Balance -= course.Price
-	if err := tx.Save(&user).Error; err != nil {
-		tx.Rollback()
+		if idempotencyKey != "" {
+			return saveIdempotentResult(tx, userID, idempotencyKey, scope, result)
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	// Create user course relationship
-	userCourse := models.UserCourse{
-		UserID:   userID,
-		CourseID: courseID,
-	}
-	if err := tx.Create(&userCourse).Error; err != nil {
-		tx.Rollback()
+	cs.clearUserCourseCache(userID)
+	return result, nil
+}
+
+// RefundCourse revokes a user's enrollment and credits back what they paid. Users may
+// only refund within the configured window and below the progress threshold; admins
+// pass force to skip both checks, which is recorded in the audit log. Module progress
+// is moved to the archive.
+func (cs *CourseService) RefundCourse(courseID, userID string, actor AuditActor, force bool) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	err := cs.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the user the same way purchases do
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, "id = ?", userID).Error; err != nil {
+			return errors.New("user not found")
+		}
+
+		// Module completions lock the enrollment too, so the progress read
+		// below cannot change before the refund commits
+		var userCourse models.UserCourse
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND course_id = ?", userID, courseID).First(&userCourse).Error; err != nil {
+			return ErrCourseNotPurchased
+		}
+
+		if !force {
+			if time.Since(userCourse.PurchasedAt) > cs.refundWindow() {
+				return ErrRefundWindowExpired
+			}
+			if progress, _, _ := courseProgress(tx, userID, courseID); progress >= cs.refundMaxProgress() {
+				return ErrRefundProgressTooHigh
+			}
+		}
+
+		var course models.Course
+		if err := tx.Unscoped().First(&course, "id = ?", courseID).Error; err != nil {
+			return errors.New("course not found")
+		}
+
+		// Refund what was actually paid, which may differ from the current price
+		amount := course.Price
+		var purchase models.Transaction
+		if err := tx.Where("user_id = ? AND course_id = ? AND type = ?", userID, courseID, models.TransactionTypePurchase).
+			Order("created_at DESC").First(&purchase).Error; err == nil {
+			amount = -purchase.Amount
+		}
+
+		// Archive module progress before removing it, including progress in the
+		// trash, since all of it is deleted below
+		if err := tx.Exec(`INSERT INTO archived_module_progresses (user_id, module_id, course_id, is_completed, completed_at, reason, archived_at)
+			SELECT user_module_progresses.user_id, user_module_progresses.module_id, modules.course_id,
+				user_module_progresses.is_completed, user_module_progresses.completed_at, ?, ?
+			FROM user_module_progresses JOIN modules ON user_module_progresses.module_id = modules.id
+			WHERE user_module_progresses.user_id = ? AND modules.course_id = ?`,
+			"refund", time.Now(), userID, courseID).Error; err != nil {
+			return err
+		}
+		// A refund is final, so the enrollment skips the trash
+		if err := tx.Unscoped().Where("user_id = ? AND module_id IN (SELECT id FROM modules WHERE course_id = ?)", userID, courseID).
+			Delete(&models.UserModuleProgress{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Delete(&userCourse).Error; err != nil {
+			return err
+		}
+
+		user.Balance += amount
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+
+		description := "Refunded " + course.Title
+		if force {
+			description = "Refunded " + course.Title + " by admin"
+		}
+		var actorID *string
+		if actor.UserID != "" {
+			actorID = &actor.UserID
+		}
+		transaction, err := recordTransaction(tx, &user, models.TransactionTypeRefund, amount, &courseID, actorID, description)
+		if err != nil {
+			return err
+		}
+
+		if force {
+			if err := recordAudit(tx, actor, models.AuditCourseRefund, "user", userID,
+				map[string]interface{}{"enrolled_course_id": courseID, "balance": user.Balance - amount},
+				map[string]interface{}{"enrolled_course_id": nil, "balance": user.Balance}); err != nil {
+				return err
+			}
+		}
+
+		result = map[string]interface{}{
+			"course_id":      courseID,
+			"refunded":       amount,
+			"user_balance":   user.Balance,
+			"transaction_id": transaction.ID,
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	tx.Commit()
+	cs.clearUserCourseCache(userID)
+	return result, nil
+}
 
-	result := map[string]interface{}{
-		"course_id":      courseID,
-		"user_balance":   user.Balance,
-		"transaction_id": userCourse.ID,
+func (cs *CourseService) refundWindow() time.Duration {
+	days, err := strconv.Atoi(cs.config.RefundWindowDays)
+	if err != nil || days < 0 {
+		days = 7
 	}
+	return time.Duration(days) * 24 * time.Hour
+}
 
-	return result, nil
+func (cs *CourseService) refundMaxProgress() float64 {
+	percent, err := strconv.ParseFloat(cs.config.RefundMaxProgress, 64)
+	if err != nil || percent < 0 {
+		percent = 30
+	}
+	return percent
 }
 
 func (cs *CourseService) GetMyCourses(userID, query string, page, limit int) ([]map[string]interface{}, map[string]interface{}, error) {
@@ -310,7 +595,8 @@ func (cs *CourseService) GetMyCourses(userID, query string, page, limit int) ([]
 	if query != "" {
 		searchTerm := "%" + strings.ToLower(query) + "%"
 		db = db.Joins("JOIN courses ON user_courses.course_id = courses.id").
-			Where("LOWER(courses.title) LIKE ? OR LOWER(courses.instructor) LIKE ? OR EXISTS (SELECT 1 FROM unnest(courses.topics) AS topic WHERE LOWER(topic) LIKE ?)",
+			Joins("LEFT JOIN instructors ON courses.instructor_id = instructors.id").
+			Where("LOWER(courses.title) LIKE ? OR LOWER(instructors.name) LIKE ? OR EXISTS (SELECT 1 FROM unnest(courses.topics) AS topic WHERE LOWER(topic) LIKE ?)",
 				searchTerm, searchTerm, searchTerm)
 	}
 
@@ -319,10 +605,16 @@ func (cs *CourseService) GetMyCourses(userID, query string, page, limit int) ([]
 
 	// Apply pagination
 	offset := (page - 1) * limit
-	if err := db.Offset(offset).Limit(limit).Preload("Course").Find(&userCourses).Error; err != nil {
+	if err := db.Offset(offset).Limit(limit).Preload("Course.Instructor").Find(&userCourses).Error; err != nil {
 		return nil, nil, err
 	}
 
+	thumbnailURLs := make([]string, len(userCourses))
+	for i, userCourse := range userCourses {
+		thumbnailURLs[i] = userCourse.Course.Thumbnail
+	}
+	thumbnails := courseThumbnails(cs.db, thumbnailURLs...)
+
 	// Convert to response format
 	result := make([]map[string]interface{}, len(userCourses))
 	for i, userCourse := range userCourses {
@@ -332,11 +624,12 @@ func (cs *CourseService) GetMyCourses(userID, query string, page, limit int) ([]
 		result[i] = map[string]interface{}{
 			"id":                  userCourse.Course.ID,
 			"title":               userCourse.Course.Title,
-			"instructor":          userCourse.Course.Instructor,
+			"instructor":          userCourse.Course.InstructorName(),
+			"instructor_id":       userCourse.Course.InstructorID,
 			"description":         userCourse.Course.Description,
 			"topics":              userCourse.Course.Topics,
 			"price":               userCourse.Course.Price,
-			"thumbnail_image":     userCourse.Course.Thumbnail,
+			"thumbnail_image":     thumbnails[userCourse.Course.Thumbnail],
 			"progress_percentage": progressPercentage,
 			"total_modules":       totalModules,
 			"completed_modules":   completedModules,
@@ -354,36 +647,8 @@ func (cs *CourseService) GetMyCourses(userID, query string, page, limit int) ([]
 	return result, pagination, nil
 }
 
+// SaveThumbnail stores an uploaded course image in every thumbnail size and
+// returns the URL to keep in the course
 func (cs *CourseService) SaveThumbnail(file *multipart.FileHeader) (string, error) {
-	// Create uploads directory if it doesn't exist
-	uploadDir := "./uploads/thumbnails"
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		return "", err
-	}
-
-	// Generate unique filename
-	filename := fmt.Sprintf("%d_%s", time.Now().Unix(), file.Filename)
-	filepath := filepath.Join(uploadDir, filename)
-
-	// Open uploaded file
-	src, err := file.Open()
-	if err != nil {
-		return "", err
-	}
-	defer src.Close()
-
-	// Create destination file
-	dst, err := os.Create(filepath)
-	if err != nil {
-		return "", err
-	}
-	defer dst.Close()
-
-	// Copy file content
-	if _, err = io.Copy(dst, src); err != nil {
-		return "", err
-	}
-
-	// Return complete URL including base URL from config
-	return fmt.Sprintf("%s/uploads/thumbnails/%s", cs.config.BaseURL, filename), nil
+	return cs.media.StoreThumbnail(file)
 }
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"yonatan/labpro/models"
+	"yonatan/labpro/thumbnail"
+
+	"gorm.io/gorm"
+)
+
+// courseThumbnails describes course thumbnails for responses, keyed by the URL
+// courses keep. Each is an object with that URL as src, srcset and
+// webp_srcset listing the sizes it was rendered in, and social for link
+// previews. Thumbnails stored before they were resized only have the one file,
+// which serves as both src and social. Courses without a thumbnail get nil.
+//
+// The objects are plain maps so they look the same after a trip through the
+// course cache.
+func courseThumbnails(db *gorm.DB, urls ...string) map[string]interface{} {
+	thumbnails := make(map[string]interface{}, len(urls))
+	var wanted []string
+	for _, url := range urls {
+		if url != "" {
+			wanted = append(wanted, url)
+		}
+	}
+	if len(wanted) == 0 {
+		return thumbnails
+	}
+
+	var variants []models.MediaAsset
+	if err := db.Where("variant_of IN ?", wanted).Find(&variants).Error; err != nil {
+		// Without the variants the original URL still shows an image
+		log.Printf("CourseService: Failed to look up thumbnail sizes: %v", err)
+	}
+	byURL := make(map[string][]models.MediaAsset, len(wanted))
+	for _, variant := range variants {
+		byURL[variant.VariantOf] = append(byURL[variant.VariantOf], variant)
+	}
+
+	for _, url := range wanted {
+		thumbnails[url] = describeThumbnail(url, byURL[url])
+	}
+	return thumbnails
+}
+
+// describeThumbnail builds the response object of one thumbnail from the files
+// of its set, whose keys end in <format>/<size>.<ext>
+func describeThumbnail(url string, variants []models.MediaAsset) map[string]interface{} {
+	social := url
+	srcsets := map[string][]string{}
+	for _, size := range thumbnail.Sizes {
+		for _, variant := range variants {
+			name := strings.TrimSuffix(path.Base(variant.Key), path.Ext(variant.Key))
+			if name != size.Name {
+				continue
+			}
+			format := path.Base(path.Dir(variant.Key))
+			if size == thumbnail.Social {
+				if format == thumbnail.FormatJPEG {
+					social = variant.URL
+				}
+				continue
+			}
+			srcsets[format] = append(srcsets[format], fmt.Sprintf("%s %dw", variant.URL, size.Width))
+		}
+	}
+
+	return map[string]interface{}{
+		"src":         url,
+		"srcset":      strings.Join(srcsets[thumbnail.FormatJPEG], ", "),
+		"webp_srcset": strings.Join(srcsets[thumbnail.FormatWebP], ", "),
+		"social":      social,
+	}
+}
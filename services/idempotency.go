@@ -0,0 +1,66 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+	"yonatan/labpro/models"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+const idempotencyKeyTTL = 24 * time.Hour
+
+var ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different request")
+
+// findIdempotentResult returns the stored result for key, or nil when the key has
+// not been used yet. Callers must hold a lock that serializes requests of userID.
+func findIdempotentResult(tx *gorm.DB, userID, key, scope string) (map[string]interface{}, error) {
+	var record models.IdempotencyKey
+	err := tx.Where("user_id = ? AND key = ?", userID, key).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Expired keys may be reused for a new request
+	if time.Now().After(record.ExpiresAt) {
+		return nil, tx.Delete(&record).Error
+	}
+
+	if record.Scope != scope {
+		return nil, ErrIdempotencyKeyReused
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal([]byte(record.Response), &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func saveIdempotentResult(tx *gorm.DB, userID, key, scope string, result map[string]interface{}) error {
+	response, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	record := models.IdempotencyKey{
+		UserID:    userID,
+		Key:       key,
+		Scope:     scope,
+		Response:  string(response),
+		ExpiresAt: time.Now().Add(idempotencyKeyTTL),
+	}
+	return tx.Create(&record).Error
+}
+
+// isUniqueViolation reports whether err is a Postgres unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"yonatan/labpro/models"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInstructorNotFound  = errors.New("instructor not found")
+	ErrInstructorRequired  = errors.New("an instructor is required")
+	ErrInstructorUserTaken = errors.New("user already has an instructor profile")
+)
+
+type InstructorService struct {
+	db           *gorm.DB
+	redisService *RedisService
+}
+
+func NewInstructorService(db *gorm.DB, redisService *RedisService) *InstructorService {
+	return &InstructorService{
+		db:           db,
+		redisService: redisService,
+	}
+}
+
+// InstructorCourseStats is one row of the instructor dashboard
+type InstructorCourseStats struct {
+	CourseID    string  `json:"course_id"`
+	Title       string  `json:"title"`
+	Price       float64 `json:"price"`
+	Enrollments int64   `json:"enrollments"`
+	Revenue     float64 `json:"revenue"`
+}
+
+type InstructorDashboard struct {
+	Instructor       *models.Instructor      `json:"instructor"`
+	Courses          []InstructorCourseStats `json:"courses"`
+	TotalEnrollments int64                   `json:"total_enrollments"`
+	TotalRevenue     float64                 `json:"total_revenue"`
+}
+
+// clearCourseCache drops cached course listings, which embed instructor names
+func (is *InstructorService) clearCourseCache() {
+	if is.redisService != nil {
+		is.redisService.DeletePattern(context.Background(), "courses:*")
+	}
+}
+
+func (is *InstructorService) GetInstructors() ([]models.Instructor, error) {
+	var instructors []models.Instructor
+	if err := is.db.Order("name").Find(&instructors).Error; err != nil {
+		return nil, err
+	}
+	return instructors, nil
+}
+
+// GetInstructor returns an instructor's public profile with the courses they teach
+func (is *InstructorService) GetInstructor(id string) (map[string]interface{}, error) {
+	var instructor models.Instructor
+	if err := is.db.Preload("Courses", func(db *gorm.DB) *gorm.DB {
+		return db.Order("courses.title")
+	}).Preload("Courses.Modules").First(&instructor, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInstructorNotFound
+		}
+		return nil, err
+	}
+
+	thumbnailURLs := make([]string, len(instructor.Courses))
+	for i, course := range instructor.Courses {
+		thumbnailURLs[i] = course.Thumbnail
+	}
+	thumbnails := courseThumbnails(is.db, thumbnailURLs...)
+
+	courses := make([]map[string]interface{}, len(instructor.Courses))
+	for i, course := range instructor.Courses {
+		courses[i] = map[string]interface{}{
+			"id":              course.ID,
+			"title":           course.Title,
+			"description":     course.Description,
+			"topics":          course.Topics,
+			"price":           course.Price,
+			"thumbnail_image": thumbnails[course.Thumbnail],
+			"total_modules":   len(course.Modules),
+		}
+	}
+
+	return map[string]interface{}{
+		"id":         instructor.ID,
+		"user_id":    instructor.UserID,
+		"name":       instructor.Name,
+		"bio":        instructor.Bio,
+		"avatar_url": instructor.AvatarURL,
+		"links":      instructor.Links,
+		"courses":    courses,
+	}, nil
+}
+
+func (is *InstructorService) GetInstructorByUserID(userID string) (*models.Instructor, error) {
+	var instructor models.Instructor
+	if err := is.db.Where("user_id = ?", userID).First(&instructor).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInstructorNotFound
+		}
+		return nil, err
+	}
+	return &instructor, nil
+}
+
+// SaveOwnProfile creates or updates the instructor profile linked to the user
+func (is *InstructorService) SaveOwnProfile(user models.User, name, bio, avatarURL string, links []string) (*models.Instructor, error) {
+	instructor, err := is.GetInstructorByUserID(user.ID)
+	if errors.Is(err, ErrInstructorNotFound) {
+		instructor = &models.Instructor{UserID: &user.ID}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(name) == "" {
+		name = strings.TrimSpace(user.FirstName + " " + user.LastName)
+	}
+	instructor.Name = strings.TrimSpace(name)
+	instructor.Bio = bio
+	instructor.AvatarURL = avatarURL
+	instructor.Links = pq.StringArray(links)
+
+	if err := is.db.Save(instructor).Error; err != nil {
+		return nil, err
+	}
+	is.clearCourseCache()
+	return instructor, nil
+}
+
+// UpdateInstructor edits any profile. A non-nil userID links the profile to that
+// account, which is how profiles migrated from free-text names get an owner.
+func (is *InstructorService) UpdateInstructor(id, name, bio, avatarURL string, links []string, userID *string, actor AuditActor) (*models.Instructor, error) {
+	var instructor models.Instructor
+	if err := is.db.First(&instructor, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInstructorNotFound
+		}
+		return nil, err
+	}
+	before := instructor
+
+	if userID != nil && *userID != "" {
+		var user models.User
+		if err := is.db.First(&user, "id = ?", *userID).Error; err != nil {
+			return nil, ErrUserNotFound
+		}
+
+		var taken int64
+		is.db.Model(&models.Instructor{}).Where("user_id = ? AND id <> ?", *userID, id).Count(&taken)
+		if taken > 0 {
+			return nil, ErrInstructorUserTaken
+		}
+		instructor.UserID = userID
+	}
+
+	if strings.TrimSpace(name) != "" {
+		instructor.Name = strings.TrimSpace(name)
+	}
+	instructor.Bio = bio
+	instructor.AvatarURL = avatarURL
+	instructor.Links = pq.StringArray(links)
+
+	err := is.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&instructor).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, models.AuditInstructorUpdate, "instructor", instructor.ID, before, instructor)
+	})
+	if err != nil {
+		return nil, err
+	}
+	is.clearCourseCache()
+	return &instructor, nil
+}
+
+// GetDashboard summarises enrollments and net revenue of the courses taught by the
+// user's instructor profile. Revenue comes from the ledger, so refunds are deducted.
+func (is *InstructorService) GetDashboard(userID string) (*InstructorDashboard, error) {
+	instructor, err := is.GetInstructorByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	dashboard := &InstructorDashboard{Instructor: instructor}
+	err = is.db.Model(&models.Course{}).
+		Select(`courses.id AS course_id, courses.title, courses.price,
+			(SELECT COUNT(*) FROM user_courses WHERE user_courses.course_id = courses.id
+				AND user_courses.deleted_at IS NULL) AS enrollments,
+			(SELECT COALESCE(-SUM(transactions.amount), 0) FROM transactions
+				WHERE transactions.course_id = courses.id AND transactions.type IN ?) AS revenue`,
+			[]string{models.TransactionTypePurchase, models.TransactionTypeRefund}).
+		Where("courses.instructor_id = ?", instructor.ID).
+		Order("revenue DESC, courses.title").
+		Scan(&dashboard.Courses).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, course := range dashboard.Courses {
+		dashboard.TotalEnrollments += course.Enrollments
+		dashboard.TotalRevenue += course.Revenue
+	}
+
+	return dashboard, nil
+}
+
+// resolveInstructor picks the profile a new or edited course is taught by: an
+// explicit profile ID, else a profile matched by name (created unlinked when no
+// profile has that name yet), else the author's own profile.
+func resolveInstructor(db *gorm.DB, user models.User, instructorID, name string) (*string, error) {
+	if instructorID != "" {
+		var instructor models.Instructor
+		if err := db.First(&instructor, "id = ?", instructorID).Error; err != nil {
+			return nil, ErrInstructorNotFound
+		}
+		return &instructor.ID, nil
+	}
+
+	if name = strings.TrimSpace(name); name != "" {
+		var instructor models.Instructor
+		err := db.Where("LOWER(name) = LOWER(?)", name).Order("created_at").First(&instructor).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			instructor = models.Instructor{Name: name}
+			err = db.Create(&instructor).Error
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &instructor.ID, nil
+	}
+
+	var instructor models.Instructor
+	if err := db.Where("user_id = ?", user.ID).First(&instructor).Error; err != nil {
+		return nil, ErrInstructorRequired
+	}
+	return &instructor.ID, nil
+}
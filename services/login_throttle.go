@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"yonatan/labpro/config"
+	"yonatan/labpro/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// loginFreeAttempts failures are allowed without delay; after that every
+	// further failure doubles the wait, starting at loginBaseBackoff
+	loginFreeAttempts = 2
+	loginBaseBackoff  = time.Second
+)
+
+var ErrTooManyLoginAttempts = errors.New("too many login attempts, try again later")
+
+// LoginThrottle slows down password guessing. Failures are counted per account
+// and per client IP; once the free attempts are used up each failure imposes an
+// exponentially growing wait, and too many failures lock the account or IP.
+type LoginThrottle struct {
+	db            *gorm.DB
+	redisService  *RedisService
+	maxAttempts   int64
+	maxIPAttempts int64
+	lockout       time.Duration
+}
+
+func NewLoginThrottle(db *gorm.DB, cfg *config.Config, redisService *RedisService) *LoginThrottle {
+	// Without Redis the counters live in this process only
+	if redisService == nil {
+		redisService = &RedisService{}
+	}
+
+	maxAttempts, err := strconv.ParseInt(cfg.LoginMaxAttempts, 10, 64)
+	if err != nil || maxAttempts < 1 {
+		maxAttempts = 5
+	}
+	maxIPAttempts, err := strconv.ParseInt(cfg.LoginIPMaxAttempts, 10, 64)
+	if err != nil || maxIPAttempts < 1 {
+		maxIPAttempts = 50
+	}
+	lockoutMinutes, err := strconv.Atoi(cfg.LoginLockoutMinutes)
+	if err != nil || lockoutMinutes < 1 {
+		lockoutMinutes = 15
+	}
+
+	return &LoginThrottle{
+		db:            db,
+		redisService:  redisService,
+		maxAttempts:   maxAttempts,
+		maxIPAttempts: maxIPAttempts,
+		lockout:       time.Duration(lockoutMinutes) * time.Minute,
+	}
+}
+
+// Check returns how long the client has to wait before it may try to log in
+// with identifier again; zero means the attempt may go ahead.
+func (lt *LoginThrottle) Check(identifier, ip string) time.Duration {
+	return lt.check(lt.accountKey(identifier), ip)
+}
+
+// CheckUser is Check for a login step that already knows the user, such as the
+// second factor
+func (lt *LoginThrottle) CheckUser(userID, ip string) time.Duration {
+	return lt.check("user:"+userID, ip)
+}
+
+func (lt *LoginThrottle) check(account, ip string) time.Duration {
+	ctx := context.Background()
+
+	_, accountWait, _ := lt.redisService.GetCounter(ctx, "login:block:account:"+account)
+	_, ipWait, _ := lt.redisService.GetCounter(ctx, "login:block:ip:"+ip)
+
+	return max(accountWait, ipWait, 0)
+}
+
+// RecordFailure counts a failed login and returns the wait it imposes
+func (lt *LoginThrottle) RecordFailure(identifier, ip string) time.Duration {
+	return lt.recordFailure(lt.accountKey(identifier), ip)
+}
+
+// RecordUserFailure counts a failed second factor against the user's account
+func (lt *LoginThrottle) RecordUserFailure(userID, ip string) time.Duration {
+	return lt.recordFailure("user:"+userID, ip)
+}
+
+func (lt *LoginThrottle) recordFailure(account, ip string) time.Duration {
+	ctx := context.Background()
+
+	var accountWait time.Duration
+	failures, err := lt.redisService.IncrementCounter(ctx, "login:failures:account:"+account, lt.lockout)
+	if err == nil {
+		switch {
+		case failures >= lt.maxAttempts:
+			accountWait = lt.lockout
+		case failures > loginFreeAttempts:
+			accountWait = min(loginBaseBackoff<<(failures-loginFreeAttempts-1), lt.lockout)
+		}
+	}
+	if accountWait > 0 {
+		lt.redisService.SetCounter(ctx, "login:block:account:"+account, failures, accountWait)
+	}
+
+	var ipWait time.Duration
+	ipFailures, err := lt.redisService.IncrementCounter(ctx, "login:failures:ip:"+ip, lt.lockout)
+	if err == nil && ipFailures >= lt.maxIPAttempts {
+		ipWait = lt.lockout
+		lt.redisService.SetCounter(ctx, "login:block:ip:"+ip, ipFailures, ipWait)
+	}
+
+	return max(accountWait, ipWait)
+}
+
+// RecordSuccess forgets the failures of the account. IP counters are kept so a
+// single valid account cannot be used to reset an IP that is guessing others.
+func (lt *LoginThrottle) RecordSuccess(identifier string) {
+	lt.clearAccount(lt.accountKey(identifier))
+}
+
+// LockedFor returns how long the user's account is still locked or backed off
+func (lt *LoginThrottle) LockedFor(userID string) time.Duration {
+	_, wait, _ := lt.redisService.GetCounter(context.Background(), "login:block:account:user:"+userID)
+	return max(wait, 0)
+}
+
+// Unlock lifts a lockout of the user's account and resets its failure count
+func (lt *LoginThrottle) Unlock(userID string) error {
+	return lt.clearAccount("user:" + userID)
+}
+
+// UnlockAccount is Unlock done by staff, which is recorded in the audit log
+func (lt *LoginThrottle) UnlockAccount(userID string, actor AuditActor) error {
+	lockedFor := lt.LockedFor(userID)
+	if err := lt.Unlock(userID); err != nil {
+		return err
+	}
+
+	return recordAudit(lt.db, actor, models.AuditUserUnlock, "user", userID,
+		map[string]interface{}{"locked_seconds": int64(lockedFor.Seconds())}, map[string]interface{}{"locked_seconds": 0})
+}
+
+func (lt *LoginThrottle) clearAccount(account string) error {
+	return lt.redisService.DeleteCounters(context.Background(),
+		"login:failures:account:"+account, "login:block:account:"+account)
+}
+
+// RetryAfterSeconds formats a wait for the Retry-After header, rounding up so
+// clients never retry early
+func RetryAfterSeconds(wait time.Duration) string {
+	return strconv.FormatInt(int64((wait+time.Second-1)/time.Second), 10)
+}
+
+// accountKey maps an identifier to the account it names, so guesses spread over
+// the username and the email address of one user are counted together. Unknown
+// identifiers are counted by their own name.
+func (lt *LoginThrottle) accountKey(identifier string) string {
+	var user models.User
+	if err := lt.db.Select("id").Where("username = ? OR email = ?", identifier, identifier).First(&user).Error; err == nil {
+		return "user:" + user.ID
+	}
+	return "name:" + strings.ToLower(strings.TrimSpace(identifier))
+}
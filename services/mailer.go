@@ -0,0 +1,108 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"yonatan/labpro/config"
+)
+
+// Mailer delivers plain-text email
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// NewMailer returns the mailer selected by cfg.MailDriver
+func NewMailer(cfg *config.Config) Mailer {
+	if cfg.MailDriver == "smtp" {
+		return NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom)
+	}
+	return NewFileMailer(cfg.MailDir, cfg.MailFrom)
+}
+
+// SMTPMailer sends mail through an SMTP server, authenticating when a username is set
+type SMTPMailer struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+}
+
+func NewSMTPMailer(host, port, username, password, from string) *SMTPMailer {
+	return &SMTPMailer{
+		addr:     net.JoinHostPort(host, port),
+		host:     host,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+func (sm *SMTPMailer) Send(to, subject, body string) error {
+	var auth smtp.Auth
+	if sm.username != "" {
+		auth = smtp.PlainAuth("", sm.username, sm.password, sm.host)
+	}
+
+	return smtp.SendMail(sm.addr, auth, envelopeAddress(sm.from), []string{to}, buildMessage(sm.from, to, subject, body))
+}
+
+// FileMailer writes each message to its own .eml file in dir, or to the log when
+// dir is empty. It is meant for development and tests.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir, from string) *FileMailer {
+	return &FileMailer{dir: dir, from: from}
+}
+
+func (fm *FileMailer) Send(to, subject, body string) error {
+	message := buildMessage(fm.from, to, subject, body)
+
+	if fm.dir == "" {
+		log.Printf("Mail to %s:\n%s", to, message)
+		return nil
+	}
+
+	if err := os.MkdirAll(fm.dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), unsafeFileChars.ReplaceAllString(to, "_"))
+	return os.WriteFile(filepath.Join(fm.dir, name), message, 0o644)
+}
+
+var (
+	unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9@._-]`)
+	headerBreaks    = strings.NewReplacer("\r", "", "\n", "")
+)
+
+func buildMessage(from, to, subject, body string) []byte {
+	var message strings.Builder
+	message.WriteString("From: " + headerBreaks.Replace(from) + "\r\n")
+	message.WriteString("To: " + headerBreaks.Replace(to) + "\r\n")
+	message.WriteString("Subject: " + headerBreaks.Replace(subject) + "\r\n")
+	message.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	message.WriteString("\r\n")
+	message.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return []byte(message.String())
+}
+
+// envelopeAddress extracts the bare address from a "Name <address>" sender
+func envelopeAddress(from string) string {
+	if address, err := mail.ParseAddress(from); err == nil {
+		return address.Address
+	}
+	return from
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"yonatan/labpro/models"
+	"yonatan/labpro/storage"
+
+	"github.com/google/uuid"
+)
+
+var ErrMediaNotFound = errors.New("file not found")
+
+// mediaCoursesSQL lists the courses whose live modules use a file, now or in an
+// earlier revision
+const mediaCoursesSQL = `SELECT course_id FROM modules
+	WHERE deleted_at IS NULL AND (pdf_content = @url OR video_content = @url OR video_stream = @stream)
+	UNION SELECT modules.course_id FROM content_revisions JOIN modules ON modules.id = content_revisions.target_id
+	WHERE content_revisions.target_type = 'module' AND modules.deleted_at IS NULL
+		AND (content_revisions.content->>'pdf_content' = @url OR content_revisions.content->>'video_content' = @url)`
+
+// FindAsset looks up a stored file by its asset ID
+func (ms *MediaService) FindAsset(id string) (*models.MediaAsset, error) {
+	// Anything but a UUID would make Postgres reject the query
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrMediaNotFound
+	}
+	var asset models.MediaAsset
+	if err := ms.db.Where("id = ?", id).Limit(1).Find(&asset).Error; err != nil {
+		return nil, err
+	}
+	if asset.ID == "" {
+		return nil, ErrMediaNotFound
+	}
+	return &asset, nil
+}
+
+// FindAssetByKey looks up a stored file by its storage key. Files stored before
+// assets were recorded get a stand-in without an ID.
+func (ms *MediaService) FindAssetByKey(key string) (*models.MediaAsset, error) {
+	var asset models.MediaAsset
+	if err := ms.db.Where("key = ?", key).Limit(1).Find(&asset).Error; err != nil {
+		return nil, err
+	}
+	if asset.ID != "" {
+		return &asset, nil
+	}
+
+	object, err := ms.storage.Stat(context.Background(), key)
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+		return nil, ErrMediaNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &models.MediaAsset{
+		Key:         object.Key,
+		URL:         object.URL,
+		ContentType: object.ContentType,
+		Size:        object.Size,
+		CreatedAt:   object.ModTime,
+	}, nil
+}
+
+// IsPublic reports whether anyone may download a file. Only course thumbnails
+// are, since the catalogue shows them to visitors.
+func (ms *MediaService) IsPublic(asset models.MediaAsset) (bool, error) {
+	if asset.Kind == models.MediaKindThumbnail {
+		return true, nil
+	}
+	var count int64
+	err := ms.db.Model(&models.Course{}).Where("thumbnail = ?", asset.URL).Count(&count).Error
+	return count > 0, err
+}
+
+// CanAccess reports whether a signed-in user may download a file: admins
+// always, users their own certificates, and course files whoever bought or may
+// edit the course
+func (ms *MediaService) CanAccess(user models.User, asset models.MediaAsset) (bool, error) {
+	if user.IsAdmin {
+		return true, nil
+	}
+	if strings.HasPrefix(asset.Key, certificatePrefix) {
+		return strings.HasPrefix(asset.Key, certificatePrefix+"certificate_"+user.ID+"_"), nil
+	}
+
+	var courseIDs []string
+	err := ms.db.Raw(mediaCoursesSQL, map[string]interface{}{
+		"url":    asset.URL,
+		"stream": streamOf(asset.Key),
+	}).Scan(&courseIDs).Error
+	if err != nil || len(courseIDs) == 0 {
+		return false, err
+	}
+
+	for _, courseID := range courseIDs {
+		enrolled, err := checkCourseAccess(ms.db, user.ID, courseID)
+		if err != nil {
+			return false, err
+		}
+		if enrolled {
+			return true, nil
+		}
+		canEdit, err := canEditCourse(ms.db, user, courseID)
+		if err != nil {
+			return false, err
+		}
+		if canEdit {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Open opens a file for serving. Backends whose files cannot be read at an
+// offset, such as S3 and Cloudinary, instead return a short-lived direct URL to
+// redirect to, which answers range requests itself.
+func (ms *MediaService) Open(asset models.MediaAsset) (io.ReadSeekCloser, *storage.Object, string, error) {
+	ctx := context.Background()
+	reader, object, err := ms.storage.Get(ctx, asset.Key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, "", ErrMediaNotFound
+	}
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if seeker, ok := reader.(io.ReadSeekCloser); ok {
+		return seeker, object, "", nil
+	}
+	reader.Close()
+
+	url, err := ms.storage.SignedURL(ctx, asset.Key, time.Minute)
+	return nil, object, url, err
+}
+
+// SignedURL returns a /media address for a stored file that works without a
+// session until it expires, for players and API clients. URLs of files that
+// are not tracked are returned as they are.
+func (ms *MediaService) SignedURL(url string) string {
+	if url == "" {
+		return url
+	}
+	var asset models.MediaAsset
+	if err := ms.db.Select("id").Where("url = ?", url).Limit(1).Find(&asset).Error; err != nil || asset.ID == "" {
+		return url
+	}
+
+	// Expiry is rounded to the minute so repeated requests share browser caches
+	expires := time.Now().Add(ms.urlTTL()).Truncate(time.Minute).Add(time.Minute).Unix()
+	return fmt.Sprintf("%s/media/%s?expires=%d&signature=%s", ms.config.BaseURL, asset.ID, expires, ms.sign(asset.ID, expires))
+}
+
+// signedContentURL signs a nullable file column for a response
+func (ms *MediaService) signedContentURL(url *string) *string {
+	if url == nil {
+		return nil
+	}
+	signed := ms.SignedURL(*url)
+	return &signed
+}
+
+// VerifySignature checks the expires and signature parameters of a signed URL.
+// It returns how long the URL stays valid, or false when it is not.
+func (ms *MediaService) VerifySignature(assetID, expiresParam, signature string) (time.Duration, bool) {
+	expires, err := strconv.ParseInt(expiresParam, 10, 64)
+	if err != nil || signature == "" {
+		return 0, false
+	}
+	remaining := time.Until(time.Unix(expires, 0))
+	if remaining <= 0 {
+		return 0, false
+	}
+	if !hmac.Equal([]byte(signature), []byte(ms.sign(assetID, expires))) {
+		return 0, false
+	}
+	return remaining, true
+}
+
+func (ms *MediaService) sign(assetID string, expires int64) string {
+	key := ms.config.MediaSigningKey
+	if key == "" {
+		key = ms.config.JWTSecret
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	fmt.Fprintf(mac, "%s\n%d", assetID, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (ms *MediaService) urlTTL() time.Duration {
+	minutes, err := strconv.Atoi(ms.config.MediaURLTTLMinutes)
+	if err != nil || minutes <= 0 {
+		minutes = 60
+	}
+	return time.Duration(minutes) * time.Minute
+}
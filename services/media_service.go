@@ -0,0 +1,458 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"path"
+	"strings"
+	"time"
+	"yonatan/labpro/config"
+	"yonatan/labpro/filecheck"
+	"yonatan/labpro/models"
+	"yonatan/labpro/storage"
+	"yonatan/labpro/thumbnail"
+
+	"gorm.io/gorm"
+)
+
+// referencedMediaSQL lists every file URL the database refers to. Raw SQL skips
+// the soft-delete scope on purpose: files of trashed courses and modules stay
+// until the trash is purged, and revisions keep replaced files for rollbacks.
+const referencedMediaSQL = `SELECT pdf_content AS url FROM modules WHERE pdf_content IS NOT NULL
+	UNION SELECT video_content FROM modules WHERE video_content IS NOT NULL
+	UNION SELECT thumbnail FROM courses WHERE thumbnail IS NOT NULL
+	UNION SELECT content->>'pdf_content' FROM content_revisions WHERE target_type = 'module'
+	UNION SELECT content->>'video_content' FROM content_revisions WHERE target_type = 'module'`
+
+// referencedStreamsSQL lists the storage prefixes of the HLS streams modules
+// play, trashed modules included
+const referencedStreamsSQL = `SELECT video_stream FROM modules WHERE video_stream IS NOT NULL`
+
+// streamPrefix holds transcoded videos, one hls/<module>/<id> prefix per stream
+const streamPrefix = "hls/"
+
+// certificatePrefix holds generated certificates. They are handed to users
+// rather than referenced from the database, so they are never orphans.
+const certificatePrefix = "certificates/"
+
+// orphanGracePeriod keeps scans away from files that were just uploaded and
+// whose course or module is still being saved
+const orphanGracePeriod = time.Hour
+
+// OrphanFile is a stored file nothing in the database refers to
+type OrphanFile struct {
+	Key     string
+	URL     string
+	Size    int64
+	ModTime time.Time
+	// Tracked is false for files stored before assets were recorded
+	Tracked bool
+}
+
+// OrphanReport is the result of reconciling storage against the database.
+// Missing lists assets whose file is no longer in storage.
+type OrphanReport struct {
+	Orphans []OrphanFile
+	Missing []models.MediaAsset
+	Deleted int
+}
+
+// MediaService records what uploads put in storage and deletes files once no
+// course, module or revision refers to them any more
+type MediaService struct {
+	db         *gorm.DB
+	config     *config.Config
+	storage    storage.Backend
+	validator  *filecheck.Validator
+	thumbnails *thumbnail.Renderer
+}
+
+func NewMediaService(db *gorm.DB, cfg *config.Config, fileStorage storage.Backend) *MediaService {
+	return &MediaService{
+		db:         db,
+		config:     cfg,
+		storage:    fileStorage,
+		validator:  filecheck.New(cfg),
+		thumbnails: thumbnail.New(cfg),
+	}
+}
+
+// Store checks an uploaded file of the given kind and saves it under dir, and
+// returns the URL to keep in the database. Refused files give an error that
+// filecheck.IsRejected recognizes.
+func (ms *MediaService) Store(kind, dir string, file *multipart.FileHeader) (string, error) {
+	if err := ms.validator.CheckSize(kind, file.Size); err != nil {
+		return "", err
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open uploaded file: %v", err)
+	}
+	defer src.Close()
+
+	checked, err := ms.validator.CheckContent(context.Background(), kind, file.Filename, src)
+	if err != nil {
+		log.Printf("MediaService: Refused %s: %v", file.Filename, err)
+		return "", err
+	}
+
+	// The client's file name only survives sanitized, so it cannot steer the key
+	key := fmt.Sprintf("%s/%d_%s", dir, time.Now().Unix(), checked.Name)
+	asset, err := ms.Put(kind, key, src, file.Size, checked.ContentType)
+	if err != nil {
+		log.Printf("MediaService: Failed to store %s: %v", key, err)
+		return "", fmt.Errorf("failed to save file: %v", err)
+	}
+
+	log.Printf("MediaService: Stored %d bytes at %s", asset.Size, asset.URL)
+	return asset.URL, nil
+}
+
+// StoreThumbnail checks an uploaded course image and stores it resized to
+// every thumbnail size, under thumbnails/<upload>/<format>/<size>. It returns
+// the URL of the detail JPEG, which courses keep; the other files are recorded
+// as its variants and go with it.
+func (ms *MediaService) StoreThumbnail(file *multipart.FileHeader) (string, error) {
+	if err := ms.validator.CheckSize(models.MediaKindThumbnail, file.Size); err != nil {
+		return "", err
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open uploaded file: %v", err)
+	}
+	defer src.Close()
+
+	checked, err := ms.validator.CheckContent(context.Background(), models.MediaKindThumbnail, file.Filename, src)
+	if err != nil {
+		log.Printf("MediaService: Refused %s: %v", file.Filename, err)
+		return "", err
+	}
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return "", fmt.Errorf("failed to read uploaded file: %v", err)
+	}
+	images, err := ms.thumbnails.Render(context.Background(), data)
+	if err != nil {
+		log.Printf("MediaService: Refused %s: %v", file.Filename, err)
+		return "", err
+	}
+
+	// The detail JPEG goes first, so the rest can point at its URL
+	for i, image := range images {
+		if image.Size == thumbnail.Detail && image.Format == thumbnail.FormatJPEG {
+			images[0], images[i] = images[i], images[0]
+			break
+		}
+	}
+
+	dir := fmt.Sprintf("thumbnails/%d_%s", time.Now().Unix(), strings.TrimSuffix(checked.Name, path.Ext(checked.Name)))
+	var stored []models.MediaAsset
+	url := ""
+	for _, image := range images {
+		key := fmt.Sprintf("%s/%s/%s%s", dir, image.Format, image.Name, image.Ext())
+		asset, err := ms.Put(models.MediaKindThumbnail, key, bytes.NewReader(image.Data), int64(len(image.Data)), image.ContentType)
+		if err == nil {
+			stored = append(stored, *asset)
+			if url == "" {
+				url = asset.URL
+			}
+			err = ms.db.Model(asset).Update("variant_of", url).Error
+		}
+		if err != nil {
+			log.Printf("MediaService: Failed to store %s: %v", key, err)
+			for _, asset := range stored {
+				ms.deleteAsset(asset)
+			}
+			return "", fmt.Errorf("failed to save file: %v", err)
+		}
+	}
+
+	log.Printf("MediaService: Stored %d thumbnail files at %s", len(stored), dir)
+	return url, nil
+}
+
+// Put stores r under key and records it as an asset of the given kind
+func (ms *MediaService) Put(kind, key string, r io.Reader, size int64, contentType string) (*models.MediaAsset, error) {
+	ctx := context.Background()
+	object, err := ms.storage.Put(ctx, key, r, size, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	asset := models.MediaAsset{
+		Key:         object.Key,
+		URL:         object.URL,
+		Kind:        kind,
+		ContentType: object.ContentType,
+		Size:        object.Size,
+	}
+	// Re-uploading a key replaces the file, so the asset is updated in place
+	err = ms.db.Where(models.MediaAsset{Key: asset.Key}).
+		Assign(models.MediaAsset{URL: asset.URL, Kind: asset.Kind, ContentType: asset.ContentType, Size: asset.Size}).
+		FirstOrCreate(&asset).Error
+	if err != nil {
+		ms.storage.Delete(ctx, object.Key)
+		return nil, err
+	}
+	return &asset, nil
+}
+
+// Release deletes the files behind urls that nothing refers to any more. Call it
+// once the transaction that dropped the references has committed. Failures are
+// only logged; `labpro orphans purge` picks up whatever is left behind.
+func (ms *MediaService) Release(urls ...string) {
+	for _, url := range urls {
+		if url == "" {
+			continue
+		}
+
+		var asset models.MediaAsset
+		if err := ms.db.Where("url = ?", url).Limit(1).Find(&asset).Error; err != nil {
+			log.Printf("MediaService: Failed to look up %s: %v", url, err)
+			continue
+		}
+		// Files stored before assets were recorded, and links to elsewhere
+		if asset.ID == "" || strings.HasPrefix(asset.Key, certificatePrefix) {
+			continue
+		}
+
+		referenced, err := ms.referenced(url)
+		if err != nil {
+			log.Printf("MediaService: Failed to check references to %s: %v", url, err)
+			continue
+		}
+		if referenced {
+			continue
+		}
+
+		ms.releaseVariants(url)
+		if err := ms.deleteAsset(asset); err != nil {
+			log.Printf("MediaService: Failed to delete %s: %v", asset.Key, err)
+			continue
+		}
+		log.Printf("MediaService: Deleted unused file %s", asset.Key)
+	}
+}
+
+// releaseVariants deletes the other sizes and formats of a thumbnail that is
+// going away
+func (ms *MediaService) releaseVariants(url string) {
+	var variants []models.MediaAsset
+	if err := ms.db.Where("variant_of = ? AND url <> ?", url, url).Find(&variants).Error; err != nil {
+		log.Printf("MediaService: Failed to look up the variants of %s: %v", url, err)
+		return
+	}
+	for _, variant := range variants {
+		if err := ms.deleteAsset(variant); err != nil {
+			log.Printf("MediaService: Failed to delete %s: %v", variant.Key, err)
+		}
+	}
+}
+
+// ReleaseStream deletes the files of transcoded streams no module plays any more.
+// Like Release, call it after the change has committed; failures are only logged.
+func (ms *MediaService) ReleaseStream(prefixes ...string) {
+	ctx := context.Background()
+	for _, prefix := range prefixes {
+		if !strings.HasPrefix(prefix, streamPrefix) {
+			continue
+		}
+
+		referenced, err := ms.streamReferenced(prefix)
+		if err != nil {
+			log.Printf("MediaService: Failed to check references to %s: %v", prefix, err)
+			continue
+		}
+		if referenced {
+			continue
+		}
+
+		// Listing storage also finds files of uploads that failed half way
+		objects, err := ms.storage.List(ctx, prefix+"/")
+		if err != nil {
+			log.Printf("MediaService: Failed to list %s: %v", prefix, err)
+			continue
+		}
+		deleted := 0
+		for _, object := range objects {
+			if err := ms.storage.Delete(ctx, object.Key); err != nil {
+				log.Printf("MediaService: Failed to delete %s: %v", object.Key, err)
+				continue
+			}
+			if err := ms.db.Where("key = ?", object.Key).Delete(&models.MediaAsset{}).Error; err != nil {
+				log.Printf("MediaService: Failed to delete the record of %s: %v", object.Key, err)
+				continue
+			}
+			deleted++
+		}
+		if deleted > 0 {
+			log.Printf("MediaService: Deleted %d files of unused stream %s", deleted, prefix)
+		}
+	}
+}
+
+// streamOf returns the stream prefix a key belongs to, if any
+func streamOf(key string) string {
+	if !strings.HasPrefix(key, streamPrefix) {
+		return ""
+	}
+	parts := strings.SplitN(key, "/", 4)
+	if len(parts) < 4 {
+		return ""
+	}
+	return strings.Join(parts[:3], "/")
+}
+
+// assetInUse reports whether anything refers to an asset, either by its URL or
+// as part of a stream or thumbnail set
+func (ms *MediaService) assetInUse(asset models.MediaAsset) (bool, error) {
+	if stream := streamOf(asset.Key); stream != "" {
+		return ms.streamReferenced(stream)
+	}
+	if asset.VariantOf != "" {
+		return ms.referenced(asset.VariantOf)
+	}
+	return ms.referenced(asset.URL)
+}
+
+func (ms *MediaService) streamReferenced(prefix string) (bool, error) {
+	var referenced bool
+	err := ms.db.Raw("SELECT EXISTS (SELECT 1 FROM ("+referencedStreamsSQL+") refs WHERE video_stream = ?)", prefix).Scan(&referenced).Error
+	return referenced, err
+}
+
+func (ms *MediaService) referenced(url string) (bool, error) {
+	var referenced bool
+	err := ms.db.Raw("SELECT EXISTS (SELECT 1 FROM ("+referencedMediaSQL+") refs WHERE url = ?)", url).Scan(&referenced).Error
+	return referenced, err
+}
+
+// deleteAsset removes the file before the record, so a failed delete can be retried
+func (ms *MediaService) deleteAsset(asset models.MediaAsset) error {
+	if err := ms.storage.Delete(context.Background(), asset.Key); err != nil {
+		return err
+	}
+	return ms.db.Delete(&asset).Error
+}
+
+// ScanOrphans compares storage with the database without changing either
+func (ms *MediaService) ScanOrphans() (*OrphanReport, error) {
+	var urls []string
+	if err := ms.db.Raw(referencedMediaSQL).Scan(&urls).Error; err != nil {
+		return nil, err
+	}
+	referenced := make(map[string]bool, len(urls))
+	for _, url := range urls {
+		referenced[url] = true
+	}
+
+	var streams []string
+	if err := ms.db.Raw(referencedStreamsSQL).Scan(&streams).Error; err != nil {
+		return nil, err
+	}
+	playing := make(map[string]bool, len(streams))
+	for _, stream := range streams {
+		playing[stream] = true
+	}
+
+	var assets []models.MediaAsset
+	if err := ms.db.Find(&assets).Error; err != nil {
+		return nil, err
+	}
+	tracked := make(map[string]models.MediaAsset, len(assets))
+	for _, asset := range assets {
+		tracked[asset.Key] = asset
+	}
+
+	objects, err := ms.storage.List(context.Background(), "")
+	if err != nil {
+		return nil, err
+	}
+
+	report := &OrphanReport{}
+	stored := make(map[string]bool, len(objects))
+	cutoff := time.Now().Add(-orphanGracePeriod)
+	for _, object := range objects {
+		stored[object.Key] = true
+		if strings.HasPrefix(object.Key, certificatePrefix) || object.ModTime.After(cutoff) {
+			continue
+		}
+
+		asset, isTracked := tracked[object.Key]
+		url := object.URL
+		if isTracked {
+			url = asset.URL
+		}
+		if referenced[url] || playing[streamOf(object.Key)] || (asset.VariantOf != "" && referenced[asset.VariantOf]) {
+			continue
+		}
+		report.Orphans = append(report.Orphans, OrphanFile{
+			Key:     object.Key,
+			URL:     url,
+			Size:    object.Size,
+			ModTime: object.ModTime,
+			Tracked: isTracked,
+		})
+	}
+
+	for _, asset := range assets {
+		if !stored[asset.Key] && asset.CreatedAt.Before(cutoff) {
+			report.Missing = append(report.Missing, asset)
+		}
+	}
+
+	return report, nil
+}
+
+// PurgeOrphans deletes the orphaned files a scan finds, along with the records
+// of assets whose file is gone and that nothing refers to
+func (ms *MediaService) PurgeOrphans() (*OrphanReport, error) {
+	report, err := ms.ScanOrphans()
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for _, orphan := range report.Orphans {
+		if err := ms.storage.Delete(context.Background(), orphan.Key); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", orphan.Key, err))
+			continue
+		}
+		if err := ms.db.Where("key = ?", orphan.Key).Delete(&models.MediaAsset{}).Error; err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", orphan.Key, err))
+			continue
+		}
+		report.Deleted++
+	}
+
+	for _, asset := range report.Missing {
+		referenced, err := ms.assetInUse(asset)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", asset.Key, err))
+			continue
+		}
+		// Broken links are reported, not hidden by dropping their record
+		if !referenced {
+			if err := ms.db.Delete(&asset).Error; err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", asset.Key, err))
+			}
+		}
+	}
+
+	return report, errors.Join(errs...)
+}
+
+// optionalURL turns a nullable file column into a URL for Release
+func optionalURL(url *string) string {
+	if url == nil {
+		return ""
+	}
+	return *url
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"yonatan/labpro/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	mfaIssuer         = "Grocademy"
+	recoveryCodeCount = 10
+)
+
+var (
+	ErrMFAAlreadyEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled      = errors.New("two-factor authentication is not enabled")
+	ErrMFASetupNotStarted = errors.New("start two-factor authentication setup first")
+	ErrInvalidMFACode     = errors.New("invalid authentication code")
+	ErrMFAEnforced        = errors.New("two-factor authentication is required for your role")
+)
+
+// MFASetup is what a user needs to add the account to an authenticator app
+type MFASetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// MFAService manages TOTP two-factor authentication and its recovery codes
+type MFAService struct {
+	db *gorm.DB
+}
+
+func NewMFAService(db *gorm.DB) *MFAService {
+	return &MFAService{db: db}
+}
+
+// BeginSetup generates a new secret for the user. It takes effect once Enable
+// confirms that the authenticator produces matching codes.
+func (ms *MFAService) BeginSetup(userID string) (*MFASetup, error) {
+	var user models.User
+	if err := ms.db.First(&user, "id = ?", userID).Error; err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.TOTPEnabledAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := ms.db.Model(&user).Updates(map[string]interface{}{"totp_secret": secret, "totp_last_step": 0}).Error; err != nil {
+		return nil, err
+	}
+
+	return &MFASetup{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(mfaIssuer, user.Username, secret),
+	}, nil
+}
+
+// Enable turns on two-factor authentication after checking a code from the
+// pending secret and returns a fresh set of recovery codes
+func (ms *MFAService) Enable(userID, code string) ([]string, error) {
+	var codes []string
+
+	err := ms.db.Transaction(func(tx *gorm.DB) error {
+		user, err := lockUser(tx, userID)
+		if err != nil {
+			return err
+		}
+		if user.TOTPEnabledAt != nil {
+			return ErrMFAAlreadyEnabled
+		}
+		if user.TOTPSecret == "" {
+			return ErrMFASetupNotStarted
+		}
+
+		step, ok := matchTOTP(user.TOTPSecret, normalizeMFACode(code), time.Now())
+		if !ok {
+			return ErrInvalidMFACode
+		}
+
+		if err := tx.Model(user).Updates(map[string]interface{}{"totp_enabled_at": time.Now(), "totp_last_step": step}).Error; err != nil {
+			return err
+		}
+
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Disable turns off two-factor authentication after checking a current code.
+// Users whose role requires it cannot turn it off.
+func (ms *MFAService) Disable(userID, code string) error {
+	return ms.db.Transaction(func(tx *gorm.DB) error {
+		user, err := verifyMFACode(tx, userID, code)
+		if err != nil {
+			return err
+		}
+		if userRequiresMFA(tx, *user) {
+			return ErrMFAEnforced
+		}
+
+		return clearMFA(tx, user.ID)
+	})
+}
+
+// RegenerateRecoveryCodes replaces every recovery code of the user after checking
+// a current code
+func (ms *MFAService) RegenerateRecoveryCodes(userID, code string) ([]string, error) {
+	var codes []string
+
+	err := ms.db.Transaction(func(tx *gorm.DB) error {
+		user, err := verifyMFACode(tx, userID, code)
+		if err != nil {
+			return err
+		}
+
+		codes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Verify checks the second factor of a login: a TOTP code or an unused recovery code
+func (ms *MFAService) Verify(userID, code string) (*models.User, error) {
+	var user *models.User
+
+	err := ms.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		user, err = verifyMFACode(tx, userID, code)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// Reset removes two-factor authentication from an account, e.g. for a user who
+// lost both the authenticator and the recovery codes
+func (ms *MFAService) Reset(userID string, actor AuditActor) error {
+	return ms.db.Transaction(func(tx *gorm.DB) error {
+		user, err := lockUser(tx, userID)
+		if err != nil {
+			return err
+		}
+		if err := clearMFA(tx, userID); err != nil {
+			return err
+		}
+
+		return recordAudit(tx, actor, models.AuditUserResetMFA, "user", userID,
+			map[string]interface{}{"mfa_enabled": user.TOTPEnabledAt != nil}, map[string]interface{}{"mfa_enabled": false})
+	})
+}
+
+// RemainingRecoveryCodes counts the unused recovery codes of the user
+func (ms *MFAService) RemainingRecoveryCodes(userID string) int64 {
+	var count int64
+	ms.db.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count)
+	return count
+}
+
+// verifyMFACode accepts a TOTP code newer than the last one used, or consumes a
+// recovery code
+func verifyMFACode(tx *gorm.DB, userID, code string) (*models.User, error) {
+	user, err := lockUser(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabledAt == nil {
+		return nil, ErrMFANotEnabled
+	}
+
+	code = normalizeMFACode(code)
+	if step, ok := matchTOTP(user.TOTPSecret, code, time.Now()); ok {
+		if step <= user.TOTPLastStep {
+			return nil, ErrInvalidMFACode
+		}
+		if err := tx.Model(user).Update("totp_last_step", step).Error; err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+
+	result := tx.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hashToken(code)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInvalidMFACode
+	}
+
+	return user, nil
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID string) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	records := make([]models.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		raw, err := randomToken(5)
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = raw[:5] + "-" + raw[5:]
+		records[i] = models.RecoveryCode{UserID: userID, CodeHash: hashToken(codes[i])}
+	}
+
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func clearMFA(tx *gorm.DB, userID string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"totp_secret":     "",
+		"totp_enabled_at": nil,
+		"totp_last_step":  0,
+	}).Error
+}
+
+func lockUser(tx *gorm.DB, userID string) (*models.User, error) {
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, "id = ?", userID).Error; err != nil {
+		return nil, ErrUserNotFound
+	}
+	return &user, nil
+}
+
+// normalizeMFACode drops the spaces apps insert for readability; recovery codes
+// are compared in lower case
+func normalizeMFACode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), " ", ""))
+}
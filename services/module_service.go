@@ -1,48 +1,43 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"mime/multipart"
-	"os"
-	"path/filepath"
+	"path"
+	"strings"
 	"time"
 	"yonatan/labpro/config"
 	"yonatan/labpro/models"
+	"yonatan/labpro/storage"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrModuleAccessDenied  = errors.New("access denied")
+	ErrVideoStreamNotReady = errors.New("video stream is not ready")
 )
 
 type ModuleService struct {
-	db                *gorm.DB
-	config            *config.Config
-	cloudinaryService *CloudinaryService
+	db     *gorm.DB
+	config *config.Config
+	media  *MediaService
 }
 
-func NewModuleService(db *gorm.DB, cfg *config.Config) *ModuleService {
-	var cloudinaryService *CloudinaryService
-	if cfg.CloudinaryURL != "" {
-		var err error
-		cloudinaryService, err = NewCloudinaryService(cfg.CloudinaryURL)
-		if err != nil {
-			log.Printf("Warning: Failed to initialize Cloudinary service: %v. Will use local storage.", err)
-		} else {
-			log.Printf("Cloudinary service initialized successfully")
-		}
-	} else {
-		log.Printf("Cloudinary URL not configured. Using local storage for file uploads.")
-	}
-
+func NewModuleService(db *gorm.DB, cfg *config.Config, fileStorage storage.Backend) *ModuleService {
 	return &ModuleService{
-		db:                db,
-		config:            cfg,
-		cloudinaryService: cloudinaryService,
+		db:     db,
+		config: cfg,
+		media:  NewMediaService(db, cfg, fileStorage),
 	}
 }
 
-func (ms *ModuleService) CreateModule(courseID, title, description string, pdfURL, videoURL *string) (*models.Module, error) {
+func (ms *ModuleService) CreateModule(courseID, title, description string, pdfURL, videoURL *string, actor AuditActor) (*models.Module, error) {
 	// Get the next order number for this course
 	var maxOrder int
 	ms.db.Model(&models.Module{}).Where("course_id = ?", courseID).Select("COALESCE(MAX(\"order\"), 0)").Scan(&maxOrder)
@@ -55,8 +50,20 @@ func (ms *ModuleService) CreateModule(courseID, title, description string, pdfUR
 		PDFContent:   pdfURL,
 		VideoContent: videoURL,
 	}
+	queueTranscode(&module, ms.config)
 
-	if err := ms.db.Create(&module).Error; err != nil {
+	err := ms.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&module).Error; err != nil {
+			return err
+		}
+		if err := recordRevision(tx, actor, models.RevisionTargetModule, module.ID, nil, moduleRevisionContent(module), nil); err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, models.AuditModuleCreate, "module", module.ID, nil, module)
+	})
+	if err != nil {
+		// The files were uploaded for this module and nothing else uses them
+		ms.media.Release(optionalURL(pdfURL), optionalURL(videoURL))
 		return nil, err
 	}
 
@@ -67,7 +74,7 @@ func (ms *ModuleService) GetModules(courseID string, userID interface{}, page, l
 	var modules []models.Module
 	var total int64
 
-	db := ms.db.Model(&models.Module{}).Preload("Course").Where("course_id = ?", courseID)
+	db := ms.db.Model(&models.Module{}).Preload("Course.Instructor").Where("course_id = ?", courseID)
 
 	// Count total
 	db.Count(&total)
@@ -78,6 +85,12 @@ func (ms *ModuleService) GetModules(courseID string, userID interface{}, page, l
 		return nil, nil, err
 	}
 
+	// Only users who may open the files get signed links to them
+	fileURL := func(url *string) *string { return url }
+	if ms.canViewFiles(userID, courseID) {
+		fileURL = ms.media.signedContentURL
+	}
+
 	// Convert to response format
 	result := make([]map[string]interface{}, len(modules))
 	for i, module := range modules {
@@ -89,10 +102,11 @@ func (ms *ModuleService) GetModules(courseID string, userID interface{}, page, l
 			isCompleted = (err == nil && progress.IsCompleted)
 		}
 
+		videoStream, videoPoster := ms.videoStreamURLs(module)
 		courseInfo := map[string]interface{}{
 			"id":         module.Course.ID,
 			"title":      module.Course.Title,
-			"instructor": module.Course.Instructor,
+			"instructor": module.Course.InstructorName(),
 		}
 
 		result[i] = map[string]interface{}{
@@ -102,8 +116,11 @@ func (ms *ModuleService) GetModules(courseID string, userID interface{}, page, l
 			"title":         module.Title,
 			"description":   module.Description,
 			"order":         module.Order,
-			"pdf_content":   module.PDFContent,
-			"video_content": module.VideoContent,
+			"pdf_content":   fileURL(module.PDFContent),
+			"video_content": fileURL(module.VideoContent),
+			"video_status":  module.VideoStatus,
+			"video_stream":  videoStream,
+			"video_poster":  videoPoster,
 			"is_completed":  isCompleted,
 			"created_at":    module.CreatedAt.Format("Jan 2, 2006"),
 			"updated_at":    module.UpdatedAt,
@@ -133,6 +150,26 @@ func (ms *ModuleService) GetModules(courseID string, userID interface{}, page, l
 	return result, pagination, nil
 }
 
+// canViewFiles reports whether the user modules are listed for may open their
+// files. Listings without a user come from the admin pages.
+func (ms *ModuleService) canViewFiles(userID interface{}, courseID string) bool {
+	if userID == nil {
+		return true
+	}
+	var user models.User
+	if err := ms.db.First(&user, "id = ?", userID).Error; err != nil {
+		return false
+	}
+	if user.IsAdmin {
+		return true
+	}
+	if hasAccess, err := ms.CheckCourseAccess(user.ID, courseID); err == nil && hasAccess {
+		return true
+	}
+	canEdit, err := ms.CanEditCourseModules(user, courseID)
+	return err == nil && canEdit
+}
+
 func (ms *ModuleService) GetModuleByID(id string, userID interface{}, userRole string) (map[string]interface{}, error) {
 	var module models.Module
 	if err := ms.db.First(&module, "id = ?", id).Error; err != nil {
@@ -143,7 +180,7 @@ func (ms *ModuleService) GetModuleByID(id string, userID interface{}, userRole s
 	if userRole != "admin" && userID != nil {
 		hasAccess, err := ms.CheckCourseAccess(userID.(string), module.CourseID)
 		if err != nil || !hasAccess {
-			return nil, errors.New("access denied")
+			return nil, ErrModuleAccessDenied
 		}
 	}
 
@@ -155,14 +192,18 @@ func (ms *ModuleService) GetModuleByID(id string, userID interface{}, userRole s
 		isCompleted = (err == nil && progress.IsCompleted)
 	}
 
+	videoStream, videoPoster := ms.videoStreamURLs(module)
 	result := map[string]interface{}{
 		"id":            module.ID,
 		"course_id":     module.CourseID,
 		"title":         module.Title,
 		"description":   module.Description,
 		"order":         module.Order,
-		"pdf_content":   module.PDFContent,
-		"video_content": module.VideoContent,
+		"pdf_content":   ms.media.signedContentURL(module.PDFContent),
+		"video_content": ms.media.signedContentURL(module.VideoContent),
+		"video_status":  module.VideoStatus,
+		"video_stream":  videoStream,
+		"video_poster":  videoPoster,
 		"is_completed":  isCompleted,
 		"created_at":    module.CreatedAt,
 		"updated_at":    module.UpdatedAt,
@@ -171,11 +212,12 @@ func (ms *ModuleService) GetModuleByID(id string, userID interface{}, userRole s
 	return result, nil
 }
 
-func (ms *ModuleService) UpdateModule(id, title, description string, pdfURL, videoURL *string) (*models.Module, error) {
+func (ms *ModuleService) UpdateModule(id, title, description string, pdfURL, videoURL *string, actor AuditActor) (*models.Module, error) {
 	var module models.Module
 	if err := ms.db.First(&module, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
+	before := module
 
 	module.Title = title
 	module.Description = description
@@ -187,39 +229,194 @@ func (ms *ModuleService) UpdateModule(id, title, description string, pdfURL, vid
 		module.VideoContent = videoURL
 	}
 
-	if err := ms.db.Save(&module).Error; err != nil {
+	var droppedStream string
+	videoChanged := optionalURL(before.VideoContent) != optionalURL(module.VideoContent)
+	if videoChanged {
+		droppedStream = queueTranscode(&module, ms.config)
+	}
+
+	err := ms.db.Transaction(func(tx *gorm.DB) error {
+		save := tx
+		if !videoChanged {
+			save = tx.Omit(videoJobColumns...)
+		}
+		if err := save.Save(&module).Error; err != nil {
+			return err
+		}
+		// The revision keeps the replaced PDF and video reachable for a rollback
+		if err := recordRevision(tx, actor, models.RevisionTargetModule, module.ID, moduleRevisionContent(before), moduleRevisionContent(module), nil); err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, models.AuditModuleUpdate, "module", module.ID, before, module)
+	})
+	if err != nil {
+		ms.media.Release(optionalURL(pdfURL), optionalURL(videoURL))
 		return nil, err
 	}
 
+	// Replaced files usually live on in the revision history and are kept for it
+	ms.media.Release(optionalURL(before.PDFContent), optionalURL(before.VideoContent))
+	ms.media.ReleaseStream(droppedStream)
+
 	return &module, nil
 }
 
-func (ms *ModuleService) DeleteModule(id string) error {
-	// Delete module progress records
-	if err := ms.db.Where("module_id = ?", id).Delete(&models.UserModuleProgress{}).Error; err != nil {
-		return err
+// RetranscodeVideo queues a module's video for transcoding again, after a
+// failed job or for videos uploaded before transcoding was enabled
+func (ms *ModuleService) RetranscodeVideo(id string) (*models.Module, error) {
+	if !transcodingEnabled(ms.config) {
+		return nil, ErrTranscodingDisabled
+	}
+
+	var module models.Module
+	if err := ms.db.First(&module, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	if optionalURL(module.VideoContent) == "" {
+		return nil, ErrModuleHasNoVideo
+	}
+
+	droppedStream := queueTranscode(&module, ms.config)
+	if err := ms.db.Model(&module).Select(videoJobColumns).UpdateColumns(&module).Error; err != nil {
+		return nil, err
+	}
+	ms.media.ReleaseStream(droppedStream)
+
+	return &module, nil
+}
+
+// OpenVideoStream opens a file of a module's HLS stream, such as "master.m3u8"
+// or "720p/segment_000.ts", for a user who may watch the module. version names
+// the stream the file belongs to, so its URLs change whenever the video does.
+func (ms *ModuleService) OpenVideoStream(user models.User, moduleID, version, file string) (io.ReadCloser, *storage.Object, error) {
+	var module models.Module
+	if err := ms.db.First(&module, "id = ?", moduleID).Error; err != nil {
+		return nil, nil, err
+	}
+
+	canWatch := user.IsAdmin
+	if !canWatch {
+		hasAccess, err := ms.CheckCourseAccess(user.ID, module.CourseID)
+		if err != nil {
+			return nil, nil, err
+		}
+		canWatch = hasAccess
+	}
+	if !canWatch {
+		canEdit, err := ms.CanEditModule(user, moduleID)
+		if err != nil {
+			return nil, nil, err
+		}
+		canWatch = canEdit
+	}
+	if !canWatch {
+		return nil, nil, ErrModuleAccessDenied
+	}
+
+	if module.VideoStatus != models.VideoStatusReady || module.VideoStream == nil || path.Base(*module.VideoStream) != version {
+		return nil, nil, ErrVideoStreamNotReady
+	}
+	return ms.media.storage.Get(context.Background(), *module.VideoStream+"/"+strings.TrimPrefix(file, "/"))
+}
+
+// VideoStreamPath returns where a module's HLS stream is served, relative to the
+// web root and to /api, or "" while the module has no stream ready
+func (ms *ModuleService) VideoStreamPath(moduleID string) string {
+	var module models.Module
+	if err := ms.db.Select("id", "video_status", "video_stream").First(&module, "id = ?", moduleID).Error; err != nil {
+		return ""
+	}
+	return videoStreamPath(module)
+}
+
+func videoStreamPath(module models.Module) string {
+	if module.VideoStatus != models.VideoStatusReady || module.VideoStream == nil {
+		return ""
+	}
+	return "/modules/" + module.ID + "/stream/" + path.Base(*module.VideoStream)
+}
+
+// videoStreamURLs returns the API addresses of a module's master playlist and
+// poster frame, both nil while the module has no stream ready
+func (ms *ModuleService) videoStreamURLs(module models.Module) (*string, *string) {
+	streamPath := videoStreamPath(module)
+	if streamPath == "" {
+		return nil, nil
 	}
+	playlist := ms.config.BaseURL + "/api" + streamPath + "/master.m3u8"
+	poster := ms.config.BaseURL + "/api" + streamPath + "/poster.jpg"
+	return &playlist, &poster
+}
+
+// CanEditCourseModules reports whether the user may add, change or reorder the
+// modules of a course
+func (ms *ModuleService) CanEditCourseModules(user models.User, courseID string) (bool, error) {
+	return canEditCourse(ms.db, user, courseID)
+}
 
-	// Delete the module
-	if err := ms.db.Delete(&models.Module{}, "id = ?", id).Error; err != nil {
+// CanEditModule reports whether the user may change or delete a module
+func (ms *ModuleService) CanEditModule(user models.User, moduleID string) (bool, error) {
+	return canEditModule(ms.db, user, moduleID)
+}
+
+// DeleteModule moves a module to the trash. Its files stay until the trash is
+// purged, so the module can be restored with them.
+func (ms *ModuleService) DeleteModule(id string, actor AuditActor) error {
+	var module models.Module
+	if err := ms.db.First(&module, "id = ?", id).Error; err != nil {
 		return err
 	}
 
-	return nil
+	return ms.db.Transaction(func(tx *gorm.DB) error {
+		tx = trashSession(tx)
+
+		// Delete module progress records
+		if err := tx.Where("module_id = ?", id).Delete(&models.UserModuleProgress{}).Error; err != nil {
+			return err
+		}
+
+		// Delete the module
+		if err := tx.Delete(&models.Module{}, "id = ?", id).Error; err != nil {
+			return err
+		}
+
+		return recordAudit(tx, actor, models.AuditModuleDelete, "module", id, module, nil)
+	})
 }
 
 func (ms *ModuleService) ReorderModules(courseID string, moduleOrder []struct {
 	ID    string `json:"id" binding:"required"`
 	Order int    `json:"order" binding:"required"`
-}) (map[string]interface{}, error) {
+}, actor AuditActor) (map[string]interface{}, error) {
 	// Start transaction
 	tx := ms.db.Begin()
 
+	// The audit log keeps the order of every module of the course, keyed by module ID
+	var modules []models.Module
+	if err := tx.Select("id", "\"order\"").Where("course_id = ?", courseID).Find(&modules).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	before := map[string]interface{}{}
+	after := map[string]interface{}{}
+	for _, module := range modules {
+		before[module.ID] = module.Order
+		after[module.ID] = module.Order
+	}
+
 	for _, item := range moduleOrder {
 		if err := tx.Model(&models.Module{}).Where("id = ? AND course_id = ?", item.ID, courseID).Update("\"order\"", item.Order).Error; err != nil {
 			tx.Rollback()
 			return nil, err
 		}
+		if _, ok := after[item.ID]; ok {
+			after[item.ID] = item.Order
+		}
+	}
+
+	if err := recordAudit(tx, actor, models.AuditModuleReorder, "course", courseID, before, after); err != nil {
+		tx.Rollback()
+		return nil, err
 	}
 
 	tx.Commit()
@@ -238,33 +435,36 @@ func (ms *ModuleService) CompleteModule(moduleID, userID string) (map[string]int
 		return nil, errors.New("module not found")
 	}
 
-	// Check if user purchased the course
-	hasAccess, err := ms.CheckCourseAccess(userID, module.CourseID)
-	if err != nil || !hasAccess {
-		return nil, errors.New("access denied. Course not purchased")
-	}
-
-	// Create or update user module progress
-	var progress models.UserModuleProgress
-	err = ms.db.Where("user_id = ? AND module_id = ?", userID, moduleID).First(&progress).Error
-	if err == gorm.ErrRecordNotFound {
-		// Create new progress record
-		progress = models.UserModuleProgress{
-			UserID:      userID,
-			ModuleID:    moduleID,
-			IsCompleted: true,
+	err := ms.db.Transaction(func(tx *gorm.DB) error {
+		// Check if user purchased the course. The enrollment stays locked until
+		// the progress is saved, so a refund cannot judge progress mid-update.
+		var enrollment models.UserCourse
+		if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).
+			Where("user_id = ? AND course_id = ?", userID, module.CourseID).First(&enrollment).Error; err != nil {
+			return errors.New("access denied. Course not purchased")
 		}
-		if err := ms.db.Create(&progress).Error; err != nil {
-			return nil, err
+
+		// Create or update user module progress
+		var progress models.UserModuleProgress
+		err := tx.Where("user_id = ? AND module_id = ?", userID, moduleID).First(&progress).Error
+		if err == gorm.ErrRecordNotFound {
+			// Create new progress record
+			progress = models.UserModuleProgress{
+				UserID:      userID,
+				ModuleID:    moduleID,
+				IsCompleted: true,
+			}
+			return tx.Create(&progress).Error
+		} else if err != nil {
+			return err
 		}
-	} else if err != nil {
-		return nil, err
-	} else {
+
 		// Update existing record
 		progress.IsCompleted = true
-		if err := ms.db.Save(&progress).Error; err != nil {
-			return nil, err
-		}
+		return tx.Save(&progress).Error
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Calculate course progress
@@ -295,7 +495,7 @@ func (ms *ModuleService) CompleteModule(moduleID, userID string) (map[string]int
 	if percentage >= 100 {
 		certificateURL, err := ms.generateCertificate(userID, module.CourseID)
 		if err == nil {
-			result["certificate_url"] = certificateURL
+			result["certificate_url"] = ms.media.SignedURL(certificateURL)
 		}
 	}
 
@@ -303,8 +503,14 @@ func (ms *ModuleService) CompleteModule(moduleID, userID string) (map[string]int
 }
 
 func (ms *ModuleService) CheckCourseAccess(userID, courseID string) (bool, error) {
+	return checkCourseAccess(ms.db, userID, courseID)
+}
+
+// checkCourseAccess reports whether a user is enrolled in a course. Module pages
+// and the /media routes both go through it.
+func checkCourseAccess(db *gorm.DB, userID, courseID string) (bool, error) {
 	var userCourse models.UserCourse
-	err := ms.db.Where("user_id = ? AND course_id = ?", userID, courseID).First(&userCourse).Error
+	err := db.Where("user_id = ? AND course_id = ?", userID, courseID).First(&userCourse).Error
 	if err == gorm.ErrRecordNotFound {
 		return false, nil
 	}
@@ -314,176 +520,15 @@ func (ms *ModuleService) CheckCourseAccess(userID, courseID string) (bool, error
 	return true, nil
 }
 
+// SavePDF and SaveVideo store module files; Store checks their content and size
 func (ms *ModuleService) SavePDF(file *multipart.FileHeader) (string, error) {
 	log.Printf("SavePDF: Starting to save PDF file: %s (size: %d bytes)", file.Filename, file.Size)
-
-	// Try Cloudinary first if configured
-	if ms.cloudinaryService != nil {
-		log.Printf("SavePDF: Using Cloudinary service")
-		return ms.cloudinaryService.UploadPDF(file)
-	}
-
-	// Fall back to local storage
-	log.Printf("SavePDF: Using local storage")
-	return ms.savePDFLocally(file)
-}
-
-func (ms *ModuleService) savePDFLocally(file *multipart.FileHeader) (string, error) {
-	log.Printf("savePDFLocally: Starting to save PDF file: %s (size: %d bytes)", file.Filename, file.Size)
-
-	// Validate file type
-	contentType := file.Header.Get("Content-Type")
-	log.Printf("savePDFLocally: File content type: %s", contentType)
-	if contentType != "application/pdf" {
-		log.Printf("savePDFLocally: Invalid file type: %s", contentType)
-		return "", errors.New("invalid file type: only PDF files are allowed")
-	}
-
-	// Validate file size (10MB limit)
-	if file.Size > 10*1024*1024 {
-		log.Printf("savePDFLocally: File too large: %d bytes", file.Size)
-		return "", errors.New("file size too large: maximum 10MB allowed for PDF files")
-	}
-
-	// Create uploads directory if it doesn't exist
-	uploadDir := "./uploads/pdfs"
-	log.Printf("savePDFLocally: Creating upload directory: %s", uploadDir)
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		log.Printf("savePDFLocally: Failed to create upload directory: %v", err)
-		return "", fmt.Errorf("failed to create upload directory: %v", err)
-	}
-
-	// Generate unique filename
-	filename := fmt.Sprintf("%d_%s", time.Now().Unix(), file.Filename)
-	filePath := filepath.Join(uploadDir, filename)
-	log.Printf("savePDFLocally: Generated file path: %s", filePath)
-
-	// Open uploaded file
-	src, err := file.Open()
-	if err != nil {
-		log.Printf("savePDFLocally: Failed to open uploaded file: %v", err)
-		return "", fmt.Errorf("failed to open uploaded file: %v", err)
-	}
-	defer src.Close()
-
-	// Create destination file
-	dst, err := os.Create(filePath)
-	if err != nil {
-		log.Printf("savePDFLocally: Failed to create destination file: %v", err)
-		return "", fmt.Errorf("failed to create destination file: %v", err)
-	}
-	defer dst.Close()
-
-	// Copy file content
-	bytesWritten, err := io.Copy(dst, src)
-	if err != nil {
-		// Clean up created file on error
-		os.Remove(filePath)
-		log.Printf("savePDFLocally: Failed to copy file content: %v", err)
-		return "", fmt.Errorf("failed to save file: %v", err)
-	}
-
-	log.Printf("savePDFLocally: Successfully wrote %d bytes to %s", bytesWritten, filePath)
-
-	// Return complete URL including base URL from config
-	resultURL := fmt.Sprintf("%s/uploads/pdfs/%s", ms.config.BaseURL, filename)
-	log.Printf("savePDFLocally: Generated URL: %s", resultURL)
-	return resultURL, nil
+	return ms.media.Store(models.MediaKindPDF, "pdfs", file)
 }
 
 func (ms *ModuleService) SaveVideo(file *multipart.FileHeader) (string, error) {
 	log.Printf("SaveVideo: Starting to save video file: %s (size: %d bytes)", file.Filename, file.Size)
-
-	// Try Cloudinary first if configured
-	if ms.cloudinaryService != nil {
-		log.Printf("SaveVideo: Using Cloudinary service")
-		return ms.cloudinaryService.UploadVideo(file)
-	}
-
-	// Fall back to local storage
-	log.Printf("SaveVideo: Using local storage")
-	return ms.saveVideoLocally(file)
-}
-
-func (ms *ModuleService) saveVideoLocally(file *multipart.FileHeader) (string, error) {
-	log.Printf("saveVideoLocally: Starting to save video file: %s (size: %d bytes)", file.Filename, file.Size)
-
-	// Validate file type (basic check)
-	contentType := file.Header.Get("Content-Type")
-	log.Printf("saveVideoLocally: File content type: %s", contentType)
-	validVideoTypes := []string{
-		"video/mp4",
-		"video/avi",
-		"video/mov",
-		"video/quicktime",
-		"video/x-msvideo",
-		"video/webm",
-		"video/ogg",
-	}
-
-	isValidType := false
-	for _, validType := range validVideoTypes {
-		if contentType == validType {
-			isValidType = true
-			break
-		}
-	}
-
-	if !isValidType {
-		log.Printf("saveVideoLocally: Invalid file type: %s", contentType)
-		return "", errors.New("invalid file type: only video files (MP4, AVI, MOV, WebM, OGG) are allowed")
-	}
-
-	// Validate file size (100MB limit)
-	if file.Size > 100*1024*1024 {
-		log.Printf("saveVideoLocally: File too large: %d bytes", file.Size)
-		return "", errors.New("file size too large: maximum 100MB allowed for video files")
-	}
-
-	// Create uploads directory if it doesn't exist
-	uploadDir := "./uploads/videos"
-	log.Printf("saveVideoLocally: Creating upload directory: %s", uploadDir)
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		log.Printf("saveVideoLocally: Failed to create upload directory: %v", err)
-		return "", fmt.Errorf("failed to create upload directory: %v", err)
-	}
-
-	// Generate unique filename
-	filename := fmt.Sprintf("%d_%s", time.Now().Unix(), file.Filename)
-	filePath := filepath.Join(uploadDir, filename)
-	log.Printf("saveVideoLocally: Generated file path: %s", filePath)
-
-	// Open uploaded file
-	src, err := file.Open()
-	if err != nil {
-		log.Printf("saveVideoLocally: Failed to open uploaded file: %v", err)
-		return "", fmt.Errorf("failed to open uploaded file: %v", err)
-	}
-	defer src.Close()
-
-	// Create destination file
-	dst, err := os.Create(filePath)
-	if err != nil {
-		log.Printf("saveVideoLocally: Failed to create destination file: %v", err)
-		return "", fmt.Errorf("failed to create destination file: %v", err)
-	}
-	defer dst.Close()
-
-	// Copy file content
-	bytesWritten, err := io.Copy(dst, src)
-	if err != nil {
-		// Clean up created file on error
-		os.Remove(filePath)
-		log.Printf("saveVideoLocally: Failed to copy file content: %v", err)
-		return "", fmt.Errorf("failed to save file: %v", err)
-	}
-
-	log.Printf("saveVideoLocally: Successfully wrote %d bytes to %s", bytesWritten, filePath)
-
-	// Return complete URL including base URL from config
-	resultURL := fmt.Sprintf("%s/uploads/videos/%s", ms.config.BaseURL, filename)
-	log.Printf("saveVideoLocally: Generated URL: %s", resultURL)
-	return resultURL, nil
+	return ms.media.Store(models.MediaKindVideo, "videos", file)
 }
 
 func (ms *ModuleService) generateCertificate(userID, courseID string) (string, error) {
@@ -495,13 +540,7 @@ func (ms *ModuleService) generateCertificate(userID, courseID string) (string, e
 		return "", err
 	}
 
-	if err := ms.db.First(&course, "id = ?", courseID).Error; err != nil {
-		return "", err
-	}
-
-	// Create certificates directory if it doesn't exist
-	uploadDir := "./uploads/certificates"
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
+	if err := ms.db.Preload("Instructor").First(&course, "id = ?", courseID).Error; err != nil {
 		return "", err
 	}
 
@@ -516,18 +555,16 @@ func (ms *ModuleService) generateCertificate(userID, courseID string) (string, e
 			"Date of Completion: %s\n",
 		user.FirstName, user.LastName, user.Username,
 		course.Title,
-		course.Instructor,
+		course.InstructorName(),
 		time.Now().Format("January 2, 2006"),
 	)
 
 	// Save certificate
-	filename := fmt.Sprintf("certificate_%s_%s_%d.txt", userID, courseID, time.Now().Unix())
-	filepath := filepath.Join(uploadDir, filename)
-
-	if err := os.WriteFile(filepath, []byte(certificateContent), 0644); err != nil {
+	key := fmt.Sprintf("certificates/certificate_%s_%s_%d.txt", userID, courseID, time.Now().Unix())
+	asset, err := ms.media.Put(models.MediaKindCertificate, key, strings.NewReader(certificateContent), int64(len(certificateContent)), "text/plain; charset=utf-8")
+	if err != nil {
 		return "", err
 	}
 
-	// Return relative URL
-	return fmt.Sprintf("/uploads/certificates/%s", filename), nil
+	return asset.URL, nil
 }
@@ -0,0 +1,444 @@
+package services
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"yonatan/labpro/config"
+	"yonatan/labpro/models"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// oidcKeyRefreshInterval bounds how often the provider's signing keys are
+// fetched again when a token names a key that is not cached
+const oidcKeyRefreshInterval = time.Minute
+
+var (
+	ErrOIDCDisabled         = errors.New("single sign-on is not configured")
+	ErrOIDCLoginFailed      = errors.New("single sign-on failed, please try again")
+	ErrOIDCEmailNotVerified = errors.New("your identity provider has not verified your email address")
+	ErrOIDCNoAccount        = errors.New("no account exists for this email address")
+)
+
+// OIDCAuthRequest is a started single sign-on login. The browser keeps State,
+// Nonce and Verifier until the provider redirects back to the callback.
+type OIDCAuthRequest struct {
+	URL      string
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+// OIDCService signs users in through an OpenID Connect provider using the
+// authorization code flow with PKCE. Users are matched by the provider's
+// subject, then by verified email, and created when neither matches.
+type OIDCService struct {
+	db     *gorm.DB
+	config *config.Config
+	client *http.Client
+
+	mu            sync.Mutex
+	provider      *oidcProvider
+	keys          map[string]*rsa.PublicKey
+	keysFetchedAt time.Time
+}
+
+type oidcProvider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcClaims struct {
+	Nonce             string      `json:"nonce"`
+	Email             string      `json:"email"`
+	EmailVerified     interface{} `json:"email_verified"`
+	Name              string      `json:"name"`
+	GivenName         string      `json:"given_name"`
+	FamilyName        string      `json:"family_name"`
+	PreferredUsername string      `json:"preferred_username"`
+	jwt.RegisteredClaims
+}
+
+func NewOIDCService(db *gorm.DB, cfg *config.Config) *OIDCService {
+	return &OIDCService{
+		db:     db,
+		config: cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Enabled reports whether a provider is configured
+func (oc *OIDCService) Enabled() bool {
+	return oc.config.OIDCIssuerURL != "" && oc.config.OIDCClientID != ""
+}
+
+// ProviderName is the label shown on the login page
+func (oc *OIDCService) ProviderName() string {
+	return oc.config.OIDCProviderName
+}
+
+// BeginLogin prepares the redirect to the provider's authorization endpoint
+func (oc *OIDCService) BeginLogin(ctx context.Context) (*OIDCAuthRequest, error) {
+	if !oc.Enabled() {
+		return nil, ErrOIDCDisabled
+	}
+
+	provider, err := oc.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Hex tokens are URL safe, and at 64 characters also a valid PKCE verifier
+	request := &OIDCAuthRequest{}
+	for _, value := range []*string{&request.State, &request.Nonce, &request.Verifier} {
+		if *value, err = randomToken(32); err != nil {
+			return nil, err
+		}
+	}
+
+	challenge := sha256.Sum256([]byte(request.Verifier))
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {oc.config.OIDCClientID},
+		"redirect_uri":          {oc.redirectURL()},
+		"scope":                 {oc.config.OIDCScopes},
+		"state":                 {request.State},
+		"nonce":                 {request.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(provider.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	request.URL = provider.AuthorizationEndpoint + separator + params.Encode()
+
+	return request, nil
+}
+
+// CompleteLogin redeems the authorization code, verifies the ID token against the
+// nonce of the login it belongs to and returns the matching user
+func (oc *OIDCService) CompleteLogin(ctx context.Context, code, verifier, nonce string) (*models.User, error) {
+	if !oc.Enabled() {
+		return nil, ErrOIDCDisabled
+	}
+
+	provider, err := oc.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	idToken, err := oc.exchangeCode(ctx, provider, code, verifier)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := oc.verifyIDToken(ctx, provider, idToken)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, ErrOIDCLoginFailed
+	}
+
+	return oc.linkUser(provider.Issuer, claims)
+}
+
+func (oc *OIDCService) redirectURL() string {
+	if oc.config.OIDCRedirectURL != "" {
+		return oc.config.OIDCRedirectURL
+	}
+	return strings.TrimRight(oc.config.BaseURL, "/") + "/auth/oidc/callback"
+}
+
+// discover loads the provider metadata once and keeps it for the life of the process
+func (oc *OIDCService) discover(ctx context.Context) (*oidcProvider, error) {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+
+	if oc.provider != nil {
+		return oc.provider, nil
+	}
+
+	issuer := strings.TrimRight(oc.config.OIDCIssuerURL, "/")
+	var provider oidcProvider
+	if err := oc.getJSON(ctx, issuer+"/.well-known/openid-configuration", &provider); err != nil {
+		return nil, err
+	}
+	if strings.TrimRight(provider.Issuer, "/") != issuer || provider.AuthorizationEndpoint == "" ||
+		provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc: invalid discovery document from %s", issuer)
+	}
+
+	oc.provider = &provider
+	return oc.provider, nil
+}
+
+func (oc *OIDCService) exchangeCode(ctx context.Context, provider *oidcProvider, code, verifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {oc.redirectURL()},
+		"client_id":     {oc.config.OIDCClientID},
+		"code_verifier": {verifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if oc.config.OIDCClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(oc.config.OIDCClientID), url.QueryEscape(oc.config.OIDCClientSecret))
+	}
+
+	resp, err := oc.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || resp.StatusCode != http.StatusOK || body.IDToken == "" {
+		return "", ErrOIDCLoginFailed
+	}
+
+	return body.IDToken, nil
+}
+
+func (oc *OIDCService) verifyIDToken(ctx context.Context, provider *oidcProvider, idToken string) (*oidcClaims, error) {
+	claims := &oidcClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return oc.signingKey(ctx, provider, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(provider.Issuer),
+		jwt.WithAudience(oc.config.OIDCClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil || claims.Subject == "" {
+		return nil, ErrOIDCLoginFailed
+	}
+
+	return claims, nil
+}
+
+// signingKey returns the provider key with the given id, fetching the key set
+// again when the provider may have rotated its keys
+func (oc *OIDCService) signingKey(ctx context.Context, provider *oidcProvider, kid string) (*rsa.PublicKey, error) {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+
+	if key := oc.cachedKey(kid); key != nil {
+		return key, nil
+	}
+	if time.Since(oc.keysFetchedAt) < oidcKeyRefreshInterval {
+		return nil, errors.New("oidc: unknown signing key")
+	}
+
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := oc.getJSON(ctx, provider.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+
+	oc.keys = map[string]*rsa.PublicKey{}
+	oc.keysFetchedAt = time.Now()
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+		e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		oc.keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+
+	if key := oc.cachedKey(kid); key != nil {
+		return key, nil
+	}
+	return nil, errors.New("oidc: unknown signing key")
+}
+
+// cachedKey looks a key up by id; tokens without a key id match a single key
+func (oc *OIDCService) cachedKey(kid string) *rsa.PublicKey {
+	if kid == "" && len(oc.keys) == 1 {
+		for _, key := range oc.keys {
+			return key
+		}
+	}
+	return oc.keys[kid]
+}
+
+// linkUser finds or creates the user for a verified ID token
+func (oc *OIDCService) linkUser(issuer string, claims *oidcClaims) (*models.User, error) {
+	var user models.User
+	now := time.Now()
+
+	err := oc.db.Transaction(func(tx *gorm.DB) error {
+		var identity models.UserIdentity
+		if err := tx.Where("issuer = ? AND subject = ?", issuer, claims.Subject).First(&identity).Error; err == nil {
+			if err := tx.First(&user, "id = ?", identity.UserID).Error; err != nil {
+				return err
+			}
+			return tx.Model(&identity).Updates(map[string]interface{}{"email": claims.Email, "last_login_at": now}).Error
+		}
+
+		// New identities are only trusted with an address the provider vouches for
+		email := strings.TrimSpace(claims.Email)
+		if email == "" || !claims.emailVerified() {
+			return ErrOIDCEmailNotVerified
+		}
+
+		if err := tx.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error; err != nil {
+			if oc.config.OIDCAutoProvision == "false" {
+				return ErrOIDCNoAccount
+			}
+			created, err := provisionOIDCUser(tx, email, claims)
+			if err != nil {
+				return err
+			}
+			user = *created
+		}
+
+		if user.EmailVerifiedAt == nil {
+			user.EmailVerifiedAt = &now
+			if err := tx.Model(&user).Update("email_verified_at", now).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(&models.UserIdentity{
+			UserID:      user.ID,
+			Issuer:      issuer,
+			Subject:     claims.Subject,
+			Email:       email,
+			LastLoginAt: &now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+var usernameDisallowed = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// provisionOIDCUser creates an account for a first-time single sign-on user. It
+// gets an unusable random password; a password can be set later through the
+// reset flow.
+func provisionOIDCUser(tx *gorm.DB, email string, claims *oidcClaims) (*models.User, error) {
+	base := claims.PreferredUsername
+	if base == "" {
+		base, _, _ = strings.Cut(email, "@")
+	}
+	base = strings.Trim(usernameDisallowed.ReplaceAllString(strings.ToLower(base), ""), ".-_")
+	if base == "" {
+		base = "user"
+	}
+
+	username := base
+	for i := 2; ; i++ {
+		var count int64
+		if err := tx.Model(&models.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			break
+		}
+		username = fmt.Sprintf("%s%d", base, i)
+	}
+
+	firstName, lastName := claims.GivenName, claims.FamilyName
+	if firstName == "" {
+		firstName, lastName, _ = strings.Cut(strings.TrimSpace(claims.Name), " ")
+	}
+	if firstName == "" {
+		firstName = username
+	}
+
+	password, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user := models.User{
+		FirstName:       firstName,
+		LastName:        lastName,
+		Username:        username,
+		Email:           email,
+		EmailVerifiedAt: &now,
+	}
+	if err := user.SetPassword(password); err != nil {
+		return nil, errors.New("failed to hash password")
+	}
+	if err := tx.Create(&user).Error; err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// emailVerified accepts both the boolean the spec asks for and the string some
+// providers send
+func (c *oidcClaims) emailVerified() bool {
+	switch verified := c.EmailVerified.(type) {
+	case bool:
+		return verified
+	case string:
+		return verified == "true"
+	}
+	return false
+}
+
+func (oc *OIDCService) getJSON(ctx context.Context, endpoint string, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := oc.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: %s returned %s", endpoint, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(target)
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"log"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -12,6 +14,24 @@ import (
 type RedisService struct {
 	client    *redis.Client
 	available bool
+
+	// counters backs the counter methods while Redis is unavailable or
+	// failing, so rate limits keep working on a single instance
+	mu        sync.Mutex
+	counters  map[string]memoryCounter
+	lastSweep time.Time
+}
+
+const (
+	// maxMemoryCounters caps the in-memory counters; each is a few dozen bytes
+	maxMemoryCounters = 100_000
+	// memoryCounterSweepInterval is how often expired counters are dropped
+	memoryCounterSweepInterval = time.Minute
+)
+
+type memoryCounter struct {
+	value     int64
+	expiresAt time.Time
 }
 
 func NewRedisService(redisAddr, redisPassword string) *RedisService {
@@ -127,6 +147,163 @@ func (rs *RedisService) DeletePattern(ctx context.Context, pattern string) error
 	return nil
 }
 
+// IncrementCounter adds one to the counter at key and returns the new value. A new
+// counter expires after ttl; incrementing does not extend it. When Redis fails
+// the counter is kept in memory instead, so limits built on it still hold.
+func (rs *RedisService) IncrementCounter(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	if !rs.available {
+		return rs.incrementMemoryCounter(key, ttl), nil
+	}
+
+	value, err := rs.client.Incr(ctx, key).Result()
+	if err != nil {
+		log.Printf("Warning: Failed to increment counter %s, counting in memory: %v", key, err)
+		return rs.incrementMemoryCounter(key, ttl), nil
+	}
+	if value == 1 {
+		rs.client.Expire(ctx, key, ttl)
+	}
+	return value, nil
+}
+
+// SetCounter stores value at key for ttl, replacing any existing counter
+func (rs *RedisService) SetCounter(ctx context.Context, key string, value int64, ttl time.Duration) error {
+	if !rs.available {
+		rs.setMemoryCounter(key, value, ttl)
+		return nil
+	}
+
+	if err := rs.client.Set(ctx, key, value, ttl).Err(); err != nil {
+		log.Printf("Warning: Failed to set counter %s, keeping it in memory: %v", key, err)
+		rs.setMemoryCounter(key, value, ttl)
+	}
+	return nil
+}
+
+// GetCounter returns the value of the counter at key and the time until it
+// expires. Missing counters read as zero.
+func (rs *RedisService) GetCounter(ctx context.Context, key string) (int64, time.Duration, error) {
+	if !rs.available {
+		value, ttl := rs.getMemoryCounter(key)
+		return value, ttl, nil
+	}
+
+	pipe := rs.client.Pipeline()
+	get := pipe.Get(ctx, key)
+	ttl := pipe.PTTL(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		log.Printf("Warning: Failed to get counter %s, reading it from memory: %v", key, err)
+		value, ttl := rs.getMemoryCounter(key)
+		return value, ttl, nil
+	}
+	if get.Err() == redis.Nil {
+		// Counted in memory while Redis was failing
+		value, ttl := rs.getMemoryCounter(key)
+		return value, ttl, nil
+	}
+
+	value, err := strconv.ParseInt(get.Val(), 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return value, ttl.Val(), nil
+}
+
+// DeleteCounters removes the counters at the given keys
+func (rs *RedisService) DeleteCounters(ctx context.Context, keys ...string) error {
+	// Counters may have been kept in memory while Redis was failing
+	rs.mu.Lock()
+	for _, key := range keys {
+		delete(rs.counters, key)
+	}
+	rs.mu.Unlock()
+
+	if !rs.available {
+		return nil
+	}
+
+	if err := rs.client.Del(ctx, keys...).Err(); err != nil {
+		log.Printf("Warning: Failed to delete counters: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (rs *RedisService) incrementMemoryCounter(key string, ttl time.Duration) int64 {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	counter, ok := rs.memoryCounter(key)
+	if !ok {
+		counter = memoryCounter{expiresAt: time.Now().Add(ttl)}
+	}
+	counter.value++
+	rs.storeMemoryCounter(key, counter)
+	return counter.value
+}
+
+func (rs *RedisService) setMemoryCounter(key string, value int64, ttl time.Duration) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.storeMemoryCounter(key, memoryCounter{value: value, expiresAt: time.Now().Add(ttl)})
+}
+
+func (rs *RedisService) getMemoryCounter(key string) (int64, time.Duration) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	counter, ok := rs.memoryCounter(key)
+	if !ok {
+		return 0, 0
+	}
+	return counter.value, time.Until(counter.expiresAt)
+}
+
+// memoryCounter returns the unexpired in-memory counter at key, dropping it once
+// it has expired. The caller must hold rs.mu.
+func (rs *RedisService) memoryCounter(key string) (memoryCounter, bool) {
+	counter, ok := rs.counters[key]
+	if ok && !time.Now().Before(counter.expiresAt) {
+		delete(rs.counters, key)
+		return memoryCounter{}, false
+	}
+	return counter, ok
+}
+
+// storeMemoryCounter saves a counter, sweeping out expired ones every
+// memoryCounterSweepInterval. Once maxMemoryCounters are live the counter
+// closest to expiring makes room, so a flood of distinct keys cannot exhaust
+// memory. The caller must hold rs.mu.
+func (rs *RedisService) storeMemoryCounter(key string, counter memoryCounter) {
+	if rs.counters == nil {
+		rs.counters = make(map[string]memoryCounter)
+	}
+
+	now := time.Now()
+	if _, exists := rs.counters[key]; !exists {
+		if now.Sub(rs.lastSweep) >= memoryCounterSweepInterval || len(rs.counters) >= maxMemoryCounters {
+			for existing, c := range rs.counters {
+				if !now.Before(c.expiresAt) {
+					delete(rs.counters, existing)
+				}
+			}
+			rs.lastSweep = now
+		}
+		if len(rs.counters) >= maxMemoryCounters {
+			var oldest string
+			var oldestExpiry time.Time
+			for existing, c := range rs.counters {
+				if oldestExpiry.IsZero() || c.expiresAt.Before(oldestExpiry) {
+					oldest, oldestExpiry = existing, c.expiresAt
+				}
+			}
+			delete(rs.counters, oldest)
+		}
+	}
+	rs.counters[key] = counter
+}
+
 // Close closes the Redis connection
 func (rs *RedisService) Close() error {
 	if rs.client != nil {
@@ -0,0 +1,328 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"yonatan/labpro/config"
+	"yonatan/labpro/models"
+	"yonatan/labpro/storage"
+
+	"gorm.io/gorm"
+)
+
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// courseContent and moduleContent are the fields that revisions keep. Everything
+// else, such as thumbnails, ordering and the publishing state, is left out.
+type courseContent struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Price       float64  `json:"price"`
+	Topics      []string `json:"topics"`
+}
+
+type moduleContent struct {
+	Title        string  `json:"title"`
+	Description  string  `json:"description"`
+	PDFContent   *string `json:"pdf_content"`
+	VideoContent *string `json:"video_content"`
+}
+
+// revisionFields lists the versioned fields of each target type in the order
+// diffs present them
+var revisionFields = map[string][]string{
+	models.RevisionTargetCourse: {"title", "description", "price", "topics"},
+	models.RevisionTargetModule: {"title", "description", "pdf_content", "video_content"},
+}
+
+func courseRevisionContent(course models.Course) courseContent {
+	return courseContent{
+		Title:       course.Title,
+		Description: course.Description,
+		Price:       course.Price,
+		Topics:      course.Topics,
+	}
+}
+
+func moduleRevisionContent(module models.Module) moduleContent {
+	return moduleContent{
+		Title:        module.Title,
+		Description:  module.Description,
+		PDFContent:   module.PDFContent,
+		VideoContent: module.VideoContent,
+	}
+}
+
+// RevisionService lists, compares and restores the revisions of courses and modules
+type RevisionService struct {
+	db           *gorm.DB
+	config       *config.Config
+	redisService *RedisService
+	media        *MediaService
+}
+
+func NewRevisionService(db *gorm.DB, cfg *config.Config, redisService *RedisService, fileStorage storage.Backend) *RevisionService {
+	return &RevisionService{
+		db:           db,
+		config:       cfg,
+		redisService: redisService,
+		media:        NewMediaService(db, cfg, fileStorage),
+	}
+}
+
+// recordRevision stores after as the target's next revision unless it matches the
+// latest one. Targets edited before revisions were kept get before recorded as
+// their first revision, so the original content can still be restored.
+func recordRevision(tx *gorm.DB, actor AuditActor, targetType, targetID string, before, after interface{}, restoredFrom *int) error {
+	var latest models.ContentRevision
+	if err := tx.Where("target_type = ? AND target_id = ?", targetType, targetID).Order("number DESC").Limit(1).Find(&latest).Error; err != nil {
+		return err
+	}
+
+	if latest.ID == "" && before != nil {
+		initial, err := json.Marshal(before)
+		if err != nil {
+			return err
+		}
+		latest = models.ContentRevision{
+			TargetType: targetType,
+			TargetID:   targetID,
+			Number:     1,
+			Content:    string(initial),
+		}
+		if err := tx.Create(&latest).Error; err != nil {
+			return err
+		}
+	}
+
+	content, err := json.Marshal(after)
+	if err != nil {
+		return err
+	}
+	if latest.ID != "" && sameRevisionContent(latest.Content, string(content)) {
+		return nil
+	}
+
+	revision := models.ContentRevision{
+		TargetType:   targetType,
+		TargetID:     targetID,
+		Number:       latest.Number + 1,
+		Content:      string(content),
+		RestoredFrom: restoredFrom,
+		ActorName:    actor.Username,
+	}
+	if actor.UserID != "" {
+		revision.ActorID = &actor.UserID
+	}
+	return tx.Create(&revision).Error
+}
+
+// sameRevisionContent compares two snapshots by value, since the database does
+// not keep the JSON exactly as it was written
+func sameRevisionContent(a, b string) bool {
+	var left, right map[string]interface{}
+	if json.Unmarshal([]byte(a), &left) != nil || json.Unmarshal([]byte(b), &right) != nil {
+		return false
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+// CanEditTarget reports whether the user may see and restore the revisions of a
+// course or module, which follows who may edit it
+func (rs *RevisionService) CanEditTarget(user models.User, targetType, targetID string) (bool, error) {
+	if targetType == models.RevisionTargetModule {
+		return canEditModule(rs.db, user, targetID)
+	}
+	return canEditCourse(rs.db, user, targetID)
+}
+
+// GetRevisions lists the revisions of a course or module, newest first
+func (rs *RevisionService) GetRevisions(targetType, targetID string, page, limit int) ([]map[string]interface{}, map[string]interface{}, error) {
+	var revisions []models.ContentRevision
+	var total int64
+
+	db := rs.db.Model(&models.ContentRevision{}).Where("target_type = ? AND target_id = ?", targetType, targetID)
+
+	// Count total
+	db.Count(&total)
+
+	// Apply pagination
+	offset := (page - 1) * limit
+	if err := db.Order("number DESC").Offset(offset).Limit(limit).Find(&revisions).Error; err != nil {
+		return nil, nil, err
+	}
+
+	result := make([]map[string]interface{}, len(revisions))
+	for i, revision := range revisions {
+		result[i] = revisionResponse(revision)
+	}
+
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+	pagination := map[string]interface{}{
+		"current_page": page,
+		"total_pages":  totalPages,
+		"total_items":  total,
+	}
+
+	return result, pagination, nil
+}
+
+func revisionResponse(revision models.ContentRevision) map[string]interface{} {
+	return map[string]interface{}{
+		"id":            revision.ID,
+		"number":        revision.Number,
+		"content":       json.RawMessage(revision.Content),
+		"restored_from": revision.RestoredFrom,
+		"actor_id":      revision.ActorID,
+		"actor_name":    revision.ActorName,
+		"created_at":    revision.CreatedAt,
+	}
+}
+
+func (rs *RevisionService) findRevision(db *gorm.DB, targetType, targetID string, number int) (models.ContentRevision, error) {
+	var revision models.ContentRevision
+	err := db.Where("target_type = ? AND target_id = ? AND number = ?", targetType, targetID, number).First(&revision).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return revision, ErrRevisionNotFound
+	}
+	return revision, err
+}
+
+// GetRevisionDiff compares a revision field by field with an earlier one, by
+// default the revision right before it. Only changed fields are listed; the
+// first revision is compared with nothing, so all its fields count as changed.
+func (rs *RevisionService) GetRevisionDiff(targetType, targetID string, number, against int) (map[string]interface{}, error) {
+	revision, err := rs.findRevision(rs.db, targetType, targetID, number)
+	if err != nil {
+		return nil, err
+	}
+	if against == 0 {
+		against = number - 1
+	}
+
+	previous := map[string]interface{}{}
+	var againstNumber interface{}
+	if against > 0 {
+		base, err := rs.findRevision(rs.db, targetType, targetID, against)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(base.Content), &previous); err != nil {
+			return nil, err
+		}
+		againstNumber = base.Number
+	}
+
+	current := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(revision.Content), &current); err != nil {
+		return nil, err
+	}
+
+	changes := []map[string]interface{}{}
+	for _, field := range revisionFields[targetType] {
+		if reflect.DeepEqual(previous[field], current[field]) {
+			continue
+		}
+		changes = append(changes, map[string]interface{}{
+			"field":  field,
+			"before": previous[field],
+			"after":  current[field],
+		})
+	}
+
+	return map[string]interface{}{
+		"revision": revisionResponse(revision),
+		"against":  againstNumber,
+		"changes":  changes,
+	}, nil
+}
+
+// RollbackCourse restores a course's content to a revision. The rollback is
+// itself recorded as a new revision, so it can be undone the same way.
+func (rs *RevisionService) RollbackCourse(courseID string, number int, actor AuditActor) (*models.Course, error) {
+	var course models.Course
+	err := rs.db.Transaction(func(tx *gorm.DB) error {
+		revision, err := rs.findRevision(tx, models.RevisionTargetCourse, courseID, number)
+		if err != nil {
+			return err
+		}
+		var content courseContent
+		if err := json.Unmarshal([]byte(revision.Content), &content); err != nil {
+			return err
+		}
+
+		if err := tx.First(&course, "id = ?", courseID).Error; err != nil {
+			return err
+		}
+		before := course
+
+		course.Title = content.Title
+		course.Description = content.Description
+		course.Price = content.Price
+		course.Topics = content.Topics
+		if err := tx.Model(&course).Select("Title", "Description", "Price", "Topics").Updates(&course).Error; err != nil {
+			return err
+		}
+
+		if err := recordAudit(tx, actor, models.AuditCourseRollback, "course", courseID, before, auditWith(course, "revision", number)); err != nil {
+			return err
+		}
+		return recordRevision(tx, actor, models.RevisionTargetCourse, courseID, courseRevisionContent(before), courseRevisionContent(course), &number)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if rs.redisService != nil {
+		rs.redisService.DeletePattern(context.Background(), "courses:*")
+	}
+	return &course, nil
+}
+
+// RollbackModule restores a module's content, including its PDF and video, to a
+// revision. Like course rollbacks it is recorded as a new revision.
+func (rs *RevisionService) RollbackModule(moduleID string, number int, actor AuditActor) (*models.Module, error) {
+	var module models.Module
+	var droppedStream string
+	err := rs.db.Transaction(func(tx *gorm.DB) error {
+		revision, err := rs.findRevision(tx, models.RevisionTargetModule, moduleID, number)
+		if err != nil {
+			return err
+		}
+		var content moduleContent
+		if err := json.Unmarshal([]byte(revision.Content), &content); err != nil {
+			return err
+		}
+
+		if err := tx.First(&module, "id = ?", moduleID).Error; err != nil {
+			return err
+		}
+		before := module
+
+		module.Title = content.Title
+		module.Description = content.Description
+		module.PDFContent = content.PDFContent
+		module.VideoContent = content.VideoContent
+		columns := []string{"Title", "Description", "PDFContent", "VideoContent"}
+		if optionalURL(before.VideoContent) != optionalURL(module.VideoContent) {
+			droppedStream = queueTranscode(&module, rs.config)
+			columns = append(columns, videoJobColumns...)
+		}
+		if err := tx.Model(&module).Select(columns).Updates(&module).Error; err != nil {
+			return err
+		}
+
+		if err := recordAudit(tx, actor, models.AuditModuleRollback, "module", moduleID, before, auditWith(module, "revision", number)); err != nil {
+			return err
+		}
+		return recordRevision(tx, actor, models.RevisionTargetModule, moduleID, moduleRevisionContent(before), moduleRevisionContent(module), &number)
+	})
+	if err != nil {
+		return nil, err
+	}
+	rs.media.ReleaseStream(droppedStream)
+
+	return &module, nil
+}
@@ -0,0 +1,262 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"yonatan/labpro/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrUserNotFound    = errors.New("user not found")
+	ErrRoleNotFound    = errors.New("role not found")
+	ErrCourseNotOwned  = errors.New("you can only edit courses you own")
+	ErrRoleNotAssigned = errors.New("user does not have this role")
+)
+
+type RoleService struct {
+	db *gorm.DB
+}
+
+func NewRoleService(db *gorm.DB) *RoleService {
+	return &RoleService{db: db}
+}
+
+// GetRoles lists every role with its permissions
+func (rs *RoleService) GetRoles() ([]models.Role, error) {
+	var roles []models.Role
+	if err := rs.db.Preload("Permissions").Order("name").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// GetUserRoles lists the roles assigned to a user
+func (rs *RoleService) GetUserRoles(userID string) ([]models.Role, error) {
+	var user models.User
+	if err := rs.db.Preload("Roles.Permissions").First(&user, "id = ?", userID).Error; err != nil {
+		return nil, ErrUserNotFound
+	}
+	return user.Roles, nil
+}
+
+// AssignRole grants a role to a user. The admin role also sets IsAdmin so the two
+// never disagree.
+func (rs *RoleService) AssignRole(userID, roleName string, actor AuditActor) error {
+	return rs.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Preload("Roles").First(&user, "id = ?", userID).Error; err != nil {
+			return ErrUserNotFound
+		}
+
+		var role models.Role
+		if err := tx.Where("name = ?", roleName).First(&role).Error; err != nil {
+			return ErrRoleNotFound
+		}
+
+		before := roleNames(user.Roles)
+		if err := tx.Model(&user).Association("Roles").Append(&role); err != nil {
+			return err
+		}
+
+		if role.Name == models.RoleAdmin && !user.IsAdmin {
+			if err := tx.Model(&user).Update("is_admin", true).Error; err != nil {
+				return err
+			}
+		}
+
+		after := before
+		if !slices.Contains(before, role.Name) {
+			after = append(slices.Clone(before), role.Name)
+		}
+		return recordAudit(tx, actor, models.AuditUserAssignRole, "user", user.ID,
+			map[string]interface{}{"roles": before}, map[string]interface{}{"roles": after})
+	})
+}
+
+// RemoveRole takes a role away from a user. Removing the admin role is refused when
+// it would leave no admin behind.
+func (rs *RoleService) RemoveRole(userID, roleName string, actor AuditActor) error {
+	return rs.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Preload("Roles").First(&user, "id = ?", userID).Error; err != nil {
+			return ErrUserNotFound
+		}
+
+		var role models.Role
+		if err := tx.Where("name = ?", roleName).First(&role).Error; err != nil {
+			return ErrRoleNotFound
+		}
+
+		assigned := false
+		for _, r := range user.Roles {
+			if r.ID == role.ID {
+				assigned = true
+			}
+		}
+		isAdminRole := role.Name == models.RoleAdmin && user.IsAdmin
+		if !assigned && !isAdminRole {
+			return ErrRoleNotAssigned
+		}
+
+		if isAdminRole {
+			var admins []models.User
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("is_admin = ?", true).Find(&admins).Error; err != nil {
+				return err
+			}
+			if len(admins) <= 1 {
+				return ErrLastAdmin
+			}
+			if err := tx.Model(&user).Update("is_admin", false).Error; err != nil {
+				return err
+			}
+		}
+
+		before := roleNames(user.Roles)
+		if err := tx.Model(&user).Association("Roles").Delete(&role); err != nil {
+			return err
+		}
+
+		after := slices.DeleteFunc(slices.Clone(before), func(name string) bool { return name == role.Name })
+		return recordAudit(tx, actor, models.AuditUserRemoveRole, "user", user.ID,
+			map[string]interface{}{"roles": before}, map[string]interface{}{"roles": after})
+	})
+}
+
+// roleNames lists the names of roles for the audit log
+func roleNames(roles []models.Role) []string {
+	names := make([]string, len(roles))
+	for i, role := range roles {
+		names[i] = role.Name
+	}
+	return names
+}
+
+// GetUserPermissions lists the permission names a user holds through their roles
+func (rs *RoleService) GetUserPermissions(user models.User) ([]string, error) {
+	if user.IsAdmin {
+		var names []string
+		err := rs.db.Model(&models.Permission{}).Order("name").Pluck("name", &names).Error
+		return names, err
+	}
+
+	var names []string
+	err := rs.db.Model(&models.Permission{}).
+		Distinct("permissions.name").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN user_roles ON user_roles.role_id = role_permissions.role_id").
+		Where("user_roles.user_id = ?", user.ID).
+		Order("permissions.name").
+		Pluck("permissions.name", &names).Error
+	return names, err
+}
+
+// HasPermission reports whether a user holds a permission. Admins hold all of them.
+func (rs *RoleService) HasPermission(user models.User, permission string) bool {
+	return userHasPermission(rs.db, user, permission)
+}
+
+// HasAnyPermission reports whether a user holds at least one permission, which is
+// what gives access to the admin area
+func (rs *RoleService) HasAnyPermission(user models.User) bool {
+	if user.IsAdmin {
+		return true
+	}
+
+	var count int64
+	rs.db.Table("user_roles").
+		Joins("JOIN role_permissions ON role_permissions.role_id = user_roles.role_id").
+		Where("user_roles.user_id = ?", user.ID).
+		Count(&count)
+	return count > 0
+}
+
+// SetRequireMFA turns mandatory two-factor authentication on or off for a role
+func (rs *RoleService) SetRequireMFA(roleName string, required bool, actor AuditActor) (*models.Role, error) {
+	var role models.Role
+	if err := rs.db.Where("name = ?", roleName).First(&role).Error; err != nil {
+		return nil, ErrRoleNotFound
+	}
+	previous := role.RequireMFA
+
+	err := rs.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&role).Update("require_mfa", required).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, models.AuditRoleRequireMFA, "role", role.Name,
+			map[string]interface{}{"require_mfa": previous}, map[string]interface{}{"require_mfa": required})
+	})
+	if err != nil {
+		return nil, err
+	}
+	role.RequireMFA = required
+
+	return &role, nil
+}
+
+// RequiresMFA reports whether one of the user's roles makes two-factor
+// authentication mandatory. Admins count as holders of the admin role.
+func (rs *RoleService) RequiresMFA(user models.User) bool {
+	return userRequiresMFA(rs.db, user)
+}
+
+func userRequiresMFA(db *gorm.DB, user models.User) bool {
+	query := db.Model(&models.Role{}).Where("require_mfa = ?", true)
+	if user.IsAdmin {
+		query = query.Where("name = ? OR id IN (?)", models.RoleAdmin,
+			db.Table("user_roles").Select("role_id").Where("user_id = ?", user.ID))
+	} else {
+		query = query.Where("id IN (?)", db.Table("user_roles").Select("role_id").Where("user_id = ?", user.ID))
+	}
+
+	var count int64
+	err := query.Count(&count).Error
+	return err == nil && count > 0
+}
+
+func userHasPermission(db *gorm.DB, user models.User, permission string) bool {
+	if user.IsAdmin {
+		return true
+	}
+
+	var count int64
+	err := db.Table("user_roles").
+		Joins("JOIN role_permissions ON role_permissions.role_id = user_roles.role_id").
+		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id").
+		Where("user_roles.user_id = ? AND permissions.name = ?", user.ID, permission).
+		Count(&count).Error
+	return err == nil && count > 0
+}
+
+// canEditCourse applies the ownership rule: courses:write_any edits every course,
+// plain courses:write only the courses the user owns
+func canEditCourse(db *gorm.DB, user models.User, courseID string) (bool, error) {
+	if userHasPermission(db, user, models.PermissionCoursesWriteAny) {
+		return true, nil
+	}
+	if !userHasPermission(db, user, models.PermissionCoursesWrite) {
+		return false, nil
+	}
+
+	var count int64
+	if err := db.Model(&models.Course{}).Where("id = ? AND owner_id = ?", courseID, user.ID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// canEditModule applies the ownership rule of the module's course
+func canEditModule(db *gorm.DB, user models.User, moduleID string) (bool, error) {
+	if userHasPermission(db, user, models.PermissionCoursesWriteAny) {
+		return true, nil
+	}
+
+	var module models.Module
+	if err := db.Select("course_id").First(&module, "id = ?", moduleID).Error; err != nil {
+		return false, err
+	}
+	return canEditCourse(db, user, module.CourseID)
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"yonatan/labpro/models"
+
+	"gorm.io/gorm"
+)
+
+type StatsService struct {
+	db           *gorm.DB
+	redisService *RedisService
+}
+
+func NewStatsService(db *gorm.DB, redisService *RedisService) *StatsService {
+	return &StatsService{
+		db:           db,
+		redisService: redisService,
+	}
+}
+
+type DailyPurchases struct {
+	Date      string  `json:"date"`
+	Purchases int64   `json:"purchases"`
+	Revenue   float64 `json:"revenue"`
+}
+
+type CourseRevenue struct {
+	CourseID    string  `json:"course_id"`
+	Title       string  `json:"title"`
+	Enrollments int64   `json:"enrollments"`
+	Revenue     float64 `json:"revenue"`
+}
+
+// CompletionFunnel counts enrollments by how far the student got through the course
+type CompletionFunnel struct {
+	CourseID  string `json:"course_id,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Enrolled  int64  `json:"enrolled"`
+	Started   int64  `json:"started"`
+	Halfway   int64  `json:"halfway"`
+	Completed int64  `json:"completed"`
+}
+
+type DashboardStats struct {
+	TotalUsers       int64              `json:"total_users"`
+	TotalCourses     int64              `json:"total_courses"`
+	TotalRevenue     float64            `json:"total_revenue"`
+	ActiveStudents   int64              `json:"active_students"`
+	DailyPurchases   []DailyPurchases   `json:"daily_purchases"`
+	RevenuePerCourse []CourseRevenue    `json:"revenue_per_course"`
+	Funnel           CompletionFunnel   `json:"funnel"`
+	CourseFunnels    []CompletionFunnel `json:"course_funnels"`
+	GeneratedAt      time.Time          `json:"generated_at"`
+}
+
+// GetDashboardStats computes platform metrics over the last `days` days of purchases.
+// Results are cached for a minute since every figure is an aggregate over whole tables.
+func (ss *StatsService) GetDashboardStats(days int) (*DashboardStats, error) {
+	cacheKey := fmt.Sprintf("stats:dashboard:%d", days)
+
+	// Try to get from cache first
+	if ss.redisService != nil {
+		var cached DashboardStats
+		if err := ss.redisService.Get(context.Background(), cacheKey, &cached); err == nil {
+			return &cached, nil
+		}
+	}
+
+	stats := &DashboardStats{GeneratedAt: time.Now()}
+
+	// Staff are admins and anyone holding a role that grants permissions, the same
+	// roles RequirePermission looks at
+	if err := ss.db.Model(&models.User{}).
+		Where("is_admin = ?", false).
+		Where(`NOT EXISTS (SELECT 1 FROM user_roles
+			JOIN role_permissions ON role_permissions.role_id = user_roles.role_id
+			WHERE user_roles.user_id = users.id)`).
+		Count(&stats.TotalUsers).Error; err != nil {
+		return nil, err
+	}
+
+	if err := ss.db.Model(&models.Course{}).Count(&stats.TotalCourses).Error; err != nil {
+		return nil, err
+	}
+
+	if err := ss.salesLedger().
+		Select("COALESCE(-SUM(transactions.amount), 0)").
+		Scan(&stats.TotalRevenue).Error; err != nil {
+		return nil, err
+	}
+
+	if err := ss.db.Model(&models.UserCourse{}).
+		Distinct("user_id").
+		Count(&stats.ActiveStudents).Error; err != nil {
+		return nil, err
+	}
+
+	dailyPurchases, err := ss.getDailyPurchases(days)
+	if err != nil {
+		return nil, err
+	}
+	stats.DailyPurchases = dailyPurchases
+
+	revenuePerCourse, err := ss.getRevenuePerCourse()
+	if err != nil {
+		return nil, err
+	}
+	stats.RevenuePerCourse = revenuePerCourse
+
+	funnel, courseFunnels, err := ss.getCompletionFunnels()
+	if err != nil {
+		return nil, err
+	}
+	stats.Funnel = funnel
+	stats.CourseFunnels = courseFunnels
+
+	// Cache the result for 1 minute
+	if ss.redisService != nil {
+		ss.redisService.Set(context.Background(), cacheKey, stats, time.Minute)
+	}
+
+	return stats, nil
+}
+
+// salesLedger selects the purchases and refunds of courses that are not deleted.
+// Revenue is what was actually paid, net of refunds, so later price changes do
+// not rewrite it.
+func (ss *StatsService) salesLedger() *gorm.DB {
+	return ss.db.Model(&models.Transaction{}).
+		Joins("JOIN courses ON transactions.course_id = courses.id AND courses.deleted_at IS NULL").
+		Where("transactions.type IN ?", []string{models.TransactionTypePurchase, models.TransactionTypeRefund})
+}
+
+func (ss *StatsService) getDailyPurchases(days int) ([]DailyPurchases, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	since := today.AddDate(0, 0, -(days - 1))
+
+	var rows []struct {
+		Day       time.Time
+		Purchases int64
+		Revenue   float64
+	}
+	err := ss.salesLedger().
+		Select("DATE(transactions.created_at) AS day, COUNT(*) FILTER (WHERE transactions.type = ?) AS purchases, COALESCE(-SUM(transactions.amount), 0) AS revenue",
+			models.TransactionTypePurchase).
+		Where("transactions.created_at >= ?", since).
+		Group("day").
+		Order("day").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byDay := make(map[string]DailyPurchases, len(rows))
+	for _, row := range rows {
+		date := row.Day.Format("2006-01-02")
+		byDay[date] = DailyPurchases{Date: date, Purchases: row.Purchases, Revenue: row.Revenue}
+	}
+
+	// Fill in days without purchases so the series has no gaps
+	result := make([]DailyPurchases, 0, days)
+	for day := since; !day.After(today); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		entry, ok := byDay[date]
+		if !ok {
+			entry = DailyPurchases{Date: date}
+		}
+		result = append(result, entry)
+	}
+
+	return result, nil
+}
+
+func (ss *StatsService) getRevenuePerCourse() ([]CourseRevenue, error) {
+	var result []CourseRevenue
+	err := ss.db.Model(&models.Course{}).
+		Select(`courses.id AS course_id, courses.title,
+			(SELECT COUNT(*) FROM user_courses WHERE user_courses.course_id = courses.id
+				AND user_courses.deleted_at IS NULL) AS enrollments,
+			(SELECT COALESCE(-SUM(transactions.amount), 0) FROM transactions
+				WHERE transactions.course_id = courses.id AND transactions.type IN ?) AS revenue`,
+			[]string{models.TransactionTypePurchase, models.TransactionTypeRefund}).
+		Order("revenue DESC, courses.title").
+		Scan(&result).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (ss *StatsService) getCompletionFunnels() (CompletionFunnel, []CompletionFunnel, error) {
+	var overall CompletionFunnel
+
+	var rows []struct {
+		CourseID     string
+		Title        string
+		TotalModules int64
+		Completed    int64
+	}
+	err := ss.db.Table("user_courses").
+		Select(`user_courses.course_id, courses.title,
+			(SELECT COUNT(*) FROM modules WHERE modules.course_id = user_courses.course_id
+				AND modules.deleted_at IS NULL) AS total_modules,
+			(SELECT COUNT(*) FROM user_module_progresses
+				JOIN modules ON user_module_progresses.module_id = modules.id
+				WHERE modules.course_id = user_courses.course_id
+				AND user_module_progresses.user_id = user_courses.user_id
+				AND user_module_progresses.is_completed = true
+				AND user_module_progresses.deleted_at IS NULL
+				AND modules.deleted_at IS NULL) AS completed`).
+		Joins("JOIN courses ON user_courses.course_id = courses.id AND courses.deleted_at IS NULL").
+		Where("user_courses.deleted_at IS NULL").
+		Order("courses.title").
+		Scan(&rows).Error
+	if err != nil {
+		return overall, nil, err
+	}
+
+	var order []string
+	byCourse := make(map[string]*CompletionFunnel)
+	for _, row := range rows {
+		funnel, ok := byCourse[row.CourseID]
+		if !ok {
+			funnel = &CompletionFunnel{CourseID: row.CourseID, Title: row.Title}
+			byCourse[row.CourseID] = funnel
+			order = append(order, row.CourseID)
+		}
+
+		for _, f := range []*CompletionFunnel{funnel, &overall} {
+			f.Enrolled++
+			if row.Completed > 0 {
+				f.Started++
+			}
+			if row.TotalModules > 0 && row.Completed*2 >= row.TotalModules {
+				f.Halfway++
+			}
+			if row.TotalModules > 0 && row.Completed >= row.TotalModules {
+				f.Completed++
+			}
+		}
+	}
+
+	courseFunnels := make([]CompletionFunnel, 0, len(order))
+	for _, courseID := range order {
+		courseFunnels = append(courseFunnels, *byCourse[courseID])
+	}
+
+	return overall, courseFunnels, nil
+}
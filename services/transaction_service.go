@@ -0,0 +1,212 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"time"
+	"yonatan/labpro/models"
+
+	"gorm.io/gorm"
+)
+
+type TransactionService struct {
+	db *gorm.DB
+}
+
+func NewTransactionService(db *gorm.DB) *TransactionService {
+	return &TransactionService{db: db}
+}
+
+// TransactionFilter narrows a ledger listing. Empty fields are ignored.
+type TransactionFilter struct {
+	UserID   string
+	Type     string
+	CourseID string
+	From     *time.Time
+	To       *time.Time
+}
+
+// NewTransactionFilter builds a filter from raw query values. Dates are whole days
+// in YYYY-MM-DD form, so "to" includes every transaction made on that day.
+func NewTransactionFilter(userID, txType, courseID, from, to string) (TransactionFilter, error) {
+	filter := TransactionFilter{
+		UserID:   userID,
+		Type:     txType,
+		CourseID: courseID,
+	}
+
+	var err error
+	if filter.From, filter.To, err = parseFilterDays(from, to); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// parseFilterDays turns a from/to pair of YYYY-MM-DD days into a half-open range
+// whose end is the start of the day after to. Empty values leave that end open.
+func parseFilterDays(from, to string) (*time.Time, *time.Time, error) {
+	var start, end *time.Time
+
+	if from != "" {
+		day, err := time.ParseInLocation("2006-01-02", from, time.Local)
+		if err != nil {
+			return nil, nil, errors.New("invalid from date, expected YYYY-MM-DD")
+		}
+		start = &day
+	}
+
+	if to != "" {
+		day, err := time.ParseInLocation("2006-01-02", to, time.Local)
+		if err != nil {
+			return nil, nil, errors.New("invalid to date, expected YYYY-MM-DD")
+		}
+		next := day.AddDate(0, 0, 1)
+		end = &next
+	}
+
+	return start, end, nil
+}
+
+// Reconciliation compares a user's stored balance with the sum of their ledger
+type Reconciliation struct {
+	UserID           string  `json:"user_id"`
+	Balance          float64 `json:"balance"`
+	LedgerBalance    float64 `json:"ledger_balance"`
+	Difference       float64 `json:"difference"`
+	TransactionCount int64   `json:"transaction_count"`
+	IsConsistent     bool    `json:"is_consistent"`
+}
+
+func (ts *TransactionService) GetTransactions(filter TransactionFilter, page, limit int) ([]map[string]interface{}, map[string]interface{}, error) {
+	var transactions []models.Transaction
+	var total int64
+
+	db := ts.db.Model(&models.Transaction{})
+
+	if filter.UserID != "" {
+		db = db.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Type != "" {
+		db = db.Where("type = ?", filter.Type)
+	}
+	if filter.CourseID != "" {
+		db = db.Where("course_id = ?", filter.CourseID)
+	}
+	if filter.From != nil {
+		db = db.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		db = db.Where("created_at < ?", *filter.To)
+	}
+
+	// Count total
+	db.Count(&total)
+
+	// Apply pagination, newest first
+	offset := (page - 1) * limit
+	if err := db.Order("created_at DESC").Offset(offset).Limit(limit).
+		Preload("User", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Course", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Find(&transactions).Error; err != nil {
+		return nil, nil, err
+	}
+
+	// Convert to response format
+	result := make([]map[string]interface{}, len(transactions))
+	for i, transaction := range transactions {
+		var courseTitle string
+		if transaction.Course != nil {
+			courseTitle = transaction.Course.Title
+		}
+
+		result[i] = map[string]interface{}{
+			"id":             transaction.ID,
+			"user_id":        transaction.UserID,
+			"username":       transaction.User.Username,
+			"type":           transaction.Type,
+			"amount":         transaction.Amount,
+			"balance_before": transaction.BalanceBefore,
+			"balance_after":  transaction.BalanceAfter,
+			"course_id":      transaction.CourseID,
+			"course_title":   courseTitle,
+			"actor_id":       transaction.ActorID,
+			"description":    transaction.Description,
+			"created_at":     transaction.CreatedAt,
+		}
+	}
+
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+
+	// Calculate pagination values
+	prevPage := page - 1
+	nextPage := page + 1
+
+	if prevPage < 1 {
+		prevPage = 1
+	}
+	if nextPage > totalPages {
+		nextPage = totalPages
+	}
+
+	pagination := map[string]interface{}{
+		"current_page": page,
+		"total_pages":  totalPages,
+		"total_items":  total,
+		"prev_page":    prevPage,
+		"next_page":    nextPage,
+	}
+
+	return result, pagination, nil
+}
+
+// Reconcile recomputes a user's balance from the ledger and reports any drift
+// from the balance stored on the user row.
+func (ts *TransactionService) Reconcile(userID string) (*Reconciliation, error) {
+	var user models.User
+	if err := ts.db.First(&user, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+
+	var row struct {
+		Total float64
+		Count int64
+	}
+	if err := ts.db.Model(&models.Transaction{}).
+		Select("COALESCE(SUM(amount), 0) AS total, COUNT(*) AS count").
+		Where("user_id = ?", userID).
+		Scan(&row).Error; err != nil {
+		return nil, err
+	}
+
+	difference := math.Round((user.Balance-row.Total)*100) / 100
+
+	return &Reconciliation{
+		UserID:           user.ID,
+		Balance:          user.Balance,
+		LedgerBalance:    row.Total,
+		Difference:       difference,
+		TransactionCount: row.Count,
+		IsConsistent:     difference == 0,
+	}, nil
+}
+
+// recordTransaction appends a ledger entry for a balance change that has already
+// been applied to user. It must run in the same database transaction as that change.
+func recordTransaction(tx *gorm.DB, user *models.User, txType string, amount float64, courseID, actorID *string, description string) (*models.Transaction, error) {
+	transaction := models.Transaction{
+		UserID:        user.ID,
+		Type:          txType,
+		Amount:        amount,
+		BalanceBefore: user.Balance - amount,
+		BalanceAfter:  user.Balance,
+		CourseID:      courseID,
+		ActorID:       actorID,
+		Description:   description,
+	}
+	if err := tx.Create(&transaction).Error; err != nil {
+		return nil, err
+	}
+
+	return &transaction, nil
+}
@@ -0,0 +1,428 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"yonatan/labpro/config"
+	"yonatan/labpro/models"
+	"yonatan/labpro/storage"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrTranscodingDisabled = errors.New("video transcoding is disabled")
+	ErrModuleHasNoVideo    = errors.New("module has no video")
+)
+
+// transcodeTimeout bounds a single transcoding job. A job that has been
+// processing for longer was lost with the server that ran it and is picked up
+// again.
+const transcodeTimeout = 2 * time.Hour
+
+// hlsSegmentSeconds is the target length of HLS segments
+const hlsSegmentSeconds = 6
+
+// hlsAudioBitrate is the AAC bitrate of every rendition, in kbit/s
+const hlsAudioBitrate = 128
+
+// hlsVideoBitrates is the bitrate ladder for common rendition heights, in
+// kbit/s. Other heights are interpolated from 720p.
+var hlsVideoBitrates = map[int]int{
+	240:  400,
+	360:  800,
+	480:  1400,
+	720:  2800,
+	1080: 5000,
+	1440: 8000,
+	2160: 14000,
+}
+
+// hlsRendition is one quality level of a stream
+type hlsRendition struct {
+	Width   int
+	Height  int
+	Bitrate int
+}
+
+func (r hlsRendition) dir() string {
+	return fmt.Sprintf("%dp", r.Height)
+}
+
+// TranscodeService turns module videos into HLS streams with ffmpeg. Jobs are
+// queued by setting a module's video status to pending and are claimed with
+// row locks, so several servers can run workers side by side.
+type TranscodeService struct {
+	db     *gorm.DB
+	config *config.Config
+	media  *MediaService
+}
+
+func NewTranscodeService(db *gorm.DB, cfg *config.Config, fileStorage storage.Backend) *TranscodeService {
+	return &TranscodeService{
+		db:     db,
+		config: cfg,
+		media:  NewMediaService(db, cfg, fileStorage),
+	}
+}
+
+func transcodingEnabled(cfg *config.Config) bool {
+	return cfg.VideoTranscoding != "false"
+}
+
+// queueTranscode resets the stream of a module whose video changed and queues
+// the new video for transcoding. It returns the prefix of the dropped stream
+// for the caller to release once the change has committed.
+func queueTranscode(module *models.Module, cfg *config.Config) string {
+	dropped := optionalURL(module.VideoStream)
+	now := time.Now()
+
+	module.VideoStream = nil
+	module.VideoError = ""
+	module.VideoStatus = ""
+	module.VideoStatusAt = &now
+	if optionalURL(module.VideoContent) != "" && transcodingEnabled(cfg) {
+		module.VideoStatus = models.VideoStatusPending
+	}
+	return dropped
+}
+
+// videoJobColumns are written by the transcoding workers. Saves of unrelated
+// module changes leave them alone.
+var videoJobColumns = []string{"VideoStatus", "VideoError", "VideoStream", "VideoStatusAt"}
+
+// Run transcodes queued videos one after another and looks for new ones every
+// interval. It blocks, so start it in a goroutine.
+func (ts *TranscodeService) Run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			processed, err := ts.ProcessNext()
+			if err != nil {
+				log.Printf("Failed to transcode a module video: %v", err)
+			}
+			if !processed {
+				break
+			}
+		}
+		<-ticker.C
+	}
+}
+
+// ProcessNext transcodes the oldest queued video, if there is one. It reports
+// whether a job was taken, whether or not it succeeded.
+func (ts *TranscodeService) ProcessNext() (bool, error) {
+	job, err := ts.claim()
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	log.Printf("TranscodeService: Transcoding the video of module %s", job.ID)
+	prefix := fmt.Sprintf("hls/%s/%d", job.ID, time.Now().UnixNano())
+	if err := ts.transcode(job, prefix); err != nil {
+		ts.media.ReleaseStream(prefix)
+		if _, finishErr := ts.finish(job, models.VideoStatusFailed, nil, err.Error()); finishErr != nil {
+			err = errors.Join(err, finishErr)
+		}
+		return true, fmt.Errorf("module %s: %w", job.ID, err)
+	}
+
+	current, err := ts.finish(job, models.VideoStatusReady, &prefix, "")
+	if err != nil || !current {
+		// The video changed or the module went away while it was transcoded
+		ts.media.ReleaseStream(prefix)
+		return true, err
+	}
+
+	log.Printf("TranscodeService: Module %s streams from %s", job.ID, prefix)
+	return true, nil
+}
+
+// claim marks the oldest queued job as processing, along with jobs that have
+// been processing for too long
+func (ts *TranscodeService) claim() (*models.Module, error) {
+	var job models.Module
+	err := ts.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("video_content IS NOT NULL AND (video_status = ? OR (video_status = ? AND video_status_at < ?))",
+				models.VideoStatusPending, models.VideoStatusProcessing, time.Now().Add(-transcodeTimeout)).
+			Order("video_status_at").
+			Limit(1).
+			Find(&job)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		// The claim time identifies this attempt, so it has to survive the
+		// round trip through the database unchanged
+		now := time.Now().Truncate(time.Microsecond)
+		job.VideoStatus = models.VideoStatusProcessing
+		job.VideoStatusAt = &now
+		return tx.Model(&job).UpdateColumns(map[string]interface{}{
+			"video_status":    models.VideoStatusProcessing,
+			"video_error":     "",
+			"video_status_at": now,
+		}).Error
+	})
+	if err != nil || job.ID == "" {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// finish records the outcome of a job unless the module has moved on since it
+// was claimed. It reports whether the outcome was recorded.
+func (ts *TranscodeService) finish(job *models.Module, status string, stream *string, message string) (bool, error) {
+	result := ts.db.Model(&models.Module{}).
+		Where("id = ? AND video_status = ? AND video_status_at = ? AND video_content = ?",
+			job.ID, models.VideoStatusProcessing, *job.VideoStatusAt, *job.VideoContent).
+		UpdateColumns(map[string]interface{}{
+			"video_status":    status,
+			"video_error":     message,
+			"video_stream":    stream,
+			"video_status_at": time.Now(),
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// transcode renders the renditions, poster frame and master playlist of a
+// module's video and stores them under prefix
+func (ts *TranscodeService) transcode(job *models.Module, prefix string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), transcodeTimeout)
+	defer cancel()
+
+	workDir, err := os.MkdirTemp("", "labpro-hls-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	source, err := ts.fetchSource(ctx, *job.VideoContent, workDir)
+	if err != nil {
+		return err
+	}
+
+	width, height, err := ts.probe(ctx, source)
+	if err != nil {
+		return err
+	}
+	renditions := ts.renditions(width, height)
+
+	outDir := filepath.Join(workDir, "hls")
+	for _, rendition := range renditions {
+		dir := filepath.Join(outDir, rendition.dir())
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		err := ts.ffmpeg(ctx,
+			"-i", source,
+			"-map", "0:v:0", "-map", "0:a:0?",
+			"-vf", fmt.Sprintf("scale=%d:%d", rendition.Width, rendition.Height),
+			"-c:v", "libx264", "-preset", "veryfast", "-profile:v", "main", "-pix_fmt", "yuv420p",
+			"-b:v", fmt.Sprintf("%dk", rendition.Bitrate),
+			"-maxrate", fmt.Sprintf("%dk", maxBitrate(rendition.Bitrate)),
+			"-bufsize", fmt.Sprintf("%dk", rendition.Bitrate*3/2),
+			"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", hlsSegmentSeconds),
+			"-c:a", "aac", "-b:a", fmt.Sprintf("%dk", hlsAudioBitrate), "-ac", "2",
+			"-f", "hls",
+			"-hls_time", strconv.Itoa(hlsSegmentSeconds),
+			"-hls_playlist_type", "vod",
+			"-hls_segment_filename", filepath.Join(dir, "segment_%03d.ts"),
+			filepath.Join(dir, "index.m3u8"),
+		)
+		if err != nil {
+			return fmt.Errorf("%s rendition: %w", rendition.dir(), err)
+		}
+	}
+
+	// The poster is taken from a representative frame rather than the first,
+	// which is often black
+	largest := renditions[len(renditions)-1]
+	err = ts.ffmpeg(ctx,
+		"-i", source,
+		"-vf", fmt.Sprintf("thumbnail,scale=%d:%d", largest.Width, largest.Height),
+		"-frames:v", "1",
+		filepath.Join(outDir, "poster.jpg"),
+	)
+	if err != nil {
+		return fmt.Errorf("poster frame: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(outDir, "master.m3u8"), []byte(masterPlaylist(renditions)), 0644); err != nil {
+		return err
+	}
+
+	return filepath.WalkDir(outDir, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(outDir, filePath)
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		info, err := file.Stat()
+		if err != nil {
+			return err
+		}
+
+		kind := models.MediaKindStream
+		if rel == "poster.jpg" {
+			kind = models.MediaKindPoster
+		}
+		_, err = ts.media.Put(kind, path.Join(prefix, filepath.ToSlash(rel)), file, info.Size(), "")
+		return err
+	})
+}
+
+// fetchSource copies the video from storage into dir. Videos stored before
+// assets were recorded are read by the key in their /uploads URL, since that
+// URL needs a signed-in user; only links to elsewhere are handed to ffmpeg.
+func (ts *TranscodeService) fetchSource(ctx context.Context, url, dir string) (string, error) {
+	var asset models.MediaAsset
+	if err := ts.db.Where("url = ?", url).Limit(1).Find(&asset).Error; err != nil {
+		return "", err
+	}
+	key := asset.Key
+	if asset.ID == "" {
+		var local bool
+		key, local = strings.CutPrefix(url, strings.TrimSuffix(ts.config.BaseURL, "/")+"/uploads/")
+		if !local {
+			return url, nil
+		}
+	}
+
+	reader, _, err := ts.media.storage.Get(ctx, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to open source video: %w", err)
+	}
+	defer reader.Close()
+
+	source := filepath.Join(dir, "source"+path.Ext(key))
+	file, err := os.Create(source)
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(file, reader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to download source video: %w", err)
+	}
+	return source, nil
+}
+
+// probe returns the dimensions of the first video stream
+func (ts *TranscodeService) probe(ctx context.Context, source string) (int, int, error) {
+	output, err := exec.CommandContext(ctx, ts.config.FFprobePath,
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=width,height",
+		"-of", "csv=s=x:p=0",
+		source,
+	).Output()
+	if err != nil {
+		return 0, 0, fmt.Errorf("ffprobe: %w", commandError(err))
+	}
+
+	var width, height int
+	if _, err := fmt.Sscanf(strings.TrimSpace(string(output)), "%dx%d", &width, &height); err != nil || width <= 0 || height <= 0 {
+		return 0, 0, errors.New("ffprobe: source has no video stream")
+	}
+	return width, height, nil
+}
+
+func (ts *TranscodeService) ffmpeg(ctx context.Context, args ...string) error {
+	args = append([]string{"-y", "-nostdin", "-v", "error"}, args...)
+	if _, err := exec.CommandContext(ctx, ts.config.FFmpegPath, args...).Output(); err != nil {
+		return fmt.Errorf("ffmpeg: %w", commandError(err))
+	}
+	return nil
+}
+
+// commandError adds the last line a failed command wrote to stderr
+func commandError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		lines := strings.Split(strings.TrimSpace(string(exitErr.Stderr)), "\n")
+		if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+			return fmt.Errorf("%v: %s", err, last)
+		}
+	}
+	return err
+}
+
+// renditions picks the configured heights that do not upscale the source,
+// smallest first. A source smaller than all of them gets a single rendition at
+// its own size.
+func (ts *TranscodeService) renditions(width, height int) []hlsRendition {
+	var heights []int
+	seen := map[int]bool{}
+	for _, field := range strings.Split(ts.config.HLSRenditions, ",") {
+		h, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || h <= 0 || h > height || seen[h] {
+			continue
+		}
+		seen[h] = true
+		heights = append(heights, h)
+	}
+	if len(heights) == 0 {
+		heights = []int{height}
+	}
+	sort.Ints(heights)
+
+	renditions := make([]hlsRendition, 0, len(heights))
+	for _, h := range heights {
+		h = evenDimension(h)
+		bitrate, ok := hlsVideoBitrates[h]
+		if !ok {
+			bitrate = hlsVideoBitrates[720] * h * h / (720 * 720)
+		}
+		renditions = append(renditions, hlsRendition{
+			Width:   evenDimension(width * h / height),
+			Height:  h,
+			Bitrate: max(bitrate, 200),
+		})
+	}
+	return renditions
+}
+
+// evenDimension rounds down to the even sizes H.264 requires
+func evenDimension(n int) int {
+	return max(n&^1, 2)
+}
+
+func maxBitrate(bitrate int) int {
+	return bitrate * 107 / 100
+}
+
+// masterPlaylist lists the renditions for players to switch between
+func masterPlaylist(renditions []hlsRendition) string {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+	for _, rendition := range renditions {
+		fmt.Fprintf(&b, "#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%dx%d\n%s/index.m3u8\n",
+			(maxBitrate(rendition.Bitrate)+hlsAudioBitrate)*1000, rendition.Width, rendition.Height, rendition.dir())
+	}
+	return b.String()
+}
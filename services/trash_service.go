@@ -0,0 +1,472 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"yonatan/labpro/config"
+	"yonatan/labpro/models"
+	"yonatan/labpro/storage"
+
+	"gorm.io/gorm"
+)
+
+// Kinds of deleted items the trash lists
+const (
+	TrashUsers   = "users"
+	TrashCourses = "courses"
+	TrashModules = "modules"
+)
+
+// TrashTypes lists the trash tabs in the order the admin page shows them
+var TrashTypes = []string{TrashUsers, TrashCourses, TrashModules}
+
+var (
+	ErrUnknownTrashType = errors.New("unknown trash type")
+	ErrNotInTrash       = errors.New("item is not in the trash")
+	ErrRestoreConflict  = errors.New("another user now has this username or email")
+	ErrCourseInTrash    = errors.New("the module's course is in the trash, restore the course first")
+)
+
+// TrashPurge counts what a purge removed for good
+type TrashPurge struct {
+	Users   int64
+	Courses int64
+	Modules int64
+}
+
+// TrashService lists and restores soft-deleted users, courses and modules, and
+// purges them once they have been in the trash longer than the retention period.
+//
+// Deleting an item soft-deletes everything that goes with it using one shared
+// timestamp (see trashSession). Restoring matches on that timestamp, so rows
+// that were already in the trash for another reason stay there.
+type TrashService struct {
+	db           *gorm.DB
+	config       *config.Config
+	redisService *RedisService
+	media        *MediaService
+}
+
+func NewTrashService(db *gorm.DB, cfg *config.Config, redisService *RedisService, fileStorage storage.Backend) *TrashService {
+	return &TrashService{
+		db:           db,
+		config:       cfg,
+		redisService: redisService,
+		media:        NewMediaService(db, cfg, fileStorage),
+	}
+}
+
+// trashSession makes every soft delete run through the returned session share
+// one deleted_at, which is what restores later match dependent rows on
+func trashSession(tx *gorm.DB) *gorm.DB {
+	// Postgres keeps microseconds; truncating keeps the stored values comparable
+	now := time.Now().Truncate(time.Microsecond)
+	return tx.Session(&gorm.Session{NowFunc: func() time.Time { return now }})
+}
+
+// retention is how long items stay in the trash, zero when they are never purged
+func (ts *TrashService) retention() time.Duration {
+	days, err := strconv.Atoi(ts.config.TrashRetentionDays)
+	if err != nil || days < 0 {
+		days = 30
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func (ts *TrashService) clearCourseCache() {
+	if ts.redisService != nil {
+		ts.redisService.DeletePattern(context.Background(), "courses:*")
+	}
+}
+
+// GetTrash lists the deleted items of one type, most recently deleted first
+func (ts *TrashService) GetTrash(itemType, query string, page, limit int) ([]map[string]interface{}, map[string]interface{}, error) {
+	var total int64
+	searchTerm := "%" + strings.ToLower(query) + "%"
+
+	var db *gorm.DB
+	switch itemType {
+	case TrashUsers:
+		db = ts.db.Unscoped().Model(&models.User{}).Where("deleted_at IS NOT NULL AND purged_at IS NULL")
+		if query != "" {
+			db = db.Where("LOWER(username) LIKE ? OR LOWER(first_name) LIKE ? OR LOWER(last_name) LIKE ? OR LOWER(email) LIKE ?",
+				searchTerm, searchTerm, searchTerm, searchTerm)
+		}
+	case TrashCourses:
+		db = ts.db.Unscoped().Model(&models.Course{}).Where("deleted_at IS NOT NULL AND purged_at IS NULL")
+		if query != "" {
+			db = db.Where("LOWER(title) LIKE ?", searchTerm)
+		}
+	case TrashModules:
+		db = ts.db.Unscoped().Model(&models.Module{}).Where("deleted_at IS NOT NULL")
+		if query != "" {
+			db = db.Where("LOWER(title) LIKE ?", searchTerm)
+		}
+	default:
+		return nil, nil, ErrUnknownTrashType
+	}
+
+	// Count total
+	db.Count(&total)
+
+	// Apply pagination
+	offset := (page - 1) * limit
+	db = db.Order("deleted_at DESC").Offset(offset).Limit(limit)
+
+	var result []map[string]interface{}
+	switch itemType {
+	case TrashUsers:
+		var users []models.User
+		if err := db.Find(&users).Error; err != nil {
+			return nil, nil, err
+		}
+		for _, user := range users {
+			result = append(result, ts.trashItem(user.ID, user.Username, user.Email, user.DeletedAt))
+		}
+	case TrashCourses:
+		var courses []models.Course
+		if err := db.Preload("Instructor").Find(&courses).Error; err != nil {
+			return nil, nil, err
+		}
+		for _, course := range courses {
+			result = append(result, ts.trashItem(course.ID, course.Title, course.InstructorName(), course.DeletedAt))
+		}
+	case TrashModules:
+		var modules []models.Module
+		if err := db.Preload("Course", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).Find(&modules).Error; err != nil {
+			return nil, nil, err
+		}
+		for _, module := range modules {
+			item := ts.trashItem(module.ID, module.Title, module.Course.Title, module.DeletedAt)
+			item["course_id"] = module.CourseID
+			item["course_in_trash"] = module.Course.DeletedAt.Valid
+			result = append(result, item)
+		}
+	}
+	if result == nil {
+		result = []map[string]interface{}{}
+	}
+
+	totalPages := int((total + int64(limit) - 1) / int64(limit))
+
+	// Calculate pagination values
+	prevPage := page - 1
+	nextPage := page + 1
+
+	if prevPage < 1 {
+		prevPage = 1
+	}
+	if nextPage > totalPages {
+		nextPage = totalPages
+	}
+
+	pagination := map[string]interface{}{
+		"current_page": page,
+		"total_pages":  totalPages,
+		"total_items":  total,
+		"prev_page":    prevPage,
+		"next_page":    nextPage,
+	}
+
+	return result, pagination, nil
+}
+
+// trashItem is the response format shared by every trash listing. name is what
+// the item is known by and detail a second line to tell similar items apart.
+func (ts *TrashService) trashItem(id, name, detail string, deletedAt gorm.DeletedAt) map[string]interface{} {
+	var purgeAt *time.Time
+	if retention := ts.retention(); retention > 0 {
+		at := deletedAt.Time.Add(retention)
+		purgeAt = &at
+	}
+
+	return map[string]interface{}{
+		"id":         id,
+		"name":       name,
+		"detail":     detail,
+		"deleted_at": deletedAt.Time,
+		"purge_at":   purgeAt,
+	}
+}
+
+// RestoreUser brings back a deleted user with the enrollments and progress that
+// were deleted along with them. It fails when the username or email has been
+// taken by another user in the meantime.
+func (ts *TrashService) RestoreUser(id string, actor AuditActor) error {
+	err := ts.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL AND purged_at IS NULL", id).First(&user).Error; err != nil {
+			return ErrNotInTrash
+		}
+		deletedAt := user.DeletedAt.Time
+
+		var taken int64
+		if err := tx.Model(&models.User{}).Where("username = ? OR email = ?", user.Username, user.Email).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			return ErrRestoreConflict
+		}
+
+		// Rows whose course or module is still in the trash take over its deletion
+		// time instead, so restoring that course or module brings them back
+		if err := tx.Exec(`UPDATE user_courses SET deleted_at = courses.deleted_at FROM courses
+			WHERE user_courses.course_id = courses.id AND user_courses.user_id = ? AND user_courses.deleted_at = ?`,
+			id, deletedAt).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`UPDATE user_module_progresses SET deleted_at = modules.deleted_at FROM modules
+			WHERE user_module_progresses.module_id = modules.id AND user_module_progresses.user_id = ? AND user_module_progresses.deleted_at = ?`,
+			id, deletedAt).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Model(&user).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+
+		return recordAudit(tx, actor, models.AuditUserRestore, "user", id,
+			map[string]interface{}{"deleted_at": deletedAt}, map[string]interface{}{"deleted_at": nil})
+	})
+
+	if err == nil {
+		ts.clearCourseCache()
+	}
+
+	return err
+}
+
+// RestoreCourse brings back a deleted course with the modules, enrollments and
+// progress that were deleted along with it
+func (ts *TrashService) RestoreCourse(id string, actor AuditActor) error {
+	err := ts.db.Transaction(func(tx *gorm.DB) error {
+		var course models.Course
+		if err := tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL AND purged_at IS NULL", id).First(&course).Error; err != nil {
+			return ErrNotInTrash
+		}
+		deletedAt := course.DeletedAt.Time
+
+		if err := tx.Unscoped().Model(&models.Module{}).
+			Where("course_id = ? AND deleted_at = ?", id, deletedAt).
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+
+		// Enrollments and progress of users who are in the trash themselves stay
+		// there, stamped with the user's deletion time
+		if err := tx.Exec(`UPDATE user_courses SET deleted_at = users.deleted_at FROM users
+			WHERE user_courses.user_id = users.id AND user_courses.course_id = ? AND user_courses.deleted_at = ?`,
+			id, deletedAt).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(`UPDATE user_module_progresses SET deleted_at = users.deleted_at FROM users
+			WHERE user_module_progresses.user_id = users.id AND user_module_progresses.deleted_at = ?
+			AND user_module_progresses.module_id IN (SELECT id FROM modules WHERE course_id = ?)`,
+			deletedAt, id).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Model(&course).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+
+		return recordAudit(tx, actor, models.AuditCourseRestore, "course", id,
+			map[string]interface{}{"deleted_at": deletedAt}, map[string]interface{}{"deleted_at": nil})
+	})
+
+	if err == nil {
+		ts.clearCourseCache()
+	}
+
+	return err
+}
+
+// RestoreModule brings back a deleted module with the progress that was deleted
+// along with it. A module whose course is in the trash comes back with the course.
+func (ts *TrashService) RestoreModule(id string, actor AuditActor) error {
+	err := ts.db.Transaction(func(tx *gorm.DB) error {
+		var module models.Module
+		if err := tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&module).Error; err != nil {
+			return ErrNotInTrash
+		}
+		deletedAt := module.DeletedAt.Time
+
+		var course models.Course
+		if err := tx.First(&course, "id = ?", module.CourseID).Error; err != nil {
+			return ErrCourseInTrash
+		}
+
+		if err := tx.Exec(`UPDATE user_module_progresses SET deleted_at = users.deleted_at FROM users
+			WHERE user_module_progresses.user_id = users.id AND user_module_progresses.module_id = ? AND user_module_progresses.deleted_at = ?`,
+			id, deletedAt).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Model(&module).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+
+		return recordAudit(tx, actor, models.AuditModuleRestore, "module", id,
+			map[string]interface{}{"deleted_at": deletedAt}, map[string]interface{}{"deleted_at": nil})
+	})
+
+	if err == nil {
+		ts.clearCourseCache()
+	}
+
+	return err
+}
+
+// Purge permanently deletes everything that has been in the trash longer than
+// the retention period. Rows that depend on a purged item go with it through
+// the database's cascading foreign keys, and files only the purged courses and
+// modules used are deleted from storage once that has committed.
+//
+// The ledger is never touched: users and courses it refers to are kept as
+// tombstones instead. Users lose their personal data and everything tied to
+// their account, courses their thumbnail and revisions; both leave the trash.
+func (ts *TrashService) Purge() (TrashPurge, error) {
+	var purged TrashPurge
+
+	retention := ts.retention()
+	if retention == 0 {
+		return purged, nil
+	}
+	now := time.Now()
+	cutoff := now.Add(-retention)
+
+	var mediaURLs, streams []string
+	err := ts.db.Transaction(func(tx *gorm.DB) error {
+		var moduleIDs []string
+		if err := tx.Unscoped().Model(&models.Module{}).
+			Where("deleted_at < ? OR course_id IN (SELECT id FROM courses WHERE deleted_at < ?)", cutoff, cutoff).
+			Pluck("id", &moduleIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Raw(`SELECT pdf_content FROM modules WHERE id IN ? AND pdf_content IS NOT NULL
+			UNION SELECT video_content FROM modules WHERE id IN ? AND video_content IS NOT NULL
+			UNION SELECT content->>'pdf_content' FROM content_revisions WHERE target_type = ? AND target_id IN ?
+			UNION SELECT content->>'video_content' FROM content_revisions WHERE target_type = ? AND target_id IN ?
+			UNION SELECT thumbnail FROM courses WHERE deleted_at < ? AND purged_at IS NULL`,
+			moduleIDs, moduleIDs, models.RevisionTargetModule, moduleIDs, models.RevisionTargetModule, moduleIDs, cutoff).
+			Scan(&mediaURLs).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.Module{}).
+			Where("id IN ? AND video_stream IS NOT NULL", moduleIDs).
+			Pluck("video_stream", &streams).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Where("deleted_at < ?", cutoff).Delete(&models.UserModuleProgress{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("deleted_at < ?", cutoff).Delete(&models.UserCourse{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Unscoped().Where("deleted_at < ?", cutoff).Delete(&models.Module{})
+		if result.Error != nil {
+			return result.Error
+		}
+		purged.Modules = result.RowsAffected
+
+		result = tx.Unscoped().Model(&models.Course{}).
+			Where("deleted_at < ? AND purged_at IS NULL AND "+ledgerCourse, cutoff).
+			Updates(map[string]interface{}{"purged_at": now, "thumbnail": ""})
+		if result.Error != nil {
+			return result.Error
+		}
+		purged.Courses = result.RowsAffected
+
+		result = tx.Unscoped().Where("deleted_at < ? AND NOT "+ledgerCourse, cutoff).Delete(&models.Course{})
+		if result.Error != nil {
+			return result.Error
+		}
+		purged.Courses += result.RowsAffected
+
+		var tombstones []string
+		if err := tx.Unscoped().Model(&models.User{}).
+			Where("deleted_at < ? AND purged_at IS NULL AND "+ledgerUser, cutoff).
+			Pluck("id", &tombstones).Error; err != nil {
+			return err
+		}
+		if err := anonymizeUsers(tx, tombstones, now); err != nil {
+			return err
+		}
+		purged.Users = int64(len(tombstones))
+
+		result = tx.Unscoped().Where("deleted_at < ? AND NOT "+ledgerUser, cutoff).Delete(&models.User{})
+		if result.Error != nil {
+			return result.Error
+		}
+		purged.Users += result.RowsAffected
+
+		// Revisions are not tied to their target by a foreign key
+		if err := tx.Where("target_type = ? AND target_id NOT IN (SELECT id FROM modules)", models.RevisionTargetModule).Delete(&models.ContentRevision{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("target_type = ? AND target_id NOT IN (SELECT id FROM courses WHERE purged_at IS NULL)", models.RevisionTargetCourse).Delete(&models.ContentRevision{}).Error
+	})
+
+	if err == nil {
+		ts.media.Release(mediaURLs...)
+		ts.media.ReleaseStream(streams...)
+	}
+
+	return purged, err
+}
+
+// ledgerCourse and ledgerUser match courses and users that ledger entries
+// refer to, which the purge keeps as tombstones
+const (
+	ledgerCourse = "EXISTS (SELECT 1 FROM transactions WHERE transactions.course_id = courses.id)"
+	ledgerUser   = "EXISTS (SELECT 1 FROM transactions WHERE transactions.user_id = users.id OR transactions.actor_id = users.id)"
+)
+
+// userOwnedTables hold rows that belong to one user and would go with the user
+// through the cascading foreign keys
+var userOwnedTables = []string{
+	"refresh_tokens", "revoked_tokens", "idempotency_keys", "archived_module_progresses", "user_roles",
+	"account_tokens", "recovery_codes", "personal_access_tokens", "user_identities", "resumable_uploads",
+}
+
+// anonymizeUsers turns purged users into tombstones: what identifies them is
+// replaced, everything tied to their account is deleted, and only the ID and
+// balance the ledger reconciles against remain
+func anonymizeUsers(tx *gorm.DB, ids []string, now time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	for _, table := range userOwnedTables {
+		if err := tx.Exec("DELETE FROM "+table+" WHERE user_id IN ?", ids).Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.Exec(`UPDATE users SET username = 'purged-' || id, email = 'purged-' || id || '@purged.invalid',
+		first_name = '', last_name = '', password = '', totp_secret = '', totp_enabled_at = NULL,
+		email_verified_at = NULL, purged_at = ? WHERE id IN ?`, now, ids).Error
+}
+
+// RunRetention purges the trash now and then every interval. It blocks, so
+// start it in its own goroutine.
+func (ts *TrashService) RunRetention(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := ts.Purge()
+		if err != nil {
+			log.Printf("Failed to purge the trash: %v", err)
+		} else if purged.Users+purged.Courses+purged.Modules > 0 {
+			log.Printf("Purged %d users, %d courses and %d modules from the trash", purged.Users, purged.Courses, purged.Modules)
+		}
+		<-ticker.C
+	}
+}
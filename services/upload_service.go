@@ -0,0 +1,446 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"yonatan/labpro/config"
+	"yonatan/labpro/filecheck"
+	"yonatan/labpro/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUploadNotFound          = errors.New("upload not found")
+	ErrUploadTooLarge          = errors.New("upload is larger than allowed")
+	ErrUploadType              = errors.New("only video and PDF files can be uploaded")
+	ErrUploadOffsetMismatch    = errors.New("upload offset does not match the bytes received")
+	ErrUploadLocked            = errors.New("another request is writing to this upload")
+	ErrUploadChecksumInvalid   = errors.New("invalid upload checksum")
+	ErrUploadChecksumAlgorithm = errors.New("unsupported checksum algorithm")
+	ErrUploadChecksumMismatch  = errors.New("checksum mismatch")
+	ErrUploadIncomplete        = errors.New("upload is not complete")
+)
+
+// UploadChecksumAlgorithms lists the algorithms chunks can be checked with
+var UploadChecksumAlgorithms = []string{"sha1", "sha256", "md5"}
+
+var checksumHashes = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"md5":    md5.New,
+}
+
+// UploadService receives large module files in chunks, following the tus
+// resumable upload protocol, and attaches them to modules once complete.
+//
+// Chunks are appended to a file in the temp dir of the server that received
+// them, so every chunk of an upload has to reach the same server.
+type UploadService struct {
+	db            *gorm.DB
+	config        *config.Config
+	moduleService *ModuleService
+	media         *MediaService
+
+	// locks holds a mutex per upload so concurrent chunks cannot interleave
+	locks sync.Map
+}
+
+func NewUploadService(db *gorm.DB, cfg *config.Config, moduleService *ModuleService) *UploadService {
+	return &UploadService{
+		db:            db,
+		config:        cfg,
+		moduleService: moduleService,
+		media:         moduleService.media,
+	}
+}
+
+// MaxSize is the largest file an upload may hold, in bytes
+func (us *UploadService) MaxSize() int64 {
+	size, err := strconv.ParseInt(us.config.ResumableUploadMaxSize, 10, 64)
+	if err != nil || size <= 0 {
+		size = 5 << 30
+	}
+	return size
+}
+
+// expiry is how long an upload is kept after its last chunk
+func (us *UploadService) expiry() time.Duration {
+	hours, err := strconv.Atoi(us.config.ResumableUploadExpiryHours)
+	if err != nil || hours <= 0 {
+		hours = 24
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+func (us *UploadService) dir() string {
+	if us.config.UploadTempDir != "" {
+		return us.config.UploadTempDir
+	}
+	return filepath.Join(os.TempDir(), "labpro-uploads")
+}
+
+func (us *UploadService) partPath(upload *models.ResumableUpload) string {
+	return filepath.Join(us.dir(), upload.ID+".part")
+}
+
+// uploadFilename keeps only the last element of a client supplied file name
+func uploadFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == ".." {
+		return "upload"
+	}
+	return name
+}
+
+// Create starts an upload of size bytes for user
+func (us *UploadService) Create(user models.User, size int64, filename, contentType string) (*models.ResumableUpload, error) {
+	if size > us.MaxSize() {
+		return nil, ErrUploadTooLarge
+	}
+	// The content is checked once it has all arrived, see Attach
+	kind, err := us.media.validator.KindOf(filename, models.MediaKindVideo, models.MediaKindPDF)
+	if err != nil {
+		return nil, ErrUploadType
+	}
+	// Kinds have their own limits, below the one for resumable uploads
+	if err := us.media.validator.CheckSize(kind, size); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUploadTooLarge, err)
+	}
+
+	upload := models.ResumableUpload{
+		UserID:      user.ID,
+		Kind:        kind,
+		Filename:    uploadFilename(filename),
+		ContentType: contentType,
+		Size:        size,
+		ExpiresAt:   time.Now().Add(us.expiry()),
+	}
+	if err := us.db.Create(&upload).Error; err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(us.dir(), 0o700); err != nil {
+		us.db.Delete(&upload)
+		return nil, fmt.Errorf("failed to create upload directory: %v", err)
+	}
+	file, err := os.OpenFile(us.partPath(&upload), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		us.db.Delete(&upload)
+		return nil, fmt.Errorf("failed to create upload file: %v", err)
+	}
+	file.Close()
+
+	log.Printf("UploadService: User %s started upload %s of %s (%d bytes)", user.ID, upload.ID, upload.Filename, size)
+	return &upload, nil
+}
+
+// Get returns one of user's unexpired uploads
+func (us *UploadService) Get(user models.User, id string) (*models.ResumableUpload, error) {
+	var upload models.ResumableUpload
+	err := us.db.Where("id = ? AND user_id = ? AND expires_at > ?", id, user.ID, time.Now()).Limit(1).Find(&upload).Error
+	if err != nil {
+		return nil, err
+	}
+	if upload.ID == "" {
+		return nil, ErrUploadNotFound
+	}
+	return &upload, nil
+}
+
+// lock claims an upload for the caller, or reports false while another
+// request holds it
+func (us *UploadService) lock(id string) (func(), bool) {
+	value, _ := us.locks.LoadOrStore(id, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	if !mu.TryLock() {
+		return nil, false
+	}
+	return mu.Unlock, true
+}
+
+// parseChecksum reads an Upload-Checksum header, "<algorithm> <base64 digest>"
+func parseChecksum(header string) (hash.Hash, []byte, error) {
+	algorithm, encoded, found := strings.Cut(strings.TrimSpace(header), " ")
+	if !found {
+		return nil, nil, ErrUploadChecksumInvalid
+	}
+	newHash, ok := checksumHashes[algorithm]
+	if !ok {
+		return nil, nil, ErrUploadChecksumAlgorithm
+	}
+	digest, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, nil, ErrUploadChecksumInvalid
+	}
+	return newHash(), digest, nil
+}
+
+// WriteChunk appends a chunk to an upload. offset has to match the bytes
+// received so far. With a checksum, a chunk that does not match it, or that
+// breaks off, is discarded as a whole; without one, whatever arrived is kept
+// so the client can resume from there.
+func (us *UploadService) WriteChunk(user models.User, id string, offset int64, checksum string, chunk io.Reader) (*models.ResumableUpload, error) {
+	var sum hash.Hash
+	var digest []byte
+	if checksum != "" {
+		var err error
+		if sum, digest, err = parseChecksum(checksum); err != nil {
+			return nil, err
+		}
+	}
+
+	unlock, ok := us.lock(id)
+	if !ok {
+		return nil, ErrUploadLocked
+	}
+	defer unlock()
+
+	upload, err := us.Get(user, id)
+	if err != nil {
+		return nil, err
+	}
+	if offset != upload.Received {
+		return upload, ErrUploadOffsetMismatch
+	}
+
+	file, err := os.OpenFile(us.partPath(upload), os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open upload file: %v", err)
+	}
+	defer file.Close()
+
+	// A chunk that was cut off before the server crashed may be on disk without
+	// having been counted
+	if err := file.Truncate(upload.Received); err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(upload.Received, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var dst io.Writer = file
+	if sum != nil {
+		dst = io.MultiWriter(file, sum)
+	}
+	remaining := upload.Size - upload.Received
+	written, copyErr := io.Copy(dst, io.LimitReader(chunk, remaining+1))
+
+	discard := written > remaining || (sum != nil && (copyErr != nil || !bytes.Equal(sum.Sum(nil), digest)))
+	if discard {
+		if err := file.Truncate(upload.Received); err != nil {
+			return nil, err
+		}
+		if copyErr != nil {
+			return upload, copyErr
+		}
+		if written > remaining {
+			return upload, ErrUploadTooLarge
+		}
+		return upload, ErrUploadChecksumMismatch
+	}
+
+	upload.Received += written
+	upload.ExpiresAt = time.Now().Add(us.expiry())
+	if err := us.db.Model(upload).Select("received", "expires_at").Updates(upload).Error; err != nil {
+		return nil, err
+	}
+	return upload, copyErr
+}
+
+// Terminate discards one of user's uploads
+func (us *UploadService) Terminate(user models.User, id string) error {
+	unlock, ok := us.lock(id)
+	if !ok {
+		return ErrUploadLocked
+	}
+	defer unlock()
+
+	upload, err := us.Get(user, id)
+	if err != nil {
+		return err
+	}
+	return us.remove(upload)
+}
+
+func (us *UploadService) remove(upload *models.ResumableUpload) error {
+	if err := us.db.Delete(upload).Error; err != nil {
+		return err
+	}
+	if err := os.Remove(us.partPath(upload)); err != nil && !os.IsNotExist(err) {
+		log.Printf("UploadService: Failed to delete %s: %v", us.partPath(upload), err)
+	}
+	us.locks.Delete(upload.ID)
+	return nil
+}
+
+// Attach checks a complete upload and moves it into storage as the module's
+// video or PDF, as its extension says. The upload is gone afterwards, and so is
+// an upload whose content the checks refuse.
+func (us *UploadService) Attach(user models.User, id, moduleID string, actor AuditActor) (*models.Module, error) {
+	unlock, ok := us.lock(id)
+	if !ok {
+		return nil, ErrUploadLocked
+	}
+	defer unlock()
+
+	upload, err := us.Get(user, id)
+	if err != nil {
+		return nil, err
+	}
+	if !upload.Complete() {
+		return nil, ErrUploadIncomplete
+	}
+
+	canEdit, err := us.moduleService.CanEditModule(user, moduleID)
+	if err != nil {
+		return nil, err
+	}
+	if !canEdit {
+		return nil, ErrModuleAccessDenied
+	}
+	var module models.Module
+	if err := us.db.First(&module, "id = ?", moduleID).Error; err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(us.partPath(upload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open upload file: %v", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload file: %v", err)
+	}
+
+	// The limits may have changed while the upload was under way
+	err = us.media.validator.CheckSize(upload.Kind, info.Size())
+	var checked *filecheck.File
+	if err == nil {
+		checked, err = us.media.validator.CheckContent(context.Background(), upload.Kind, upload.Filename, file)
+	}
+	if filecheck.IsRejected(err) {
+		log.Printf("UploadService: Refused upload %s: %v", upload.ID, err)
+		if removeErr := us.remove(upload); removeErr != nil {
+			log.Printf("UploadService: Failed to delete refused upload %s: %v", upload.ID, removeErr)
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dir := "videos"
+	if upload.Kind == models.MediaKindPDF {
+		dir = "pdfs"
+	}
+	key := fmt.Sprintf("%s/%d_%s", dir, time.Now().Unix(), checked.Name)
+	asset, err := us.media.Put(upload.Kind, key, file, upload.Size, checked.ContentType)
+	if err != nil {
+		log.Printf("UploadService: Failed to store %s: %v", key, err)
+		return nil, fmt.Errorf("failed to save file: %v", err)
+	}
+
+	var pdfURL, videoURL *string
+	if upload.Kind == models.MediaKindPDF {
+		pdfURL = &asset.URL
+	} else {
+		videoURL = &asset.URL
+	}
+	updated, err := us.moduleService.UpdateModule(module.ID, module.Title, module.Description, pdfURL, videoURL, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := us.remove(upload); err != nil {
+		log.Printf("UploadService: Failed to delete attached upload %s: %v", upload.ID, err)
+	}
+	log.Printf("UploadService: Attached upload %s to module %s as %s", upload.ID, module.ID, asset.Key)
+	return updated, nil
+}
+
+// PurgeExpired discards uploads that have not received a chunk in time, and
+// files in the upload directory no upload refers to
+func (us *UploadService) PurgeExpired() (int, error) {
+	var expired []models.ResumableUpload
+	if err := us.db.Where("expires_at <= ?", time.Now()).Find(&expired).Error; err != nil {
+		return 0, err
+	}
+	purged := 0
+	for i := range expired {
+		unlock, ok := us.lock(expired[i].ID)
+		if !ok {
+			continue
+		}
+		err := us.remove(&expired[i])
+		unlock()
+		if err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	// Files of uploads whose user was deleted, or whose row never got created
+	entries, err := os.ReadDir(us.dir())
+	if os.IsNotExist(err) {
+		return purged, nil
+	}
+	if err != nil {
+		return purged, err
+	}
+	var live []string
+	if err := us.db.Model(&models.ResumableUpload{}).Pluck("id", &live).Error; err != nil {
+		return purged, err
+	}
+	known := make(map[string]bool, len(live))
+	for _, id := range live {
+		known[id+".part"] = true
+	}
+	cutoff := time.Now().Add(-us.expiry())
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || entry.IsDir() || known[entry.Name()] || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(us.dir(), entry.Name())); err != nil {
+			log.Printf("UploadService: Failed to delete %s: %v", entry.Name(), err)
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// RunExpiry purges expired uploads now and then every interval. It blocks, so
+// start it in its own goroutine.
+func (us *UploadService) RunExpiry(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := us.PurgeExpired()
+		if err != nil {
+			log.Printf("Failed to purge expired uploads: %v", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d expired uploads", purged)
+		}
+		<-ticker.C
+	}
+}
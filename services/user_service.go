@@ -3,12 +3,17 @@ package services
 import (
 	"errors"
 	"strings"
+	"time"
 	"yonatan/labpro/models"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrLastAdmin is returned when an operation would leave no admin account behind
+var ErrLastAdmin = errors.New("cannot delete the last remaining admin")
+
 type UserService struct {
 	db *gorm.DB
 }
@@ -95,34 +100,84 @@ func (us *UserService) GetUserByID(id string) (map[string]interface{}, error) {
 		"last_name":         user.LastName,
 		"balance":           user.Balance,
 		"courses_purchased": coursesPurchased,
+		"email_verified":    user.EmailVerifiedAt != nil,
+		"mfa_enabled":       user.TOTPEnabledAt != nil,
 	}
 
 	return result, nil
 }
 
-func (us *UserService) UpdateUserBalance(id string, increment float64) (*models.User, error) {
-	var user models.User
-	if err := us.db.First(&user, "id = ?", id).Error; err != nil {
+// GetUserEnrolledCourses returns the courses a user is currently enrolled in, most recent purchase first
+func (us *UserService) GetUserEnrolledCourses(id string) ([]models.Course, error) {
+	var courses []models.Course
+	err := us.db.Joins("JOIN user_courses ON user_courses.course_id = courses.id AND user_courses.deleted_at IS NULL").
+		Where("user_courses.user_id = ?", id).
+		Order("user_courses.purchased_at DESC").
+		Find(&courses).Error
+	if err != nil {
 		return nil, err
 	}
 
-	user.Balance += increment
-	if user.Balance < 0 {
-		user.Balance = 0
-	}
+	return courses, nil
+}
+
+// UpdateUserBalance applies an admin balance change and records it in the ledger.
+// The balance never drops below zero; the ledger entry holds the amount actually applied.
+func (us *UserService) UpdateUserBalance(id string, increment float64, actor AuditActor) (*models.User, error) {
+	var user models.User
+
+	err := us.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, "id = ?", id).Error; err != nil {
+			return err
+		}
+
+		previous := user.Balance
+		user.Balance += increment
+		if user.Balance < 0 {
+			user.Balance = 0
+		}
+
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+
+		amount := user.Balance - previous
+		if amount == 0 {
+			return nil
+		}
+
+		txType := models.TransactionTypeTopUp
+		description := "Balance top-up by admin"
+		if amount < 0 {
+			txType = models.TransactionTypeAdjustment
+			description = "Balance adjustment by admin"
+		}
+
+		var actorID *string
+		if actor.UserID != "" {
+			actorID = &actor.UserID
+		}
+
+		if _, err := recordTransaction(tx, &user, txType, amount, nil, actorID, description); err != nil {
+			return err
+		}
 
-	if err := us.db.Save(&user).Error; err != nil {
+		return recordAudit(tx, actor, models.AuditUserBalance, "user", user.ID,
+			map[string]interface{}{"balance": previous}, map[string]interface{}{"balance": user.Balance})
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return &user, nil
 }
 
-func (us *UserService) UpdateUser(id, email, username, firstName, lastName, password string) (*models.User, error) {
+func (us *UserService) UpdateUser(id, email, username, firstName, lastName, password string, actor AuditActor) (*models.User, error) {
 	var user models.User
 	if err := us.db.First(&user, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
+	before := user
 
 	// Check if username or email already exists (excluding current user)
 	var existingUser models.User
@@ -130,6 +185,10 @@ func (us *UserService) UpdateUser(id, email, username, firstName, lastName, pass
 		return nil, errors.New("username or email already exists")
 	}
 
+	// A new address has to be confirmed again
+	if user.Email != email {
+		user.EmailVerifiedAt = nil
+	}
 	user.Email = email
 	user.Username = username
 	user.FirstName = firstName
@@ -144,54 +203,118 @@ func (us *UserService) UpdateUser(id, email, username, firstName, lastName, pass
 		user.Password = string(hashedPassword)
 	}
 
-	if err := us.db.Save(&user).Error; err != nil {
+	err := us.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+
+		// A password change must end every existing session
+		var after interface{} = user
+		if password != "" {
+			if err := revokeUserSessions(tx, user.ID); err != nil {
+				return err
+			}
+			after = auditWith(user, "password", "changed")
+		}
+
+		return recordAudit(tx, actor, models.AuditUserUpdate, "user", user.ID, before, after)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return &user, nil
 }
 
-func (us *UserService) DeleteUser(id string) error {
-	// Check if user exists
+// RevokeSessions signs a user out everywhere by revoking all of their tokens
+func (us *UserService) RevokeSessions(id string, actor AuditActor) error {
 	var user models.User
 	if err := us.db.First(&user, "id = ?", id).Error; err != nil {
 		return errors.New("user not found")
 	}
 
-	// Don't allow deleting admin user
-	if user.Username == "admin" {
-		return errors.New("cannot delete admin user")
+	return us.db.Transaction(func(tx *gorm.DB) error {
+		if err := revokeUserSessions(tx, user.ID); err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, models.AuditUserRevokeSessions, "user", user.ID, nil, nil)
+	})
+}
+
+func (us *UserService) DeleteUser(id string, actor AuditActor) error {
+	// Check if user exists
+	var user models.User
+	if err := us.db.First(&user, "id = ?", id).Error; err != nil {
+		return errors.New("user not found")
 	}
 
-	// Start transaction
-	tx := us.db.Begin()
+	return us.db.Transaction(func(tx *gorm.DB) error {
+		tx = trashSession(tx)
+
+		// Lock every admin row so two admins deleting each other cannot both succeed
+		if user.IsAdmin {
+			var admins []models.User
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("is_admin = ?", true).Find(&admins).Error; err != nil {
+				return err
+			}
+			if len(admins) <= 1 {
+				return ErrLastAdmin
+			}
+		}
+
+		// Record the deletion first, the actor may be gone afterwards
+		if err := recordAudit(tx, actor, models.AuditUserDelete, "user", user.ID, user, nil); err != nil {
+			return err
+		}
 
-	// Delete user's course purchases
-	if err := tx.Where("user_id = ?", id).Delete(&models.UserCourse{}).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
+		// Delete user's course purchases
+		if err := tx.Where("user_id = ?", id).Delete(&models.UserCourse{}).Error; err != nil {
+			return err
+		}
 
-	// Delete user's module progress
-	if err := tx.Where("user_id = ?", id).Delete(&models.UserModuleProgress{}).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
+		// Delete user's module progress
+		if err := tx.Where("user_id = ?", id).Delete(&models.UserModuleProgress{}).Error; err != nil {
+			return err
+		}
 
-	// Delete user
-	if err := tx.Delete(&user).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
+		// A user in the trash must not stay signed in
+		if err := revokeUserSessions(tx, id); err != nil {
+			return err
+		}
 
-	tx.Commit()
-	return nil
+		// Delete user
+		return tx.Delete(&user).Error
+	})
 }
 
-func (us *UserService) CreateUser(firstName, lastName, username, email, password string, isAdmin bool) (*models.User, error) {
+func (us *UserService) CreateUser(firstName, lastName, username, email, password string, isAdmin bool, actor AuditActor) (*models.User, error) {
+	return us.createUser(models.User{
+		FirstName: firstName,
+		LastName:  lastName,
+		Username:  username,
+		Email:     email,
+		IsAdmin:   isAdmin,
+	}, password, actor)
+}
+
+// CreateAdmin creates an admin account whose password has to be changed on first login
+func (us *UserService) CreateAdmin(username, email, password string) (*models.User, error) {
+	return us.createUser(models.User{
+		FirstName:          "Admin",
+		LastName:           "User",
+		Username:           username,
+		Email:              email,
+		IsAdmin:            true,
+		MustChangePassword: true,
+	}, password, AuditActor{})
+}
+
+// createUser inserts user with password hashed
+func (us *UserService) createUser(user models.User, password string, actor AuditActor) (*models.User, error) {
 	// Check if user already exists
 	var existingUser models.User
-	if err := us.db.Where("username = ? OR email = ?", username, email).First(&existingUser).Error; err == nil {
+	if err := us.db.Where("username = ? OR email = ?", user.Username, user.Email).First(&existingUser).Error; err == nil {
 		return nil, errors.New("user with this username or email already exists")
 	}
 
@@ -200,18 +323,19 @@ func (us *UserService) CreateUser(firstName, lastName, username, email, password
 	if err != nil {
 		return nil, err
 	}
+	user.Password = string(hashedPassword)
 
-	// Create user
-	user := models.User{
-		FirstName: firstName,
-		LastName:  lastName,
-		Username:  username,
-		Email:     email,
-		Password:  string(hashedPassword),
-		IsAdmin:   isAdmin,
-	}
+	// Create user; the admin creating it vouches for the address
+	now := time.Now()
+	user.EmailVerifiedAt = &now
 
-	if err := us.db.Create(&user).Error; err != nil {
+	err = us.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, models.AuditUserCreate, "user", user.ID, nil, user)
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -219,3 +343,38 @@ func (us *UserService) CreateUser(firstName, lastName, username, email, password
 	user.Password = ""
 	return &user, nil
 }
+
+// HasAdmin reports whether at least one admin account exists
+func (us *UserService) HasAdmin() (bool, error) {
+	var count int64
+	if err := us.db.Model(&models.User{}).Where("is_admin = ?", true).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// BootstrapAdmin creates the first admin from the given credentials when no admin
+// exists yet. It returns nil without error when an admin is already present or no
+// credentials were configured.
+func (us *UserService) BootstrapAdmin(username, email, password string) (*models.User, error) {
+	// Accounts seeded by earlier releases still carry the well-known default password
+	var legacy models.User
+	if err := us.db.Where("username = ? AND is_admin = ?", "admin", true).First(&legacy).Error; err == nil {
+		if !legacy.MustChangePassword && legacy.CheckPassword("admin123") {
+			if err := us.db.Model(&legacy).Update("must_change_password", true).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	hasAdmin, err := us.HasAdmin()
+	if err != nil || hasAdmin {
+		return nil, err
+	}
+
+	if username == "" || email == "" || password == "" {
+		return nil, nil
+	}
+
+	return us.CreateAdmin(username, email, password)
+}
@@ -0,0 +1,238 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/cloudinary/cloudinary-go/v2"
+	"github.com/cloudinary/cloudinary-go/v2/api"
+	"github.com/cloudinary/cloudinary-go/v2/api/admin"
+	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
+)
+
+// cloudinaryFolder prefixes the public ID of every file we upload
+const cloudinaryFolder = "labpro"
+
+// Cloudinary stores files as public Cloudinary assets. Images and videos are
+// uploaded as such so Cloudinary can transform them; everything else is raw.
+type Cloudinary struct {
+	client *cloudinary.Cloudinary
+}
+
+func NewCloudinary(cloudinaryURL string) (*Cloudinary, error) {
+	if cloudinaryURL == "" {
+		return nil, errors.New("cloudinary URL is not configured")
+	}
+	client, err := cloudinary.NewFromURL(cloudinaryURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize cloudinary: %v", err)
+	}
+	return &Cloudinary{client: client}, nil
+}
+
+// asset maps a key to its Cloudinary resource type and public ID. Raw public
+// IDs keep the file extension, image and video ones do not.
+func (c *Cloudinary) asset(key string) (api.AssetType, string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	publicID := cloudinaryFolder + "/" + key
+	assetType := cloudinaryAssetType(key)
+	if assetType != api.File {
+		publicID = strings.TrimSuffix(publicID, path.Ext(publicID))
+	}
+	return assetType, publicID, nil
+}
+
+// deliveryURL returns the public address of a key
+func (c *Cloudinary) deliveryURL(key string) (string, error) {
+	assetType, publicID, err := c.asset(key)
+	if err != nil {
+		return "", err
+	}
+	if assetType != api.File {
+		publicID += path.Ext(key)
+	}
+	a, err := c.client.Media(publicID)
+	if err != nil {
+		return "", err
+	}
+	a.AssetType = assetType
+	a.Config.URL.Secure = true
+	return a.String()
+}
+
+func (c *Cloudinary) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*Object, error) {
+	assetType, publicID, err := c.asset(key)
+	if err != nil {
+		return nil, err
+	}
+
+	overwrite := true
+	result, err := c.client.Upload.Upload(ctx, r, uploader.UploadParams{
+		PublicID:     publicID,
+		ResourceType: string(assetType),
+		Type:         api.Upload,
+		Overwrite:    &overwrite,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload to cloudinary: %v", err)
+	}
+	if result.Error.Message != "" {
+		return nil, fmt.Errorf("failed to upload to cloudinary: %s", result.Error.Message)
+	}
+
+	if contentType == "" {
+		contentType = contentTypeFor(key)
+	}
+	return &Object{
+		Key:         key,
+		URL:         result.SecureURL,
+		Size:        int64(result.Bytes),
+		ContentType: contentType,
+		ModTime:     result.CreatedAt,
+	}, nil
+}
+
+// Get downloads the file from its delivery URL
+func (c *Cloudinary) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	object, err := c.Stat(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, object.URL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, nil, fmt.Errorf("cloudinary download of %s failed: %s", key, resp.Status)
+	}
+	return resp.Body, object, nil
+}
+
+func (c *Cloudinary) Delete(ctx context.Context, key string) error {
+	assetType, publicID, err := c.asset(key)
+	if err != nil {
+		return err
+	}
+	result, err := c.client.Upload.Destroy(ctx, uploader.DestroyParams{
+		PublicID:     publicID,
+		ResourceType: string(assetType),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete from cloudinary: %v", err)
+	}
+	if result.Error.Message != "" {
+		return fmt.Errorf("failed to delete from cloudinary: %s", result.Error.Message)
+	}
+	// "not found" is fine, anything else besides "ok" is not
+	if result.Result != "ok" && result.Result != "not found" {
+		return fmt.Errorf("failed to delete from cloudinary: %s", result.Result)
+	}
+	return nil
+}
+
+// SignedURL returns the public delivery URL, since uploads are public assets
+func (c *Cloudinary) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return c.deliveryURL(key)
+}
+
+func (c *Cloudinary) Stat(ctx context.Context, key string) (*Object, error) {
+	assetType, publicID, err := c.asset(key)
+	if err != nil {
+		return nil, err
+	}
+	result, err := c.client.Admin.Asset(ctx, admin.AssetParams{
+		AssetType:    assetType,
+		DeliveryType: api.Upload,
+		PublicID:     publicID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Error.Message != "" {
+		if strings.Contains(strings.ToLower(result.Error.Message), "not found") {
+			return nil, ErrNotFound
+		}
+		return nil, errors.New(result.Error.Message)
+	}
+	return &Object{
+		Key:         key,
+		URL:         result.SecureURL,
+		Size:        int64(result.Bytes),
+		ContentType: contentTypeFor(key),
+		ModTime:     result.CreatedAt,
+	}, nil
+}
+
+// List goes through the images, videos and raw files under our folder
+func (c *Cloudinary) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	for _, assetType := range []api.AssetType{api.Image, api.Video, api.File} {
+		nextCursor := ""
+		for {
+			result, err := c.client.Admin.Assets(ctx, admin.AssetsParams{
+				AssetType:    assetType,
+				DeliveryType: string(api.Upload),
+				Prefix:       cloudinaryFolder + "/" + prefix,
+				MaxResults:   500,
+				NextCursor:   nextCursor,
+			})
+			if err != nil {
+				return nil, err
+			}
+			if result.Error.Message != "" {
+				return nil, errors.New(result.Error.Message)
+			}
+
+			for _, asset := range result.Assets {
+				key := strings.TrimPrefix(asset.PublicID, cloudinaryFolder+"/")
+				if assetType != api.File && asset.Format != "" {
+					key += "." + asset.Format
+				}
+				objects = append(objects, Object{
+					Key:         key,
+					URL:         asset.SecureURL,
+					Size:        int64(asset.Bytes),
+					ContentType: contentTypeFor(key),
+					ModTime:     asset.CreatedAt,
+				})
+			}
+			if result.NextCursor == "" {
+				break
+			}
+			nextCursor = result.NextCursor
+		}
+	}
+	return objects, nil
+}
+
+// cloudinaryAssetType picks the resource type Cloudinary should treat a key as
+func cloudinaryAssetType(key string) api.AssetType {
+	contentType := contentTypeFor(key)
+	switch {
+	case strings.HasPrefix(contentType, "image/"):
+		return api.Image
+	case strings.HasPrefix(contentType, "video/"):
+		return api.Video
+	default:
+		return api.File
+	}
+}
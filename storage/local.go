@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Local keeps files in a directory on disk. The directory is expected to be
+// served as-is under baseURL, so its URLs are public and never expire.
+type Local struct {
+	root    string
+	baseURL string
+}
+
+func NewLocal(root, baseURL string) *Local {
+	return &Local{root: root, baseURL: baseURL}
+}
+
+func (l *Local) path(key string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(l.root, filepath.FromSlash(key)), nil
+}
+
+func (l *Local) url(key string) string {
+	return l.baseURL + "/" + key
+}
+
+// Put writes to a temporary file first so readers never see a partial upload
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*Object, error) {
+	filePath, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return nil, err
+	}
+	written, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filePath)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+
+	if contentType == "" {
+		contentType = contentTypeFor(key)
+	}
+	return &Object{
+		Key:         key,
+		URL:         l.url(key),
+		Size:        written,
+		ContentType: contentType,
+		ModTime:     time.Now(),
+	}, nil
+}
+
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	object, err := l.Stat(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	filePath, _ := l.path(key)
+	file, err := os.Open(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, object, nil
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	filePath, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (l *Local) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	if _, err := cleanKey(key); err != nil {
+		return "", err
+	}
+	return l.url(key), nil
+}
+
+func (l *Local) Stat(ctx context.Context, key string) (*Object, error) {
+	filePath, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(filePath)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Object{
+		Key:         key,
+		URL:         l.url(key),
+		Size:        info.Size(),
+		ContentType: contentTypeFor(key),
+		ModTime:     info.ModTime(),
+	}, nil
+}
+
+// List skips dot files, which include uploads still being written
+func (l *Local) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	err := filepath.WalkDir(l.root, func(filePath string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && filePath == l.root {
+			return filepath.SkipAll
+		}
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(entry.Name(), ".") && filePath != l.root {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		relative, err := filepath.Rel(l.root, filePath)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(relative)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, Object{
+			Key:         key,
+			URL:         l.url(key),
+			Size:        info.Size(),
+			ContentType: contentTypeFor(key),
+			ModTime:     info.ModTime(),
+		})
+		return nil
+	})
+	return objects, err
+}
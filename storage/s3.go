@@ -0,0 +1,402 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	s3Algorithm       = "AWS4-HMAC-SHA256"
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	s3DateFormat      = "20060102T150405Z"
+)
+
+// S3Config points the S3 backend at a bucket. Endpoint is the service URL, e.g.
+// https://s3.eu-west-1.amazonaws.com or http://localhost:9000 for MinIO.
+// PublicURL, when set, replaces the bucket URL in the addresses saved for
+// uploads, e.g. for a CDN in front of a public bucket.
+type S3Config struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	PublicURL       string
+	// PathStyle addresses objects as endpoint/bucket/key instead of
+	// bucket.endpoint/key, which MinIO and most self-hosted stores need
+	PathStyle bool
+	// Client defaults to http.DefaultClient
+	Client *http.Client
+}
+
+// S3 stores files in an S3-compatible bucket, signing requests with AWS
+// Signature Version 4
+type S3 struct {
+	config   S3Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+func NewS3(cfg S3Config) (*S3, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, errors.New("S3 storage needs an endpoint and a bucket")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("S3 storage needs an access key ID and a secret access key")
+	}
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	client := cfg.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &S3{config: cfg, endpoint: endpoint, client: client}, nil
+}
+
+// objectURL returns the bucket address of a key. The path is already escaped
+// the way SigV4 expects.
+func (s *S3) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	escapedKey := s3Escape(key, false)
+	if s.config.PathStyle {
+		u.RawPath = u.Path + "/" + s3Escape(s.config.Bucket, true) + "/" + escapedKey
+	} else {
+		u.Host = s.config.Bucket + "." + u.Host
+		u.RawPath = u.Path + "/" + escapedKey
+	}
+	u.Path, _ = url.PathUnescape(u.RawPath)
+	return &u
+}
+
+// bucketURL returns the address bucket-level requests such as listings go to
+func (s *S3) bucketURL() *url.URL {
+	u := *s.endpoint
+	if s.config.PathStyle {
+		u.Path += "/" + s.config.Bucket
+	} else {
+		u.Host = s.config.Bucket + "." + u.Host
+		u.Path += "/"
+	}
+	u.RawPath = ""
+	return &u
+}
+
+func (s *S3) publicURL(key string) string {
+	if s.config.PublicURL != "" {
+		return strings.TrimSuffix(s.config.PublicURL, "/") + "/" + s3Escape(key, false)
+	}
+	return s.objectURL(key).String()
+}
+
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*Object, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if size < 0 {
+		// S3 needs the length up front
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		r, size = bytes.NewReader(data), int64(len(data))
+	}
+	if contentType == "" {
+		contentType = contentTypeFor(key)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key).String(), r)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	return &Object{
+		Key:         key,
+		URL:         s.publicURL(key),
+		Size:        size,
+		ContentType: contentType,
+		ModTime:     time.Now(),
+	}, nil
+}
+
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	resp, err := s.request(ctx, http.MethodGet, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.Body, s.object(key, resp), nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	resp, err := s.request(ctx, http.MethodDelete, key)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// SignedURL presigns a GET request, so the file can be read from private buckets
+func (s *S3) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	seconds := int(expiry / time.Second)
+	if seconds < 1 || seconds > 7*24*60*60 {
+		return "", errors.New("signed S3 URLs must expire within one second and seven days")
+	}
+
+	u := s.objectURL(key)
+	now := time.Now().UTC()
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", s3Algorithm)
+	query.Set("X-Amz-Credential", s.config.AccessKeyID+"/"+s.scope(now))
+	query.Set("X-Amz-Date", now.Format(s3DateFormat))
+	query.Set("X-Amz-Expires", strconv.Itoa(seconds))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		u.EscapedPath(),
+		s3CanonicalQuery(query),
+		"host:" + u.Host + "\n",
+		"host",
+		s3UnsignedPayload,
+	}, "\n")
+	query.Set("X-Amz-Signature", s.signature(now, canonicalRequest))
+
+	u.RawQuery = s3CanonicalQuery(query)
+	return u.String(), nil
+}
+
+func (s *S3) Stat(ctx context.Context, key string) (*Object, error) {
+	resp, err := s.request(ctx, http.MethodHead, key)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return s.object(key, resp), nil
+}
+
+// s3ListResult is the part of a ListObjectsV2 response we read
+type s3ListResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+// List pages through ListObjectsV2. Content types are guessed from the keys
+// since listings do not include them.
+func (s *S3) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	continuationToken := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		if prefix != "" {
+			query.Set("prefix", prefix)
+		}
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+		u := s.bucketURL()
+		u.RawQuery = s3CanonicalQuery(query)
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := s.do(req)
+		if err != nil {
+			return nil, err
+		}
+		var result s3ListResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid S3 listing: %v", err)
+		}
+
+		for _, content := range result.Contents {
+			objects = append(objects, Object{
+				Key:         content.Key,
+				URL:         s.publicURL(content.Key),
+				Size:        content.Size,
+				ContentType: contentTypeFor(content.Key),
+				ModTime:     content.LastModified,
+			})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		continuationToken = result.NextContinuationToken
+	}
+}
+
+// request sends a body-less request for key
+func (s *S3) request(ctx context.Context, method, key string) (*http.Response, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.do(req)
+}
+
+// do signs and sends a request, turning error statuses into errors
+func (s *S3) do(req *http.Request) (*http.Response, error) {
+	s.sign(req, time.Now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return nil, fmt.Errorf("S3 %s %s failed: %s %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(body)))
+}
+
+func (s *S3) object(key string, resp *http.Response) *Object {
+	object := &Object{
+		Key:         key,
+		URL:         s.publicURL(key),
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	if modTime, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		object.ModTime = modTime
+	}
+	return object
+}
+
+// sign adds a SigV4 Authorization header. Payloads are left unsigned so large
+// uploads can be streamed.
+func (s *S3) sign(req *http.Request, now time.Time) {
+	req.Header.Set("X-Amz-Date", now.Format(s3DateFormat))
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedPayload)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + s3UnsignedPayload + "\n" +
+		"x-amz-date:" + now.Format(s3DateFormat) + "\n"
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		s3CanonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		s3UnsignedPayload,
+	}, "\n")
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.config.AccessKeyID, s.scope(now), signedHeaders, s.signature(now, canonicalRequest)))
+}
+
+func (s *S3) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + s.config.Region + "/s3/aws4_request"
+}
+
+func (s *S3) signature(now time.Time, canonicalRequest string) string {
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		now.Format(s3DateFormat),
+		s.scope(now),
+		hex.EncodeToString(hashed[:]),
+	}, "\n")
+
+	key := s3HMAC([]byte("AWS4"+s.config.SecretAccessKey), now.Format("20060102"))
+	key = s3HMAC(key, s.config.Region)
+	key = s3HMAC(key, "s3")
+	key = s3HMAC(key, "aws4_request")
+	return hex.EncodeToString(s3HMAC(key, stringToSign))
+}
+
+func s3HMAC(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3CanonicalQuery sorts and escapes query parameters as SigV4 requires
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, s3Escape(key, true)+"="+s3Escape(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape percent-encodes everything but unreserved characters, and slashes
+// too when encodeSlash is set
+func s3Escape(value string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,129 @@
+// Package storage keeps uploaded files behind a common interface so the rest of
+// the application does not care whether they live on local disk, in an
+// S3-compatible bucket or on Cloudinary.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"path"
+	"strings"
+	"time"
+	"yonatan/labpro/config"
+)
+
+// Storage drivers accepted in STORAGE_DRIVER
+const (
+	DriverLocal      = "local"
+	DriverS3         = "s3"
+	DriverCloudinary = "cloudinary"
+)
+
+var (
+	ErrNotFound   = errors.New("file not found")
+	ErrInvalidKey = errors.New("invalid file key")
+)
+
+// Object describes a stored file. URL is the address saved in the database and
+// handed to browsers.
+type Object struct {
+	Key         string
+	URL         string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// Backend stores files under slash-separated keys such as "pdfs/123_notes.pdf".
+// Deleting a missing key is not an error.
+type Backend interface {
+	// Put stores r under key, replacing any file already there. size may be -1
+	// when unknown.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*Object, error)
+	// Get opens a stored file; the caller closes the reader
+	Get(ctx context.Context, key string) (io.ReadCloser, *Object, error)
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL that grants read access to the file for expiry.
+	// Backends that serve files publicly return the public URL.
+	SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+	Stat(ctx context.Context, key string) (*Object, error)
+	// List returns every stored file whose key starts with prefix
+	List(ctx context.Context, prefix string) ([]Object, error)
+}
+
+// New returns the backend selected by cfg.StorageDriver. When no driver is set,
+// Cloudinary is used if CLOUDINARY_URL is configured and local disk otherwise.
+func New(cfg *config.Config) (Backend, error) {
+	driver := cfg.StorageDriver
+	if driver == "" {
+		driver = DriverLocal
+		if cfg.CloudinaryURL != "" {
+			driver = DriverCloudinary
+		}
+	}
+
+	switch driver {
+	case DriverLocal:
+		return NewLocal(cfg.UploadPath, strings.TrimSuffix(cfg.BaseURL, "/")+"/uploads"), nil
+	case DriverS3:
+		return NewS3(S3Config{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+			PublicURL:       cfg.S3PublicURL,
+			PathStyle:       cfg.S3ForcePathStyle != "false",
+		})
+	case DriverCloudinary:
+		return NewCloudinary(cfg.CloudinaryURL)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", driver)
+	}
+}
+
+// NewOrLocal returns the backend selected by cfg, falling back to local disk
+// with a warning when that backend cannot be set up
+func NewOrLocal(cfg *config.Config) Backend {
+	backend, err := New(cfg)
+	if err != nil {
+		log.Printf("Warning: Failed to initialize file storage: %v. Will use local storage.", err)
+		return NewLocal(cfg.UploadPath, strings.TrimSuffix(cfg.BaseURL, "/")+"/uploads")
+	}
+	return backend
+}
+
+// cleanKey rejects keys that are empty, absolute or that climb out of the
+// storage root
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	cleaned := path.Clean(key)
+	if cleaned != key || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
+
+// streamingTypes covers HLS playlists and segments, which system MIME tables
+// often lack or, for .ts, map to something else
+var streamingTypes = map[string]string{
+	".m3u8": "application/vnd.apple.mpegurl",
+	".ts":   "video/mp2t",
+}
+
+// contentTypeFor guesses a file's content type from its key
+func contentTypeFor(key string) string {
+	if contentType, ok := streamingTypes[path.Ext(key)]; ok {
+		return contentType
+	}
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,1147 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"yonatan/labpro/config"
+	apiAdminControllers "yonatan/labpro/controllers/api/admin"
+	"yonatan/labpro/database"
+	"yonatan/labpro/filecheck"
+	"yonatan/labpro/models"
+	apiRoutes "yonatan/labpro/routes/api"
+	"yonatan/labpro/services"
+	"yonatan/labpro/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+var adminTestDB *gorm.DB
+
+func setupAdminTestDB() {
+	cfg := config.LoadTestWithProjectRoot()
+
+	var err error
+	adminTestDB, err = gorm.Open(postgres.Open(cfg.DatabaseURL), &gorm.Config{})
+	if err != nil {
+		panic("Failed to connect to test database: " + err.Error())
+	}
+
+	// Set the global database instance
+	database.DB = adminTestDB
+
+	// Build the schema the way deployments do
+	migrateTestDB(adminTestDB)
+}
+
+func cleanupAdminTestDB() {
+	// Clean up test data in correct order due to foreign key constraints
+	adminTestDB.Exec("DELETE FROM audit_events")
+	adminTestDB.Exec("DELETE FROM content_revisions")
+	adminTestDB.Exec("DELETE FROM media_assets")
+	adminTestDB.Exec("DELETE FROM archived_module_progresses")
+	adminTestDB.Exec("DELETE FROM idempotency_keys")
+	adminTestDB.Exec("DELETE FROM transactions")
+	adminTestDB.Exec("DELETE FROM user_module_progresses")
+	adminTestDB.Exec("DELETE FROM user_courses")
+	adminTestDB.Exec("DELETE FROM modules")
+	adminTestDB.Exec("DELETE FROM courses")
+	adminTestDB.Exec("DELETE FROM instructors")
+	adminTestDB.Exec("DELETE FROM user_roles")
+	adminTestDB.Exec("DELETE FROM users")
+}
+
+func setupAdminTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	cfg := config.LoadTestWithProjectRoot()
+
+	// Stats are computed without a cache so every assertion sees fresh numbers
+	statsService := services.NewStatsService(adminTestDB, nil)
+	transactionService := services.NewTransactionService(adminTestDB)
+
+	adminStatsController := apiAdminControllers.NewStatsAPIController(statsService)
+	adminTransactionController := apiAdminControllers.NewTransactionAPIController(transactionService)
+	adminRoleController := apiAdminControllers.NewRoleAPIController(services.NewRoleService(adminTestDB))
+	adminAuditController := apiAdminControllers.NewAuditAPIController(services.NewAuditService(adminTestDB))
+	adminTrashController := apiAdminControllers.NewTrashAPIController(services.NewTrashService(adminTestDB, cfg, nil, testFileStorage(cfg)))
+
+	api := router.Group("/api")
+	apiRoutes.SetupAdminRoutes(api, adminStatsController, adminTransactionController, adminRoleController, adminAuditController, adminTrashController, cfg)
+
+	return router
+}
+
+func createAdminTestUser(username string, isAdmin bool) models.User {
+	user := models.User{
+		Username:  username,
+		Email:     username + "@test.com",
+		FirstName: "Test",
+		LastName:  "User",
+		Balance:   1000.0,
+		IsAdmin:   isAdmin,
+	}
+	user.SetPassword("password123")
+	adminTestDB.Create(&user)
+	return user
+}
+
+// seedTestRoles restores the grants the roles_permissions migration seeds,
+// which tests that edit roles may have changed.
+func seedTestRoles(db *gorm.DB) {
+	grants := map[string][]string{
+		models.RoleAdmin: {
+			models.PermissionStatsRead, models.PermissionCoursesWrite, models.PermissionCoursesWriteAny,
+			models.PermissionUsersRead, models.PermissionUsersWrite, models.PermissionBalancesWrite,
+			models.PermissionTransactionsRead, models.PermissionRefundsWrite, models.PermissionRolesWrite,
+			models.PermissionAuditRead,
+		},
+		models.RoleInstructor:    {models.PermissionCoursesWrite},
+		models.RoleContentEditor: {models.PermissionCoursesWrite, models.PermissionCoursesWriteAny},
+		models.RoleSupport:       {models.PermissionUsersRead, models.PermissionTransactionsRead, models.PermissionRefundsWrite},
+		models.RoleFinance: {
+			models.PermissionStatsRead, models.PermissionUsersRead, models.PermissionTransactionsRead,
+			models.PermissionBalancesWrite, models.PermissionRefundsWrite,
+		},
+	}
+
+	for roleName, permissionNames := range grants {
+		role := models.Role{Name: roleName}
+		db.Where("name = ?", roleName).FirstOrCreate(&role)
+
+		var permissions []models.Permission
+		for _, name := range permissionNames {
+			permission := models.Permission{Name: name}
+			db.Where("name = ?", name).FirstOrCreate(&permission)
+			permissions = append(permissions, permission)
+		}
+		db.Model(&role).Association("Permissions").Replace(permissions)
+	}
+}
+
+func createAdminTestToken(user models.User) string {
+	cfg := config.LoadTestWithProjectRoot()
+	authService := services.NewAuthService(cfg)
+	tokens, _, err := authService.Login(user.Username, "password123")
+	if err != nil {
+		return ""
+	}
+	return tokens.AccessToken
+}
+
+func TestAdminStats(t *testing.T) {
+	setupAdminTestDB()
+	defer cleanupAdminTestDB()
+	router := setupAdminTestRouter()
+
+	t.Run("GET /api/admin/stats", func(t *testing.T) {
+		t.Run("should compute stats from the database", func(t *testing.T) {
+			cleanupAdminTestDB()
+			seedTestRoles(adminTestDB)
+			cfg := config.LoadTestWithProjectRoot()
+			courseService := services.NewCourseService(adminTestDB, cfg, nil, testFileStorage(cfg))
+
+			adminUser := createAdminTestUser("statsadmin", true)
+			student1 := createAdminTestUser("student1", false)
+			student2 := createAdminTestUser("student2", false)
+			createAdminTestUser("student3", false)
+
+			// Staff holding a role are not counted as users
+			instructor := createAdminTestUser("statsinstructor", false)
+			var instructorRole models.Role
+			adminTestDB.Where("name = ?", models.RoleInstructor).First(&instructorRole)
+			adminTestDB.Model(&instructor).Association("Roles").Append(&instructorRole)
+
+			course := models.Course{
+				Title:  "Stats Course",
+				Price:  100.0,
+				Topics: pq.StringArray{"stats"},
+			}
+			adminTestDB.Create(&course)
+
+			module1 := models.Module{CourseID: course.ID, Title: "Module 1", Description: "First", Order: 1}
+			module2 := models.Module{CourseID: course.ID, Title: "Module 2", Description: "Second", Order: 2}
+			adminTestDB.Create(&module1)
+			adminTestDB.Create(&module2)
+
+			_, err := courseService.BuyCourse(course.ID, student1.ID, "")
+			assert.NoError(t, err)
+			_, err = courseService.BuyCourse(course.ID, student2.ID, "")
+			assert.NoError(t, err)
+
+			// Revenue is what was paid, so a later price change does not rewrite it
+			adminTestDB.Model(&course).Update("price", 300.0)
+
+			// Nor do sales of deleted courses count anywhere
+			deleted := models.Course{Title: "Deleted Course", Price: 50.0, Topics: pq.StringArray{"stats"}}
+			adminTestDB.Create(&deleted)
+			_, err = courseService.BuyCourse(deleted.ID, student1.ID, "")
+			assert.NoError(t, err)
+			adminTestDB.Delete(&deleted)
+
+			// student1 completes the course, student2 only half of it
+			adminTestDB.Create(&models.UserModuleProgress{UserID: student1.ID, ModuleID: module1.ID, IsCompleted: true})
+			adminTestDB.Create(&models.UserModuleProgress{UserID: student1.ID, ModuleID: module2.ID, IsCompleted: true})
+			adminTestDB.Create(&models.UserModuleProgress{UserID: student2.ID, ModuleID: module1.ID, IsCompleted: true})
+
+			// Modules in the trash count toward neither the total nor what was completed
+			trashed := models.Module{CourseID: course.ID, Title: "Module 3", Description: "Trashed", Order: 3}
+			adminTestDB.Create(&trashed)
+			adminTestDB.Create(&models.UserModuleProgress{UserID: student2.ID, ModuleID: trashed.ID, IsCompleted: true})
+			adminTestDB.Delete(&trashed)
+
+			req, _ := http.NewRequest("GET", "/api/admin/stats?days=7", nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", createAdminTestToken(adminUser)))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var response map[string]interface{}
+			err = json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, "success", response["status"])
+
+			data := response["data"].(map[string]interface{})
+			assert.Equal(t, float64(3), data["total_users"])
+			assert.Equal(t, float64(1), data["total_courses"])
+			assert.Equal(t, 200.0, data["total_revenue"])
+			assert.Equal(t, float64(2), data["active_students"])
+
+			daily := data["daily_purchases"].([]interface{})
+			assert.Len(t, daily, 7)
+			today := daily[len(daily)-1].(map[string]interface{})
+			assert.Equal(t, float64(2), today["purchases"])
+			assert.Equal(t, 200.0, today["revenue"])
+
+			revenue := data["revenue_per_course"].([]interface{})
+			assert.Len(t, revenue, 1)
+			assert.Equal(t, 200.0, revenue[0].(map[string]interface{})["revenue"])
+
+			funnel := data["funnel"].(map[string]interface{})
+			assert.Equal(t, float64(2), funnel["enrolled"])
+			assert.Equal(t, float64(2), funnel["started"])
+			assert.Equal(t, float64(2), funnel["halfway"])
+			assert.Equal(t, float64(1), funnel["completed"])
+		})
+
+		t.Run("should fail for non-admin user", func(t *testing.T) {
+			cleanupAdminTestDB()
+
+			student := createAdminTestUser("student", false)
+
+			req, _ := http.NewRequest("GET", "/api/admin/stats", nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", createAdminTestToken(student)))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusForbidden, w.Code)
+		})
+
+		t.Run("should fail without authentication", func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/api/admin/stats", nil)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		})
+	})
+}
+
+func TestAdminTransactions(t *testing.T) {
+	setupAdminTestDB()
+	defer cleanupAdminTestDB()
+	router := setupAdminTestRouter()
+
+	cfg := config.LoadTestWithProjectRoot()
+	userService := services.NewUserService(adminTestDB)
+	courseService := services.NewCourseService(adminTestDB, cfg, nil, testFileStorage(cfg))
+
+	t.Run("GET /api/admin/transactions", func(t *testing.T) {
+		t.Run("should list and filter ledger entries", func(t *testing.T) {
+			cleanupAdminTestDB()
+
+			adminUser := createAdminTestUser("ledgeradmin", true)
+			student := createAdminTestUser("ledgerstudent", false)
+			adminTestDB.Model(&student).Update("balance", 0)
+
+			course := models.Course{
+				Title:  "Ledger Course",
+				Price:  40.0,
+				Topics: pq.StringArray{"ledger"},
+			}
+			adminTestDB.Create(&course)
+
+			_, err := userService.UpdateUserBalance(student.ID, 100.0, services.AuditActor{UserID: adminUser.ID})
+			assert.NoError(t, err)
+			_, err = courseService.BuyCourse(course.ID, student.ID, "")
+			assert.NoError(t, err)
+
+			token := createAdminTestToken(adminUser)
+
+			req, _ := http.NewRequest("GET", fmt.Sprintf("/api/admin/transactions?user_id=%s", student.ID), nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var response map[string]interface{}
+			err = json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, "success", response["status"])
+
+			data := response["data"].([]interface{})
+			assert.Len(t, data, 2)
+
+			// Newest first
+			purchase := data[0].(map[string]interface{})
+			assert.Equal(t, models.TransactionTypePurchase, purchase["type"])
+			assert.Equal(t, -40.0, purchase["amount"])
+			assert.Equal(t, 100.0, purchase["balance_before"])
+			assert.Equal(t, 60.0, purchase["balance_after"])
+			assert.Equal(t, course.ID, purchase["course_id"])
+
+			topUp := data[1].(map[string]interface{})
+			assert.Equal(t, models.TransactionTypeTopUp, topUp["type"])
+			assert.Equal(t, adminUser.ID, topUp["actor_id"])
+
+			req, _ = http.NewRequest("GET", fmt.Sprintf("/api/admin/transactions?user_id=%s&type=topup", student.ID), nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			err = json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Len(t, response["data"].([]interface{}), 1)
+		})
+
+		t.Run("should reject an invalid date filter", func(t *testing.T) {
+			cleanupAdminTestDB()
+
+			adminUser := createAdminTestUser("ledgeradmin", true)
+
+			req, _ := http.NewRequest("GET", "/api/admin/transactions?from=yesterday", nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", createAdminTestToken(adminUser)))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+
+		t.Run("should fail for non-admin user", func(t *testing.T) {
+			cleanupAdminTestDB()
+
+			student := createAdminTestUser("student", false)
+
+			req, _ := http.NewRequest("GET", "/api/admin/transactions", nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", createAdminTestToken(student)))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusForbidden, w.Code)
+		})
+	})
+
+	t.Run("GET /api/admin/users/:id/reconciliation", func(t *testing.T) {
+		t.Run("should detect drift between balance and ledger", func(t *testing.T) {
+			cleanupAdminTestDB()
+
+			adminUser := createAdminTestUser("ledgeradmin", true)
+			student := createAdminTestUser("ledgerstudent", false)
+			adminTestDB.Model(&student).Update("balance", 0)
+
+			_, err := userService.UpdateUserBalance(student.ID, 75.0, services.AuditActor{UserID: adminUser.ID})
+			assert.NoError(t, err)
+
+			token := createAdminTestToken(adminUser)
+			url := fmt.Sprintf("/api/admin/users/%s/reconciliation", student.ID)
+
+			req, _ := http.NewRequest("GET", url, nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var response map[string]interface{}
+			err = json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+
+			data := response["data"].(map[string]interface{})
+			assert.Equal(t, true, data["is_consistent"])
+			assert.Equal(t, 75.0, data["ledger_balance"])
+
+			// A balance change that bypasses the ledger shows up as a difference
+			adminTestDB.Model(&student).Update("balance", 80)
+
+			req, _ = http.NewRequest("GET", url, nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			err = json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+
+			data = response["data"].(map[string]interface{})
+			assert.Equal(t, false, data["is_consistent"])
+			assert.Equal(t, 5.0, data["difference"])
+		})
+
+		t.Run("should return 404 for unknown user", func(t *testing.T) {
+			cleanupAdminTestDB()
+
+			adminUser := createAdminTestUser("ledgeradmin", true)
+
+			req, _ := http.NewRequest("GET", "/api/admin/users/00000000-0000-0000-0000-000000000000/reconciliation", nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", createAdminTestToken(adminUser)))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusNotFound, w.Code)
+		})
+	})
+}
+
+func TestAdminRoles(t *testing.T) {
+	setupAdminTestDB()
+	seedTestRoles(adminTestDB)
+	defer cleanupAdminTestDB()
+	router := setupAdminTestRouter()
+
+	roleService := services.NewRoleService(adminTestDB)
+
+	t.Run("permission checks", func(t *testing.T) {
+		t.Run("should forbid stats for support staff", func(t *testing.T) {
+			cleanupAdminTestDB()
+
+			supportUser := createAdminTestUser("supportstaff", false)
+			assert.NoError(t, roleService.AssignRole(supportUser.ID, models.RoleSupport, services.AuditActor{}))
+
+			req, _ := http.NewRequest("GET", "/api/admin/stats", nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", createAdminTestToken(supportUser)))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusForbidden, w.Code)
+		})
+
+		t.Run("should allow stats for finance staff", func(t *testing.T) {
+			cleanupAdminTestDB()
+
+			financeUser := createAdminTestUser("financestaff", false)
+			assert.NoError(t, roleService.AssignRole(financeUser.ID, models.RoleFinance, services.AuditActor{}))
+
+			req, _ := http.NewRequest("GET", "/api/admin/stats", nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", createAdminTestToken(financeUser)))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+		})
+
+		t.Run("should forbid role management without roles:write", func(t *testing.T) {
+			cleanupAdminTestDB()
+
+			financeUser := createAdminTestUser("financestaff", false)
+			assert.NoError(t, roleService.AssignRole(financeUser.ID, models.RoleFinance, services.AuditActor{}))
+
+			req, _ := http.NewRequest("GET", "/api/admin/roles", nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", createAdminTestToken(financeUser)))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusForbidden, w.Code)
+		})
+	})
+
+	t.Run("POST /api/admin/users/:id/roles", func(t *testing.T) {
+		t.Run("should assign and remove a role", func(t *testing.T) {
+			cleanupAdminTestDB()
+
+			adminUser := createAdminTestUser("roleadmin", true)
+			student := createAdminTestUser("rolestudent", false)
+			token := createAdminTestToken(adminUser)
+			url := fmt.Sprintf("/api/admin/users/%s/roles", student.ID)
+
+			req, _ := http.NewRequest("POST", url, bytes.NewBufferString(`{"role":"instructor"}`))
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.True(t, roleService.HasPermission(student, models.PermissionCoursesWrite))
+			assert.False(t, roleService.HasPermission(student, models.PermissionStatsRead))
+
+			req, _ = http.NewRequest("DELETE", url+"/instructor", nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.False(t, roleService.HasPermission(student, models.PermissionCoursesWrite))
+		})
+
+		t.Run("should reject unknown roles", func(t *testing.T) {
+			cleanupAdminTestDB()
+
+			adminUser := createAdminTestUser("roleadmin", true)
+			student := createAdminTestUser("rolestudent", false)
+
+			req, _ := http.NewRequest("POST", fmt.Sprintf("/api/admin/users/%s/roles", student.ID), bytes.NewBufferString(`{"role":"superhero"}`))
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", createAdminTestToken(adminUser)))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusNotFound, w.Code)
+		})
+
+		t.Run("should keep is_admin in sync with the admin role", func(t *testing.T) {
+			cleanupAdminTestDB()
+
+			adminUser := createAdminTestUser("roleadmin", true)
+			student := createAdminTestUser("rolestudent", false)
+
+			assert.NoError(t, roleService.AssignRole(student.ID, models.RoleAdmin, services.AuditActor{}))
+
+			var promoted models.User
+			adminTestDB.First(&promoted, "id = ?", student.ID)
+			assert.True(t, promoted.IsAdmin)
+
+			assert.NoError(t, roleService.RemoveRole(student.ID, models.RoleAdmin, services.AuditActor{}))
+			adminTestDB.First(&promoted, "id = ?", student.ID)
+			assert.False(t, promoted.IsAdmin)
+
+			// The last admin keeps the role even without an explicit user_roles row
+			assert.ErrorIs(t, roleService.RemoveRole(adminUser.ID, models.RoleAdmin, services.AuditActor{}), services.ErrLastAdmin)
+		})
+	})
+}
+
+func TestAuditLog(t *testing.T) {
+	setupAdminTestDB()
+	seedTestRoles(adminTestDB)
+	defer cleanupAdminTestDB()
+	router := setupAdminTestRouter()
+
+	userService := services.NewUserService(adminTestDB)
+
+	t.Run("GET /api/admin/audit", func(t *testing.T) {
+		t.Run("should record who changed what from where", func(t *testing.T) {
+			cleanupAdminTestDB()
+
+			adminUser := createAdminTestUser("auditadmin", true)
+			student := createAdminTestUser("auditstudent", false)
+			token := createAdminTestToken(adminUser)
+
+			req, _ := http.NewRequest("POST", fmt.Sprintf("/api/admin/users/%s/roles", student.ID), bytes.NewBufferString(`{"role":"instructor"}`))
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("User-Agent", "audit-test/1.0")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			req, _ = http.NewRequest("GET", "/api/admin/audit?target_id="+student.ID, nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var response map[string]interface{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+			events := response["data"].([]interface{})
+			assert.Len(t, events, 1)
+
+			event := events[0].(map[string]interface{})
+			assert.Equal(t, models.AuditUserAssignRole, event["action"])
+			assert.Equal(t, "user", event["target_type"])
+			assert.Equal(t, adminUser.ID, event["actor_id"])
+			assert.Equal(t, "auditadmin", event["actor_name"])
+			assert.Equal(t, "audit-test/1.0", event["user_agent"])
+			assert.NotEmpty(t, event["ip"])
+			assert.Equal(t, map[string]interface{}{"roles": []interface{}{}}, event["before"])
+			assert.Equal(t, map[string]interface{}{"roles": []interface{}{"instructor"}}, event["after"])
+		})
+
+		t.Run("should keep only the changed fields", func(t *testing.T) {
+			cleanupAdminTestDB()
+
+			adminUser := createAdminTestUser("auditadmin", true)
+			student := createAdminTestUser("auditstudent", false)
+			actor := services.AuditActor{UserID: adminUser.ID, Username: adminUser.Username}
+
+			_, err := userService.UpdateUser(student.ID, "renamed@test.com", student.Username, student.FirstName, student.LastName, "", actor)
+			assert.NoError(t, err)
+
+			var event models.AuditEvent
+			assert.NoError(t, adminTestDB.Where("action = ? AND target_id = ?", models.AuditUserUpdate, student.ID).First(&event).Error)
+			assert.JSONEq(t, `{"email":"auditstudent@test.com"}`, *event.Before)
+			assert.JSONEq(t, `{"email":"renamed@test.com"}`, *event.After)
+
+			// Events cannot be rewritten afterwards
+			assert.ErrorIs(t, adminTestDB.Model(&event).Update("action", "user.create").Error, models.ErrAuditEventImmutable)
+		})
+
+		t.Run("should not audit changes without an actor", func(t *testing.T) {
+			cleanupAdminTestDB()
+
+			student := createAdminTestUser("auditstudent", false)
+			_, err := userService.UpdateUserBalance(student.ID, 10.0, services.AuditActor{})
+			assert.NoError(t, err)
+
+			var count int64
+			adminTestDB.Model(&models.AuditEvent{}).Where("target_id = ?", student.ID).Count(&count)
+			assert.Equal(t, int64(0), count)
+		})
+
+		t.Run("should filter and export as CSV", func(t *testing.T) {
+			cleanupAdminTestDB()
+
+			adminUser := createAdminTestUser("auditadmin", true)
+			student := createAdminTestUser("auditstudent", false)
+			actor := services.AuditActor{UserID: adminUser.ID, Username: adminUser.Username, IP: "10.0.0.1", UserAgent: "=cmd"}
+
+			_, err := userService.UpdateUserBalance(student.ID, 25.0, actor)
+			assert.NoError(t, err)
+			assert.NoError(t, userService.RevokeSessions(student.ID, actor))
+
+			token := createAdminTestToken(adminUser)
+
+			req, _ := http.NewRequest("GET", "/api/admin/audit?action="+models.AuditUserBalance+"&q=auditadmin", nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var response map[string]interface{}
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			events := response["data"].([]interface{})
+			assert.Len(t, events, 1)
+			assert.Equal(t, map[string]interface{}{"balance": 1025.0}, events[0].(map[string]interface{})["after"])
+
+			req, _ = http.NewRequest("GET", "/api/admin/audit?format=csv&target_id="+student.ID, nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Header().Get("Content-Type"), "text/csv")
+
+			lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+			assert.Len(t, lines, 3)
+			assert.True(t, strings.HasPrefix(lines[0], "created_at,actor_id,actor_name,action"))
+			assert.Contains(t, w.Body.String(), models.AuditUserRevokeSessions)
+			// Values a spreadsheet would evaluate are defused
+			assert.Contains(t, w.Body.String(), "'=cmd")
+		})
+
+		t.Run("should reject an invalid date filter", func(t *testing.T) {
+			cleanupAdminTestDB()
+
+			adminUser := createAdminTestUser("auditadmin", true)
+
+			req, _ := http.NewRequest("GET", "/api/admin/audit?from=yesterday", nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", createAdminTestToken(adminUser)))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+
+		t.Run("should forbid staff without audit:read", func(t *testing.T) {
+			cleanupAdminTestDB()
+
+			financeUser := createAdminTestUser("auditfinance", false)
+			assert.NoError(t, services.NewRoleService(adminTestDB).AssignRole(financeUser.ID, models.RoleFinance, services.AuditActor{}))
+
+			req, _ := http.NewRequest("GET", "/api/admin/audit", nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", createAdminTestToken(financeUser)))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusForbidden, w.Code)
+		})
+	})
+}
+
+func TestTrash(t *testing.T) {
+	setupAdminTestDB()
+	seedTestRoles(adminTestDB)
+	defer cleanupAdminTestDB()
+	router := setupAdminTestRouter()
+
+	cfg := config.LoadTestWithProjectRoot()
+	userService := services.NewUserService(adminTestDB)
+	courseService := services.NewCourseService(adminTestDB, cfg, nil, testFileStorage(cfg))
+	moduleService := services.NewModuleService(adminTestDB, cfg, testFileStorage(cfg))
+	trashService := services.NewTrashService(adminTestDB, cfg, nil, testFileStorage(cfg))
+
+	// createEnrollment sets up a course with two modules, one of them completed by student
+	createEnrollment := func(student models.User) (models.Course, []models.Module) {
+		course := models.Course{Title: "Trash Course", Description: "Test", Price: 50.0}
+		adminTestDB.Create(&course)
+		modules := []models.Module{
+			{CourseID: course.ID, Title: "Module 1", Description: "Test", Order: 1},
+			{CourseID: course.ID, Title: "Module 2", Description: "Test", Order: 2},
+		}
+		adminTestDB.Create(&modules)
+		adminTestDB.Create(&models.UserCourse{UserID: student.ID, CourseID: course.ID})
+		adminTestDB.Create(&models.UserModuleProgress{UserID: student.ID, ModuleID: modules[0].ID, IsCompleted: true})
+		return course, modules
+	}
+
+	post := func(path, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", path, nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("should soft-delete a course and restore it with what was deleted along", func(t *testing.T) {
+		cleanupAdminTestDB()
+
+		adminUser := createAdminTestUser("trashadmin", true)
+		student := createAdminTestUser("trashstudent", false)
+		token := createAdminTestToken(adminUser)
+		course, modules := createEnrollment(student)
+
+		// A module deleted earlier stays in the trash when the course comes back
+		assert.NoError(t, moduleService.DeleteModule(modules[1].ID, services.AuditActor{}))
+		assert.NoError(t, courseService.DeleteCourse(course.ID, services.AuditActor{}))
+
+		var count int64
+		adminTestDB.Model(&models.UserCourse{}).Where("course_id = ?", course.ID).Count(&count)
+		assert.Equal(t, int64(0), count)
+		adminTestDB.Unscoped().Model(&models.UserModuleProgress{}).Where("user_id = ?", student.ID).Count(&count)
+		assert.Equal(t, int64(1), count)
+
+		req, _ := http.NewRequest("GET", "/api/admin/trash/modules", nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		items := response["data"].([]interface{})
+		assert.Len(t, items, 2)
+		item := items[0].(map[string]interface{})
+		assert.Equal(t, "Trash Course", item["detail"])
+		assert.Equal(t, true, item["course_in_trash"])
+		assert.NotNil(t, item["purge_at"])
+
+		// Modules cannot come back without their course
+		w = post("/api/admin/trash/modules/"+modules[0].ID+"/restore", token)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		w = post("/api/admin/trash/courses/"+course.ID+"/restore", token)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		adminTestDB.Model(&models.Module{}).Where("course_id = ?", course.ID).Count(&count)
+		assert.Equal(t, int64(1), count)
+		adminTestDB.Model(&models.UserCourse{}).Where("course_id = ?", course.ID).Count(&count)
+		assert.Equal(t, int64(1), count)
+		adminTestDB.Model(&models.UserModuleProgress{}).Where("user_id = ?", student.ID).Count(&count)
+		assert.Equal(t, int64(1), count)
+
+		w = post("/api/admin/trash/modules/"+modules[1].ID+"/restore", token)
+		assert.Equal(t, http.StatusOK, w.Code)
+		adminTestDB.Model(&models.Module{}).Where("course_id = ?", course.ID).Count(&count)
+		assert.Equal(t, int64(2), count)
+
+		// Restoring twice finds nothing in the trash
+		w = post("/api/admin/trash/courses/"+course.ID+"/restore", token)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		adminTestDB.Model(&models.AuditEvent{}).Where("action = ? AND target_id = ?", models.AuditCourseRestore, course.ID).Count(&count)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("should restore a user with their enrollments", func(t *testing.T) {
+		cleanupAdminTestDB()
+
+		adminUser := createAdminTestUser("trashadmin", true)
+		student := createAdminTestUser("trashstudent", false)
+		token := createAdminTestToken(adminUser)
+		course, _ := createEnrollment(student)
+
+		assert.NoError(t, userService.DeleteUser(student.ID, services.AuditActor{}))
+
+		// Deleted users cannot sign in
+		_, _, err := services.NewAuthService(cfg).Login(student.Username, "password123")
+		assert.Error(t, err)
+
+		w := post("/api/admin/trash/users/"+student.ID+"/restore", token)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var count int64
+		adminTestDB.Model(&models.UserCourse{}).Where("user_id = ? AND course_id = ?", student.ID, course.ID).Count(&count)
+		assert.Equal(t, int64(1), count)
+		adminTestDB.Model(&models.UserModuleProgress{}).Where("user_id = ?", student.ID).Count(&count)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("should refuse to restore a user whose username was taken", func(t *testing.T) {
+		cleanupAdminTestDB()
+
+		adminUser := createAdminTestUser("trashadmin", true)
+		student := createAdminTestUser("trashstudent", false)
+		assert.NoError(t, userService.DeleteUser(student.ID, services.AuditActor{}))
+		createAdminTestUser("trashstudent", false)
+
+		w := post("/api/admin/trash/users/"+student.ID+"/restore", createAdminTestToken(adminUser))
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("should purge items past the retention period", func(t *testing.T) {
+		cleanupAdminTestDB()
+
+		student := createAdminTestUser("trashstudent", false)
+		course, _ := createEnrollment(student)
+		recent := models.Course{Title: "Recent Course", Description: "Test", Price: 10.0}
+		adminTestDB.Create(&recent)
+
+		assert.NoError(t, courseService.DeleteCourse(course.ID, services.AuditActor{}))
+		assert.NoError(t, courseService.DeleteCourse(recent.ID, services.AuditActor{}))
+		adminTestDB.Unscoped().Model(&models.Course{}).Where("id = ?", course.ID).Update("deleted_at", time.Now().AddDate(0, 0, -31))
+		adminTestDB.Unscoped().Model(&models.Module{}).Where("course_id = ?", course.ID).Update("deleted_at", time.Now().AddDate(0, 0, -31))
+
+		purged, err := trashService.Purge()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), purged.Courses)
+		assert.Equal(t, int64(2), purged.Modules)
+
+		var count int64
+		adminTestDB.Unscoped().Model(&models.Course{}).Where("id IN ?", []string{course.ID, recent.ID}).Count(&count)
+		assert.Equal(t, int64(1), count)
+		adminTestDB.Unscoped().Model(&models.UserCourse{}).Where("course_id = ?", course.ID).Count(&count)
+		assert.Equal(t, int64(0), count)
+	})
+
+	t.Run("should keep the ledger of purged users and courses", func(t *testing.T) {
+		cleanupAdminTestDB()
+
+		adminUser := createAdminTestUser("trashadmin", true)
+		buyer := createAdminTestUser("trashbuyer", false)
+		idle := createAdminTestUser("trashidle", false)
+		course := models.Course{Title: "Sold Course", Description: "Test", Price: 50.0}
+		adminTestDB.Create(&course)
+		_, err := courseService.BuyCourse(course.ID, buyer.ID, "")
+		assert.NoError(t, err)
+
+		var before int64
+		adminTestDB.Model(&models.Transaction{}).Where("user_id = ?", buyer.ID).Count(&before)
+		assert.Equal(t, int64(1), before)
+
+		assert.NoError(t, userService.DeleteUser(buyer.ID, services.AuditActor{}))
+		assert.NoError(t, userService.DeleteUser(idle.ID, services.AuditActor{}))
+		assert.NoError(t, courseService.DeleteCourse(course.ID, services.AuditActor{}))
+		monthAgo := time.Now().AddDate(0, 0, -31)
+		adminTestDB.Unscoped().Model(&models.User{}).Where("id IN ?", []string{buyer.ID, idle.ID}).Update("deleted_at", monthAgo)
+		adminTestDB.Unscoped().Model(&models.Course{}).Where("id = ?", course.ID).Update("deleted_at", monthAgo)
+
+		purged, err := trashService.Purge()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), purged.Users)
+		assert.Equal(t, int64(1), purged.Courses)
+
+		// The purchase is still there, pointing at the same user and course
+		var transactions []models.Transaction
+		adminTestDB.Where("user_id = ?", buyer.ID).Find(&transactions)
+		assert.Len(t, transactions, 1)
+		assert.Equal(t, course.ID, *transactions[0].CourseID)
+
+		// The buyer remains only as an anonymous tombstone, the idle user is gone
+		var tombstone models.User
+		assert.NoError(t, adminTestDB.Unscoped().First(&tombstone, "id = ?", buyer.ID).Error)
+		assert.NotNil(t, tombstone.PurgedAt)
+		assert.Equal(t, "purged-"+buyer.ID, tombstone.Username)
+		assert.Empty(t, tombstone.FirstName)
+		assert.Error(t, adminTestDB.Unscoped().First(&models.User{}, "id = ?", idle.ID).Error)
+
+		var sold models.Course
+		assert.NoError(t, adminTestDB.Unscoped().First(&sold, "id = ?", course.ID).Error)
+		assert.NotNil(t, sold.PurgedAt)
+
+		// Tombstones have left the trash for good
+		token := createAdminTestToken(adminUser)
+		w := post("/api/admin/trash/users/"+buyer.ID+"/restore", token)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w = post("/api/admin/trash/courses/"+course.ID+"/restore", token)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		// Purging again changes nothing
+		purged, err = trashService.Purge()
+		assert.NoError(t, err)
+		assert.Zero(t, purged.Users+purged.Courses)
+	})
+
+	t.Run("should forbid staff who cannot delete users", func(t *testing.T) {
+		cleanupAdminTestDB()
+
+		editor := createAdminTestUser("trasheditor", false)
+		assert.NoError(t, services.NewRoleService(adminTestDB).AssignRole(editor.ID, models.RoleContentEditor, services.AuditActor{}))
+		token := createAdminTestToken(editor)
+
+		req, _ := http.NewRequest("GET", "/api/admin/trash/users", nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		req, _ = http.NewRequest("GET", "/api/admin/trash/courses", nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
+// thumbnailUpload returns a blank PNG of the given size as an uploaded file
+func thumbnailUpload(t *testing.T, filename string, width, height int) *multipart.FileHeader {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("thumbnail", filename)
+	assert.NoError(t, err)
+	assert.NoError(t, png.Encode(part, image.NewRGBA(image.Rect(0, 0, width, height))))
+	writer.Close()
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(10 << 20)
+	assert.NoError(t, err)
+	return form.File["thumbnail"][0]
+}
+
+func TestMediaCleanup(t *testing.T) {
+	setupAdminTestDB()
+	defer cleanupAdminTestDB()
+
+	cfg := config.LoadTestWithProjectRoot()
+	storageRoot := t.TempDir()
+	fileStorage := storage.NewLocal(storageRoot, cfg.BaseURL+"/uploads")
+	mediaService := services.NewMediaService(adminTestDB, cfg, fileStorage)
+	courseService := services.NewCourseService(adminTestDB, cfg, nil, fileStorage)
+	moduleService := services.NewModuleService(adminTestDB, cfg, fileStorage)
+	trashService := services.NewTrashService(adminTestDB, cfg, nil, fileStorage)
+
+	store := func(kind, key string) string {
+		asset, err := mediaService.Put(kind, key, strings.NewReader("content of "+key), -1, "")
+		assert.NoError(t, err)
+		return asset.URL
+	}
+	stored := func(key string) bool {
+		_, err := fileStorage.Stat(context.Background(), key)
+		return err == nil
+	}
+
+	t.Run("should delete a replaced thumbnail once the course is saved", func(t *testing.T) {
+		cleanupAdminTestDB()
+
+		oldThumbnail := store(models.MediaKindThumbnail, "thumbnails/old.png")
+		course := models.Course{Title: "Media Course", Description: "Test", Price: 10.0, Thumbnail: oldThumbnail}
+		adminTestDB.Create(&course)
+
+		course.Thumbnail = store(models.MediaKindThumbnail, "thumbnails/new.png")
+		_, err := courseService.UpdateCourse(&course, services.AuditActor{})
+		assert.NoError(t, err)
+
+		assert.False(t, stored("thumbnails/old.png"))
+		assert.True(t, stored("thumbnails/new.png"))
+		var count int64
+		adminTestDB.Model(&models.MediaAsset{}).Where("url = ?", oldThumbnail).Count(&count)
+		assert.Equal(t, int64(0), count)
+	})
+
+	t.Run("should store thumbnails in every size and delete them together", func(t *testing.T) {
+		cleanupAdminTestDB()
+
+		// A stand-in for ffmpeg that answers with a WebP header
+		ffmpeg := filepath.Join(t.TempDir(), "ffmpeg")
+		assert.NoError(t, os.WriteFile(ffmpeg, []byte("#!/bin/sh\ncat > /dev/null\nprintf 'RIFF\\000\\000\\000\\000WEBPVP8 '\n"), 0755))
+		thumbnailCfg := *cfg
+		thumbnailCfg.FFmpegPath = ffmpeg
+		courseService := services.NewCourseService(adminTestDB, &thumbnailCfg, nil, fileStorage)
+
+		url, err := courseService.SaveThumbnail(thumbnailUpload(t, "My Cover.png", 1600, 900))
+		assert.NoError(t, err)
+		assert.True(t, strings.HasSuffix(url, "/jpeg/detail.jpg"), url)
+		var assets []models.MediaAsset
+		adminTestDB.Where("variant_of = ?", url).Find(&assets)
+		assert.Len(t, assets, 6)
+
+		course := models.Course{Title: "Media Course", Description: "Test", Price: 10.0, Thumbnail: url}
+		adminTestDB.Create(&course)
+		result, err := courseService.GetCourseByID(course.ID, nil)
+		assert.NoError(t, err)
+		base := strings.TrimSuffix(url, "jpeg/detail.jpg")
+		assert.Equal(t, map[string]interface{}{
+			"src":         url,
+			"srcset":      base + "jpeg/card.jpg 640w, " + base + "jpeg/detail.jpg 1280w",
+			"webp_srcset": base + "webp/card.webp 640w, " + base + "webp/detail.webp 1280w",
+			"social":      base + "jpeg/social.jpg",
+		}, result["thumbnail_image"])
+
+		// Every size is in use while the course shows the thumbnail
+		past := time.Now().Add(-2 * time.Hour)
+		for _, asset := range assets {
+			assert.NoError(t, os.Chtimes(filepath.Join(storageRoot, filepath.FromSlash(asset.Key)), past, past))
+		}
+		report, err := mediaService.ScanOrphans()
+		assert.NoError(t, err)
+		for _, orphan := range report.Orphans {
+			assert.False(t, strings.HasPrefix(orphan.URL, base), orphan.Key)
+		}
+
+		// Replacing it deletes them all
+		course.Thumbnail = store(models.MediaKindThumbnail, "thumbnails/plain.png")
+		_, err = courseService.UpdateCourse(&course, services.AuditActor{})
+		assert.NoError(t, err)
+		for _, asset := range assets {
+			assert.False(t, stored(asset.Key), asset.Key)
+		}
+		var count int64
+		adminTestDB.Model(&models.MediaAsset{}).Where("variant_of = ?", url).Count(&count)
+		assert.Equal(t, int64(0), count)
+
+		// Thumbnails stored before they were resized only have the one file
+		result, err = courseService.GetCourseByID(course.ID, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{
+			"src":         course.Thumbnail,
+			"srcset":      "",
+			"webp_srcset": "",
+			"social":      course.Thumbnail,
+		}, result["thumbnail_image"])
+
+		_, err = courseService.SaveThumbnail(thumbnailUpload(t, "tiny.png", 320, 180))
+		assert.ErrorIs(t, err, filecheck.ErrImageTooSmall)
+	})
+
+	t.Run("should keep replaced module files the revision history refers to", func(t *testing.T) {
+		cleanupAdminTestDB()
+
+		course := models.Course{Title: "Media Course", Description: "Test", Price: 10.0}
+		adminTestDB.Create(&course)
+		module, err := moduleService.CreateModule(course.ID, "Module", "Test", stringPtr(store(models.MediaKindPDF, "pdfs/v1.pdf")), nil, services.AuditActor{})
+		assert.NoError(t, err)
+
+		_, err = moduleService.UpdateModule(module.ID, "Module", "Test", stringPtr(store(models.MediaKindPDF, "pdfs/v2.pdf")), nil, services.AuditActor{})
+		assert.NoError(t, err)
+		assert.True(t, stored("pdfs/v1.pdf"), "a rollback can still bring back the first PDF")
+		assert.True(t, stored("pdfs/v2.pdf"))
+
+		// Deleted modules keep their files while they can be restored
+		assert.NoError(t, moduleService.DeleteModule(module.ID, services.AuditActor{}))
+		assert.True(t, stored("pdfs/v2.pdf"))
+
+		// Purging the module drops its revisions, and with them the last references
+		adminTestDB.Unscoped().Model(&models.Module{}).Where("id = ?", module.ID).Update("deleted_at", time.Now().AddDate(0, 0, -31))
+		_, err = trashService.Purge()
+		assert.NoError(t, err)
+		assert.False(t, stored("pdfs/v1.pdf"))
+		assert.False(t, stored("pdfs/v2.pdf"))
+
+		var count int64
+		adminTestDB.Model(&models.MediaAsset{}).Count(&count)
+		assert.Equal(t, int64(0), count)
+	})
+
+	t.Run("should keep files another module still uses", func(t *testing.T) {
+		cleanupAdminTestDB()
+
+		course := models.Course{Title: "Media Course", Description: "Test", Price: 10.0}
+		adminTestDB.Create(&course)
+		shared := store(models.MediaKindVideo, "videos/shared.mp4")
+		adminTestDB.Create(&models.Module{CourseID: course.ID, Title: "Keeper", Description: "Test", Order: 1, VideoContent: &shared})
+
+		mediaService.Release(shared)
+		assert.True(t, stored("videos/shared.mp4"))
+	})
+
+	t.Run("should scan and purge files nothing refers to", func(t *testing.T) {
+		cleanupAdminTestDB()
+
+		course := models.Course{Title: "Media Course", Description: "Test", Price: 10.0, Thumbnail: store(models.MediaKindThumbnail, "thumbnails/used.png")}
+		adminTestDB.Create(&course)
+		store(models.MediaKindPDF, "pdfs/tracked-orphan.pdf")
+		store(models.MediaKindCertificate, "certificates/certificate.txt")
+		_, err := fileStorage.Put(context.Background(), "videos/untracked-orphan.mp4", strings.NewReader("video"), -1, "")
+		assert.NoError(t, err)
+		missing := store(models.MediaKindPDF, "pdfs/missing.pdf")
+		assert.NoError(t, fileStorage.Delete(context.Background(), "pdfs/missing.pdf"))
+
+		// Fresh uploads are left alone, they may not be attached yet
+		report, err := mediaService.ScanOrphans()
+		assert.NoError(t, err)
+		assert.Empty(t, report.Orphans)
+
+		past := time.Now().Add(-2 * time.Hour)
+		for _, key := range []string{"thumbnails/used.png", "pdfs/tracked-orphan.pdf", "certificates/certificate.txt", "videos/untracked-orphan.mp4"} {
+			assert.NoError(t, os.Chtimes(filepath.Join(storageRoot, filepath.FromSlash(key)), past, past))
+		}
+		adminTestDB.Model(&models.MediaAsset{}).Where("1 = 1").Update("created_at", past)
+
+		report, err = mediaService.ScanOrphans()
+		assert.NoError(t, err)
+		keys := []string{}
+		for _, orphan := range report.Orphans {
+			keys = append(keys, orphan.Key)
+		}
+		assert.ElementsMatch(t, []string{"pdfs/tracked-orphan.pdf", "videos/untracked-orphan.mp4"}, keys)
+		assert.Len(t, report.Missing, 1)
+		assert.Equal(t, missing, report.Missing[0].URL)
+
+		report, err = mediaService.PurgeOrphans()
+		assert.NoError(t, err)
+		assert.Equal(t, 2, report.Deleted)
+		assert.False(t, stored("pdfs/tracked-orphan.pdf"))
+		assert.False(t, stored("videos/untracked-orphan.mp4"))
+		assert.True(t, stored("thumbnails/used.png"))
+		assert.True(t, stored("certificates/certificate.txt"))
+
+		var count int64
+		adminTestDB.Model(&models.MediaAsset{}).Where("url = ?", missing).Count(&count)
+		assert.Equal(t, int64(0), count)
+	})
+}
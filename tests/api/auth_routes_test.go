@@ -2,11 +2,20 @@ package api
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 	"yonatan/labpro/config"
 	apiControllers "yonatan/labpro/controllers/api"
 	"yonatan/labpro/database"
@@ -22,6 +31,9 @@ import (
 
 var testDB *gorm.DB
 
+// authTestMailDir collects the mail sent by the auth test router
+var authTestMailDir = filepath.Join(os.TempDir(), "labpro-test-mail")
+
 func setupTestDB() {
 	cfg := config.LoadTestWithProjectRoot()
 
@@ -34,38 +46,64 @@ func setupTestDB() {
 	// Set the global database instance
 	database.DB = testDB
 
-	// Auto migrate the schema
-	err = testDB.AutoMigrate(
-		&models.User{},
-		&models.Course{},
-		&models.Module{},
-		&models.UserCourse{},
-		&models.UserModuleProgress{},
-	)
-	if err != nil {
-		panic("Failed to migrate test database: " + err.Error())
-	}
+	// Build the schema the way deployments do
+	migrateTestDB(testDB)
 }
 
 func cleanupTestDB() {
 	// Clean up test data
+	testDB.Exec("DELETE FROM archived_module_progresses")
+	testDB.Exec("DELETE FROM idempotency_keys")
+	testDB.Exec("DELETE FROM transactions")
 	testDB.Exec("DELETE FROM user_module_progresses")
 	testDB.Exec("DELETE FROM user_courses")
 	testDB.Exec("DELETE FROM modules")
 	testDB.Exec("DELETE FROM courses")
+	testDB.Exec("DELETE FROM instructors")
+	testDB.Exec("DELETE FROM user_roles")
 	testDB.Exec("DELETE FROM users")
 }
 
+// lastMailedToken returns the token from the newest link mailed to the address
+func lastMailedToken(t *testing.T, email string) string {
+	entries, err := os.ReadDir(authTestMailDir)
+	if !assert.NoError(t, err) {
+		return ""
+	}
+
+	// File names start with a timestamp, so the last match is the newest
+	token := ""
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), "-"+email+".eml") {
+			continue
+		}
+		content, _ := os.ReadFile(filepath.Join(authTestMailDir, entry.Name()))
+		if match := mailedTokenPattern.FindSubmatch(content); match != nil {
+			token = string(match[1])
+		}
+	}
+	return token
+}
+
+var mailedTokenPattern = regexp.MustCompile(`token=([0-9a-f]+\.[0-9a-f]+)`)
+
 func setupAuthTestRouter() *gin.Engine {
+	cfg := config.LoadTestWithProjectRoot()
+	return setupAuthTestRouterWithThrottle(services.NewLoginThrottle(testDB, cfg, nil))
+}
+
+func setupAuthTestRouterWithThrottle(loginThrottle *services.LoginThrottle) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
 
 	cfg := config.LoadTestWithProjectRoot()
 	authService := services.NewAuthService(cfg)
-	authController := apiControllers.NewAuthAPIController(authService)
+	accountService := services.NewAccountService(testDB, cfg, services.NewFileMailer(authTestMailDir, cfg.MailFrom))
+	authController := apiControllers.NewAuthAPIController(authService, loginThrottle, accountService, services.NewMFAService(testDB))
+	accessTokenController := apiControllers.NewAccessTokenAPIController(services.NewAccessTokenService(testDB))
 
 	api := router.Group("/api")
-	apiRoutes.SetupAuthRoutes(api, authController, cfg)
+	apiRoutes.SetupAuthRoutes(api, authController, accessTokenController, cfg)
 
 	return router
 }
@@ -523,4 +561,768 @@ func TestAuthRoutes(t *testing.T) {
 			assert.Equal(t, http.StatusUnauthorized, w.Code)
 		})
 	})
+
+	t.Run("POST /api/auth/refresh", func(t *testing.T) {
+		t.Run("should rotate refresh token and reject reuse", func(t *testing.T) {
+			cleanupTestDB() // Clean before test
+
+			user := models.User{
+				Username:  "refreshuser",
+				Email:     "refresh@example.com",
+				FirstName: "Refresh",
+				LastName:  "User",
+			}
+			user.SetPassword("password123")
+			testDB.Create(&user)
+
+			loginData := loginForTokens(t, router, "refreshuser", "password123")
+			refreshToken := loginData["refresh_token"].(string)
+			assert.NotEmpty(t, refreshToken)
+
+			// First refresh succeeds and returns a new pair
+			w := postRefresh(router, refreshToken)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var response map[string]interface{}
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, "success", response["status"])
+
+			data := response["data"].(map[string]interface{})
+			newToken := data["token"].(string)
+			newRefreshToken := data["refresh_token"].(string)
+			assert.NotEqual(t, refreshToken, newRefreshToken)
+
+			// The new access token works
+			profileReq, _ := http.NewRequest("GET", "/api/auth/self", nil)
+			profileReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", newToken))
+			profileW := httptest.NewRecorder()
+			router.ServeHTTP(profileW, profileReq)
+			assert.Equal(t, http.StatusOK, profileW.Code)
+
+			// Reusing the rotated token fails and ends the whole session family
+			w = postRefresh(router, refreshToken)
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+			w = postRefresh(router, newRefreshToken)
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+			profileW = httptest.NewRecorder()
+			router.ServeHTTP(profileW, profileReq)
+			assert.Equal(t, http.StatusUnauthorized, profileW.Code)
+		})
+
+		t.Run("should fail with unknown refresh token", func(t *testing.T) {
+			w := postRefresh(router, "not-a-real-token")
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		})
+
+		t.Run("should fail with missing refresh token", func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/api/auth/refresh", bytes.NewBufferString("{}"))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+
+		t.Run("should purge expired refresh and revoked tokens", func(t *testing.T) {
+			cleanupTestDB() // Clean before test
+
+			user := models.User{
+				Username:  "purgeuser",
+				Email:     "purge@example.com",
+				FirstName: "Purge",
+				LastName:  "User",
+			}
+			user.SetPassword("password123")
+			testDB.Create(&user)
+
+			// A live session, whose refresh token must survive the purge
+			refreshToken := loginForTokens(t, router, "purgeuser", "password123")["refresh_token"].(string)
+
+			past := time.Now().Add(-time.Minute)
+			testDB.Create(&models.RefreshToken{UserID: user.ID, TokenHash: "expired", AccessJTI: "expired", ExpiresAt: past})
+			testDB.Create(&models.RevokedToken{JTI: "expired", UserID: user.ID, ExpiresAt: past})
+			testDB.Create(&models.RevokedToken{JTI: "live", UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)})
+
+			purged, err := services.NewAuthService(config.LoadTestWithProjectRoot()).PurgeExpiredTokens()
+			assert.NoError(t, err)
+			assert.Equal(t, int64(2), purged)
+
+			var count int64
+			testDB.Model(&models.RefreshToken{}).Where("token_hash = ?", "expired").Count(&count)
+			assert.Zero(t, count)
+			testDB.Model(&models.RevokedToken{}).Where("jti = ?", "expired").Count(&count)
+			assert.Zero(t, count)
+			testDB.Model(&models.RevokedToken{}).Where("jti = ?", "live").Count(&count)
+			assert.Equal(t, int64(1), count)
+
+			w := postRefresh(router, refreshToken)
+			assert.Equal(t, http.StatusOK, w.Code)
+		})
+	})
+
+	t.Run("POST /api/auth/logout with session", func(t *testing.T) {
+		t.Run("should revoke access and refresh tokens", func(t *testing.T) {
+			cleanupTestDB() // Clean before test
+
+			user := models.User{
+				Username:  "logoutuser",
+				Email:     "logout@example.com",
+				FirstName: "Logout",
+				LastName:  "User",
+			}
+			user.SetPassword("password123")
+			testDB.Create(&user)
+
+			loginData := loginForTokens(t, router, "logoutuser", "password123")
+			token := loginData["token"].(string)
+			refreshToken := loginData["refresh_token"].(string)
+
+			jsonData, _ := json.Marshal(map[string]interface{}{"refresh_token": refreshToken})
+			req, _ := http.NewRequest("POST", "/api/auth/logout", bytes.NewBuffer(jsonData))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			profileReq, _ := http.NewRequest("GET", "/api/auth/self", nil)
+			profileReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+			profileW := httptest.NewRecorder()
+			router.ServeHTTP(profileW, profileReq)
+			assert.Equal(t, http.StatusUnauthorized, profileW.Code)
+
+			w = postRefresh(router, refreshToken)
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		})
+	})
+
+	t.Run("POST /api/auth/change-password", func(t *testing.T) {
+		t.Run("should change password, clear the forced change and rotate sessions", func(t *testing.T) {
+			cleanupTestDB() // Clean before test
+
+			user := models.User{
+				Username:           "changeuser",
+				Email:              "change@example.com",
+				FirstName:          "Change",
+				LastName:           "User",
+				MustChangePassword: true,
+			}
+			user.SetPassword("password123")
+			testDB.Create(&user)
+
+			loginData := loginForTokens(t, router, "changeuser", "password123")
+			assert.Equal(t, true, loginData["must_change_password"])
+			token := loginData["token"].(string)
+
+			w := postChangePassword(router, token, "wrongpassword", "newpassword456")
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			w = postChangePassword(router, token, "password123", "newpassword456")
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var response map[string]interface{}
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			newToken := response["data"].(map[string]interface{})["token"].(string)
+
+			// The old session has ended, the new one works
+			profileReq, _ := http.NewRequest("GET", "/api/auth/self", nil)
+			profileReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+			profileW := httptest.NewRecorder()
+			router.ServeHTTP(profileW, profileReq)
+			assert.Equal(t, http.StatusUnauthorized, profileW.Code)
+
+			profileReq, _ = http.NewRequest("GET", "/api/auth/self", nil)
+			profileReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", newToken))
+			profileW = httptest.NewRecorder()
+			router.ServeHTTP(profileW, profileReq)
+			assert.Equal(t, http.StatusOK, profileW.Code)
+
+			var updated models.User
+			testDB.First(&updated, "id = ?", user.ID)
+			assert.False(t, updated.MustChangePassword)
+			assert.True(t, updated.CheckPassword("newpassword456"))
+		})
+
+		t.Run("should fail when reusing the current password", func(t *testing.T) {
+			cleanupTestDB() // Clean before test
+
+			user := models.User{
+				Username:  "sameuser",
+				Email:     "same@example.com",
+				FirstName: "Same",
+				LastName:  "User",
+			}
+			user.SetPassword("password123")
+			testDB.Create(&user)
+
+			token := loginForTokens(t, router, "sameuser", "password123")["token"].(string)
+
+			w := postChangePassword(router, token, "password123", "password123")
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	})
+}
+
+func loginForTokens(t *testing.T, router *gin.Engine, identifier, password string) map[string]interface{} {
+	jsonData, _ := json.Marshal(map[string]interface{}{
+		"identifier": identifier,
+		"password":   password,
+	})
+	req, _ := http.NewRequest("POST", "/api/auth/login", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+
+	return response["data"].(map[string]interface{})
+}
+
+func postRefresh(router *gin.Engine, refreshToken string) *httptest.ResponseRecorder {
+	jsonData, _ := json.Marshal(map[string]interface{}{"refresh_token": refreshToken})
+	req, _ := http.NewRequest("POST", "/api/auth/refresh", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func postChangePassword(router *gin.Engine, token, currentPassword, newPassword string) *httptest.ResponseRecorder {
+	jsonData, _ := json.Marshal(map[string]interface{}{
+		"current_password": currentPassword,
+		"new_password":     newPassword,
+		"confirm_password": newPassword,
+	})
+	req, _ := http.NewRequest("POST", "/api/auth/change-password", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestLoginThrottle(t *testing.T) {
+	// Setup test database
+	setupTestDB()
+	defer cleanupTestDB()
+
+	cfg := config.LoadTestWithProjectRoot()
+	cfg.LoginMaxAttempts = "3"
+
+	login := func(router *gin.Engine, identifier, password, ip string) *httptest.ResponseRecorder {
+		jsonData, _ := json.Marshal(map[string]interface{}{
+			"identifier": identifier,
+			"password":   password,
+		})
+		req, _ := http.NewRequest("POST", "/api/auth/login", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = ip + ":1234"
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	createUser := func() models.User {
+		user := models.User{
+			Username:  "lockeduser",
+			Email:     "locked@example.com",
+			FirstName: "Locked",
+			LastName:  "User",
+		}
+		user.SetPassword("password123")
+		testDB.Create(&user)
+		return user
+	}
+
+	t.Run("should lock the account after repeated failures", func(t *testing.T) {
+		cleanupTestDB()
+		loginThrottle := services.NewLoginThrottle(testDB, cfg, nil)
+		router := setupAuthTestRouterWithThrottle(loginThrottle)
+		user := createUser()
+
+		for i := 0; i < 2; i++ {
+			w := login(router, "lockeduser", "wrongpassword", "10.0.0.1")
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		}
+
+		// The third failure reaches the limit; it still answers 401 but tells
+		// the client how long to wait
+		w := login(router, "lockeduser", "wrongpassword", "10.0.0.1")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+		// The lock covers the email address and other IPs, even with the right password
+		w = login(router, "locked@example.com", "password123", "10.0.0.2")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.NotEmpty(t, w.Header().Get("Retry-After"))
+		assert.Greater(t, loginThrottle.LockedFor(user.ID), time.Duration(0))
+	})
+
+	t.Run("should allow login again after an unlock", func(t *testing.T) {
+		cleanupTestDB()
+		loginThrottle := services.NewLoginThrottle(testDB, cfg, nil)
+		router := setupAuthTestRouterWithThrottle(loginThrottle)
+		user := createUser()
+
+		for i := 0; i < 3; i++ {
+			login(router, "lockeduser", "wrongpassword", "10.0.0.1")
+		}
+		w := login(router, "lockeduser", "password123", "10.0.0.1")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+		assert.NoError(t, loginThrottle.Unlock(user.ID))
+		assert.Equal(t, time.Duration(0), loginThrottle.LockedFor(user.ID))
+
+		w = login(router, "lockeduser", "password123", "10.0.0.1")
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("should lock an IP guessing many accounts", func(t *testing.T) {
+		cleanupTestDB()
+		ipCfg := *cfg
+		ipCfg.LoginIPMaxAttempts = "4"
+		router := setupAuthTestRouterWithThrottle(services.NewLoginThrottle(testDB, &ipCfg, nil))
+		createUser()
+
+		for i := 0; i < 4; i++ {
+			login(router, fmt.Sprintf("guess%d", i), "wrongpassword", "10.0.0.9")
+		}
+
+		w := login(router, "lockeduser", "password123", "10.0.0.9")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+		// Other clients are unaffected
+		w = login(router, "lockeduser", "password123", "10.0.0.10")
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
+func TestAccountRecovery(t *testing.T) {
+	// Setup test database
+	setupTestDB()
+	defer cleanupTestDB()
+	os.RemoveAll(authTestMailDir)
+	defer os.RemoveAll(authTestMailDir)
+
+	router := setupAuthTestRouter()
+
+	post := func(path string, body map[string]interface{}, token string) *httptest.ResponseRecorder {
+		jsonData, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", path, bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	register := func() {
+		w := post("/api/auth/register", map[string]interface{}{
+			"username":         "mailuser",
+			"email":            "mail@example.com",
+			"first_name":       "Mail",
+			"last_name":        "User",
+			"password":         "password123",
+			"confirm_password": "password123",
+		}, "")
+		assert.Equal(t, http.StatusCreated, w.Code)
+	}
+
+	t.Run("POST /api/auth/verify-email", func(t *testing.T) {
+		t.Run("should verify the address from the registration mail once", func(t *testing.T) {
+			cleanupTestDB()
+			register()
+
+			token := lastMailedToken(t, "mail@example.com")
+			assert.NotEmpty(t, token)
+
+			w := post("/api/auth/verify-email", map[string]interface{}{"token": token}, "")
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var user models.User
+			testDB.Where("username = ?", "mailuser").First(&user)
+			assert.NotNil(t, user.EmailVerifiedAt)
+
+			// Tokens are single-use
+			w = post("/api/auth/verify-email", map[string]interface{}{"token": token}, "")
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+
+		t.Run("should reject a tampered token", func(t *testing.T) {
+			cleanupTestDB()
+			register()
+
+			secret, _, _ := strings.Cut(lastMailedToken(t, "mail@example.com"), ".")
+			w := post("/api/auth/verify-email", map[string]interface{}{"token": secret + ".00"}, "")
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+
+		t.Run("should resend the link to unverified users only", func(t *testing.T) {
+			cleanupTestDB()
+			register()
+
+			cfg := config.LoadTestWithProjectRoot()
+			tokens, _, err := services.NewAuthService(cfg).Login("mailuser", "password123")
+			assert.NoError(t, err)
+			first := lastMailedToken(t, "mail@example.com")
+
+			w := post("/api/auth/verify-email/resend", nil, tokens.AccessToken)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			second := lastMailedToken(t, "mail@example.com")
+			assert.NotEqual(t, first, second)
+
+			w = post("/api/auth/verify-email", map[string]interface{}{"token": second}, "")
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			w = post("/api/auth/verify-email/resend", nil, tokens.AccessToken)
+			assert.Equal(t, http.StatusConflict, w.Code)
+		})
+	})
+
+	t.Run("POST /api/auth/forgot-password", func(t *testing.T) {
+		t.Run("should answer the same for unknown addresses", func(t *testing.T) {
+			cleanupTestDB()
+
+			w := post("/api/auth/forgot-password", map[string]interface{}{"email": "nobody@example.com"}, "")
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Empty(t, lastMailedToken(t, "nobody@example.com"))
+		})
+
+		t.Run("should reset the password with the mailed token", func(t *testing.T) {
+			cleanupTestDB()
+			register()
+
+			w := post("/api/auth/forgot-password", map[string]interface{}{"email": "mail@example.com"}, "")
+			assert.Equal(t, http.StatusOK, w.Code)
+			token := lastMailedToken(t, "mail@example.com")
+
+			var user models.User
+			testDB.Where("username = ?", "mailuser").First(&user)
+
+			w = post("/api/auth/reset-password", map[string]interface{}{
+				"token":            token,
+				"new_password":     "newpassword123",
+				"confirm_password": "newpassword123",
+			}, "")
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			cfg := config.LoadTestWithProjectRoot()
+			authService := services.NewAuthService(cfg)
+			_, _, err := authService.Login("mailuser", "password123")
+			assert.ErrorIs(t, err, services.ErrInvalidCredentials)
+			_, _, err = authService.Login("mailuser", "newpassword123")
+			assert.NoError(t, err)
+
+			// Receiving the link proves the address
+			testDB.First(&user, "id = ?", user.ID)
+			assert.NotNil(t, user.EmailVerifiedAt)
+
+			w = post("/api/auth/reset-password", map[string]interface{}{
+				"token":            token,
+				"new_password":     "anotherpassword123",
+				"confirm_password": "anotherpassword123",
+			}, "")
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+
+		t.Run("should not accept a verification token for a reset", func(t *testing.T) {
+			cleanupTestDB()
+			register()
+
+			w := post("/api/auth/reset-password", map[string]interface{}{
+				"token":            lastMailedToken(t, "mail@example.com"),
+				"new_password":     "newpassword123",
+				"confirm_password": "newpassword123",
+			}, "")
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	})
+}
+
+// totpTestCode computes the RFC 6238 code an authenticator app shows at the given time
+func totpTestCode(secret string, at time.Time) string {
+	key, _ := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(at.Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value%1000000)
+}
+
+func TestTwoFactor(t *testing.T) {
+	// Setup test database
+	setupTestDB()
+	defer cleanupTestDB()
+
+	router := setupAuthTestRouter()
+
+	post := func(path string, body map[string]interface{}, token string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		jsonData, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", path, bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		data, _ := response["data"].(map[string]interface{})
+		return w, data
+	}
+
+	// enroll creates a user with two-factor authentication switched on and returns
+	// the secret and the recovery codes
+	enroll := func() (string, []interface{}) {
+		user := models.User{
+			Username:  "mfauser",
+			Email:     "mfa@example.com",
+			FirstName: "MFA",
+			LastName:  "User",
+		}
+		user.SetPassword("password123")
+		testDB.Create(&user)
+
+		token := loginForTokens(t, router, "mfauser", "password123")["token"].(string)
+
+		w, data := post("/api/auth/mfa/setup", nil, token)
+		assert.Equal(t, http.StatusOK, w.Code)
+		secret := data["secret"].(string)
+		assert.Contains(t, data["provisioning_uri"], "otpauth://totp/")
+
+		w, data = post("/api/auth/mfa/enable", map[string]interface{}{"code": totpTestCode(secret, time.Now())}, token)
+		assert.Equal(t, http.StatusOK, w.Code)
+		codes := data["recovery_codes"].([]interface{})
+		assert.Len(t, codes, 10)
+
+		return secret, codes
+	}
+
+	startLogin := func() string {
+		data := loginForTokens(t, router, "mfauser", "password123")
+		assert.Equal(t, true, data["mfa_required"])
+		assert.Nil(t, data["token"])
+		return data["mfa_token"].(string)
+	}
+
+	t.Run("should require a second factor after enabling", func(t *testing.T) {
+		cleanupTestDB()
+		secret, _ := enroll()
+
+		mfaToken := startLogin()
+
+		// The pending token is no access token
+		req, _ := http.NewRequest("GET", "/api/auth/self", nil)
+		req.Header.Set("Authorization", "Bearer "+mfaToken)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w, _ = post("/api/auth/mfa/verify", map[string]interface{}{"mfa_token": mfaToken, "code": "000000"}, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		// The code of the next period is accepted once
+		code := totpTestCode(secret, time.Now().Add(30*time.Second))
+		w, data := post("/api/auth/mfa/verify", map[string]interface{}{"mfa_token": mfaToken, "code": code}, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotEmpty(t, data["token"])
+		assert.NotEmpty(t, data["refresh_token"])
+
+		w, _ = post("/api/auth/mfa/verify", map[string]interface{}{"mfa_token": startLogin(), "code": code}, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("should accept each recovery code once", func(t *testing.T) {
+		cleanupTestDB()
+		_, codes := enroll()
+
+		w, _ := post("/api/auth/mfa/verify", map[string]interface{}{"mfa_token": startLogin(), "code": codes[0]}, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w, _ = post("/api/auth/mfa/verify", map[string]interface{}{"mfa_token": startLogin(), "code": codes[0]}, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("should complete a pending login only once", func(t *testing.T) {
+		cleanupTestDB()
+		_, codes := enroll()
+
+		mfaToken := startLogin()
+		w, _ := post("/api/auth/mfa/verify", map[string]interface{}{"mfa_token": mfaToken, "code": codes[0]}, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// Replaying the pending token fails before the code is looked at
+		w, _ = post("/api/auth/mfa/verify", map[string]interface{}{"mfa_token": mfaToken, "code": codes[1]}, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		var unused int64
+		testDB.Model(&models.RecoveryCode{}).Where("used_at IS NULL").Count(&unused)
+		assert.Equal(t, int64(9), unused)
+	})
+
+	t.Run("should throttle wrong codes when changing settings", func(t *testing.T) {
+		cleanupTestDB()
+		_, codes := enroll()
+
+		w, data := post("/api/auth/mfa/verify", map[string]interface{}{"mfa_token": startLogin(), "code": codes[0]}, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		token := data["token"].(string)
+
+		for i := 0; i < 3; i++ {
+			w, _ = post("/api/auth/mfa/disable", map[string]interface{}{"code": "000000"}, token)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		}
+		assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+		// Even a valid code waits out the backoff
+		w, _ = post("/api/auth/mfa/disable", map[string]interface{}{"code": codes[1]}, token)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	})
+
+	t.Run("should disable with a valid code", func(t *testing.T) {
+		cleanupTestDB()
+		_, codes := enroll()
+
+		w, data := post("/api/auth/mfa/verify", map[string]interface{}{"mfa_token": startLogin(), "code": codes[0]}, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		token := data["token"].(string)
+
+		w, _ = post("/api/auth/mfa/disable", map[string]interface{}{"code": "000000"}, token)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w, _ = post("/api/auth/mfa/disable", map[string]interface{}{"code": codes[1]}, token)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		data = loginForTokens(t, router, "mfauser", "password123")
+		assert.NotEmpty(t, data["token"])
+
+		var count int64
+		testDB.Model(&models.RecoveryCode{}).Count(&count)
+		assert.Equal(t, int64(0), count)
+	})
+}
+
+func TestPersonalAccessTokens(t *testing.T) {
+	// Setup test database
+	setupTestDB()
+	defer cleanupTestDB()
+
+	router := setupAuthTestRouter()
+
+	request := func(method, path string, body map[string]interface{}, token string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		jsonData, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, path, bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w, response
+	}
+
+	login := func() string {
+		user := models.User{
+			Username:  "scriptuser",
+			Email:     "script@example.com",
+			FirstName: "Script",
+			LastName:  "User",
+		}
+		user.SetPassword("password123")
+		testDB.Create(&user)
+
+		return loginForTokens(t, router, "scriptuser", "password123")["token"].(string)
+	}
+
+	t.Run("should create, list and revoke a token", func(t *testing.T) {
+		cleanupTestDB()
+		session := login()
+
+		w, response := request("POST", "/api/auth/tokens", map[string]interface{}{
+			"name":   "reports",
+			"scopes": []string{"users", "courses", "users"},
+		}, session)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		data := response["data"].(map[string]interface{})
+		plain := data["token"].(string)
+		assert.True(t, strings.HasPrefix(plain, models.AccessTokenPrefix))
+		assert.Equal(t, plain[len(plain)-4:], data["hint"])
+		assert.Equal(t, []interface{}{"courses", "users"}, data["scopes"])
+
+		// Only the hash is stored
+		var stored models.PersonalAccessToken
+		testDB.First(&stored, "id = ?", data["id"])
+		assert.NotEqual(t, plain, stored.TokenHash)
+		assert.WithinDuration(t, time.Now().AddDate(0, 0, 30), stored.ExpiresAt, time.Minute)
+
+		w, response = request("GET", "/api/auth/tokens", nil, session)
+		assert.Equal(t, http.StatusOK, w.Code)
+		tokens := response["data"].([]interface{})
+		assert.Len(t, tokens, 1)
+		assert.Nil(t, tokens[0].(map[string]interface{})["token"])
+
+		w, _ = request("DELETE", "/api/auth/tokens/"+stored.ID, nil, session)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w, _ = request("DELETE", "/api/auth/tokens/"+stored.ID, nil, session)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w, response = request("GET", "/api/auth/tokens", nil, session)
+		assert.Len(t, response["data"], 0)
+	})
+
+	t.Run("should reject unknown scopes and bad expiry", func(t *testing.T) {
+		cleanupTestDB()
+		session := login()
+
+		w, _ := request("POST", "/api/auth/tokens", map[string]interface{}{"name": "bad", "scopes": []string{"everything"}}, session)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w, _ = request("POST", "/api/auth/tokens", map[string]interface{}{"name": "bad", "scopes": []string{}}, session)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w, _ = request("POST", "/api/auth/tokens", map[string]interface{}{"name": "bad", "scopes": []string{"me"}, "expires_in_days": 1000}, session)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("should not let a token manage tokens", func(t *testing.T) {
+		cleanupTestDB()
+		session := login()
+
+		_, response := request("POST", "/api/auth/tokens", map[string]interface{}{"name": "all", "scopes": models.TokenScopes}, session)
+		plain := response["data"].(map[string]interface{})["token"].(string)
+
+		w, _ := request("GET", "/api/auth/tokens", nil, plain)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w, _ = request("GET", "/api/auth/self", nil, plain)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
 }
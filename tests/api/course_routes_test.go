@@ -7,7 +7,10 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 	"yonatan/labpro/config"
 	apiAdminControllers "yonatan/labpro/controllers/api/admin"
 	apiUserControllers "yonatan/labpro/controllers/api/user"
@@ -21,6 +24,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var courseTestDB *gorm.DB
@@ -37,25 +41,23 @@ func setupCourseTestDB() {
 	// Set the global database instance
 	database.DB = courseTestDB
 
-	// Auto migrate the schema
-	err = courseTestDB.AutoMigrate(
-		&models.User{},
-		&models.Course{},
-		&models.Module{},
-		&models.UserCourse{},
-		&models.UserModuleProgress{},
-	)
-	if err != nil {
-		panic("Failed to migrate test database: " + err.Error())
-	}
+	// Build the schema the way deployments do
+	migrateTestDB(courseTestDB)
 }
 
 func cleanupCourseTestDB() {
 	// Clean up test data in correct order due to foreign key constraints
+	courseTestDB.Exec("DELETE FROM content_revisions")
+	courseTestDB.Exec("DELETE FROM media_assets")
+	courseTestDB.Exec("DELETE FROM archived_module_progresses")
+	courseTestDB.Exec("DELETE FROM idempotency_keys")
+	courseTestDB.Exec("DELETE FROM transactions")
 	courseTestDB.Exec("DELETE FROM user_module_progresses")
 	courseTestDB.Exec("DELETE FROM user_courses")
 	courseTestDB.Exec("DELETE FROM modules")
 	courseTestDB.Exec("DELETE FROM courses")
+	courseTestDB.Exec("DELETE FROM instructors")
+	courseTestDB.Exec("DELETE FROM user_roles")
 	courseTestDB.Exec("DELETE FROM users")
 }
 
@@ -70,7 +72,7 @@ func setupCourseTestRouter() *gin.Engine {
 	redisService := services.NewRedisService(cfg.RedisAddr, cfg.RedisPassword)
 
 	// Initialize services
-	courseService := services.NewCourseService(courseTestDB, cfg, redisService)
+	courseService := services.NewCourseService(courseTestDB, cfg, redisService, testFileStorage(cfg))
 
 	// Initialize controllers
 	userCourseController := apiUserControllers.NewCourseAPIController(courseService)
@@ -100,7 +102,6 @@ func createTestCourse() models.Course {
 	course := models.Course{
 		Title:       "Test Course",
 		Description: "A test course for unit testing",
-		Instructor:  "Test Instructor",
 		Price:       100.0,
 		Thumbnail:   "test-thumbnail.jpg",
 		Topics:      pq.StringArray{"programming", "testing"},
@@ -112,8 +113,11 @@ func createTestCourse() models.Course {
 func createUserToken(user models.User) string {
 	cfg := config.LoadTestWithProjectRoot()
 	authService := services.NewAuthService(cfg)
-	token, _, _ := authService.Login(user.Username, "password123")
-	return token
+	tokens, _, err := authService.Login(user.Username, "password123")
+	if err != nil {
+		return ""
+	}
+	return tokens.AccessToken
 }
 
 func TestCourseRoutes(t *testing.T) {
@@ -166,14 +170,12 @@ func TestCourseRoutes(t *testing.T) {
 			course1 := models.Course{
 				Title:       "Go Programming",
 				Description: "Learn Go programming language",
-				Instructor:  "Go Expert",
 				Price:       150.0,
 				Topics:      pq.StringArray{"go", "programming"},
 			}
 			course2 := models.Course{
 				Title:       "Python Basics",
 				Description: "Learn Python fundamentals",
-				Instructor:  "Python Expert",
 				Price:       120.0,
 				Topics:      pq.StringArray{"python", "programming"},
 			}
@@ -222,7 +224,6 @@ func TestCourseRoutes(t *testing.T) {
 				course := models.Course{
 					Title:       fmt.Sprintf("Course %d", i),
 					Description: fmt.Sprintf("Description for course %d", i),
-					Instructor:  "Test Instructor",
 					Price:       float64(100 + i*10),
 					Topics:      pq.StringArray{"programming"},
 				}
@@ -421,6 +422,14 @@ func TestCourseRoutes(t *testing.T) {
 			var updatedUser models.User
 			courseTestDB.First(&updatedUser, "id = ?", user.ID)
 			assert.Equal(t, user.Balance-course.Price, updatedUser.Balance)
+
+			// Verify the purchase was recorded in the ledger
+			var transaction models.Transaction
+			err = courseTestDB.Where("user_id = ? AND type = ?", user.ID, models.TransactionTypePurchase).First(&transaction).Error
+			assert.NoError(t, err)
+			assert.Equal(t, -course.Price, transaction.Amount)
+			assert.Equal(t, updatedUser.Balance, transaction.BalanceAfter)
+			assert.Equal(t, transaction.ID, response["data"].(map[string]interface{})["transaction_id"])
 		})
 
 		t.Run("should fail with insufficient balance", func(t *testing.T) {
@@ -510,6 +519,155 @@ func TestCourseRoutes(t *testing.T) {
 			assert.Equal(t, "error", response["status"])
 		})
 
+		t.Run("should return the original result for a repeated Idempotency-Key", func(t *testing.T) {
+			cleanupCourseTestDB()
+
+			user := createTestUser(false)
+			course := createTestCourse()
+			token := createUserToken(user)
+
+			var results []map[string]interface{}
+			for i := 0; i < 2; i++ {
+				req, _ := http.NewRequest("POST", fmt.Sprintf("/api/courses/%s/buy", course.ID), nil)
+				req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+				req.Header.Set("Idempotency-Key", "retry-key-1")
+
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, http.StatusOK, w.Code)
+
+				var response map[string]interface{}
+				err := json.Unmarshal(w.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				results = append(results, response["data"].(map[string]interface{}))
+			}
+
+			assert.Equal(t, results[0]["transaction_id"], results[1]["transaction_id"])
+			assert.Equal(t, results[0]["user_balance"], results[1]["user_balance"])
+
+			// Only one purchase was made
+			var updatedUser models.User
+			courseTestDB.First(&updatedUser, "id = ?", user.ID)
+			assert.Equal(t, user.Balance-course.Price, updatedUser.Balance)
+
+			var enrollments int64
+			courseTestDB.Model(&models.UserCourse{}).Where("user_id = ? AND course_id = ?", user.ID, course.ID).Count(&enrollments)
+			assert.Equal(t, int64(1), enrollments)
+		})
+
+		t.Run("should reject an Idempotency-Key reused for another course", func(t *testing.T) {
+			cleanupCourseTestDB()
+
+			user := createTestUser(false)
+			course := createTestCourse()
+			otherCourse := createTestCourse()
+			token := createUserToken(user)
+
+			req, _ := http.NewRequest("POST", fmt.Sprintf("/api/courses/%s/buy", course.ID), nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+			req.Header.Set("Idempotency-Key", "retry-key-2")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			req, _ = http.NewRequest("POST", fmt.Sprintf("/api/courses/%s/buy", otherCourse.ID), nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+			req.Header.Set("Idempotency-Key", "retry-key-2")
+
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		})
+
+		t.Run("should not double-spend under parallel purchases", func(t *testing.T) {
+			cleanupCourseTestDB()
+
+			user := createTestUser(false)
+			course := createTestCourse()
+			token := createUserToken(user)
+
+			const attempts = 10
+			codes := make(chan int, attempts)
+			var wg sync.WaitGroup
+			for i := 0; i < attempts; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					req, _ := http.NewRequest("POST", fmt.Sprintf("/api/courses/%s/buy", course.ID), nil)
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+					w := httptest.NewRecorder()
+					router.ServeHTTP(w, req)
+					codes <- w.Code
+				}()
+			}
+			wg.Wait()
+			close(codes)
+
+			succeeded := 0
+			for code := range codes {
+				if code == http.StatusOK {
+					succeeded++
+				} else {
+					assert.Equal(t, http.StatusBadRequest, code)
+				}
+			}
+			assert.Equal(t, 1, succeeded)
+
+			var enrollments int64
+			courseTestDB.Model(&models.UserCourse{}).Where("user_id = ? AND course_id = ?", user.ID, course.ID).Count(&enrollments)
+			assert.Equal(t, int64(1), enrollments)
+
+			var updatedUser models.User
+			courseTestDB.First(&updatedUser, "id = ?", user.ID)
+			assert.Equal(t, user.Balance-course.Price, updatedUser.Balance)
+		})
+
+		t.Run("should not overdraw the balance under parallel purchases of different courses", func(t *testing.T) {
+			cleanupCourseTestDB()
+
+			user := createTestUser(false)
+			courseTestDB.Model(&user).Update("balance", 250.0)
+			token := createUserToken(user)
+
+			const attempts = 5
+			courses := make([]models.Course, attempts)
+			for i := range courses {
+				courses[i] = createTestCourse()
+			}
+
+			var succeeded int32
+			var wg sync.WaitGroup
+			for _, course := range courses {
+				wg.Add(1)
+				go func(courseID string) {
+					defer wg.Done()
+					req, _ := http.NewRequest("POST", fmt.Sprintf("/api/courses/%s/buy", courseID), nil)
+					req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+					w := httptest.NewRecorder()
+					router.ServeHTTP(w, req)
+					if w.Code == http.StatusOK {
+						atomic.AddInt32(&succeeded, 1)
+					}
+				}(course.ID)
+			}
+			wg.Wait()
+
+			// 250 buys exactly two courses priced 100
+			assert.Equal(t, int32(2), succeeded)
+
+			var updatedUser models.User
+			courseTestDB.First(&updatedUser, "id = ?", user.ID)
+			assert.Equal(t, 50.0, updatedUser.Balance)
+
+			var purchases int64
+			courseTestDB.Model(&models.Transaction{}).Where("user_id = ? AND type = ?", user.ID, models.TransactionTypePurchase).Count(&purchases)
+			assert.Equal(t, int64(2), purchases)
+		})
+
 		t.Run("should fail without authentication", func(t *testing.T) {
 			req, _ := http.NewRequest("POST", "/api/courses/some-id/buy", nil)
 
@@ -520,6 +678,183 @@ func TestCourseRoutes(t *testing.T) {
 		})
 	})
 
+	t.Run("POST /api/courses/:courseId/refund", func(t *testing.T) {
+		// buyCourse purchases through the API so the ledger records what was paid
+		buyCourse := func(t *testing.T, token, courseID string) {
+			req, _ := http.NewRequest("POST", fmt.Sprintf("/api/courses/%s/buy", courseID), nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+		}
+
+		// createModules adds count modules and marks the first completed ones as done for user
+		createModules := func(courseID, userID string, count, completed int) {
+			for i := 0; i < count; i++ {
+				module := models.Module{CourseID: courseID, Title: fmt.Sprintf("Module %d", i+1), Description: "Module", Order: i + 1}
+				courseTestDB.Create(&module)
+				if i < completed {
+					courseTestDB.Create(&models.UserModuleProgress{UserID: userID, ModuleID: module.ID, IsCompleted: true})
+				}
+			}
+		}
+
+		postRefund := func(token, courseID string) *httptest.ResponseRecorder {
+			req, _ := http.NewRequest("POST", fmt.Sprintf("/api/courses/%s/refund", courseID), nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+
+		t.Run("should refund course and archive progress", func(t *testing.T) {
+			cleanupCourseTestDB()
+
+			user := createTestUser(false)
+			course := createTestCourse()
+			token := createUserToken(user)
+
+			buyCourse(t, token, course.ID)
+			createModules(course.ID, user.ID, 4, 2)
+			// Progress in the trash is archived along with the rest
+			var trashed models.UserModuleProgress
+			courseTestDB.Where("user_id = ?", user.ID).First(&trashed)
+			courseTestDB.Delete(&trashed)
+
+			w := postRefund(token, course.ID)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var response map[string]interface{}
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, "success", response["status"])
+			assert.Equal(t, "Course refunded successfully", response["message"])
+
+			data := response["data"].(map[string]interface{})
+			assert.Equal(t, course.Price, data["refunded"])
+			assert.Equal(t, user.Balance, data["user_balance"])
+
+			// Balance is restored and the enrollment is gone
+			var updatedUser models.User
+			courseTestDB.First(&updatedUser, "id = ?", user.ID)
+			assert.Equal(t, user.Balance, updatedUser.Balance)
+
+			var enrollments int64
+			courseTestDB.Model(&models.UserCourse{}).Where("user_id = ? AND course_id = ?", user.ID, course.ID).Count(&enrollments)
+			assert.Equal(t, int64(0), enrollments)
+
+			// Progress is moved to the archive rather than deleted
+			var active, archived int64
+			courseTestDB.Unscoped().Model(&models.UserModuleProgress{}).Where("user_id = ?", user.ID).Count(&active)
+			courseTestDB.Model(&models.ArchivedModuleProgress{}).Where("user_id = ? AND course_id = ?", user.ID, course.ID).Count(&archived)
+			assert.Equal(t, int64(0), active)
+			assert.Equal(t, int64(2), archived)
+
+			var refund models.Transaction
+			err = courseTestDB.Where("user_id = ? AND type = ?", user.ID, models.TransactionTypeRefund).First(&refund).Error
+			assert.NoError(t, err)
+			assert.Equal(t, course.Price, refund.Amount)
+		})
+
+		t.Run("should fail after the refund window", func(t *testing.T) {
+			cleanupCourseTestDB()
+
+			user := createTestUser(false)
+			course := createTestCourse()
+			token := createUserToken(user)
+
+			buyCourse(t, token, course.ID)
+			courseTestDB.Model(&models.UserCourse{}).
+				Where("user_id = ? AND course_id = ?", user.ID, course.ID).
+				Update("purchased_at", time.Now().AddDate(0, 0, -30))
+
+			w := postRefund(token, course.ID)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var response map[string]interface{}
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Contains(t, response["message"], "window")
+		})
+
+		t.Run("should fail when progress is above the threshold", func(t *testing.T) {
+			cleanupCourseTestDB()
+
+			user := createTestUser(false)
+			course := createTestCourse()
+			token := createUserToken(user)
+
+			buyCourse(t, token, course.ID)
+			createModules(course.ID, user.ID, 2, 1)
+
+			w := postRefund(token, course.ID)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			var response map[string]interface{}
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Contains(t, response["message"], "progress")
+
+			var enrollments int64
+			courseTestDB.Model(&models.UserCourse{}).Where("user_id = ? AND course_id = ?", user.ID, course.ID).Count(&enrollments)
+			assert.Equal(t, int64(1), enrollments)
+		})
+
+		t.Run("should judge progress saved while the refund waited", func(t *testing.T) {
+			cleanupCourseTestDB()
+
+			user := createTestUser(false)
+			course := createTestCourse()
+			token := createUserToken(user)
+
+			buyCourse(t, token, course.ID)
+			createModules(course.ID, user.ID, 2, 0)
+			var module models.Module
+			courseTestDB.Where("course_id = ?", course.ID).First(&module)
+
+			// A module completion in flight holds the enrollment like CompleteModule does
+			tx := courseTestDB.Begin()
+			assert.NoError(t, tx.Clauses(clause.Locking{Strength: "SHARE"}).
+				Where("user_id = ? AND course_id = ?", user.ID, course.ID).First(&models.UserCourse{}).Error)
+
+			done := make(chan *httptest.ResponseRecorder, 1)
+			go func() { done <- postRefund(token, course.ID) }()
+			select {
+			case <-done:
+				t.Fatal("the refund did not wait for the enrollment lock")
+			case <-time.After(200 * time.Millisecond):
+			}
+
+			assert.NoError(t, tx.Create(&models.UserModuleProgress{UserID: user.ID, ModuleID: module.ID, IsCompleted: true}).Error)
+			assert.NoError(t, tx.Commit().Error)
+
+			w := <-done
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), "progress")
+		})
+
+		t.Run("should fail for a course that was not purchased", func(t *testing.T) {
+			cleanupCourseTestDB()
+
+			user := createTestUser(false)
+			course := createTestCourse()
+
+			w := postRefund(createUserToken(user), course.ID)
+			assert.Equal(t, http.StatusNotFound, w.Code)
+		})
+
+		t.Run("should fail without authentication", func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/api/courses/some-id/refund", nil)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		})
+	})
+
 	// Admin Course Routes Tests
 	t.Run("Admin Course Routes", func(t *testing.T) {
 		t.Run("GET /api/courses (admin)", func(t *testing.T) {
@@ -1014,3 +1349,456 @@ func TestCourseRoutes(t *testing.T) {
 		})
 	})
 }
+
+func TestCourseOwnership(t *testing.T) {
+	setupCourseTestDB()
+	seedTestRoles(courseTestDB)
+	defer cleanupCourseTestDB()
+	router := setupCourseTestRouter()
+
+	roleService := services.NewRoleService(courseTestDB)
+
+	createInstructor := func(username string) models.User {
+		user := models.User{
+			Username:  username,
+			Email:     username + "@example.com",
+			FirstName: "Test",
+			LastName:  "Instructor",
+		}
+		user.SetPassword("password123")
+		courseTestDB.Create(&user)
+		assert.NoError(t, roleService.AssignRole(user.ID, models.RoleInstructor, services.AuditActor{}))
+		return user
+	}
+
+	updateCourse := func(courseID, token string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		writer.WriteField("title", "Renamed Course")
+		writer.WriteField("instructor", "Test Instructor")
+		writer.WriteField("price", "50")
+		writer.Close()
+
+		req, _ := http.NewRequest("PUT", "/api/courses/"+courseID, &body)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("should let an instructor create and edit their own course", func(t *testing.T) {
+		cleanupCourseTestDB()
+
+		instructor := createInstructor("owner")
+		token := createUserToken(instructor)
+
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		writer.WriteField("title", "Owned Course")
+		writer.WriteField("instructor", "Owner")
+		writer.WriteField("price", "25")
+		writer.Close()
+
+		req, _ := http.NewRequest("POST", "/api/courses", &body)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var course models.Course
+		courseTestDB.Where("title = ?", "Owned Course").First(&course)
+		if assert.NotNil(t, course.OwnerID) {
+			assert.Equal(t, instructor.ID, *course.OwnerID)
+		}
+
+		assert.Equal(t, http.StatusOK, updateCourse(course.ID, token).Code)
+	})
+
+	t.Run("should forbid an instructor from editing someone else's course", func(t *testing.T) {
+		cleanupCourseTestDB()
+
+		owner := createInstructor("owner")
+		other := createInstructor("other")
+
+		course := createTestCourse()
+		courseTestDB.Model(&course).Update("owner_id", owner.ID)
+
+		w := updateCourse(course.ID, createUserToken(other))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		req, _ := http.NewRequest("DELETE", "/api/courses/"+course.ID, nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", createUserToken(other)))
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("should let a content editor edit any course", func(t *testing.T) {
+		cleanupCourseTestDB()
+
+		owner := createInstructor("owner")
+		course := createTestCourse()
+		courseTestDB.Model(&course).Update("owner_id", owner.ID)
+
+		editor := createInstructor("editor")
+		assert.NoError(t, roleService.AssignRole(editor.ID, models.RoleContentEditor, services.AuditActor{}))
+
+		assert.Equal(t, http.StatusOK, updateCourse(course.ID, createUserToken(editor)).Code)
+	})
+
+	t.Run("should forbid course authoring without a role", func(t *testing.T) {
+		cleanupCourseTestDB()
+
+		student := createTestUser(false)
+		course := createTestCourse()
+
+		assert.Equal(t, http.StatusForbidden, updateCourse(course.ID, createUserToken(student)).Code)
+	})
+}
+
+func TestCoursePublishing(t *testing.T) {
+	setupCourseTestDB()
+	seedTestRoles(courseTestDB)
+	defer cleanupCourseTestDB()
+	router := setupCourseTestRouter()
+
+	cfg := config.LoadTestWithProjectRoot()
+	courseService := services.NewCourseService(courseTestDB, cfg, services.NewRedisService(cfg.RedisAddr, cfg.RedisPassword), testFileStorage(cfg))
+	roleService := services.NewRoleService(courseTestDB)
+
+	createStaff := func(username string, roles ...string) models.User {
+		user := models.User{
+			Username:  username,
+			Email:     username + "@example.com",
+			FirstName: "Test",
+			LastName:  "Staff",
+		}
+		user.SetPassword("password123")
+		courseTestDB.Create(&user)
+		for _, role := range roles {
+			assert.NoError(t, roleService.AssignRole(user.ID, role, services.AuditActor{}))
+		}
+		return user
+	}
+
+	setStatus := func(courseID, token string, payload map[string]interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest("PUT", "/api/courses/"+courseID+"/status", bytes.NewBuffer(body))
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	get := func(path, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	catalogueSize := func(token string) int {
+		w := get("/api/courses", token)
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		data, _ := response["data"].([]interface{})
+		return len(data)
+	}
+
+	t.Run("should keep new courses out of the catalogue until published", func(t *testing.T) {
+		cleanupCourseTestDB()
+
+		instructor := createStaff("owner", models.RoleInstructor)
+		editor := createStaff("editor", models.RoleContentEditor)
+		student := createTestUser(false)
+		studentToken := createUserToken(student)
+
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		writer.WriteField("title", "Draft Course")
+		writer.WriteField("instructor", "Owner")
+		writer.WriteField("price", "25")
+		writer.Close()
+
+		req, _ := http.NewRequest("POST", "/api/courses", &body)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", createUserToken(instructor)))
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var course models.Course
+		courseTestDB.Where("title = ?", "Draft Course").First(&course)
+		assert.Equal(t, models.CourseStatusDraft, course.Status)
+
+		assert.Equal(t, 0, catalogueSize(studentToken))
+		assert.Equal(t, http.StatusNotFound, get("/api/courses/"+course.ID, studentToken).Code)
+		assert.Equal(t, http.StatusOK, get("/api/courses/"+course.ID, createUserToken(instructor)).Code)
+
+		_, err := courseService.BuyCourse(course.ID, student.ID, "")
+		assert.ErrorIs(t, err, services.ErrCourseNotPurchasable)
+
+		// The owner may submit the course for review but not publish it
+		assert.Equal(t, http.StatusOK, setStatus(course.ID, createUserToken(instructor), map[string]interface{}{"status": "review"}).Code)
+		assert.Equal(t, http.StatusForbidden, setStatus(course.ID, createUserToken(instructor), map[string]interface{}{"status": "published"}).Code)
+
+		assert.Equal(t, http.StatusOK, setStatus(course.ID, createUserToken(editor), map[string]interface{}{"status": "published"}).Code)
+		assert.Equal(t, 1, catalogueSize(studentToken))
+		assert.Equal(t, http.StatusOK, get("/api/courses/"+course.ID, studentToken).Code)
+
+		var event models.AuditEvent
+		assert.NoError(t, courseTestDB.Where("action = ? AND target_id = ?", models.AuditCourseStatus, course.ID).Order("created_at DESC").First(&event).Error)
+	})
+
+	t.Run("should keep archived courses open to enrolled users only", func(t *testing.T) {
+		cleanupCourseTestDB()
+
+		editor := createStaff("editor", models.RoleContentEditor)
+		enrolled := createTestUser(false)
+		course := createTestCourse()
+		_, err := courseService.BuyCourse(course.ID, enrolled.ID, "")
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, setStatus(course.ID, createUserToken(editor), map[string]interface{}{"status": "archived"}).Code)
+
+		outsider := createStaff("outsider")
+		outsiderToken := createUserToken(outsider)
+		assert.Equal(t, 0, catalogueSize(outsiderToken))
+		assert.Equal(t, http.StatusNotFound, get("/api/courses/"+course.ID, outsiderToken).Code)
+		assert.Equal(t, http.StatusOK, get("/api/courses/"+course.ID, createUserToken(enrolled)).Code)
+
+		_, err = courseService.BuyCourse(course.ID, outsider.ID, "")
+		assert.ErrorIs(t, err, services.ErrCourseNotPurchasable)
+	})
+
+	t.Run("should reject schedules that do not fit the status", func(t *testing.T) {
+		cleanupCourseTestDB()
+
+		editor := createStaff("editor", models.RoleContentEditor)
+		token := createUserToken(editor)
+		course := createTestCourse()
+		soon := time.Now().Add(time.Hour)
+
+		w := setStatus(course.ID, token, map[string]interface{}{"status": "published", "publish_at": soon})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = setStatus(course.ID, token, map[string]interface{}{"status": "draft", "publish_at": soon, "unpublish_at": soon.Add(-time.Minute)})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = setStatus(course.ID, token, map[string]interface{}{"status": "hidden"})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("should publish and archive courses on schedule", func(t *testing.T) {
+		cleanupCourseTestDB()
+
+		editor := createStaff("editor", models.RoleContentEditor)
+		past := time.Now().Add(-time.Minute)
+		later := time.Now().Add(time.Hour)
+
+		scheduled := createTestCourse()
+		_, err := courseService.SetCourseStatus(editor, scheduled.ID, services.CourseStatusChange{Status: models.CourseStatusReview, PublishAt: &past}, services.AuditActor{})
+		assert.NoError(t, err)
+
+		expiring := createTestCourse()
+		_, err = courseService.SetCourseStatus(editor, expiring.ID, services.CourseStatusChange{Status: models.CourseStatusPublished, UnpublishAt: &past}, services.AuditActor{})
+		assert.NoError(t, err)
+
+		waiting := createTestCourse()
+		_, err = courseService.SetCourseStatus(editor, waiting.ID, services.CourseStatusChange{Status: models.CourseStatusDraft, PublishAt: &later}, services.AuditActor{})
+		assert.NoError(t, err)
+
+		flipped, err := courseService.PublishScheduled()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), flipped.Published)
+		assert.Equal(t, int64(1), flipped.Archived)
+
+		courseTestDB.First(&scheduled, "id = ?", scheduled.ID)
+		assert.Equal(t, models.CourseStatusPublished, scheduled.Status)
+		assert.Nil(t, scheduled.PublishAt)
+
+		courseTestDB.First(&expiring, "id = ?", expiring.ID)
+		assert.Equal(t, models.CourseStatusArchived, expiring.Status)
+		assert.Nil(t, expiring.UnpublishAt)
+
+		courseTestDB.First(&waiting, "id = ?", waiting.ID)
+		assert.Equal(t, models.CourseStatusDraft, waiting.Status)
+		assert.NotNil(t, waiting.PublishAt)
+	})
+}
+
+func TestContentRevisions(t *testing.T) {
+	setupCourseTestDB()
+	seedTestRoles(courseTestDB)
+	defer cleanupCourseTestDB()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	cfg := config.LoadTestWithProjectRoot()
+	redisService := services.NewRedisService(cfg.RedisAddr, cfg.RedisPassword)
+	courseService := services.NewCourseService(courseTestDB, cfg, redisService, testFileStorage(cfg))
+	moduleService := services.NewModuleService(courseTestDB, cfg, testFileStorage(cfg))
+	api := router.Group("/api")
+	apiRoutes.SetupCourseRoutes(api, apiAdminControllers.NewCourseAPIController(courseService), apiUserControllers.NewCourseAPIController(courseService), cfg)
+	apiRoutes.SetupRevisionRoutes(api, apiAdminControllers.NewRevisionAPIController(services.NewRevisionService(courseTestDB, cfg, redisService, testFileStorage(cfg))), cfg)
+
+	request := func(method, path, token string) map[string]interface{} {
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, path)
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response
+	}
+
+	t.Run("should record course edits and roll them back", func(t *testing.T) {
+		cleanupCourseTestDB()
+
+		admin := createTestUser(true)
+		token := createUserToken(admin)
+		actor := services.AuditActor{UserID: admin.ID, Username: admin.Username}
+
+		course, err := courseService.CreateCourse(&models.Course{Title: "First Title", Price: 10, Topics: pq.StringArray{"go"}}, actor)
+		assert.NoError(t, err)
+
+		edited := *course
+		edited.Title = "Second Title"
+		edited.Price = 20
+		_, err = courseService.UpdateCourse(&edited, actor)
+		assert.NoError(t, err)
+
+		response := request("GET", "/api/courses/"+course.ID+"/revisions", token)
+		revisions := response["data"].([]interface{})
+		assert.Len(t, revisions, 2)
+		assert.Equal(t, float64(2), revisions[0].(map[string]interface{})["number"])
+
+		response = request("GET", "/api/courses/"+course.ID+"/revisions/2", token)
+		changes := response["data"].(map[string]interface{})["changes"].([]interface{})
+		if assert.Len(t, changes, 2) {
+			title := changes[0].(map[string]interface{})
+			assert.Equal(t, "title", title["field"])
+			assert.Equal(t, "First Title", title["before"])
+			assert.Equal(t, "Second Title", title["after"])
+			assert.Equal(t, "price", changes[1].(map[string]interface{})["field"])
+		}
+
+		request("POST", "/api/courses/"+course.ID+"/revisions/1/rollback", token)
+
+		var restored models.Course
+		courseTestDB.First(&restored, "id = ?", course.ID)
+		assert.Equal(t, "First Title", restored.Title)
+		assert.Equal(t, float64(10), restored.Price)
+
+		var latest models.ContentRevision
+		courseTestDB.Where("target_id = ?", course.ID).Order("number DESC").First(&latest)
+		assert.Equal(t, 3, latest.Number)
+		if assert.NotNil(t, latest.RestoredFrom) {
+			assert.Equal(t, 1, *latest.RestoredFrom)
+		}
+	})
+
+	t.Run("should keep the original content of courses edited for the first time", func(t *testing.T) {
+		cleanupCourseTestDB()
+
+		course := createTestCourse()
+		edited := course
+		edited.Title = "Renamed"
+		_, err := courseService.UpdateCourse(&edited, services.AuditActor{})
+		assert.NoError(t, err)
+
+		var revisions []models.ContentRevision
+		courseTestDB.Where("target_id = ?", course.ID).Order("number").Find(&revisions)
+		if assert.Len(t, revisions, 2) {
+			assert.Contains(t, revisions[0].Content, "Test Course")
+			assert.Contains(t, revisions[1].Content, "Renamed")
+		}
+	})
+
+	t.Run("should restore a module's replaced PDF", func(t *testing.T) {
+		cleanupCourseTestDB()
+
+		admin := createTestUser(true)
+		token := createUserToken(admin)
+		course := createTestCourse()
+
+		oldPDF := "/uploads/pdfs/old.pdf"
+		newPDF := "/uploads/pdfs/new.pdf"
+		module, err := moduleService.CreateModule(course.ID, "Module", "Description", &oldPDF, nil, services.AuditActor{})
+		assert.NoError(t, err)
+		_, err = moduleService.UpdateModule(module.ID, "Module", "Description", &newPDF, nil, services.AuditActor{})
+		assert.NoError(t, err)
+
+		response := request("GET", "/api/modules/"+module.ID+"/revisions/2", token)
+		changes := response["data"].(map[string]interface{})["changes"].([]interface{})
+		if assert.Len(t, changes, 1) {
+			assert.Equal(t, "pdf_content", changes[0].(map[string]interface{})["field"])
+		}
+
+		request("POST", "/api/modules/"+module.ID+"/revisions/1/rollback", token)
+
+		var restored models.Module
+		courseTestDB.First(&restored, "id = ?", module.ID)
+		if assert.NotNil(t, restored.PDFContent) {
+			assert.Equal(t, oldPDF, *restored.PDFContent)
+		}
+	})
+
+	t.Run("should hide revisions of other instructors' courses", func(t *testing.T) {
+		cleanupCourseTestDB()
+
+		instructor := models.User{Username: "instructor", Email: "instructor@example.com", FirstName: "Test", LastName: "Instructor"}
+		instructor.SetPassword("password123")
+		courseTestDB.Create(&instructor)
+		assert.NoError(t, services.NewRoleService(courseTestDB).AssignRole(instructor.ID, models.RoleInstructor, services.AuditActor{}))
+
+		course := createTestCourse()
+
+		req, _ := http.NewRequest("GET", "/api/courses/"+course.ID+"/revisions", nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", createUserToken(instructor)))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
+
+func TestVerifiedEmailPurchases(t *testing.T) {
+	// Setup test database
+	setupCourseTestDB()
+	defer cleanupCourseTestDB()
+
+	cfg := config.LoadTestWithProjectRoot()
+	cfg.AllowUnverifiedPurchases = "false"
+	courseService := services.NewCourseService(courseTestDB, cfg, services.NewRedisService(cfg.RedisAddr, cfg.RedisPassword), testFileStorage(cfg))
+
+	t.Run("should refuse purchases from unverified users when required", func(t *testing.T) {
+		cleanupCourseTestDB()
+		user := createTestUser(false)
+		course := createTestCourse()
+
+		_, err := courseService.BuyCourse(course.ID, user.ID, "")
+		assert.ErrorIs(t, err, services.ErrEmailNotVerified)
+	})
+
+	t.Run("should allow purchases once the email is verified", func(t *testing.T) {
+		cleanupCourseTestDB()
+		user := createTestUser(false)
+		course := createTestCourse()
+		courseTestDB.Model(&user).Update("email_verified_at", time.Now())
+
+		_, err := courseService.BuyCourse(course.ID, user.ID, "")
+		assert.NoError(t, err)
+	})
+}
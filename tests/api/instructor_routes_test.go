@@ -0,0 +1,315 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"yonatan/labpro/config"
+	apiAdminControllers "yonatan/labpro/controllers/api/admin"
+	apiUserControllers "yonatan/labpro/controllers/api/user"
+	"yonatan/labpro/database"
+	"yonatan/labpro/models"
+	apiRoutes "yonatan/labpro/routes/api"
+	"yonatan/labpro/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+var instructorTestDB *gorm.DB
+
+func setupInstructorTestDB() {
+	cfg := config.LoadTestWithProjectRoot()
+
+	var err error
+	instructorTestDB, err = gorm.Open(postgres.Open(cfg.DatabaseURL), &gorm.Config{})
+	if err != nil {
+		panic("Failed to connect to test database: " + err.Error())
+	}
+
+	// Set the global database instance
+	database.DB = instructorTestDB
+
+	// Build the schema the way deployments do
+	migrateTestDB(instructorTestDB)
+}
+
+func cleanupInstructorTestDB() {
+	// Clean up test data in correct order due to foreign key constraints
+	instructorTestDB.Exec("DELETE FROM archived_module_progresses")
+	instructorTestDB.Exec("DELETE FROM idempotency_keys")
+	instructorTestDB.Exec("DELETE FROM transactions")
+	instructorTestDB.Exec("DELETE FROM user_module_progresses")
+	instructorTestDB.Exec("DELETE FROM user_courses")
+	instructorTestDB.Exec("DELETE FROM modules")
+	instructorTestDB.Exec("DELETE FROM courses")
+	instructorTestDB.Exec("DELETE FROM instructors")
+	instructorTestDB.Exec("DELETE FROM user_roles")
+	instructorTestDB.Exec("DELETE FROM users")
+}
+
+func setupInstructorTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	cfg := config.LoadTestWithProjectRoot()
+
+	// Initialize services
+	courseService := services.NewCourseService(instructorTestDB, cfg, nil, testFileStorage(cfg))
+	instructorService := services.NewInstructorService(instructorTestDB, nil)
+
+	// Initialize controllers
+	userCourseController := apiUserControllers.NewCourseAPIController(courseService)
+	adminCourseController := apiAdminControllers.NewCourseAPIController(courseService)
+	userInstructorController := apiUserControllers.NewInstructorAPIController(instructorService)
+	adminInstructorController := apiAdminControllers.NewInstructorAPIController(instructorService)
+
+	api := router.Group("/api")
+	apiRoutes.SetupCourseRoutes(api, adminCourseController, userCourseController, cfg)
+	apiRoutes.SetupInstructorRoutes(api, adminInstructorController, userInstructorController, cfg)
+
+	return router
+}
+
+func createInstructorTestUser(username string, role string) models.User {
+	user := models.User{
+		Username:  username,
+		Email:     username + "@test.com",
+		FirstName: "Test",
+		LastName:  username,
+		Balance:   500.0,
+	}
+	user.SetPassword("password123")
+	instructorTestDB.Create(&user)
+	if role != "" {
+		services.NewRoleService(instructorTestDB).AssignRole(user.ID, role, services.AuditActor{})
+	}
+	return user
+}
+
+func createInstructorTestToken(user models.User) string {
+	cfg := config.LoadTestWithProjectRoot()
+	authService := services.NewAuthService(cfg)
+	tokens, _, err := authService.Login(user.Username, "password123")
+	if err != nil {
+		return ""
+	}
+	return tokens.AccessToken
+}
+
+func postInstructorTestCourse(router *gin.Engine, token string, fields map[string]string) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for key, value := range fields {
+		writer.WriteField(key, value)
+	}
+	writer.Close()
+
+	req, _ := http.NewRequest("POST", "/api/courses", &body)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestInstructorRoutes(t *testing.T) {
+	setupInstructorTestDB()
+	seedTestRoles(instructorTestDB)
+	defer cleanupInstructorTestDB()
+	router := setupInstructorTestRouter()
+
+	t.Run("POST /api/courses instructor resolution", func(t *testing.T) {
+		t.Run("should turn an instructor name into a reusable profile", func(t *testing.T) {
+			cleanupInstructorTestDB()
+
+			admin := createInstructorTestUser("courseadmin", models.RoleAdmin)
+			token := createInstructorTestToken(admin)
+
+			w := postInstructorTestCourse(router, token, map[string]string{"title": "Go 101", "instructor": "Jane Doe", "price": "10"})
+			assert.Equal(t, http.StatusCreated, w.Code)
+			w = postInstructorTestCourse(router, token, map[string]string{"title": "Go 201", "instructor": "jane doe", "price": "20"})
+			assert.Equal(t, http.StatusCreated, w.Code)
+
+			var instructors []models.Instructor
+			instructorTestDB.Find(&instructors)
+			assert.Len(t, instructors, 1)
+
+			var linked int64
+			instructorTestDB.Model(&models.Course{}).Where("instructor_id = ?", instructors[0].ID).Count(&linked)
+			assert.Equal(t, int64(2), linked)
+		})
+
+		t.Run("should default to the author's own profile", func(t *testing.T) {
+			cleanupInstructorTestDB()
+
+			teacher := createInstructorTestUser("teacher", models.RoleInstructor)
+			token := createInstructorTestToken(teacher)
+
+			// Without a profile there is no instructor to fall back on
+			w := postInstructorTestCourse(router, token, map[string]string{"title": "No Teacher", "price": "10"})
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			req, _ := http.NewRequest("PUT", "/api/instructors/me", bytes.NewBufferString(`{"bio":"Gopher","links":["https://example.com"]}`))
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+			req.Header.Set("Content-Type", "application/json")
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			w = postInstructorTestCourse(router, token, map[string]string{"title": "With Teacher", "price": "10"})
+			assert.Equal(t, http.StatusCreated, w.Code)
+
+			var course models.Course
+			instructorTestDB.Preload("Instructor").Where("title = ?", "With Teacher").First(&course)
+			assert.Equal(t, "Test teacher", course.InstructorName())
+			if assert.NotNil(t, course.Instructor) {
+				assert.Equal(t, teacher.ID, *course.Instructor.UserID)
+			}
+		})
+	})
+
+	t.Run("GET /api/instructors/:id", func(t *testing.T) {
+		t.Run("should return the profile with its courses", func(t *testing.T) {
+			cleanupInstructorTestDB()
+
+			student := createInstructorTestUser("student", "")
+			instructor := models.Instructor{Name: "Profile Owner", Bio: "Teaches things"}
+			instructorTestDB.Create(&instructor)
+			instructorTestDB.Create(&models.Course{Title: "Taught Course", Price: 15, InstructorID: &instructor.ID})
+			instructorTestDB.Create(&models.Course{Title: "Other Course", Price: 15})
+
+			req, _ := http.NewRequest("GET", "/api/instructors/"+instructor.ID, nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", createInstructorTestToken(student)))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var response map[string]interface{}
+			err := json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+
+			data := response["data"].(map[string]interface{})
+			assert.Equal(t, "Profile Owner", data["name"])
+			courses := data["courses"].([]interface{})
+			assert.Len(t, courses, 1)
+			assert.Equal(t, "Taught Course", courses[0].(map[string]interface{})["title"])
+		})
+
+		t.Run("should return 404 for unknown instructor", func(t *testing.T) {
+			cleanupInstructorTestDB()
+
+			student := createInstructorTestUser("student", "")
+
+			req, _ := http.NewRequest("GET", "/api/instructors/00000000-0000-0000-0000-000000000000", nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", createInstructorTestToken(student)))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusNotFound, w.Code)
+		})
+	})
+
+	t.Run("GET /api/instructors/me/dashboard", func(t *testing.T) {
+		t.Run("should report enrollments and revenue net of refunds", func(t *testing.T) {
+			cleanupInstructorTestDB()
+
+			cfg := config.LoadTestWithProjectRoot()
+			courseService := services.NewCourseService(instructorTestDB, cfg, nil, testFileStorage(cfg))
+			instructorService := services.NewInstructorService(instructorTestDB, nil)
+
+			teacher := createInstructorTestUser("teacher", models.RoleInstructor)
+			profile, err := instructorService.SaveOwnProfile(teacher, "", "", "", nil)
+			assert.NoError(t, err)
+
+			course := models.Course{Title: "Paid Course", Price: 40, InstructorID: &profile.ID}
+			instructorTestDB.Create(&course)
+
+			buyer := createInstructorTestUser("buyer", "")
+			refunder := createInstructorTestUser("refunder", "")
+			_, err = courseService.BuyCourse(course.ID, buyer.ID, "")
+			assert.NoError(t, err)
+			_, err = courseService.BuyCourse(course.ID, refunder.ID, "")
+			assert.NoError(t, err)
+			_, err = courseService.RefundCourse(course.ID, refunder.ID, services.AuditActor{UserID: refunder.ID}, false)
+			assert.NoError(t, err)
+
+			req, _ := http.NewRequest("GET", "/api/instructors/me/dashboard", nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", createInstructorTestToken(teacher)))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var response map[string]interface{}
+			err = json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+
+			data := response["data"].(map[string]interface{})
+			assert.Equal(t, 1.0, data["total_enrollments"])
+			assert.Equal(t, 40.0, data["total_revenue"])
+		})
+
+		t.Run("should return 404 without a profile", func(t *testing.T) {
+			cleanupInstructorTestDB()
+
+			teacher := createInstructorTestUser("teacher", models.RoleInstructor)
+
+			req, _ := http.NewRequest("GET", "/api/instructors/me/dashboard", nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", createInstructorTestToken(teacher)))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusNotFound, w.Code)
+		})
+	})
+
+	t.Run("PUT /api/instructors/:id", func(t *testing.T) {
+		t.Run("should link a migrated profile to a user", func(t *testing.T) {
+			cleanupInstructorTestDB()
+
+			editor := createInstructorTestUser("editor", models.RoleContentEditor)
+			teacher := createInstructorTestUser("teacher", models.RoleInstructor)
+			instructor := models.Instructor{Name: "Legacy Name"}
+			instructorTestDB.Create(&instructor)
+
+			body := fmt.Sprintf(`{"name":"Legacy Name","user_id":"%s"}`, teacher.ID)
+			req, _ := http.NewRequest("PUT", "/api/instructors/"+instructor.ID, bytes.NewBufferString(body))
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", createInstructorTestToken(editor)))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			instructorTestDB.First(&instructor, "id = ?", instructor.ID)
+			if assert.NotNil(t, instructor.UserID) {
+				assert.Equal(t, teacher.ID, *instructor.UserID)
+			}
+		})
+
+		t.Run("should forbid instructors from editing other profiles", func(t *testing.T) {
+			cleanupInstructorTestDB()
+
+			teacher := createInstructorTestUser("teacher", models.RoleInstructor)
+			instructor := models.Instructor{Name: "Someone Else"}
+			instructorTestDB.Create(&instructor)
+
+			req, _ := http.NewRequest("PUT", "/api/instructors/"+instructor.ID, bytes.NewBufferString(`{"name":"Hijacked"}`))
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", createInstructorTestToken(teacher)))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusForbidden, w.Code)
+		})
+	})
+}
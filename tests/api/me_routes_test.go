@@ -0,0 +1,206 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"yonatan/labpro/config"
+	apiAdminControllers "yonatan/labpro/controllers/api/admin"
+	apiUserControllers "yonatan/labpro/controllers/api/user"
+	"yonatan/labpro/database"
+	"yonatan/labpro/models"
+	apiRoutes "yonatan/labpro/routes/api"
+	"yonatan/labpro/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+var meTestDB *gorm.DB
+
+func setupMeTestDB() {
+	cfg := config.LoadTestWithProjectRoot()
+
+	var err error
+	meTestDB, err = gorm.Open(postgres.Open(cfg.DatabaseURL), &gorm.Config{})
+	if err != nil {
+		panic("Failed to connect to test database: " + err.Error())
+	}
+
+	// Set the global database instance
+	database.DB = meTestDB
+
+	// Build the schema the way deployments do
+	migrateTestDB(meTestDB)
+}
+
+func cleanupMeTestDB() {
+	// Clean up test data in correct order due to foreign key constraints
+	meTestDB.Exec("DELETE FROM archived_module_progresses")
+	meTestDB.Exec("DELETE FROM idempotency_keys")
+	meTestDB.Exec("DELETE FROM transactions")
+	meTestDB.Exec("DELETE FROM user_module_progresses")
+	meTestDB.Exec("DELETE FROM user_courses")
+	meTestDB.Exec("DELETE FROM modules")
+	meTestDB.Exec("DELETE FROM courses")
+	meTestDB.Exec("DELETE FROM instructors")
+	meTestDB.Exec("DELETE FROM user_roles")
+	meTestDB.Exec("DELETE FROM users")
+}
+
+func setupMeTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	cfg := config.LoadTestWithProjectRoot()
+
+	// Initialize services
+	courseService := services.NewCourseService(meTestDB, cfg, nil, testFileStorage(cfg))
+	transactionService := services.NewTransactionService(meTestDB)
+
+	// Initialize controllers
+	userCourseController := apiUserControllers.NewCourseAPIController(courseService)
+	adminCourseController := apiAdminControllers.NewCourseAPIController(courseService)
+	userTransactionController := apiUserControllers.NewTransactionAPIController(transactionService)
+
+	api := router.Group("/api")
+	apiRoutes.SetupCourseRoutes(api, adminCourseController, userCourseController, cfg)
+	apiRoutes.SetupMeRoutes(api, userTransactionController, cfg)
+
+	return router
+}
+
+func createMeTestUser(username string) models.User {
+	user := models.User{
+		Username:  username,
+		Email:     username + "@test.com",
+		FirstName: "Test",
+		LastName:  "User",
+		Balance:   0,
+	}
+	user.SetPassword("password123")
+	meTestDB.Create(&user)
+	return user
+}
+
+func createMeTestToken(user models.User) string {
+	cfg := config.LoadTestWithProjectRoot()
+	authService := services.NewAuthService(cfg)
+	tokens, _, err := authService.Login(user.Username, "password123")
+	if err != nil {
+		return ""
+	}
+	return tokens.AccessToken
+}
+
+func TestMeRoutes(t *testing.T) {
+	setupMeTestDB()
+	defer cleanupMeTestDB()
+	router := setupMeTestRouter()
+
+	userService := services.NewUserService(meTestDB)
+
+	t.Run("GET /api/me/transactions", func(t *testing.T) {
+		t.Run("should list only the current user's transactions", func(t *testing.T) {
+			cleanupMeTestDB()
+
+			user := createMeTestUser("ledgeruser")
+			other := createMeTestUser("otheruser")
+
+			_, err := userService.UpdateUserBalance(user.ID, 200.0, services.AuditActor{})
+			assert.NoError(t, err)
+			_, err = userService.UpdateUserBalance(other.ID, 50.0, services.AuditActor{})
+			assert.NoError(t, err)
+
+			course := models.Course{
+				Title:  "Ledger Course",
+				Price:  120.0,
+				Topics: pq.StringArray{"ledger"},
+			}
+			meTestDB.Create(&course)
+
+			token := createMeTestToken(user)
+
+			req, _ := http.NewRequest("POST", fmt.Sprintf("/api/courses/%s/buy", course.ID), nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			req, _ = http.NewRequest("GET", "/api/me/transactions", nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var response map[string]interface{}
+			err = json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+
+			assert.Equal(t, "success", response["status"])
+			assert.Equal(t, "Transactions retrieved successfully", response["message"])
+			assert.NotNil(t, response["pagination"])
+
+			data := response["data"].([]interface{})
+			assert.Len(t, data, 2)
+
+			// The running balance of the newest entry equals the stored balance
+			var updatedUser models.User
+			meTestDB.First(&updatedUser, "id = ?", user.ID)
+			latest := data[0].(map[string]interface{})
+			assert.Equal(t, models.TransactionTypePurchase, latest["type"])
+			assert.Equal(t, "Ledger Course", latest["course_title"])
+			assert.Equal(t, updatedUser.Balance, latest["balance_after"])
+
+			total := 0.0
+			for _, entry := range data {
+				assert.Equal(t, user.ID, entry.(map[string]interface{})["user_id"])
+				total += entry.(map[string]interface{})["amount"].(float64)
+			}
+			assert.Equal(t, updatedUser.Balance, total)
+		})
+
+		t.Run("should filter by type", func(t *testing.T) {
+			cleanupMeTestDB()
+
+			user := createMeTestUser("ledgeruser")
+			_, err := userService.UpdateUserBalance(user.ID, 30.0, services.AuditActor{})
+			assert.NoError(t, err)
+			_, err = userService.UpdateUserBalance(user.ID, -10.0, services.AuditActor{})
+			assert.NoError(t, err)
+
+			req, _ := http.NewRequest("GET", "/api/me/transactions?type=adjustment", nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", createMeTestToken(user)))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var response map[string]interface{}
+			err = json.Unmarshal(w.Body.Bytes(), &response)
+			assert.NoError(t, err)
+
+			data := response["data"].([]interface{})
+			assert.Len(t, data, 1)
+			assert.Equal(t, -10.0, data[0].(map[string]interface{})["amount"])
+		})
+
+		t.Run("should fail without authentication", func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/api/me/transactions", nil)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		})
+	})
+}
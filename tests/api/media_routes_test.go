@@ -0,0 +1,149 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"yonatan/labpro/config"
+	webControllers "yonatan/labpro/controllers/web"
+	"yonatan/labpro/models"
+	webMediaRoutes "yonatan/labpro/routes/web/media"
+	"yonatan/labpro/services"
+	"yonatan/labpro/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMediaRoutes(t *testing.T) {
+	setupModuleTestDB()
+	defer cleanupModuleTestDB()
+
+	cfg := config.LoadTestWithProjectRoot()
+	cfg.MediaURLTTLMinutes = "10"
+	fileStorage := storage.NewLocal(t.TempDir(), cfg.BaseURL+"/uploads")
+	mediaService := services.NewMediaService(moduleTestDB, cfg, fileStorage)
+	moduleService := services.NewModuleService(moduleTestDB, cfg, fileStorage)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	webMediaRoutes.SetupMediaRoutes(router.Group("/"), webControllers.NewMediaController(mediaService))
+
+	request := func(method, path, token string, headers map[string]string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		if token != "" {
+			req.AddCookie(&http.Cookie{Name: "token", Value: token})
+		}
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	cleanupModuleTestDB()
+	student := createModuleTestUser(false)
+	outsider := models.User{Username: "outsider", Email: "outsider@example.com", FirstName: "Out", LastName: "Sider"}
+	outsider.SetPassword("password123")
+	moduleTestDB.Create(&outsider)
+
+	course := createModuleTestCourse()
+	enrollUserInCourse(student.ID, course.ID)
+
+	video, err := mediaService.Put(models.MediaKindVideo, "videos/1_lesson.mp4", strings.NewReader("0123456789"), 10, "video/mp4")
+	assert.NoError(t, err)
+	thumbnail, err := mediaService.Put(models.MediaKindThumbnail, "thumbnails/1_cover.png", strings.NewReader("png"), 3, "image/png")
+	assert.NoError(t, err)
+	_, err = moduleService.CreateModule(course.ID, "Lesson", "A lesson", nil, &video.URL, services.AuditActor{})
+	assert.NoError(t, err)
+
+	studentToken := createModuleUserToken(student)
+	outsiderToken := createModuleUserToken(outsider)
+	mediaPath := "/media/" + video.ID
+
+	t.Run("course files need a session with access to the course", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, request("GET", mediaPath, "", nil).Code)
+		assert.Equal(t, http.StatusForbidden, request("GET", mediaPath, outsiderToken, nil).Code)
+
+		w := request("GET", mediaPath, studentToken, nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "0123456789", w.Body.String())
+		assert.Equal(t, "video/mp4", w.Header().Get("Content-Type"))
+		assert.Equal(t, "private, no-cache", w.Header().Get("Cache-Control"))
+		assert.NotEmpty(t, w.Header().Get("ETag"))
+
+		w = request("GET", mediaPath, studentToken, map[string]string{"If-None-Match": w.Header().Get("ETag")})
+		assert.Equal(t, http.StatusNotModified, w.Code)
+	})
+
+	t.Run("range requests return part of the file", func(t *testing.T) {
+		w := request("GET", mediaPath, studentToken, map[string]string{"Range": "bytes=2-5"})
+		assert.Equal(t, http.StatusPartialContent, w.Code)
+		assert.Equal(t, "2345", w.Body.String())
+		assert.Equal(t, "bytes 2-5/10", w.Header().Get("Content-Range"))
+		assert.Equal(t, "bytes", w.Header().Get("Accept-Ranges"))
+	})
+
+	t.Run("signed URLs work without a session until they expire", func(t *testing.T) {
+		signed, err := url.Parse(mediaService.SignedURL(video.URL))
+		assert.NoError(t, err)
+		assert.Equal(t, mediaPath, signed.Path)
+
+		w := request("GET", signed.RequestURI(), "", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Cache-Control"), "private, max-age=")
+
+		tampered := signed.Query()
+		tampered.Set("expires", "4102444800")
+		assert.Equal(t, http.StatusUnauthorized, request("GET", mediaPath+"?"+tampered.Encode(), "", nil).Code)
+
+		expired := signed.Query()
+		expired.Set("expires", "946684800")
+		assert.Equal(t, http.StatusUnauthorized, request("GET", mediaPath+"?"+expired.Encode(), "", nil).Code)
+
+		// A signature for one file does not open another
+		other, err := mediaService.Put(models.MediaKindPDF, "pdfs/1_notes.pdf", strings.NewReader("%PDF"), 4, "application/pdf")
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, request("GET", "/media/"+other.ID+"?"+signed.RawQuery, "", nil).Code)
+	})
+
+	t.Run("module responses hand out signed URLs to users with access", func(t *testing.T) {
+		modules, _, err := moduleService.GetModules(course.ID, student.ID, 1, 10)
+		assert.NoError(t, err)
+		assert.Contains(t, *modules[0]["video_content"].(*string), "/media/"+video.ID+"?expires=")
+
+		modules, _, err = moduleService.GetModules(course.ID, outsider.ID, 1, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, video.URL, *modules[0]["video_content"].(*string))
+	})
+
+	t.Run("thumbnails are public", func(t *testing.T) {
+		w := request("GET", "/media/"+thumbnail.ID, "", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "public, max-age=86400", w.Header().Get("Cache-Control"))
+	})
+
+	t.Run("unknown and malformed IDs are not found", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, request("GET", "/media/00000000-0000-0000-0000-000000000000", studentToken, nil).Code)
+		assert.Equal(t, http.StatusNotFound, request("GET", "/media/not-a-uuid", studentToken, nil).Code)
+		assert.Equal(t, http.StatusNotFound, request("GET", "/media/1%27%20OR%201=1", "", nil).Code)
+	})
+
+	t.Run("saved /uploads addresses are access checked too", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, request("GET", "/uploads/videos/1_lesson.mp4", "", nil).Code)
+		assert.Equal(t, http.StatusOK, request("GET", "/uploads/videos/1_lesson.mp4", studentToken, nil).Code)
+		assert.Equal(t, http.StatusOK, request("GET", "/uploads/thumbnails/1_cover.png", "", nil).Code)
+		assert.Equal(t, http.StatusNotFound, request("GET", "/uploads/videos/missing.mp4", studentToken, nil).Code)
+	})
+
+	t.Run("certificates are only for their owner", func(t *testing.T) {
+		certificate, err := mediaService.Put(models.MediaKindCertificate, "certificates/certificate_"+student.ID+"_"+course.ID+"_1.txt", strings.NewReader("certificate"), -1, "")
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, request("GET", "/media/"+certificate.ID, studentToken, nil).Code)
+		assert.Equal(t, http.StatusForbidden, request("GET", "/media/"+certificate.ID, outsiderToken, nil).Code)
+	})
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"testing"
+	"yonatan/labpro/config"
+	"yonatan/labpro/database"
+	"yonatan/labpro/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// schemaModels lists every model the application reads and writes
+var schemaModels = []interface{}{
+	&models.Permission{},
+	&models.Role{},
+	&models.User{},
+	&models.Instructor{},
+	&models.Course{},
+	&models.Module{},
+	&models.UserCourse{},
+	&models.UserModuleProgress{},
+	&models.RefreshToken{},
+	&models.RevokedToken{},
+	&models.Transaction{},
+	&models.IdempotencyKey{},
+	&models.ArchivedModuleProgress{},
+	&models.AccountToken{},
+	&models.RecoveryCode{},
+	&models.PersonalAccessToken{},
+	&models.UserIdentity{},
+	&models.AuditEvent{},
+	&models.ContentRevision{},
+	&models.MediaAsset{},
+	&models.ResumableUpload{},
+}
+
+// migrateTestDB applies every pending SQL migration, so the tests run against
+// the schema deployments get rather than one derived from the models
+func migrateTestDB(db *gorm.DB) {
+	if _, err := database.MigrateUp(db, 0); err != nil {
+		panic("Failed to migrate test database: " + err.Error())
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	cfg := config.LoadTestWithProjectRoot()
+	db, err := gorm.Open(postgres.Open(cfg.DatabaseURL), &gorm.Config{})
+	require.NoError(t, err)
+	migrateTestDB(db)
+
+	t.Run("the migrations create every column the models use", func(t *testing.T) {
+		for _, model := range schemaModels {
+			stmt := &gorm.Statement{DB: db}
+			require.NoError(t, stmt.Parse(model))
+			if !assert.True(t, db.Migrator().HasTable(model), stmt.Schema.Table) {
+				continue
+			}
+			for _, field := range stmt.Schema.Fields {
+				if field.DBName != "" {
+					assert.True(t, db.Migrator().HasColumn(model, field.DBName), "%s.%s", stmt.Schema.Table, field.DBName)
+				}
+			}
+		}
+	})
+
+	t.Run("the ledger refuses deletes of the users and courses it refers to", func(t *testing.T) {
+		user := models.User{Username: "ledgeruser", Email: "ledger@example.com", FirstName: "Ledger", LastName: "User", Balance: 50}
+		require.NoError(t, db.Create(&user).Error)
+		course := models.Course{Title: "Ledger Course", Price: 50}
+		require.NoError(t, db.Create(&course).Error)
+		require.NoError(t, db.Create(&models.Transaction{UserID: user.ID, Type: models.TransactionTypePurchase, Amount: -50,
+			BalanceBefore: 100, BalanceAfter: 50, CourseID: &course.ID, ActorID: &user.ID}).Error)
+		defer func() {
+			db.Exec("DELETE FROM transactions WHERE user_id = ?", user.ID)
+			db.Exec("DELETE FROM courses WHERE id = ?", course.ID)
+			db.Exec("DELETE FROM users WHERE id = ?", user.ID)
+		}()
+
+		assert.Error(t, db.Exec("DELETE FROM users WHERE id = ?", user.ID).Error)
+		assert.Error(t, db.Exec("DELETE FROM courses WHERE id = ?", course.ID).Error)
+
+		var entry models.Transaction
+		require.NoError(t, db.First(&entry, "user_id = ?", user.ID).Error)
+		assert.Equal(t, course.ID, *entry.CourseID)
+		assert.Equal(t, user.ID, *entry.ActorID)
+	})
+
+	t.Run("migrating all the way down and up again ends where it started", func(t *testing.T) {
+		migrations, err := database.LoadMigrations()
+		require.NoError(t, err)
+
+		// Always leave the schema in place for the other suites
+		defer migrateTestDB(db)
+
+		reverted, err := database.MigrateDown(db, len(migrations))
+		require.NoError(t, err)
+		assert.Len(t, reverted, len(migrations))
+
+		pending, err := database.PendingMigrations(db)
+		require.NoError(t, err)
+		assert.Len(t, pending, len(migrations))
+
+		// Down migrations leave nothing behind but the bookkeeping table
+		tables, err := db.Migrator().GetTables()
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"schema_migrations"}, tables)
+
+		applied, err := database.MigrateUp(db, 0)
+		require.NoError(t, err)
+		assert.Len(t, applied, len(migrations))
+
+		pending, err = database.PendingMigrations(db)
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+		for _, model := range schemaModels {
+			assert.True(t, db.Migrator().HasTable(model))
+		}
+
+		// The role seed comes back with the schema
+		var admin models.Role
+		assert.NoError(t, db.Preload("Permissions").Where("name = ?", models.RoleAdmin).First(&admin).Error)
+		assert.NotEmpty(t, admin.Permissions)
+	})
+}
@@ -17,6 +17,7 @@ import (
 	"yonatan/labpro/models"
 	apiRoutes "yonatan/labpro/routes/api"
 	"yonatan/labpro/services"
+	"yonatan/labpro/storage"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lib/pq"
@@ -39,25 +40,24 @@ func setupModuleTestDB() {
 	// Set the global database instance
 	database.DB = moduleTestDB
 
-	// Auto migrate the schema
-	err = moduleTestDB.AutoMigrate(
-		&models.User{},
-		&models.Course{},
-		&models.Module{},
-		&models.UserCourse{},
-		&models.UserModuleProgress{},
-	)
-	if err != nil {
-		panic("Failed to migrate test database: " + err.Error())
-	}
+	// Build the schema the way deployments do
+	migrateTestDB(moduleTestDB)
 }
 
 func cleanupModuleTestDB() {
 	// Clean up test data in correct order due to foreign key constraints
+	moduleTestDB.Exec("DELETE FROM resumable_uploads")
+	moduleTestDB.Exec("DELETE FROM content_revisions")
+	moduleTestDB.Exec("DELETE FROM media_assets")
+	moduleTestDB.Exec("DELETE FROM archived_module_progresses")
+	moduleTestDB.Exec("DELETE FROM idempotency_keys")
+	moduleTestDB.Exec("DELETE FROM transactions")
 	moduleTestDB.Exec("DELETE FROM user_module_progresses")
 	moduleTestDB.Exec("DELETE FROM user_courses")
 	moduleTestDB.Exec("DELETE FROM modules")
 	moduleTestDB.Exec("DELETE FROM courses")
+	moduleTestDB.Exec("DELETE FROM instructors")
+	moduleTestDB.Exec("DELETE FROM user_roles")
 	moduleTestDB.Exec("DELETE FROM users")
 }
 
@@ -69,7 +69,7 @@ func setupModuleTestRouter() *gin.Engine {
 	cfg := config.LoadTestWithProjectRoot()
 
 	// Initialize services
-	moduleService := services.NewModuleService(moduleTestDB, cfg)
+	moduleService := services.NewModuleService(moduleTestDB, cfg, testFileStorage(cfg))
 
 	// Initialize controllers
 	userModuleController := apiUserControllers.NewModuleAPIController(moduleService)
@@ -99,7 +99,6 @@ func createModuleTestCourse() models.Course {
 	course := models.Course{
 		Title:       "Test Course",
 		Description: "A test course for module testing",
-		Instructor:  "Test Instructor",
 		Price:       100.0,
 		Thumbnail:   "test-thumbnail.jpg",
 		Topics:      pq.StringArray{"programming", "testing"},
@@ -124,14 +123,22 @@ func createModuleTestModule(courseID string, order int) models.Module {
 func createModuleUserToken(user models.User) string {
 	cfg := config.LoadTestWithProjectRoot()
 	authService := services.NewAuthService(cfg)
-	token, _, _ := authService.Login(user.Username, "password123")
-	return token
+	tokens, _, err := authService.Login(user.Username, "password123")
+	if err != nil {
+		return ""
+	}
+	return tokens.AccessToken
 }
 
 func stringPtr(s string) *string {
 	return &s
 }
 
+// testFileStorage keeps uploads on local disk, like the default configuration
+func testFileStorage(cfg *config.Config) storage.Backend {
+	return storage.NewLocal(cfg.UploadPath, cfg.BaseURL+"/uploads")
+}
+
 // getProjectRoot finds the project root by looking for go.mod file (similar to config package)
 func getProjectRoot() string {
 	dir, err := os.Getwd()
@@ -1202,3 +1209,207 @@ func TestModuleRoutes(t *testing.T) {
 		})
 	})
 }
+
+// fakeFFmpeg writes stand-ins for ffmpeg and ffprobe that report a 1280x720
+// source and write placeholder playlists, segments and posters. Sources that
+// are not local files cannot be probed.
+func fakeFFmpeg(t *testing.T) (string, string) {
+	dir := t.TempDir()
+	ffprobe := filepath.Join(dir, "ffprobe")
+	ffmpeg := filepath.Join(dir, "ffmpeg")
+	assert.NoError(t, os.WriteFile(ffprobe, []byte(`#!/bin/sh
+for arg; do source="$arg"; done
+if [ ! -f "$source" ]; then
+	echo "$source: Server returned 401 Unauthorized" >&2
+	exit 1
+fi
+echo 1280x720
+`), 0755))
+	assert.NoError(t, os.WriteFile(ffmpeg, []byte(`#!/bin/sh
+for arg; do out="$arg"; done
+mkdir -p "$(dirname "$out")"
+case "$out" in
+*.m3u8)
+	printf '#EXTM3U\n#EXTINF:6.0,\nsegment_000.ts\n#EXT-X-ENDLIST\n' > "$out"
+	printf 'segment' > "$(dirname "$out")/segment_000.ts"
+	;;
+*)
+	printf 'poster' > "$out"
+	;;
+esac
+`), 0755))
+	return ffmpeg, ffprobe
+}
+
+func TestVideoTranscoding(t *testing.T) {
+	setupModuleTestDB()
+	defer cleanupModuleTestDB()
+
+	cfg := config.LoadTestWithProjectRoot()
+	cfg.UploadPath = t.TempDir()
+	cfg.VideoTranscoding = "true"
+	cfg.HLSRenditions = "360,720,1080"
+	cfg.FFmpegPath, cfg.FFprobePath = fakeFFmpeg(t)
+
+	fileStorage := storage.NewLocal(cfg.UploadPath, cfg.BaseURL+"/uploads")
+	mediaService := services.NewMediaService(moduleTestDB, cfg, fileStorage)
+	moduleService := services.NewModuleService(moduleTestDB, cfg, fileStorage)
+	transcoder := services.NewTranscodeService(moduleTestDB, cfg, fileStorage)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	apiRoutes.SetupModuleRoutes(router.Group("/api"), apiAdminControllers.NewModuleAPIController(moduleService), apiUserControllers.NewModuleAPIController(moduleService), cfg)
+
+	get := func(path, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	storeVideo := func(key string) string {
+		asset, err := mediaService.Put(models.MediaKindVideo, key, bytes.NewReader([]byte("video")), 5, "video/mp4")
+		assert.NoError(t, err)
+		return asset.URL
+	}
+
+	loadModule := func(id string) models.Module {
+		var module models.Module
+		assert.NoError(t, moduleTestDB.First(&module, "id = ?", id).Error)
+		return module
+	}
+
+	t.Run("uploaded videos are transcoded and streamed to enrolled users", func(t *testing.T) {
+		cleanupModuleTestDB()
+
+		student := createModuleTestUser(false)
+		course := createModuleTestCourse()
+		enrollUserInCourse(student.ID, course.ID)
+		outsider := models.User{Username: "outsider", Email: "outsider@example.com", FirstName: "Out", LastName: "Sider"}
+		outsider.SetPassword("password123")
+		moduleTestDB.Create(&outsider)
+
+		videoURL := storeVideo("videos/1_intro.mp4")
+		module, err := moduleService.CreateModule(course.ID, "Intro", "Introduction", nil, &videoURL, services.AuditActor{})
+		assert.NoError(t, err)
+		assert.Equal(t, models.VideoStatusPending, module.VideoStatus)
+
+		processed, err := transcoder.ProcessNext()
+		assert.True(t, processed)
+		assert.NoError(t, err)
+
+		transcoded := loadModule(module.ID)
+		assert.Equal(t, models.VideoStatusReady, transcoded.VideoStatus)
+		assert.NotNil(t, transcoded.VideoStream)
+
+		processed, err = transcoder.ProcessNext()
+		assert.False(t, processed, "nothing is left in the queue")
+		assert.NoError(t, err)
+
+		token := createModuleUserToken(student)
+		w := get("/api/modules/"+module.ID, token)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		data := response["data"].(map[string]interface{})
+		assert.Equal(t, models.VideoStatusReady, data["video_status"])
+		streamURL, _ := data["video_stream"].(string)
+		assert.NotEmpty(t, streamURL)
+		streamPath := streamURL[len(cfg.BaseURL):]
+
+		w = get(streamPath, token)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/vnd.apple.mpegurl", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), "360p/index.m3u8")
+		assert.Contains(t, w.Body.String(), "RESOLUTION=1280x720")
+		assert.NotContains(t, w.Body.String(), "1080p", "the source is not upscaled")
+
+		segmentPath := streamPath[:len(streamPath)-len("master.m3u8")] + "720p/segment_000.ts"
+		w = get(segmentPath, token)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "video/mp2t", w.Header().Get("Content-Type"))
+
+		w = get(segmentPath, createModuleUserToken(outsider))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = get(fmt.Sprintf("/api/modules/%s/stream/0/master.m3u8", module.ID), token)
+		assert.Equal(t, http.StatusNotFound, w.Code, "old stream versions are gone")
+
+		// A new video queues a new job and drops the stream of the old one
+		newVideoURL := storeVideo("videos/2_intro.mp4")
+		updated, err := moduleService.UpdateModule(module.ID, "Intro", "Introduction", nil, &newVideoURL, services.AuditActor{})
+		assert.NoError(t, err)
+		assert.Equal(t, models.VideoStatusPending, updated.VideoStatus)
+		assert.Nil(t, updated.VideoStream)
+		_, err = os.Stat(filepath.Join(cfg.UploadPath, *transcoded.VideoStream, "master.m3u8"))
+		assert.True(t, os.IsNotExist(err))
+
+		w = get(streamPath, token)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("videos stored before assets were recorded are read from storage", func(t *testing.T) {
+		cleanupModuleTestDB()
+
+		admin := createModuleTestUser(true)
+		course := createModuleTestCourse()
+		assert.NoError(t, os.MkdirAll(filepath.Join(cfg.UploadPath, "videos"), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(cfg.UploadPath, "videos", "1_legacy.mp4"), []byte("video"), 0644))
+		videoURL := cfg.BaseURL + "/uploads/videos/1_legacy.mp4"
+		module := models.Module{CourseID: course.ID, Title: "Legacy", Description: "Legacy video", Order: 1,
+			VideoContent: &videoURL}
+		assert.NoError(t, moduleTestDB.Create(&module).Error)
+
+		req, _ := http.NewRequest("POST", "/api/modules/"+module.ID+"/transcode", nil)
+		req.Header.Set("Authorization", "Bearer "+createModuleUserToken(admin))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+
+		processed, err := transcoder.ProcessNext()
+		assert.True(t, processed)
+		assert.NoError(t, err)
+		transcoded := loadModule(module.ID)
+		assert.Equal(t, models.VideoStatusReady, transcoded.VideoStatus)
+		assert.NotNil(t, transcoded.VideoStream)
+	})
+
+	t.Run("failed jobs are recorded and can be queued again", func(t *testing.T) {
+		cleanupModuleTestDB()
+
+		admin := createModuleTestUser(true)
+		course := createModuleTestCourse()
+		videoURL := storeVideo("videos/1_broken.mp4")
+		module, err := moduleService.CreateModule(course.ID, "Broken", "Broken video", nil, &videoURL, services.AuditActor{})
+		assert.NoError(t, err)
+
+		failing := filepath.Join(t.TempDir(), "ffmpeg")
+		assert.NoError(t, os.WriteFile(failing, []byte("#!/bin/sh\necho 'Invalid data found when processing input' >&2\nexit 1\n"), 0755))
+		workingFFmpeg := cfg.FFmpegPath
+		cfg.FFmpegPath = failing
+		defer func() { cfg.FFmpegPath = workingFFmpeg }()
+
+		processed, err := transcoder.ProcessNext()
+		assert.True(t, processed)
+		assert.Error(t, err)
+
+		failed := loadModule(module.ID)
+		assert.Equal(t, models.VideoStatusFailed, failed.VideoStatus)
+		assert.Contains(t, failed.VideoError, "Invalid data found")
+		assert.Nil(t, failed.VideoStream)
+
+		req, _ := http.NewRequest("POST", "/api/modules/"+module.ID+"/transcode", nil)
+		req.Header.Set("Authorization", "Bearer "+createModuleUserToken(admin))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Equal(t, models.VideoStatusPending, loadModule(module.ID).VideoStatus)
+
+		cfg.FFmpegPath = workingFFmpeg
+		processed, err = transcoder.ProcessNext()
+		assert.True(t, processed)
+		assert.NoError(t, err)
+		assert.Equal(t, models.VideoStatusReady, loadModule(module.ID).VideoStatus)
+	})
+}
@@ -0,0 +1,337 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"yonatan/labpro/config"
+	webControllers "yonatan/labpro/controllers/web"
+	"yonatan/labpro/models"
+	webAuthRoutes "yonatan/labpro/routes/web/auth"
+	"yonatan/labpro/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubIdP is a minimal OpenID Connect provider. It issues codes for the
+// identity in Claims and checks the PKCE verifier when they are redeemed.
+type stubIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	claims map[string]interface{}
+	codes  map[string]url.Values
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	idp := &stubIdP{key: key, codes: map[string]url.Values{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"use": "sig",
+				"kid": "stub",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		clientID, secret, _ := r.BasicAuth()
+
+		idp.mu.Lock()
+		authRequest, ok := idp.codes[r.PostForm.Get("code")]
+		delete(idp.codes, r.PostForm.Get("code"))
+		claims := idp.claims
+		idp.mu.Unlock()
+
+		challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || clientID != "labpro" || secret != "stub-secret" ||
+			authRequest.Get("code_challenge") != base64.RawURLEncoding.EncodeToString(challenge[:]) ||
+			authRequest.Get("redirect_uri") != r.PostForm.Get("redirect_uri") {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":   idp.server.URL,
+			"aud":   "labpro",
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"iat":   time.Now().Unix(),
+			"nonce": authRequest.Get("nonce"),
+		})
+		for name, value := range claims {
+			token.Claims.(jwt.MapClaims)[name] = value
+		}
+		token.Header["kid"] = "stub"
+		signed, _ := token.SignedString(key)
+
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "stub", "token_type": "Bearer", "id_token": signed})
+	})
+	idp.server = httptest.NewServer(mux)
+
+	return idp
+}
+
+// authorize plays the user approving the login at the provider and returns the
+// callback URL the provider would redirect to
+func (idp *stubIdP) authorize(t *testing.T, location string) string {
+	parsed, err := url.Parse(location)
+	assert.NoError(t, err)
+	query := parsed.Query()
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	assert.Equal(t, "code", query.Get("response_type"))
+
+	code := "code-" + query.Get("state")
+	idp.mu.Lock()
+	idp.codes[code] = query
+	idp.mu.Unlock()
+
+	callback, _ := url.Parse(query.Get("redirect_uri"))
+	callback.RawQuery = url.Values{"code": {code}, "state": {query.Get("state")}}.Encode()
+	return callback.RequestURI()
+}
+
+func setupOIDCTestRouter(cfg *config.Config) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	_, file, _, _ := runtime.Caller(0)
+	router.LoadHTMLGlob(filepath.Join(filepath.Dir(file), "..", "..", "templates", "**", "*"))
+
+	authService := services.NewAuthService(cfg)
+	authController := webControllers.NewAuthController(
+		authService,
+		services.NewLoginThrottle(testDB, cfg, nil),
+		services.NewAccountService(testDB, cfg, services.NewFileMailer(authTestMailDir, cfg.MailFrom)),
+		services.NewMFAService(testDB),
+		services.NewOIDCService(testDB, cfg),
+	)
+	accessTokenController := webControllers.NewAccessTokenController(services.NewAccessTokenService(testDB))
+
+	webAuthRoutes.SetupAuthRoutes(router.Group("/"), authController, accessTokenController)
+
+	return router
+}
+
+func TestOIDCLogin(t *testing.T) {
+	// Setup test database
+	setupTestDB()
+	defer cleanupTestDB()
+
+	idp := newStubIdP(t)
+	defer idp.server.Close()
+
+	cfg := config.LoadTestWithProjectRoot()
+	cfg.BaseURL = "http://labpro.test"
+	cfg.OIDCIssuerURL = idp.server.URL
+	cfg.OIDCClientID = "labpro"
+	cfg.OIDCClientSecret = "stub-secret"
+	router := setupOIDCTestRouter(cfg)
+
+	// signIn runs the whole flow and returns the callback response
+	signIn := func(t *testing.T, claims map[string]interface{}) *httptest.ResponseRecorder {
+		idp.mu.Lock()
+		idp.claims = claims
+		idp.mu.Unlock()
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/auth/oidc/login", nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.True(t, strings.HasPrefix(w.Header().Get("Location"), idp.server.URL+"/authorize?"))
+
+		callback := idp.authorize(t, w.Header().Get("Location"))
+		cookies := w.Result().Cookies()
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", callback, nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	sessionCookie := func(w *httptest.ResponseRecorder) string {
+		for _, cookie := range w.Result().Cookies() {
+			if cookie.Name == "token" {
+				return cookie.Value
+			}
+		}
+		return ""
+	}
+
+	t.Run("should show the button only when configured", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/auth/login", nil)
+		router.ServeHTTP(w, req)
+		assert.Contains(t, w.Body.String(), "/auth/oidc/login")
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/auth/oidc/login", nil)
+		setupOIDCTestRouter(config.LoadTestWithProjectRoot()).ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("should create an account for a new verified user", func(t *testing.T) {
+		cleanupTestDB()
+
+		w := signIn(t, map[string]interface{}{
+			"sub":                "subject-1",
+			"email":              "Jane.Doe@example.com",
+			"email_verified":     true,
+			"given_name":         "Jane",
+			"family_name":        "Doe",
+			"preferred_username": "jane.doe",
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotEmpty(t, sessionCookie(w))
+
+		var user models.User
+		assert.NoError(t, testDB.Where("email = ?", "Jane.Doe@example.com").First(&user).Error)
+		assert.Equal(t, "jane.doe", user.Username)
+		assert.Equal(t, "Jane", user.FirstName)
+		assert.NotNil(t, user.EmailVerifiedAt)
+
+		var identity models.UserIdentity
+		assert.NoError(t, testDB.Where("user_id = ?", user.ID).First(&identity).Error)
+		assert.Equal(t, "subject-1", identity.Subject)
+		assert.Equal(t, idp.server.URL, identity.Issuer)
+	})
+
+	t.Run("should link an existing account by verified email", func(t *testing.T) {
+		cleanupTestDB()
+
+		existing := models.User{
+			Username:  "jdoe",
+			Email:     "jane@example.com",
+			FirstName: "Jane",
+			LastName:  "Doe",
+		}
+		existing.SetPassword("password123")
+		testDB.Create(&existing)
+
+		w := signIn(t, map[string]interface{}{"sub": "subject-2", "email": "JANE@example.com", "email_verified": true})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotEmpty(t, sessionCookie(w))
+
+		var count int64
+		testDB.Model(&models.User{}).Count(&count)
+		assert.Equal(t, int64(1), count)
+
+		// Later logins follow the subject, even after the address changes at the provider
+		w = signIn(t, map[string]interface{}{"sub": "subject-2", "email": "jane.doe@example.org", "email_verified": true})
+		assert.Equal(t, http.StatusOK, w.Code)
+		testDB.Model(&models.User{}).Count(&count)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("should refuse an unverified email", func(t *testing.T) {
+		cleanupTestDB()
+
+		w := signIn(t, map[string]interface{}{"sub": "subject-3", "email": "eve@example.com", "email_verified": false})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Empty(t, sessionCookie(w))
+
+		var count int64
+		testDB.Model(&models.User{}).Count(&count)
+		assert.Equal(t, int64(0), count)
+	})
+
+	t.Run("should not create accounts when provisioning is off", func(t *testing.T) {
+		cleanupTestDB()
+
+		closedCfg := *cfg
+		closedCfg.OIDCAutoProvision = "false"
+		closedRouter := setupOIDCTestRouter(&closedCfg)
+
+		idp.mu.Lock()
+		idp.claims = map[string]interface{}{"sub": "subject-4", "email": "new@example.com", "email_verified": true}
+		idp.mu.Unlock()
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/auth/oidc/login", nil)
+		closedRouter.ServeHTTP(w, req)
+		callback := idp.authorize(t, w.Header().Get("Location"))
+
+		cookies := w.Result().Cookies()
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", callback, nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		closedRouter.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("should reject a callback without the matching state", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/auth/oidc/login", nil)
+		router.ServeHTTP(w, req)
+		callback := idp.authorize(t, w.Header().Get("Location"))
+
+		// The cookie is missing, as for a callback forged by another site
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", callback, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("should reject a code redeemed with another PKCE verifier", func(t *testing.T) {
+		cleanupTestDB()
+
+		idp.mu.Lock()
+		idp.claims = map[string]interface{}{"sub": "subject-5", "email": "pkce@example.com", "email_verified": true}
+		idp.mu.Unlock()
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/auth/oidc/login", nil)
+		router.ServeHTTP(w, req)
+		callback := idp.authorize(t, w.Header().Get("Location"))
+
+		var pending *http.Cookie
+		for _, cookie := range w.Result().Cookies() {
+			if cookie.Name == "oidc_login" {
+				pending = cookie
+			}
+		}
+		parts := strings.Split(pending.Value, ".")
+		pending.Value = parts[0] + "." + parts[1] + "." + strings.Repeat("0", len(parts[2]))
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", callback, nil)
+		req.AddCookie(pending)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
@@ -0,0 +1,251 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"yonatan/labpro/config"
+	apiAdminControllers "yonatan/labpro/controllers/api/admin"
+	"yonatan/labpro/filecheck"
+	"yonatan/labpro/models"
+	apiRoutes "yonatan/labpro/routes/api"
+	"yonatan/labpro/services"
+	"yonatan/labpro/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResumableUploads(t *testing.T) {
+	setupModuleTestDB()
+	defer cleanupModuleTestDB()
+
+	cfg := config.LoadTestWithProjectRoot()
+	cfg.UploadPath = t.TempDir()
+	cfg.UploadTempDir = t.TempDir()
+	cfg.ResumableUploadMaxSize = "1024"
+	cfg.MaxFileSize = "64"
+	cfg.VideoTranscoding = "false"
+
+	fileStorage := storage.NewLocal(cfg.UploadPath, cfg.BaseURL+"/uploads")
+	moduleService := services.NewModuleService(moduleTestDB, cfg, fileStorage)
+	uploadService := services.NewUploadService(moduleTestDB, cfg, moduleService)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	apiRoutes.SetupUploadRoutes(router.Group("/api"), apiAdminControllers.NewUploadAPIController(uploadService), cfg)
+
+	request := func(method, path, token string, headers map[string]string, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		req.Header.Set("Tus-Resumable", "1.0.0")
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	metadata := func(filename, filetype string) string {
+		return "filename " + base64.StdEncoding.EncodeToString([]byte(filename)) +
+			",filetype " + base64.StdEncoding.EncodeToString([]byte(filetype))
+	}
+
+	create := func(token string, size int, filename, filetype string) string {
+		w := request("POST", "/api/uploads", token, map[string]string{
+			"Upload-Length":   strconv.Itoa(size),
+			"Upload-Metadata": metadata(filename, filetype),
+		}, "")
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		return w.Header().Get("Location")
+	}
+
+	patch := func(location, token string, offset int, chunk, checksum string) *httptest.ResponseRecorder {
+		headers := map[string]string{
+			"Content-Type":  "application/offset+octet-stream",
+			"Upload-Offset": strconv.Itoa(offset),
+		}
+		if checksum != "" {
+			headers["Upload-Checksum"] = checksum
+		}
+		return request("PATCH", location, token, headers, chunk)
+	}
+
+	sha := func(chunk string) string {
+		sum := sha256.Sum256([]byte(chunk))
+		return "sha256 " + base64.StdEncoding.EncodeToString(sum[:])
+	}
+
+	cleanupModuleTestDB()
+	admin := createModuleTestUser(true)
+	adminToken := createModuleUserToken(admin)
+	course := createModuleTestCourse()
+	module := createModuleTestModule(course.ID, 1)
+
+	t.Run("discovery needs no token", func(t *testing.T) {
+		w := request("OPTIONS", "/api/uploads", "", nil, "")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "1.0.0", w.Header().Get("Tus-Version"))
+		assert.Contains(t, w.Header().Get("Tus-Extension"), "checksum")
+		assert.Equal(t, "1024", w.Header().Get("Tus-Max-Size"))
+		assert.Contains(t, w.Header().Get("Tus-Checksum-Algorithm"), "sha256")
+	})
+
+	t.Run("uploads need a token", func(t *testing.T) {
+		w := request("POST", "/api/uploads", "", map[string]string{"Upload-Length": "10"}, "")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("a video arrives in checked chunks and is attached to a module", func(t *testing.T) {
+		// Ten bytes that start like an MP4 file
+		video := "\x00\x00\x00\x0aftyp89"
+
+		location := create(adminToken, 10, "../lecture.mp4", "video/mp4")
+		assert.True(t, strings.HasPrefix(location, "/api/uploads/"))
+
+		w := request("HEAD", location, adminToken, nil, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "0", w.Header().Get("Upload-Offset"))
+		assert.Equal(t, "10", w.Header().Get("Upload-Length"))
+		assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+		w = patch(location, adminToken, 0, video[:5], sha(video[:5]))
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "5", w.Header().Get("Upload-Offset"))
+		assert.NotEmpty(t, w.Header().Get("Upload-Expires"))
+
+		// A corrupted chunk is dropped and the offset stays where it was
+		w = patch(location, adminToken, 5, "typ8X", sha(video[5:]))
+		assert.Equal(t, 460, w.Code)
+		assert.Equal(t, "5", request("HEAD", location, adminToken, nil, "").Header().Get("Upload-Offset"))
+
+		// So is a chunk sent for the wrong offset
+		w = patch(location, adminToken, 3, video[3:], "")
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		w = patch(location, adminToken, 5, video[5:], "md5 bm90IGEgc3Vt")
+		assert.Equal(t, 460, w.Code)
+		w = patch(location, adminToken, 5, video[5:], "crc32 AAAA")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = patch(location, adminToken, 5, video[5:], sha(video[5:]))
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "10", w.Header().Get("Upload-Offset"))
+
+		w = request("POST", location+"/attach", adminToken, map[string]string{"Content-Type": "application/json"}, `{"module_id":"`+module.ID+`"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var updated models.Module
+		require.NoError(t, moduleTestDB.First(&updated, "id = ?", module.ID).Error)
+		require.NotNil(t, updated.VideoContent)
+		assert.True(t, strings.HasSuffix(*updated.VideoContent, "_lecture.mp4"))
+
+		var asset models.MediaAsset
+		require.NoError(t, moduleTestDB.First(&asset, "url = ?", *updated.VideoContent).Error)
+		assert.Equal(t, models.MediaKindVideo, asset.Kind)
+		assert.Equal(t, int64(10), asset.Size)
+		data, err := os.ReadFile(filepath.Join(cfg.UploadPath, asset.Key))
+		require.NoError(t, err)
+		assert.Equal(t, video, string(data))
+
+		// The upload is gone once attached
+		assert.Equal(t, http.StatusNotFound, request("HEAD", location, adminToken, nil, "").Code)
+		entries, _ := os.ReadDir(cfg.UploadTempDir)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("incomplete uploads cannot be attached", func(t *testing.T) {
+		location := create(adminToken, 10, "notes.pdf", "application/pdf")
+		assert.Equal(t, http.StatusNoContent, patch(location, adminToken, 0, "%PDF", "").Code)
+
+		w := request("POST", location+"/attach", adminToken, map[string]string{"Content-Type": "application/json"}, `{"module_id":"`+module.ID+`"}`)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		// Bytes past the declared length are refused
+		w = patch(location, adminToken, 4, "more than six", "")
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		assert.Equal(t, "4", request("HEAD", location, adminToken, nil, "").Header().Get("Upload-Offset"))
+
+		assert.Equal(t, http.StatusNoContent, request("DELETE", location, adminToken, nil, "").Code)
+		assert.Equal(t, http.StatusNotFound, request("HEAD", location, adminToken, nil, "").Code)
+	})
+
+	t.Run("uploads whose content does not match their type are refused", func(t *testing.T) {
+		location := create(adminToken, 10, "clip.mp4", "video/mp4")
+		assert.Equal(t, http.StatusNoContent, patch(location, adminToken, 0, "MZ\x90\x00exe...", "").Code)
+
+		w := request("POST", location+"/attach", adminToken, map[string]string{"Content-Type": "application/json"}, `{"module_id":"`+module.ID+`"}`)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, http.StatusNotFound, request("HEAD", location, adminToken, nil, "").Code)
+	})
+
+	t.Run("uploads are checked when they start", func(t *testing.T) {
+		w := request("POST", "/api/uploads", adminToken, map[string]string{"Upload-Length": "2048", "Upload-Metadata": metadata("big.mp4", "video/mp4")}, "")
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+		w = request("POST", "/api/uploads", adminToken, map[string]string{"Upload-Length": "10", "Upload-Metadata": metadata("tool.exe", "application/x-msdownload")}, "")
+		assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+
+		w = request("POST", "/api/uploads", adminToken, map[string]string{"Upload-Length": "10", "Upload-Metadata": metadata("clip.mp4", "video/mp4"), "Tus-Resumable": "0.2.2"}, "")
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	})
+
+	t.Run("files are held to the limit of their kind", func(t *testing.T) {
+		// Within the resumable upload limit, but not the one for PDFs
+		w := request("POST", "/api/uploads", adminToken, map[string]string{"Upload-Length": "100", "Upload-Metadata": metadata("notes.pdf", "application/pdf")}, "")
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+		// Limits lowered while an upload was under way apply when it is attached
+		location := create(adminToken, 10, "notes.pdf", "application/pdf")
+		assert.Equal(t, http.StatusNoContent, patch(location, adminToken, 0, "%PDF-1.7\n.", "").Code)
+
+		stricter := *cfg
+		stricter.MaxFileSize = "8"
+		strictService := services.NewUploadService(moduleTestDB, &stricter, services.NewModuleService(moduleTestDB, &stricter, fileStorage))
+		id := location[strings.LastIndex(location, "/")+1:]
+		_, err := strictService.Attach(admin, id, module.ID, services.AuditActor{})
+		assert.ErrorIs(t, err, filecheck.ErrTooLarge)
+		assert.Equal(t, http.StatusNotFound, request("HEAD", location, adminToken, nil, "").Code)
+	})
+
+	t.Run("uploads belong to the user who started them", func(t *testing.T) {
+		location := create(adminToken, 10, "clip.mp4", "video/mp4")
+
+		other := models.User{Username: "otheradmin", Email: "otheradmin@example.com", FirstName: "Other", LastName: "Admin", IsAdmin: true}
+		other.SetPassword("password123")
+		moduleTestDB.Create(&other)
+		otherToken := createModuleUserToken(other)
+
+		assert.Equal(t, http.StatusNotFound, request("HEAD", location, otherToken, nil, "").Code)
+		assert.Equal(t, http.StatusNotFound, patch(location, otherToken, 0, "0123456789", "").Code)
+	})
+
+	t.Run("abandoned uploads expire", func(t *testing.T) {
+		location := create(adminToken, 10, "stale.mp4", "video/mp4")
+		id := location[strings.LastIndex(location, "/")+1:]
+		moduleTestDB.Model(&models.ResumableUpload{}).Where("id = ?", id).Update("expires_at", time.Now().Add(-time.Minute))
+
+		assert.Equal(t, http.StatusNotFound, request("HEAD", location, adminToken, nil, "").Code)
+
+		purged, err := uploadService.PurgeExpired()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, purged)
+		_, err = os.Stat(filepath.Join(cfg.UploadTempDir, id+".part"))
+		assert.True(t, os.IsNotExist(err))
+
+		var count int64
+		moduleTestDB.Model(&models.ResumableUpload{}).Where("id = ?", id).Count(&count)
+		assert.Zero(t, count)
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 	"yonatan/labpro/config"
 	apiAdminUserControllers "yonatan/labpro/controllers/api/admin"
 	"yonatan/labpro/database"
@@ -34,28 +35,27 @@ func setupUserTestDB() {
 	// Set the global database instance
 	database.DB = userTestDB
 
-	// Auto migrate the schema
-	err = userTestDB.AutoMigrate(
-		&models.User{},
-		&models.Course{},
-		&models.Module{},
-		&models.UserCourse{},
-		&models.UserModuleProgress{},
-	)
-	if err != nil {
-		panic("Failed to migrate test database: " + err.Error())
-	}
+	// Build the schema the way deployments do
+	migrateTestDB(userTestDB)
 }
 
 func cleanupUserTestDB() {
 	// Clean up test data in correct order due to foreign key constraints
+	userTestDB.Exec("DELETE FROM archived_module_progresses")
+	userTestDB.Exec("DELETE FROM idempotency_keys")
+	userTestDB.Exec("DELETE FROM transactions")
 	userTestDB.Exec("DELETE FROM user_module_progresses")
 	userTestDB.Exec("DELETE FROM user_courses")
 	userTestDB.Exec("DELETE FROM modules")
 	userTestDB.Exec("DELETE FROM courses")
+	userTestDB.Exec("DELETE FROM instructors")
+	userTestDB.Exec("DELETE FROM user_roles")
 	userTestDB.Exec("DELETE FROM users")
 }
 
+// userTestLoginThrottle is shared with the router so tests can lock accounts
+var userTestLoginThrottle *services.LoginThrottle
+
 func setupUserTestRouter() *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
@@ -64,9 +64,11 @@ func setupUserTestRouter() *gin.Engine {
 
 	// Initialize services
 	userService := services.NewUserService(userTestDB)
+	userTestLoginThrottle = services.NewLoginThrottle(userTestDB, cfg, nil)
+	mfaService := services.NewMFAService(userTestDB)
 
 	// Initialize controllers
-	adminUserController := apiAdminUserControllers.NewUserAPIController(userService)
+	adminUserController := apiAdminUserControllers.NewUserAPIController(userService, userTestLoginThrottle, mfaService)
 
 	api := router.Group("/api")
 	apiRoutes.SetupUserRoutes(api, adminUserController, cfg)
@@ -91,8 +93,11 @@ func createUserTestUser(email, username string, isAdmin bool) models.User {
 func createUserTestToken(user models.User) string {
 	cfg := config.LoadTestWithProjectRoot()
 	authService := services.NewAuthService(cfg)
-	token, _, _ := authService.Login(user.Username, "password123")
-	return token
+	tokens, _, err := authService.Login(user.Username, "password123")
+	if err != nil {
+		return ""
+	}
+	return tokens.AccessToken
 }
 
 func TestGetUsers(t *testing.T) {
@@ -656,3 +661,293 @@ func TestDeleteUser(t *testing.T) {
 	// Cleanup admin user
 	userTestDB.Delete(&adminUser)
 }
+
+func TestLastAdminProtection(t *testing.T) {
+	// Setup
+	setupUserTestDB()
+	defer cleanupUserTestDB()
+	router := setupUserTestRouter()
+	userService := services.NewUserService(userTestDB)
+
+	t.Run("Refuse to delete the only admin regardless of username", func(t *testing.T) {
+		cleanupUserTestDB()
+
+		onlyAdmin := createUserTestUser("owner@test.com", "owner", true)
+
+		err := userService.DeleteUser(onlyAdmin.ID, services.AuditActor{})
+		assert.ErrorIs(t, err, services.ErrLastAdmin)
+
+		var count int64
+		userTestDB.Model(&models.User{}).Where("id = ?", onlyAdmin.ID).Count(&count)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("Allow deleting an admin named admin while another admin remains", func(t *testing.T) {
+		cleanupUserTestDB()
+
+		adminUser := createUserTestUser("admin@test.com", "admin", true)
+		otherAdmin := createUserTestUser("other@test.com", "otheradmin", true)
+
+		req, _ := http.NewRequest("DELETE", "/api/users/"+adminUser.ID, nil)
+		req.Header.Set("Authorization", "Bearer "+createUserTestToken(otherAdmin))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+}
+
+func TestBootstrapAdmin(t *testing.T) {
+	// Setup
+	setupUserTestDB()
+	defer cleanupUserTestDB()
+	router := setupUserTestRouter()
+	userService := services.NewUserService(userTestDB)
+
+	t.Run("Create the first admin with a forced password change", func(t *testing.T) {
+		cleanupUserTestDB()
+
+		admin, err := userService.BootstrapAdmin("bootadmin", "boot@test.com", "password123")
+		assert.NoError(t, err)
+		assert.NotNil(t, admin)
+		assert.True(t, admin.IsAdmin)
+		assert.True(t, admin.MustChangePassword)
+
+		// The flag is part of the insert, so the account is never open without it
+		var stored models.User
+		userTestDB.First(&stored, "id = ?", admin.ID)
+		assert.True(t, stored.MustChangePassword)
+
+		// Other routes stay closed until the password has been changed
+		req, _ := http.NewRequest("GET", "/api/users", nil)
+		req.Header.Set("Authorization", "Bearer "+createUserTestToken(*admin))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		var response map[string]interface{}
+		err = json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "Password change required", response["message"])
+	})
+
+	t.Run("Do nothing once an admin exists", func(t *testing.T) {
+		cleanupUserTestDB()
+
+		createUserTestUser("existing@test.com", "existingadmin", true)
+
+		admin, err := userService.BootstrapAdmin("bootadmin", "boot@test.com", "password123")
+		assert.NoError(t, err)
+		assert.Nil(t, admin)
+
+		var count int64
+		userTestDB.Model(&models.User{}).Where("username = ?", "bootadmin").Count(&count)
+		assert.Equal(t, int64(0), count)
+	})
+
+	t.Run("Flag a legacy admin still using the default password", func(t *testing.T) {
+		cleanupUserTestDB()
+
+		legacy := models.User{Username: "admin", Email: "admin@labpro.com", FirstName: "Admin", LastName: "User", IsAdmin: true}
+		legacy.SetPassword("admin123")
+		userTestDB.Create(&legacy)
+
+		_, err := userService.BootstrapAdmin("", "", "")
+		assert.NoError(t, err)
+
+		userTestDB.First(&legacy, "id = ?", legacy.ID)
+		assert.True(t, legacy.MustChangePassword)
+	})
+}
+
+func TestUnlockUser(t *testing.T) {
+	// Setup
+	setupUserTestDB()
+	defer cleanupUserTestDB()
+	router := setupUserTestRouter()
+
+	t.Run("Admin unlocks a locked account", func(t *testing.T) {
+		cleanupUserTestDB()
+
+		admin := createUserTestUser("admin@test.com", "admin", true)
+		target := createUserTestUser("target@test.com", "target", false)
+		token := createUserTestToken(admin)
+
+		for i := 0; i < 5; i++ {
+			userTestLoginThrottle.RecordFailure("target", "10.0.0.1")
+		}
+		assert.Greater(t, userTestLoginThrottle.LockedFor(target.ID), time.Duration(0))
+
+		req, _ := http.NewRequest("GET", "/api/users/"+target.ID, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		data := response["data"].(map[string]interface{})
+		assert.NotEqual(t, "0", data["login_locked_for"])
+
+		req, _ = http.NewRequest("POST", "/api/users/"+target.ID+"/unlock", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, time.Duration(0), userTestLoginThrottle.LockedFor(target.ID))
+	})
+
+	t.Run("Unlock an unknown user", func(t *testing.T) {
+		cleanupUserTestDB()
+
+		admin := createUserTestUser("admin@test.com", "admin", true)
+
+		req, _ := http.NewRequest("POST", "/api/users/00000000-0000-0000-0000-000000000000/unlock", nil)
+		req.Header.Set("Authorization", "Bearer "+createUserTestToken(admin))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Non-admin cannot unlock", func(t *testing.T) {
+		cleanupUserTestDB()
+
+		user := createUserTestUser("user@test.com", "user", false)
+
+		req, _ := http.NewRequest("POST", "/api/users/"+user.ID+"/unlock", nil)
+		req.Header.Set("Authorization", "Bearer "+createUserTestToken(user))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
+
+func TestTwoFactorEnforcement(t *testing.T) {
+	// Setup
+	setupUserTestDB()
+	defer cleanupUserTestDB()
+	router := setupUserTestRouter()
+
+	var role models.Role
+	userTestDB.Where(models.Role{Name: models.RoleAdmin}).FirstOrCreate(&role)
+	roleService := services.NewRoleService(userTestDB)
+	defer roleService.SetRequireMFA(models.RoleAdmin, false, services.AuditActor{})
+
+	getUsers := func(user models.User) int {
+		req, _ := http.NewRequest("GET", "/api/users", nil)
+		req.Header.Set("Authorization", "Bearer "+createUserTestToken(user))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("Admin without two-factor authentication is refused once the role requires it", func(t *testing.T) {
+		cleanupUserTestDB()
+
+		admin := createUserTestUser("admin@test.com", "admin", true)
+
+		_, err := roleService.SetRequireMFA(models.RoleAdmin, true, services.AuditActor{})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, getUsers(admin))
+
+		now := time.Now()
+		userTestDB.Model(&admin).Update("totp_enabled_at", now)
+		admin.TOTPEnabledAt = &now
+		assert.Equal(t, http.StatusOK, getUsers(admin))
+
+		_, err = roleService.SetRequireMFA(models.RoleAdmin, false, services.AuditActor{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Admin resets the two-factor authentication of a user", func(t *testing.T) {
+		cleanupUserTestDB()
+
+		admin := createUserTestUser("admin@test.com", "admin", true)
+		target := createUserTestUser("target@test.com", "target", false)
+		userTestDB.Model(&target).Updates(map[string]interface{}{"totp_secret": "JBSWY3DPEHPK3PXP", "totp_enabled_at": time.Now()})
+		userTestDB.Create(&models.RecoveryCode{UserID: target.ID, CodeHash: "hash"})
+
+		req, _ := http.NewRequest("POST", "/api/users/"+target.ID+"/mfa/reset", nil)
+		req.Header.Set("Authorization", "Bearer "+createUserTestToken(admin))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		userTestDB.First(&target, "id = ?", target.ID)
+		assert.Nil(t, target.TOTPEnabledAt)
+		assert.Empty(t, target.TOTPSecret)
+
+		var count int64
+		userTestDB.Model(&models.RecoveryCode{}).Where("user_id = ?", target.ID).Count(&count)
+		assert.Equal(t, int64(0), count)
+	})
+}
+
+func TestPersonalAccessTokenScopes(t *testing.T) {
+	// Setup
+	setupUserTestDB()
+	defer cleanupUserTestDB()
+	router := setupUserTestRouter()
+	accessTokenService := services.NewAccessTokenService(userTestDB)
+
+	getUsers := func(token string) int {
+		req, _ := http.NewRequest("GET", "/api/users", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("Token with the users scope acts as its owner", func(t *testing.T) {
+		cleanupUserTestDB()
+
+		admin := createUserTestUser("admin@test.com", "admin", true)
+		token, plain, err := accessTokenService.CreateToken(admin.ID, "sync", []string{models.TokenScopeUsers}, 0)
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, getUsers(plain))
+
+		userTestDB.First(token, "id = ?", token.ID)
+		assert.NotNil(t, token.LastUsedAt)
+	})
+
+	t.Run("Token without the users scope is refused", func(t *testing.T) {
+		cleanupUserTestDB()
+
+		admin := createUserTestUser("admin@test.com", "admin", true)
+		_, plain, err := accessTokenService.CreateToken(admin.ID, "catalogue", []string{models.TokenScopeCourses}, 0)
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusForbidden, getUsers(plain))
+	})
+
+	t.Run("Scope does not grant permissions the owner lacks", func(t *testing.T) {
+		cleanupUserTestDB()
+
+		user := createUserTestUser("user@test.com", "user", false)
+		_, plain, err := accessTokenService.CreateToken(user.ID, "sync", []string{models.TokenScopeUsers}, 0)
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusForbidden, getUsers(plain))
+	})
+
+	t.Run("Revoked and expired tokens are rejected", func(t *testing.T) {
+		cleanupUserTestDB()
+
+		admin := createUserTestUser("admin@test.com", "admin", true)
+		revoked, revokedPlain, _ := accessTokenService.CreateToken(admin.ID, "old", []string{models.TokenScopeUsers}, 0)
+		assert.NoError(t, accessTokenService.RevokeToken(admin.ID, revoked.ID))
+		assert.Equal(t, http.StatusUnauthorized, getUsers(revokedPlain))
+
+		expired, expiredPlain, _ := accessTokenService.CreateToken(admin.ID, "stale", []string{models.TokenScopeUsers}, 1)
+		userTestDB.Model(expired).Update("expires_at", time.Now().Add(-time.Hour))
+		assert.Equal(t, http.StatusUnauthorized, getUsers(expiredPlain))
+
+		assert.Equal(t, http.StatusUnauthorized, getUsers(models.AccessTokenPrefix+"unknown"))
+	})
+}
@@ -0,0 +1,31 @@
+package database
+
+import (
+	"testing"
+	"yonatan/labpro/database"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	t.Run("should load every migration with up and down SQL in order", func(t *testing.T) {
+		migrations, err := database.LoadMigrations()
+		assert.NoError(t, err)
+		assert.NotEmpty(t, migrations)
+
+		for i, migration := range migrations {
+			// Versions are sequential so gaps from lost files are caught early
+			assert.Equal(t, int64(i+1), migration.Version)
+			assert.NotEmpty(t, migration.Name)
+			assert.NotEmpty(t, migration.Up)
+			assert.NotEmpty(t, migration.Down)
+		}
+	})
+
+	t.Run("should start with the baseline migration", func(t *testing.T) {
+		migrations, err := database.LoadMigrations()
+		assert.NoError(t, err)
+		assert.Equal(t, "baseline", migrations[0].Name)
+		assert.Contains(t, migrations[0].Up, "CREATE TABLE IF NOT EXISTS users")
+	})
+}
@@ -0,0 +1,203 @@
+package filecheck
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"yonatan/labpro/config"
+	"yonatan/labpro/filecheck"
+	"yonatan/labpro/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eicar is the standard antivirus test string
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// fakeClamd answers clamd's INSTREAM command like the real daemon, reporting
+// files that contain the EICAR string as infected. It returns the address to
+// give NewClamAV.
+func fakeClamd(t *testing.T, network, address string) string {
+	listener, err := net.Listen(network, address)
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				command := make([]byte, len("zINSTREAM\x00"))
+				if _, err := io.ReadFull(conn, command); err != nil || string(command) != "zINSTREAM\x00" {
+					conn.Write([]byte("UNKNOWN COMMAND\x00"))
+					return
+				}
+				var data bytes.Buffer
+				for {
+					var size uint32
+					if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+						return
+					}
+					if size == 0 {
+						break
+					}
+					if _, err := io.CopyN(&data, conn, int64(size)); err != nil {
+						return
+					}
+				}
+				if strings.Contains(data.String(), "EICAR-STANDARD-ANTIVIRUS-TEST-FILE") {
+					conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+					return
+				}
+				conn.Write([]byte("stream: OK\x00"))
+			}(conn)
+		}
+	}()
+
+	if network == "unix" {
+		return "unix://" + address
+	}
+	return listener.Addr().String()
+}
+
+func pdf(body string) *bytes.Reader {
+	return bytes.NewReader([]byte("%PDF-1.7\n" + body))
+}
+
+func TestCheckContent(t *testing.T) {
+	validator := filecheck.NewValidator(1<<20, 10<<20, nil)
+	ctx := context.Background()
+
+	t.Run("the content type comes from the content", func(t *testing.T) {
+		file, err := validator.CheckContent(ctx, models.MediaKindPDF, "notes.PDF", pdf("notes"))
+		require.NoError(t, err)
+		assert.Equal(t, "application/pdf", file.ContentType)
+		assert.True(t, strings.HasSuffix(file.Name, "_notes.pdf"))
+
+		mp4 := bytes.NewReader([]byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom"))
+		file, err = validator.CheckContent(ctx, models.MediaKindVideo, "intro.mp4", mp4)
+		require.NoError(t, err)
+		assert.Equal(t, "video/mp4", file.ContentType)
+
+		png := bytes.NewReader([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"))
+		file, err = validator.CheckContent(ctx, models.MediaKindThumbnail, "cover.png", png)
+		require.NoError(t, err)
+		assert.Equal(t, "image/png", file.ContentType)
+	})
+
+	t.Run("the reader is rewound for storing", func(t *testing.T) {
+		r := pdf("notes")
+		_, err := validator.CheckContent(ctx, models.MediaKindPDF, "notes.pdf", r)
+		require.NoError(t, err)
+		data, _ := io.ReadAll(r)
+		assert.Equal(t, "%PDF-1.7\nnotes", string(data))
+	})
+
+	t.Run("extensions outside the kind's list are refused", func(t *testing.T) {
+		for _, name := range []string{"notes.exe", "notes", "notes.pdf.html", "cover.svg"} {
+			_, err := validator.CheckContent(ctx, models.MediaKindPDF, name, pdf(""))
+			assert.ErrorIs(t, err, filecheck.ErrExtensionNotAllowed, name)
+			assert.True(t, filecheck.IsRejected(err))
+		}
+		_, err := validator.CheckContent(ctx, models.MediaKindThumbnail, "cover.svg", bytes.NewReader([]byte("<svg onload=alert(1)>")))
+		assert.ErrorIs(t, err, filecheck.ErrExtensionNotAllowed)
+	})
+
+	t.Run("content that does not match the extension is refused", func(t *testing.T) {
+		_, err := validator.CheckContent(ctx, models.MediaKindPDF, "notes.pdf", bytes.NewReader([]byte("<html><script>")))
+		assert.ErrorIs(t, err, filecheck.ErrContentMismatch)
+
+		// A PNG renamed to .jpg is not a JPEG
+		_, err = validator.CheckContent(ctx, models.MediaKindThumbnail, "cover.jpg", bytes.NewReader([]byte("\x89PNG\r\n\x1a\n")))
+		assert.ErrorIs(t, err, filecheck.ErrContentMismatch)
+
+		_, err = validator.CheckContent(ctx, models.MediaKindVideo, "intro.mp4", bytes.NewReader(nil))
+		assert.ErrorIs(t, err, filecheck.ErrContentMismatch)
+	})
+}
+
+func TestCheckSize(t *testing.T) {
+	validator := filecheck.New(&config.Config{MaxFileSize: "1024", MaxVideoSize: "4096"})
+
+	assert.NoError(t, validator.CheckSize(models.MediaKindPDF, 1024))
+	assert.ErrorIs(t, validator.CheckSize(models.MediaKindPDF, 1025), filecheck.ErrTooLarge)
+	assert.ErrorIs(t, validator.CheckSize(models.MediaKindThumbnail, 2048), filecheck.ErrTooLarge)
+	assert.NoError(t, validator.CheckSize(models.MediaKindVideo, 4096))
+	assert.ErrorIs(t, validator.CheckSize(models.MediaKindVideo, 4097), filecheck.ErrTooLarge)
+
+	defaults := filecheck.New(&config.Config{MaxFileSize: "lots"})
+	assert.Equal(t, int64(10<<20), defaults.MaxSize(models.MediaKindPDF))
+	assert.Equal(t, int64(100<<20), defaults.MaxSize(models.MediaKindVideo))
+}
+
+func TestSafeName(t *testing.T) {
+	for name, stem := range map[string]string{
+		"notes.pdf":              "_notes.pdf",
+		"../../etc/passwd.pdf":   "_passwd.pdf",
+		`..\..\windows\evil.pdf`: "_evil.pdf",
+		"my lecture (v2).pdf":    "_my-lecture-v2.pdf",
+		"\x00.pdf":               "_file.pdf",
+		"....pdf":                "_file.pdf",
+	} {
+		safe := filecheck.SafeName(name, ".pdf")
+		assert.True(t, strings.HasSuffix(safe, stem), "%q became %q", name, safe)
+		assert.NotContains(t, safe, "/")
+		assert.NotContains(t, safe, "..")
+	}
+
+	assert.NotEqual(t, filecheck.SafeName("notes.pdf", ".pdf"), filecheck.SafeName("notes.pdf", ".pdf"))
+	assert.LessOrEqual(t, len(filecheck.SafeName(strings.Repeat("a", 300)+".pdf", ".pdf")), 17+64+4)
+}
+
+func TestClamAVScanning(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("over TCP", func(t *testing.T) {
+		scanner := filecheck.NewClamAV(fakeClamd(t, "tcp", "127.0.0.1:0"))
+		validator := filecheck.NewValidator(1<<20, 10<<20, scanner)
+
+		_, err := validator.CheckContent(ctx, models.MediaKindPDF, "notes.pdf", pdf("clean notes"))
+		assert.NoError(t, err)
+
+		_, err = validator.CheckContent(ctx, models.MediaKindPDF, "notes.pdf", pdf(eicar))
+		assert.ErrorIs(t, err, filecheck.ErrInfected)
+		assert.Contains(t, err.Error(), "Eicar-Test-Signature")
+		assert.True(t, filecheck.IsRejected(err))
+
+		// Files bigger than one INSTREAM chunk are streamed in pieces
+		large := strings.Repeat("x", 200<<10) + eicar
+		assert.ErrorIs(t, scanner.Scan(ctx, strings.NewReader(large)), filecheck.ErrInfected)
+	})
+
+	t.Run("over a unix socket", func(t *testing.T) {
+		dir, err := os.MkdirTemp("", "clamd")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		scanner := filecheck.NewClamAV(fakeClamd(t, "unix", filepath.Join(dir, "clamd.sock")))
+		assert.NoError(t, scanner.Scan(ctx, strings.NewReader("clean")))
+		assert.ErrorIs(t, scanner.Scan(ctx, strings.NewReader(eicar)), filecheck.ErrInfected)
+	})
+
+	t.Run("an unreachable scanner fails closed", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		address := listener.Addr().String()
+		listener.Close()
+
+		validator := filecheck.New(&config.Config{ClamAVAddress: "tcp://" + address})
+		_, err = validator.CheckContent(ctx, models.MediaKindPDF, "notes.pdf", pdf("notes"))
+		assert.Error(t, err)
+		assert.False(t, filecheck.IsRejected(err))
+	})
+}
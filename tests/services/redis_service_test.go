@@ -0,0 +1,147 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"yonatan/labpro/config"
+	"yonatan/labpro/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyRedis is a stand-in Redis server that answers the connection check and
+// then goes away, the way a Redis outage looks to a running instance
+type flakyRedis struct {
+	listener net.Listener
+	mu       sync.Mutex
+	conns    []net.Conn
+}
+
+func startFlakyRedis(t *testing.T) *flakyRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &flakyRedis{listener: listener}
+	go server.serve()
+	t.Cleanup(server.stop)
+	return server
+}
+
+func (s *flakyRedis) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns = append(s.conns, conn)
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+}
+
+// handle reads RESP commands and answers just enough for a client to connect
+func (s *flakyRedis) handle(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		var count int
+		if _, err := fmt.Sscanf(line, "*%d", &count); err != nil {
+			return
+		}
+		var args []string
+		for i := 0; i < count; i++ {
+			if _, err := reader.ReadString('\n'); err != nil {
+				return
+			}
+			arg, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			args = append(args, strings.TrimRight(arg, "\r\n"))
+		}
+
+		switch strings.ToUpper(args[0]) {
+		case "PING":
+			conn.Write([]byte("+PONG\r\n"))
+		case "HELLO":
+			conn.Write([]byte("-ERR unknown command 'HELLO'\r\n"))
+		default:
+			conn.Write([]byte("+OK\r\n"))
+		}
+	}
+}
+
+func (s *flakyRedis) stop() {
+	s.listener.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+}
+
+func TestRedisCounters(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("counters fall back to memory when Redis fails", func(t *testing.T) {
+		server := startFlakyRedis(t)
+		redisService := services.NewRedisService(server.listener.Addr().String(), "")
+		defer redisService.Close()
+		require.True(t, redisService.IsAvailable())
+		server.stop()
+
+		for want := int64(1); want <= 3; want++ {
+			value, err := redisService.IncrementCounter(ctx, "login:failures:account:user:1", time.Minute)
+			require.NoError(t, err)
+			assert.Equal(t, want, value)
+		}
+
+		require.NoError(t, redisService.SetCounter(ctx, "login:block:account:user:1", 3, time.Minute))
+		value, ttl, err := redisService.GetCounter(ctx, "login:block:account:user:1")
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), value)
+		assert.Greater(t, ttl, 50*time.Second)
+	})
+
+	t.Run("in-memory counters are capped", func(t *testing.T) {
+		redisService := &services.RedisService{}
+
+		_, err := redisService.IncrementCounter(ctx, "login:failures:ip:first", time.Second)
+		require.NoError(t, err)
+		for i := 0; i < 100_000; i++ {
+			_, err := redisService.IncrementCounter(ctx, fmt.Sprintf("login:failures:ip:%d", i), time.Hour)
+			require.NoError(t, err)
+		}
+
+		// The counter closest to expiring made room for the newest
+		value, _, _ := redisService.GetCounter(ctx, "login:failures:ip:first")
+		assert.Zero(t, value)
+		value, _, _ = redisService.GetCounter(ctx, "login:failures:ip:99999")
+		assert.Equal(t, int64(1), value)
+	})
+
+	t.Run("a throttle keeps locking accounts while Redis fails", func(t *testing.T) {
+		server := startFlakyRedis(t)
+		redisService := services.NewRedisService(server.listener.Addr().String(), "")
+		defer redisService.Close()
+		server.stop()
+
+		throttle := services.NewLoginThrottle(nil, &config.Config{}, redisService)
+		var wait time.Duration
+		for i := 0; i < 5; i++ {
+			wait = throttle.RecordUserFailure("1", "203.0.113.7")
+		}
+		assert.Equal(t, 15*time.Minute, wait)
+		assert.Greater(t, throttle.CheckUser("1", "203.0.113.7"), 14*time.Minute)
+	})
+}
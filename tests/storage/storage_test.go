@@ -0,0 +1,250 @@
+package storage
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"yonatan/labpro/config"
+	"yonatan/labpro/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 is a minimal in-memory S3-compatible server in the spirit of MinIO.
+// It only accepts path-style requests that carry a SigV4 signature.
+type fakeS3 struct {
+	mu      sync.Mutex
+	bucket  string
+	objects map[string]fakeObject
+}
+
+type fakeObject struct {
+	data        []byte
+	contentType string
+	modTime     time.Time
+}
+
+func newFakeS3(bucket string) *httptest.Server {
+	fake := &fakeS3{bucket: bucket, objects: map[string]fakeObject{}}
+	return httptest.NewServer(fake)
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	signed := strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=") ||
+		(r.URL.Query().Get("X-Amz-Algorithm") == "AWS4-HMAC-SHA256" && r.URL.Query().Get("X-Amz-Signature") != "")
+	if !signed {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+		return
+	}
+
+	if r.Method == http.MethodGet && r.URL.Path == "/"+f.bucket && r.URL.Query().Get("list-type") == "2" {
+		f.list(w, r.URL.Query().Get("prefix"))
+		return
+	}
+
+	prefix := "/" + f.bucket + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, prefix)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[key] = fakeObject{data: data, contentType: r.Header.Get("Content-Type"), modTime: time.Now()}
+	case http.MethodGet, http.MethodHead:
+		object, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", object.contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(object.data)))
+		w.Header().Set("Last-Modified", object.modTime.UTC().Format(http.TimeFormat))
+		if r.Method == http.MethodGet {
+			w.Write(object.data)
+		}
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// list answers ListObjectsV2 in a single page
+func (f *fakeS3) list(w http.ResponseWriter, prefix string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	result := struct {
+		XMLName     xml.Name `xml:"ListBucketResult"`
+		IsTruncated bool
+		Contents    []struct {
+			Key          string
+			Size         int
+			LastModified string
+		}
+	}{}
+	for key, object := range f.objects {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		result.Contents = append(result.Contents, struct {
+			Key          string
+			Size         int
+			LastModified string
+		}{key, len(object.data), object.modTime.UTC().Format(time.RFC3339)})
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(result)
+}
+
+// exerciseBackend runs the Put/Stat/Get/List/Delete round trip every backend must support
+func exerciseBackend(t *testing.T, backend storage.Backend) {
+	ctx := context.Background()
+	content := "%PDF-1.4 module notes"
+
+	object, err := backend.Put(ctx, "pdfs/1_notes.pdf", strings.NewReader(content), int64(len(content)), "application/pdf")
+	require.NoError(t, err)
+	assert.Equal(t, "pdfs/1_notes.pdf", object.Key)
+	assert.Equal(t, int64(len(content)), object.Size)
+	assert.True(t, strings.HasSuffix(object.URL, "/pdfs/1_notes.pdf"))
+
+	stat, err := backend.Stat(ctx, "pdfs/1_notes.pdf")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(content)), stat.Size)
+	assert.Equal(t, "application/pdf", stat.ContentType)
+
+	reader, _, err := backend.Get(ctx, "pdfs/1_notes.pdf")
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	require.NoError(t, err)
+	assert.Equal(t, content, string(data))
+
+	_, err = backend.Put(ctx, "videos/1_intro.mp4", strings.NewReader("video"), -1, "video/mp4")
+	require.NoError(t, err)
+	listed, err := backend.List(ctx, "pdfs/")
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, "pdfs/1_notes.pdf", listed[0].Key)
+	assert.Equal(t, object.URL, listed[0].URL)
+	listed, err = backend.List(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, listed, 2)
+	require.NoError(t, backend.Delete(ctx, "videos/1_intro.mp4"))
+
+	signedURL, err := backend.SignedURL(ctx, "pdfs/1_notes.pdf", time.Minute)
+	require.NoError(t, err)
+	assert.NotEmpty(t, signedURL)
+
+	require.NoError(t, backend.Delete(ctx, "pdfs/1_notes.pdf"))
+	_, err = backend.Stat(ctx, "pdfs/1_notes.pdf")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	// Deleting twice is fine
+	assert.NoError(t, backend.Delete(ctx, "pdfs/1_notes.pdf"))
+
+	for _, key := range []string{"", "/etc/passwd", "../secret.txt", "pdfs/../../secret.txt", "pdfs\\notes.pdf"} {
+		_, err := backend.Put(ctx, key, strings.NewReader(content), int64(len(content)), "")
+		assert.ErrorIs(t, err, storage.ErrInvalidKey, key)
+	}
+}
+
+func TestLocalBackend(t *testing.T) {
+	exerciseBackend(t, storage.NewLocal(t.TempDir(), "http://localhost:8080/uploads"))
+
+	t.Run("listing an empty upload directory finds nothing", func(t *testing.T) {
+		backend := storage.NewLocal(t.TempDir()+"/missing", "http://localhost:8080/uploads")
+		listed, err := backend.List(context.Background(), "")
+		assert.NoError(t, err)
+		assert.Empty(t, listed)
+	})
+}
+
+func TestS3Backend(t *testing.T) {
+	server := newFakeS3("labpro")
+	defer server.Close()
+
+	backend, err := storage.NewS3(storage.S3Config{
+		Endpoint:        server.URL,
+		Bucket:          "labpro",
+		AccessKeyID:     "minio",
+		SecretAccessKey: "minio-secret",
+		PathStyle:       true,
+	})
+	require.NoError(t, err)
+
+	exerciseBackend(t, backend)
+
+	t.Run("signed URLs can be fetched without credentials", func(t *testing.T) {
+		ctx := context.Background()
+		_, err := backend.Put(ctx, "videos/1_intro.mp4", strings.NewReader("video"), -1, "")
+		require.NoError(t, err)
+
+		signedURL, err := backend.SignedURL(ctx, "videos/1_intro.mp4", time.Minute)
+		require.NoError(t, err)
+		assert.Contains(t, signedURL, "X-Amz-Expires=60")
+
+		resp, err := http.Get(signedURL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "video", string(data))
+		assert.Equal(t, "video/mp4", resp.Header.Get("Content-Type"))
+	})
+
+	t.Run("public URL replaces the bucket address", func(t *testing.T) {
+		withCDN, err := storage.NewS3(storage.S3Config{
+			Endpoint:        server.URL,
+			Bucket:          "labpro",
+			AccessKeyID:     "minio",
+			SecretAccessKey: "minio-secret",
+			PublicURL:       "https://cdn.example.com/",
+			PathStyle:       true,
+		})
+		require.NoError(t, err)
+
+		object, err := withCDN.Put(context.Background(), "thumbnails/1_cover image.png", strings.NewReader("png"), 3, "image/png")
+		require.NoError(t, err)
+		assert.Equal(t, "https://cdn.example.com/thumbnails/1_cover%20image.png", object.URL)
+	})
+}
+
+func TestNewSelectsDriver(t *testing.T) {
+	cfg := &config.Config{UploadPath: t.TempDir(), BaseURL: "http://localhost:8080"}
+
+	backend, err := storage.New(cfg)
+	require.NoError(t, err)
+	assert.IsType(t, &storage.Local{}, backend)
+
+	cfg.StorageDriver = storage.DriverS3
+	_, err = storage.New(cfg)
+	assert.Error(t, err, "S3 without a bucket is rejected")
+
+	cfg.S3Endpoint = "http://localhost:9000"
+	cfg.S3Bucket = "labpro"
+	cfg.S3AccessKeyID = "minio"
+	cfg.S3SecretAccessKey = "minio-secret"
+	backend, err = storage.New(cfg)
+	require.NoError(t, err)
+	assert.IsType(t, &storage.S3{}, backend)
+
+	cfg.StorageDriver = "ftp"
+	_, err = storage.New(cfg)
+	assert.Error(t, err)
+}
@@ -0,0 +1,197 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"yonatan/labpro/filecheck"
+	"yonatan/labpro/thumbnail"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	red   = color.RGBA{255, 0, 0, 255}
+	blue  = color.RGBA{0, 0, 255, 255}
+	green = color.RGBA{0, 255, 0, 255}
+)
+
+// stripes returns an image split into bands of the given colors, side by side
+// when horizontal and stacked otherwise
+func stripes(width, height int, horizontal bool, colors ...color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			band := y * len(colors) / height
+			if horizontal {
+				band = x * len(colors) / width
+			}
+			img.Set(x, y, colors[band])
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+// jpegWithOrientation encodes img as a JPEG carrying an EXIF orientation tag
+func jpegWithOrientation(t *testing.T, img image.Image, orientation uint16) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+
+	var tiff bytes.Buffer
+	tiff.WriteString("MM\x00\x2a")
+	binary.Write(&tiff, binary.BigEndian, uint32(8))
+	binary.Write(&tiff, binary.BigEndian, uint16(1))
+	binary.Write(&tiff, binary.BigEndian, []uint16{0x0112, 3})
+	binary.Write(&tiff, binary.BigEndian, uint32(1))
+	binary.Write(&tiff, binary.BigEndian, []uint16{orientation, 0})
+	binary.Write(&tiff, binary.BigEndian, uint32(0))
+	payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+
+	app1 := []byte{0xff, 0xe1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(payload)+2))
+	data := append([]byte{0xff, 0xd8}, app1...)
+	data = append(data, payload...)
+	return append(data, buf.Bytes()[2:]...)
+}
+
+func decode(t *testing.T, data []byte) image.Image {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	return img
+}
+
+func near(t *testing.T, want color.RGBA, got color.Color, msg string) {
+	r, g, b, _ := got.RGBA()
+	diff := func(a uint8, b uint32) int {
+		d := int(a) - int(b>>8)
+		if d < 0 {
+			d = -d
+		}
+		return d
+	}
+	assert.True(t, diff(want.R, r) < 60 && diff(want.G, g) < 60 && diff(want.B, b) < 60, "%s: want %v, got %v", msg, want, got)
+}
+
+func find(images []thumbnail.Image, size thumbnail.Size, format string) *thumbnail.Image {
+	for i := range images {
+		if images[i].Size == size && images[i].Format == format {
+			return &images[i]
+		}
+	}
+	return nil
+}
+
+// fakeFFmpeg writes a stand-in for ffmpeg that answers with a WebP header, or
+// fails when broken is set
+func fakeFFmpeg(t *testing.T, broken bool) string {
+	path := filepath.Join(t.TempDir(), "ffmpeg")
+	script := "#!/bin/sh\ncat > /dev/null\nprintf 'RIFF\\000\\000\\000\\000WEBPVP8 '\n"
+	if broken {
+		script = "#!/bin/sh\necho 'Unknown encoder libwebp' >&2\nexit 1\n"
+	}
+	require.NoError(t, os.WriteFile(path, []byte(script), 0755))
+	return path
+}
+
+func TestRender(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("every size is rendered at its exact dimensions", func(t *testing.T) {
+		images, err := thumbnail.NewRenderer("").Render(ctx, encodePNG(t, stripes(1600, 1200, false, red)))
+		require.NoError(t, err)
+		require.Len(t, images, len(thumbnail.Sizes))
+
+		for _, size := range thumbnail.Sizes {
+			image := find(images, size, thumbnail.FormatJPEG)
+			require.NotNil(t, image, size.Name)
+			assert.Equal(t, "image/jpeg", image.ContentType)
+			assert.Equal(t, ".jpg", image.Ext())
+			bounds := decode(t, image.Data).Bounds()
+			assert.Equal(t, size.Width, bounds.Dx(), size.Name)
+			assert.Equal(t, size.Height, bounds.Dy(), size.Name)
+		}
+	})
+
+	t.Run("images are cropped around the center", func(t *testing.T) {
+		// A tall image whose middle third is all a 16:9 crop keeps
+		images, err := thumbnail.NewRenderer("").Render(ctx, encodePNG(t, stripes(640, 1080, false, red, blue, green)))
+		require.NoError(t, err)
+
+		card := decode(t, find(images, thumbnail.Card, thumbnail.FormatJPEG).Data)
+		near(t, blue, card.At(5, 5), "top left")
+		near(t, blue, card.At(320, 180), "center")
+		near(t, blue, card.At(634, 354), "bottom right")
+	})
+
+	t.Run("EXIF orientation is applied and then dropped", func(t *testing.T) {
+		// Stored sideways: turning it clockwise puts the red half on top
+		data := jpegWithOrientation(t, stripes(1440, 720, true, red, blue), 6)
+		images, err := thumbnail.NewRenderer("").Render(ctx, data)
+		require.NoError(t, err)
+
+		card := find(images, thumbnail.Card, thumbnail.FormatJPEG)
+		assert.False(t, bytes.Contains(card.Data, []byte("Exif")))
+		img := decode(t, card.Data)
+		near(t, red, img.At(320, 20), "top")
+		near(t, blue, img.At(320, 340), "bottom")
+
+		// Upright images are left as they are
+		images, err = thumbnail.NewRenderer("").Render(ctx, jpegWithOrientation(t, stripes(1440, 720, true, red, blue), 1))
+		require.NoError(t, err)
+		img = decode(t, find(images, thumbnail.Card, thumbnail.FormatJPEG).Data)
+		near(t, red, img.At(20, 340), "left")
+		near(t, blue, img.At(620, 340), "right")
+	})
+
+	t.Run("transparency becomes white", func(t *testing.T) {
+		images, err := thumbnail.NewRenderer("").Render(ctx, encodePNG(t, image.NewNRGBA(image.Rect(0, 0, 1280, 720))))
+		require.NoError(t, err)
+		near(t, color.RGBA{255, 255, 255, 255}, decode(t, images[0].Data).At(100, 100), "transparent")
+	})
+
+	t.Run("images that are too small or unreadable are refused", func(t *testing.T) {
+		_, err := thumbnail.NewRenderer("").Render(ctx, encodePNG(t, stripes(600, 400, false, red)))
+		assert.ErrorIs(t, err, filecheck.ErrImageTooSmall)
+		assert.True(t, filecheck.IsRejected(err))
+
+		// Large enough once turned, but not as stored
+		_, err = thumbnail.NewRenderer("").Render(ctx, jpegWithOrientation(t, stripes(700, 400, false, red), 8))
+		assert.ErrorIs(t, err, filecheck.ErrImageTooSmall)
+
+		_, err = thumbnail.NewRenderer("").Render(ctx, []byte("\xff\xd8\xff\xe0 not really a jpeg"))
+		assert.ErrorIs(t, err, filecheck.ErrContentMismatch)
+	})
+
+	t.Run("WebP comes from ffmpeg when it can", func(t *testing.T) {
+		images, err := thumbnail.NewRenderer(fakeFFmpeg(t, false)).Render(ctx, encodePNG(t, stripes(1600, 1200, false, red)))
+		require.NoError(t, err)
+		require.Len(t, images, 2*len(thumbnail.Sizes))
+		for _, size := range thumbnail.Sizes {
+			webp := find(images, size, thumbnail.FormatWebP)
+			require.NotNil(t, webp, size.Name)
+			assert.Equal(t, "image/webp", webp.ContentType)
+			assert.Equal(t, ".webp", webp.Ext())
+			assert.True(t, bytes.HasPrefix(webp.Data, []byte("RIFF")))
+		}
+
+		// Without a working encoder the thumbnail is still stored as JPEG
+		images, err = thumbnail.NewRenderer(fakeFFmpeg(t, true)).Render(ctx, encodePNG(t, stripes(1600, 1200, false, red)))
+		require.NoError(t, err)
+		assert.Len(t, images, len(thumbnail.Sizes))
+		assert.Nil(t, find(images, thumbnail.Card, thumbnail.FormatWebP))
+	})
+}
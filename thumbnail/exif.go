@@ -0,0 +1,126 @@
+package thumbnail
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+)
+
+// exifOrientationTag is the EXIF tag cameras use to say how the sensor was held
+const exifOrientationTag = 0x0112
+
+// orientation reads the EXIF orientation of a JPEG file, from 1 (upright) to
+// 8. Other formats and files without the tag are taken as upright.
+func orientation(data []byte) int {
+	if !bytes.HasPrefix(data, []byte{0xff, 0xd8}) {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xff {
+			return 1
+		}
+		marker := data[i+1]
+		switch {
+		case marker == 0xff:
+			// Fill byte before a marker
+			i++
+			continue
+		case marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7):
+			// Markers without a segment
+			i += 2
+			continue
+		case marker == 0xda || marker == 0xd9:
+			// EXIF comes before the image data
+			return 1
+		}
+
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// tiffOrientation finds the orientation tag in the first IFD of EXIF data
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for n := 0; n < entries; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) != exifOrientationTag {
+			continue
+		}
+		// A SHORT value sits in the first bytes of the value field
+		value := int(order.Uint16(tiff[entry+8:]))
+		if value < 1 || value > 8 {
+			return 1
+		}
+		return value
+	}
+	return 1
+}
+
+// orient turns an image the way its EXIF orientation asks, so it displays
+// upright once the EXIF data is gone
+func orient(src image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	// Orientations 5 to 8 are turned a quarter, swapping width and height
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	if orientation >= 5 {
+		dst = image.NewRGBA(image.Rect(0, 0, height, width))
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // flip horizontally
+				dx, dy = width-1-x, y
+			case 3: // turn half way
+				dx, dy = width-1-x, height-1-y
+			case 4: // flip vertically
+				dx, dy = x, height-1-y
+			case 5: // transpose
+				dx, dy = y, x
+			case 6: // turn clockwise
+				dx, dy = height-1-y, x
+			case 7: // transverse
+				dx, dy = height-1-y, width-1-x
+			case 8: // turn counterclockwise
+				dx, dy = y, width-1-x
+			}
+			dst.Set(dx, dy, src.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,158 @@
+// Package thumbnail turns an uploaded course image into the fixed set of sizes
+// the site shows: a card for listings, a larger detail image and a social
+// preview for link unfurls. Every size is cropped around the center to its
+// aspect ratio and encoded afresh, so EXIF and other metadata never survive.
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"log"
+	"yonatan/labpro/config"
+	"yonatan/labpro/filecheck"
+
+	// Decoders for the formats filecheck accepts as thumbnails
+	_ "image/gif"
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Size is one rendition of a thumbnail
+type Size struct {
+	Name   string
+	Width  int
+	Height int
+}
+
+// Card and detail share an aspect ratio so they can be offered together in a
+// srcset; social matches the 1.91:1 ratio link previews use
+var (
+	Card   = Size{Name: "card", Width: 640, Height: 360}
+	Detail = Size{Name: "detail", Width: 1280, Height: 720}
+	Social = Size{Name: "social", Width: 1200, Height: 630}
+)
+
+// Sizes lists every size a thumbnail is rendered in
+var Sizes = []Size{Card, Detail, Social}
+
+// Formats thumbnails are encoded in
+const (
+	FormatJPEG = "jpeg"
+	FormatWebP = "webp"
+)
+
+// maxPixels keeps decoding from taking unbounded memory; a small file can
+// still claim enormous dimensions
+const maxPixels = 50_000_000
+
+const jpegQuality = 85
+
+// Image is one size of a thumbnail in one format
+type Image struct {
+	Size
+	Format      string
+	ContentType string
+	Data        []byte
+}
+
+// Ext returns the file extension for the image's format
+func (i Image) Ext() string {
+	if i.Format == FormatWebP {
+		return ".webp"
+	}
+	return ".jpg"
+}
+
+// Renderer resizes thumbnails. JPEG is encoded in process; WebP is left to
+// ffmpeg, which is already needed for videos.
+type Renderer struct {
+	ffmpegPath string
+}
+
+// New returns a renderer that encodes WebP with the configured ffmpeg
+func New(cfg *config.Config) *Renderer {
+	return NewRenderer(cfg.FFmpegPath)
+}
+
+// NewRenderer returns a renderer that runs ffmpegPath for WebP. With an empty
+// path only JPEG is produced.
+func NewRenderer(ffmpegPath string) *Renderer {
+	return &Renderer{ffmpegPath: ffmpegPath}
+}
+
+// Render decodes an image and returns it in every size as JPEG, followed by
+// the same sizes as WebP when ffmpeg could encode them. WebP is best effort:
+// browsers fall back to JPEG, so a missing or limited ffmpeg only costs bytes.
+// Images too small for the card size give an error wrapping
+// filecheck.ErrImageTooSmall.
+func (r *Renderer) Render(ctx context.Context, data []byte) ([]Image, error) {
+	header, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: the image cannot be read", filecheck.ErrContentMismatch)
+	}
+	if header.Width*header.Height > maxPixels {
+		return nil, fmt.Errorf("%w: images may have at most %d megapixels", filecheck.ErrTooLarge, maxPixels/1_000_000)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: the image cannot be read", filecheck.ErrContentMismatch)
+	}
+	src = orient(src, orientation(data))
+
+	bounds := src.Bounds()
+	if bounds.Dx() < Card.Width || bounds.Dy() < Card.Height {
+		return nil, fmt.Errorf("%w: thumbnails must be at least %dx%d pixels", filecheck.ErrImageTooSmall, Card.Width, Card.Height)
+	}
+
+	resized := make([]image.Image, len(Sizes))
+	images := make([]Image, 0, 2*len(Sizes))
+	for i, size := range Sizes {
+		resized[i] = fit(src, size)
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, resized[i], &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+		images = append(images, Image{Size: size, Format: FormatJPEG, ContentType: "image/jpeg", Data: buf.Bytes()})
+	}
+
+	if r.ffmpegPath == "" {
+		return images, nil
+	}
+	webp := make([]Image, 0, len(Sizes))
+	for i, size := range Sizes {
+		data, err := r.encodeWebP(ctx, resized[i])
+		if err != nil {
+			// A partial set would leave holes in the srcset
+			log.Printf("Thumbnail: Skipping WebP: %v", err)
+			return images, nil
+		}
+		webp = append(webp, Image{Size: size, Format: FormatWebP, ContentType: "image/webp", Data: data})
+	}
+	return append(images, webp...), nil
+}
+
+// fit crops src around its center to the aspect ratio of size and scales the
+// crop to exactly that size
+func fit(src image.Image, size Size) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width*size.Height > height*size.Width {
+		width = height * size.Width / size.Height
+	} else {
+		height = width * size.Height / size.Width
+	}
+	x := bounds.Min.X + (bounds.Dx()-width)/2
+	y := bounds.Min.Y + (bounds.Dy()-height)/2
+
+	dst := image.NewRGBA(image.Rect(0, 0, size.Width, size.Height))
+	// Transparent areas turn white rather than the black JPEG would give them
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, image.Rect(x, y, x+width, y+height), draw.Over, nil)
+	return dst
+}
@@ -0,0 +1,50 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// webpTimeout bounds one ffmpeg run; thumbnails encode in well under a second
+const webpTimeout = 30 * time.Second
+
+// webpQuality is libwebp's lossy quality, from 0 to 100
+const webpQuality = "80"
+
+// encodeWebP pipes an image through ffmpeg's libwebp encoder. The image goes
+// in as uncompressed PNG, so nothing is lost on the way.
+func (r *Renderer) encodeWebP(ctx context.Context, img image.Image) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, webpTimeout)
+	defer cancel()
+
+	var in bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.NoCompression}
+	if err := encoder.Encode(&in, img); err != nil {
+		return nil, err
+	}
+
+	var out, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, r.ffmpegPath,
+		"-hide_banner", "-loglevel", "error",
+		"-f", "png_pipe", "-i", "pipe:0",
+		"-c:v", "libwebp", "-quality", webpQuality,
+		"-f", "webp", "pipe:1")
+	cmd.Stdin = &in
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	data := out.Bytes()
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, fmt.Errorf("ffmpeg did not produce a WebP image")
+	}
+	return data, nil
+}